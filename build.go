@@ -0,0 +1,280 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/rubex"
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// BuildSystem is the content of a .sublime-build file or of an
+	// entry in the project build_systems.
+	BuildSystem struct {
+		Name       string            `json:"name,omitempty"`
+		Cmd        []string          `json:"cmd,omitempty"`
+		ShellCmd   string            `json:"shell_cmd,omitempty"`
+		WorkingDir string            `json:"working_dir,omitempty"`
+		FileRegex  string            `json:"file_regex,omitempty"`
+		LineRegex  string            `json:"line_regex,omitempty"`
+		Selector   string            `json:"selector,omitempty"`
+		Env        map[string]string `json:"env,omitempty"`
+		Variants   []*BuildSystem    `json:"variants,omitempty"`
+		// Platform specific overrides
+		Windows *BuildSystem `json:"windows,omitempty"`
+		OSX     *BuildSystem `json:"osx,omitempty"`
+		Linux   *BuildSystem `json:"linux,omitempty"`
+	}
+
+	// BuildResult is a location found in the build output
+	// by the file and line regexes.
+	BuildResult struct {
+		File    string
+		Line    int
+		Column  int
+		Message string
+		// The region of the output line in the panel
+		Region text.Region
+	}
+)
+
+// Variant returns the build system with the overrides of the named
+// variant and of the current platform applied. The base build system
+// is returned for an empty name.
+func (b *BuildSystem) Variant(name string) (*BuildSystem, error) {
+	ret := &BuildSystem{}
+	ret.merge(b)
+	ret.merge(b.platform())
+	if name == "" {
+		return ret, nil
+	}
+	for _, v := range b.Variants {
+		if v.Name == name {
+			ret.merge(v)
+			ret.merge(v.platform())
+			return ret, nil
+		}
+	}
+	return nil, fmt.Errorf("Build system %s has no variant %s", b.Name, name)
+}
+
+func (b *BuildSystem) platform() *BuildSystem {
+	switch GetEditor().Plat() {
+	case "Windows":
+		return b.Windows
+	case "OSX":
+		return b.OSX
+	}
+	return b.Linux
+}
+
+// Overrides the fields set in o, the variants and
+// platform overrides aren't merged.
+func (b *BuildSystem) merge(o *BuildSystem) {
+	if o == nil {
+		return
+	}
+	if o.Name != "" {
+		b.Name = o.Name
+	}
+	// shell_cmd takes precedence over cmd so setting
+	// either of them replaces both
+	if len(o.Cmd) != 0 || o.ShellCmd != "" {
+		b.Cmd = append([]string(nil), o.Cmd...)
+		b.ShellCmd = o.ShellCmd
+	}
+	if o.WorkingDir != "" {
+		b.WorkingDir = o.WorkingDir
+	}
+	if o.FileRegex != "" {
+		b.FileRegex = o.FileRegex
+	}
+	if o.LineRegex != "" {
+		b.LineRegex = o.LineRegex
+	}
+	if o.Selector != "" {
+		b.Selector = o.Selector
+	}
+	if len(o.Env) != 0 && b.Env == nil {
+		b.Env = make(map[string]string, len(o.Env))
+	}
+	for k, v := range o.Env {
+		b.Env[k] = v
+	}
+}
+
+// BuildSystems returns the build systems of the window project
+// followed by the ones loaded from packages.
+func (w *Window) BuildSystems() []*BuildSystem {
+	ret := append([]*BuildSystem(nil), w.Project().BuildSystems()...)
+	return append(ret, GetEditor().BuildSystems()...)
+}
+
+// ExtractVariables returns the variables available for expansion
+// in build systems, the same sublime provides.
+func (w *Window) ExtractVariables() map[string]string {
+	ed := GetEditor()
+	vars := map[string]string{
+		"packages": ed.PackagesPath(),
+		"platform": ed.Plat(),
+	}
+	if v := w.ActiveView(); v != nil && v.FileName() != "" {
+		fn := v.FileName()
+		base := filepath.Base(fn)
+		ext := filepath.Ext(base)
+		vars["file"] = fn
+		vars["file_path"] = filepath.Dir(fn)
+		vars["file_name"] = base
+		vars["file_extension"] = strings.TrimPrefix(ext, ".")
+		vars["file_base_name"] = strings.TrimSuffix(base, ext)
+	}
+	if folders := w.Project().Folders(); len(folders) != 0 {
+		vars["folder"] = folders[0]
+	}
+	if fn := w.Project().FileName(); fn != "" {
+		base := filepath.Base(fn)
+		vars["project"] = fn
+		vars["project_path"] = filepath.Dir(fn)
+		vars["project_name"] = base
+		vars["project_base_name"] = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return vars
+}
+
+// ExpandVariables replaces the $name, ${name} and ${name:default} variables
+// in s with their values in vars, the default is used when the variable
+// isn't set and is expanded itself. Unknown variables expand to an empty
+// string and \$ is a literal $.
+func ExpandVariables(s string, vars map[string]string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '$':
+			buf.WriteByte('$')
+			i++
+		case c != '$' || i+1 == len(s):
+			buf.WriteByte(c)
+		case s[i+1] == '{':
+			end := closingBrace(s, i+2)
+			if end == -1 {
+				buf.WriteString(s[i:])
+				return buf.String()
+			}
+			name, def := s[i+2:end], ""
+			hasDef := false
+			if j := strings.IndexByte(name, ':'); j != -1 {
+				name, def, hasDef = name[:j], name[j+1:], true
+			}
+			if v, ok := vars[name]; ok && v != "" {
+				buf.WriteString(v)
+			} else if hasDef {
+				buf.WriteString(ExpandVariables(def, vars))
+			}
+			i = end
+		default:
+			j := i + 1
+			for j < len(s) && isVariableChar(s[j]) {
+				j++
+			}
+			if j == i+1 {
+				buf.WriteByte(c)
+				continue
+			}
+			buf.WriteString(vars[s[i+1:j]])
+			i = j - 1
+		}
+	}
+	return buf.String()
+}
+
+// Returns the index of the brace closing the one opened
+// right before i, -1 if there's none.
+func closingBrace(s string, i int) int {
+	depth := 1
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isVariableChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// BuildResults returns the locations found in the view using its
+// result_file_regex and result_line_regex settings, the way sublime does.
+// The file regex captures the file, line, column and message. The line
+// regex captures the line, column and message in the file last matched
+// by the file regex, which then only has to capture the file name.
+// Relative files are relative to the result_base_dir setting.
+func BuildResults(v *View) (ret []BuildResult) {
+	s := v.Settings()
+	fre, lre := compileResultRegex(s.String("result_file_regex", "")), compileResultRegex(s.String("result_line_regex", ""))
+	if fre == nil {
+		return nil
+	}
+	dir := s.String("result_base_dir", "")
+	file := ""
+	for _, l := range v.Lines(text.Region{A: 0, B: v.Size()}) {
+		var groups []string
+		line := v.Substr(l)
+		if m := fre.FindStringSubmatch(line); len(m) > 1 {
+			file = m[1]
+			groups = m[2:]
+			if len(groups) == 0 && lre != nil {
+				// only names the file of the lines below
+				continue
+			}
+		} else if lre == nil || file == "" {
+			continue
+		} else if m := lre.FindStringSubmatch(line); m != nil {
+			groups = m[1:]
+		} else {
+			continue
+		}
+		r := BuildResult{File: file, Region: l}
+		if !filepath.IsAbs(r.File) && dir != "" {
+			r.File = filepath.Join(dir, r.File)
+		}
+		for i, g := range groups {
+			switch i {
+			case 0:
+				r.Line, _ = strconv.Atoi(g)
+			case 1:
+				r.Column, _ = strconv.Atoi(g)
+			case 2:
+				r.Message = g
+			}
+		}
+		ret = append(ret, r)
+	}
+	return
+}
+
+// Returns a nil regex for an invalid or empty pattern.
+func compileResultRegex(pattern string) *rubex.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	re, err := rubex.Compile(pattern)
+	if err != nil {
+		log.Warn("Invalid result regex %q: %s", pattern, err)
+		return nil
+	}
+	return re
+}
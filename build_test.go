@@ -0,0 +1,99 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandVariables(t *testing.T) {
+	vars := map[string]string{
+		"file":      "/tmp/main.go",
+		"file_path": "/tmp",
+		"empty":     "",
+	}
+	tests := []struct {
+		in, exp string
+	}{
+		{"go run $file", "go run /tmp/main.go"},
+		{"${file_path}/bin", "/tmp/bin"},
+		{"$file_path/bin", "/tmp/bin"},
+		{"${project_path:${file_path}}", "/tmp"},
+		{"${project_path:default}", "default"},
+		{"${empty:default}", "default"},
+		{"$unknown-", "-"},
+		{`\$file`, "$file"},
+		{"$ and $", "$ and $"},
+		{"${file", "${file"},
+	}
+	for i, test := range tests {
+		if out := ExpandVariables(test.in, vars); out != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, out)
+		}
+	}
+}
+
+func TestBuildSystemVariant(t *testing.T) {
+	var b BuildSystem
+	data := `{
+		"cmd": ["make"],
+		"env": {"A": "a"},
+		"file_regex": "^(.*):([0-9]+)",
+		"variants": [
+			{"name": "Clean", "shell_cmd": "make clean", "env": {"B": "b"}}
+		]
+	}`
+	if err := json.Unmarshal([]byte(data), &b); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		variant string
+		exp     *BuildSystem
+	}{
+		{"", &BuildSystem{Cmd: []string{"make"}, Env: map[string]string{"A": "a"}, FileRegex: "^(.*):([0-9]+)"}},
+		{"Clean", &BuildSystem{Name: "Clean", ShellCmd: "make clean", Env: map[string]string{"A": "a", "B": "b"}, FileRegex: "^(.*):([0-9]+)"}},
+	}
+	for i, test := range tests {
+		if v, err := b.Variant(test.variant); err != nil {
+			t.Errorf("Test %d: Expected no error, but got %s", i, err)
+		} else if !reflect.DeepEqual(v, test.exp) {
+			t.Errorf("Test %d: Expected %+v, but got %+v", i, test.exp, v)
+		}
+	}
+	if _, err := b.Variant("none"); err == nil {
+		t.Errorf("Expected an error for an unknown variant")
+	}
+}
+
+func TestBuildResults(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	p := w.CreateOutputPanel("exec")
+	p.Settings().Set("result_file_regex", `^([^:]+):([0-9]+):([0-9]+): (.*)$`)
+	p.Settings().Set("result_line_regex", `^  line ([0-9]+):([0-9]*) (.*)$`)
+	p.Settings().Set("result_base_dir", "/src")
+	e := p.BeginEdit()
+	p.Insert(e, 0, "building\nmain.go:3:5: undefined: x\n  line 7: unused\n/abs.go:1:1: syntax error\n")
+	p.EndEdit(e)
+
+	exp := []BuildResult{
+		{filepath.Join("/src", "main.go"), 3, 5, "undefined: x", p.Line(9)},
+		{filepath.Join("/src", "main.go"), 7, 0, "unused", p.Line(35)},
+		{"/abs.go", 1, 1, "syntax error", p.Line(52)},
+	}
+	res := BuildResults(p)
+	if len(res) != len(exp) {
+		t.Fatalf("Expected %d results, but got %d: %+v", len(exp), len(res), res)
+	}
+	for i := range exp {
+		if !reflect.DeepEqual(res[i], exp[i]) {
+			t.Errorf("Test %d: Expected %+v, but got %+v", i, exp[i], res[i])
+		}
+	}
+}
@@ -115,9 +115,10 @@ func (d *DefaultCommand) IsVisible() bool {
 	return true
 }
 
-// By default the string "TODO" is return as the description.
+// By default a command has no description, commands
+// with one are listed in the command palette.
 func (d *DefaultCommand) Description() string {
-	return "TODO"
+	return ""
 }
 
 // The BypassUndoCommand defaults to bypassing the
@@ -23,8 +23,8 @@ func TestDefaultCommand(t *testing.T) {
 		t.Errorf("Expected IsVisible to return true, but got %v", dc.IsVisible())
 	}
 
-	if dc.Description() != "TODO" {
-		t.Errorf("Expected Description to return \"TODO\", but got %v", dc.Description())
+	if dc.Description() != "" {
+		t.Errorf("Expected Description to return \"\", but got %v", dc.Description())
 	}
 }
 
@@ -43,7 +43,7 @@ func TestBypassUndoCommand(t *testing.T) {
 		t.Errorf("Expected IsVisible to return true, but got %v", bc.IsVisible())
 	}
 
-	if bc.Description() != "TODO" {
-		t.Errorf("Expected Description to return \"TODO\", but got %v", bc.Description())
+	if bc.Description() != "" {
+		t.Errorf("Expected Description to return \"\", but got %v", bc.Description())
 	}
 }
@@ -35,6 +35,7 @@ type (
 		WindowCommands      wndcmd
 		log                 bool
 		verbose             bool
+		macro               macroRecorder
 	}
 )
 
@@ -102,6 +103,10 @@ func (ch *commandHandler) RunWindowCommand(wnd *Window, name string, args Args)
 	}
 	log.Logf(lvl, "Running window command: %s %v", name, args)
 	t := time.Now()
+	rec, ran := ch.macro.begin(), false
+	defer func() {
+		ch.macro.end(rec, name, args, ran)
+	}()
 	if c, ok := ch.WindowCommands[name].(WindowCommand); c != nil && ok {
 		if err := ch.init(c, args); err != nil && ch.verbose {
 			log.Debug("Command initialization failed: %s", err)
@@ -111,6 +116,7 @@ func (ch *commandHandler) RunWindowCommand(wnd *Window, name string, args Args)
 			return err
 		} else {
 			log.Logf(lvl, "Ran Window command: %s %s", name, time.Since(t))
+			ran = true
 		}
 	} else {
 		log.Logf(lvl, "No such window command: %s", name)
@@ -133,6 +139,10 @@ func (ch *commandHandler) RunTextCommand(view *View, name string, args Args) err
 		lvl = log.DEBUG
 	}
 	log.Logf(lvl, "Running text command: %s %v", name, args)
+	rec, ran := ch.macro.begin(), false
+	defer func() {
+		ch.macro.end(rec, name, args, ran)
+	}()
 	if c, ok := ch.TextCommands[name].(TextCommand); c != nil && ok {
 		if err := ch.init(c, args); err != nil && ch.verbose {
 			log.Debug("Command initialization failed: %s", err)
@@ -141,6 +151,7 @@ func (ch *commandHandler) RunTextCommand(view *View, name string, args Args) err
 			log.Logf(lvl, "Command execution failed: %s", err)
 			return err
 		}
+		ran = true
 	} else if w := view.Window(); w != nil {
 		log.Logf(lvl, "Try to run window command: %s %v", name, args)
 		if c, ok := ch.WindowCommands[name].(WindowCommand); c != nil && ok {
@@ -148,6 +159,7 @@ func (ch *commandHandler) RunTextCommand(view *View, name string, args Args) err
 				log.Logf(lvl, "Command execution failed: %s", err)
 				return err
 			}
+			ran = true
 		}
 	}
 	log.Logf(lvl, "Ran text command: %s %s", name, time.Since(t))
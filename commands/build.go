@@ -0,0 +1,493 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// Build command runs the build system selected by the window
+	// "build_system" setting, or the one which selector best matches
+	// the active view, through the exec command.
+	Build struct {
+		lime.DefaultCommand
+		Variant string
+	}
+
+	// Exec command runs cmd, or shell_cmd in the platform shell,
+	// streaming its output to the "exec" output panel. Running it
+	// with kill set stops the process running in the window.
+	Exec struct {
+		lime.DefaultCommand
+		cmd        []string
+		shellCmd   string
+		workingDir string
+		fileRegex  string
+		lineRegex  string
+		env        map[string]string
+		kill       bool
+	}
+
+	// NextResult command goes to the next result of the
+	// last build or find in files of the window.
+	NextResult struct {
+		lime.DefaultCommand
+	}
+
+	// PrevResult command goes to the previous result of
+	// the last build or find in files of the window.
+	PrevResult struct {
+		lime.DefaultCommand
+	}
+
+	// ShowPanel command shows the given panel, with toggle
+	// set the panel is hidden if it's already shown.
+	ShowPanel struct {
+		lime.DefaultCommand
+		Panel  string
+		Toggle bool
+	}
+
+	// HidePanel command hides the shown panel.
+	HidePanel struct {
+		lime.DefaultCommand
+	}
+
+	// The process run by the exec command in a window.
+	process struct {
+		cmd    *exec.Cmd
+		out    *panelWriter
+		killed bool
+	}
+
+	// Appends what's written to the end of the panel, until it's closed.
+	panelWriter struct {
+		sync.Mutex
+		v      *lime.View
+		closed bool
+		// The end of the last write which may continue in the next one,
+		// the bytes of an incomplete character or the "\r" of a "\r\n"
+		pending []byte
+	}
+)
+
+const (
+	execPanel = "exec"
+	// The panel setting holding the index of the current build result
+	resultKey = "lime.build.result"
+)
+
+var (
+	processes = make(map[*lime.Window]*process)
+	procLock  sync.Mutex
+	// The view holding the results next_result goes through
+	results = make(map[*lime.Window]*lime.View)
+)
+
+// Run executes the Build command.
+func (c *Build) Run(w *lime.Window) error {
+	b, err := buildSystem(w)
+	if err != nil {
+		return err
+	}
+	if b, err = b.Variant(c.Variant); err != nil {
+		return err
+	}
+	vars := w.ExtractVariables()
+	cmd := make([]interface{}, len(b.Cmd))
+	for i, s := range b.Cmd {
+		cmd[i] = lime.ExpandVariables(s, vars)
+	}
+	// the build runs in the directory of the active file by default
+	wd := b.WorkingDir
+	if wd == "" {
+		wd = "${file_path}"
+	}
+	env := make(map[string]interface{}, len(b.Env))
+	for k, v := range b.Env {
+		env[k] = lime.ExpandVariables(v, vars)
+	}
+	return lime.GetEditor().CommandHandler().RunWindowCommand(w, "exec", lime.Args{
+		"cmd":         cmd,
+		"shell_cmd":   lime.ExpandVariables(b.ShellCmd, vars),
+		"working_dir": lime.ExpandVariables(wd, vars),
+		"file_regex":  b.FileRegex,
+		"line_regex":  b.LineRegex,
+		"env":         env,
+	})
+}
+
+// Returns the build system named by the window "build_system" setting, or
+// if it's not set the one which selector scores best on the active view.
+func buildSystem(w *lime.Window) (*lime.BuildSystem, error) {
+	bs := w.BuildSystems()
+	if name := w.Settings().String("build_system", ""); name != "" {
+		for _, b := range bs {
+			if b.Name == name {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("No build system named %s", name)
+	}
+	v := w.ActiveView()
+	if v == nil {
+		return nil, fmt.Errorf("No build system for %s", w)
+	}
+	var (
+		best  *lime.BuildSystem
+		score int
+	)
+	for _, b := range bs {
+		if b.Selector == "" {
+			continue
+		}
+		if s := v.ScoreSelector(0, b.Selector); s > score {
+			best, score = b, s
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("No build system for %s", v)
+	}
+	return best, nil
+}
+
+// Init parses the args, cmd being a list of strings and env a map.
+func (c *Exec) Init(args lime.Args) error {
+	*c = Exec{}
+	if v, ok := args["cmd"]; ok {
+		cmd, err := toStrings(v)
+		if err != nil {
+			return fmt.Errorf("Invalid exec cmd: %s", err)
+		}
+		c.cmd = cmd
+	}
+	c.shellCmd, _ = args["shell_cmd"].(string)
+	c.workingDir, _ = args["working_dir"].(string)
+	c.fileRegex, _ = args["file_regex"].(string)
+	c.lineRegex, _ = args["line_regex"].(string)
+	c.kill, _ = args["kill"].(bool)
+	switch env := args["env"].(type) {
+	case nil:
+	case map[string]interface{}:
+		c.env = make(map[string]string, len(env))
+		for k, v := range env {
+			c.env[k] = fmt.Sprint(v)
+		}
+	case lime.Args:
+		c.env = make(map[string]string, len(env))
+		for k, v := range env {
+			c.env[k] = fmt.Sprint(v)
+		}
+	default:
+		return fmt.Errorf("Expected exec env to be a map, not %T", env)
+	}
+	return nil
+}
+
+func toStrings(v interface{}) ([]string, error) {
+	switch l := v.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return l, nil
+	case []interface{}:
+		ret := make([]string, len(l))
+		for i, s := range l {
+			str, ok := s.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, not %v", s)
+			}
+			ret[i] = str
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("expected a list, not %T", v)
+}
+
+// Run executes the Exec command.
+func (c *Exec) Run(w *lime.Window) error {
+	killed := killProcess(w)
+	if c.kill {
+		if p := w.FindOutputPanel(execPanel); p != nil && killed {
+			(&panelWriter{v: p}).Write([]byte("[Cancelled]\n"))
+		}
+		return nil
+	}
+
+	v := w.CreateOutputPanel(execPanel)
+	s := v.Settings()
+	s.Set("result_file_regex", c.fileRegex)
+	s.Set("result_line_regex", c.lineRegex)
+	s.Set("result_base_dir", c.workingDir)
+	s.Erase(resultKey)
+	v.EraseRegions(resultKey)
+	w.ShowPanel("output." + execPanel)
+	setResultsView(w, v)
+
+	var cmd *exec.Cmd
+	switch {
+	case c.shellCmd != "" && runtime.GOOS == "windows":
+		cmd = exec.Command("cmd.exe", "/C", c.shellCmd)
+	case c.shellCmd != "":
+		cmd = exec.Command("/bin/sh", "-c", c.shellCmd)
+	case len(c.cmd) != 0:
+		cmd = exec.Command(c.cmd[0], c.cmd[1:]...)
+	default:
+		return fmt.Errorf("Nothing to execute, cmd or shell_cmd is required")
+	}
+	cmd.Dir = c.workingDir
+	cmd.Env = os.Environ()
+	keys := make([]string, 0, len(c.env))
+	for k := range c.env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmd.Env = append(cmd.Env, k+"="+c.env[k])
+	}
+	out := &panelWriter{v: v}
+	cmd.Stdout, cmd.Stderr = out, out
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(out, "%s\n[Finished]\n", err)
+		return err
+	}
+	p := &process{cmd: cmd, out: out}
+	procLock.Lock()
+	processes[w] = p
+	procLock.Unlock()
+
+	go func() {
+		err := cmd.Wait()
+		procLock.Lock()
+		killed := p.killed
+		procLock.Unlock()
+		// the process is forgotten only once it's done writing,
+		// until then a new run closes its writer
+		defer func() {
+			procLock.Lock()
+			if processes[w] == p {
+				delete(processes, w)
+			}
+			procLock.Unlock()
+		}()
+
+		elapsed := time.Since(start).Seconds()
+		// the output of a killed process is either replaced
+		// by a new run or was cancelled explicitly
+		switch {
+		case killed:
+		case err != nil:
+			code := -1
+			if ee, ok := err.(*exec.ExitError); ok {
+				code = ee.ExitCode()
+			}
+			fmt.Fprintf(out, "[Finished in %.1fs with exit code %d]\n", elapsed, code)
+		default:
+			fmt.Fprintf(out, "[Finished in %.1fs]\n", elapsed)
+		}
+	}()
+	return nil
+}
+
+// Kills the process the exec command is running in the window,
+// returns false if there was none. The output of the process still
+// being read is dropped, so the panel can be reused right away.
+func killProcess(w *lime.Window) bool {
+	procLock.Lock()
+	defer procLock.Unlock()
+	p, ok := processes[w]
+	if ok {
+		p.killed = true
+		p.cmd.Process.Kill()
+		p.out.close()
+		delete(processes, w)
+	}
+	return ok
+}
+
+// Forgets the process and the results of a closed window.
+func forgetWindow(w *lime.Window) {
+	killProcess(w)
+	procLock.Lock()
+	defer procLock.Unlock()
+	delete(results, w)
+}
+
+func (pw *panelWriter) Write(p []byte) (int, error) {
+	pw.Lock()
+	defer pw.Unlock()
+	if pw.closed {
+		return len(p), nil
+	}
+	data := append(pw.pending, p...)
+	n := utf8Boundary(data)
+	if n > 0 && data[n-1] == '\r' {
+		n--
+	}
+	pw.pending = append([]byte(nil), data[n:]...)
+	if n == 0 {
+		return len(p), nil
+	}
+	s := strings.Replace(string(data[:n]), "\r\n", "\n", -1)
+	e := pw.v.BeginEdit()
+	pw.v.Insert(e, pw.v.Size(), s)
+	pw.v.EndEdit(e)
+	return len(p), nil
+}
+
+// Returns the length of the beginning of data which doesn't end in the
+// middle of a UTF-8 encoded character.
+func utf8Boundary(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				break
+			}
+			return i
+		}
+	}
+	return len(data)
+}
+
+// Stops writing to the panel, waiting for the write in progress.
+func (pw *panelWriter) close() {
+	pw.Lock()
+	defer pw.Unlock()
+	pw.closed = true
+}
+
+// Run executes the NextResult command.
+func (c *NextResult) Run(w *lime.Window) error {
+	return gotoResult(w, 1)
+}
+
+// Run executes the PrevResult command.
+func (c *PrevResult) Run(w *lime.Window) error {
+	return gotoResult(w, -1)
+}
+
+// Sets the view next_result and prev_result go through in the window.
+func setResultsView(w *lime.Window, v *lime.View) {
+	procLock.Lock()
+	defer procLock.Unlock()
+	results[w] = v
+}
+
+// Returns the view of the last results of the window, the "exec" output
+// panel if there's none.
+func resultsView(w *lime.Window) *lime.View {
+	procLock.Lock()
+	v := results[w]
+	procLock.Unlock()
+	panel := w.FindOutputPanel(execPanel)
+	if v == nil || v == panel {
+		return panel
+	}
+	// the results view could have been closed since
+	for _, ov := range w.Views() {
+		if ov == v {
+			return v
+		}
+	}
+	return panel
+}
+
+// Moves the current result by dir, highlighting it in the results view
+// and opening its file with the cursor placed at the result line, or at
+// the match of the line for the find in files results.
+func gotoResult(w *lime.Window, dir int) error {
+	p := resultsView(w)
+	if p == nil {
+		return nil
+	}
+	results := lime.BuildResults(p)
+	n := len(results)
+	if n == 0 {
+		return nil
+	}
+	i := p.Settings().Int(resultKey, -1)
+	switch {
+	case i < 0 && dir < 0:
+		i = n - 1
+	case i < 0:
+		i = 0
+	default:
+		i = (i + dir + n) % n
+	}
+	p.Settings().Set(resultKey, i)
+	r := results[i]
+
+	fe := lime.GetEditor().Frontend()
+	p.AddRegions(resultKey, []text.Region{r.Region}, "markup.deleted", "", render.DRAW_NO_FILL)
+	fe.Show(p, r.Region)
+
+	v := w.FindOpenFile(r.File)
+	if v == nil {
+		v = w.OpenFile(r.File, 0)
+	} else {
+		w.SetActiveView(v)
+	}
+	pattern := p.Settings().String(lime.FindPatternKey, "")
+	flags := p.Settings().Int(lime.FindFlagsKey, 0)
+	v.WhenLoaded(func() {
+		pt := v.TextPoint(text.Max(r.Line-1, 0), text.Max(r.Column-1, 0))
+		sel := text.Region{A: pt, B: pt}
+		if pattern != "" {
+			m := v.Find(pattern, pt, flags)
+			if m.A != -1 && m.A <= v.Line(pt).End() {
+				sel = m
+			}
+		}
+		v.Sel().Clear()
+		v.Sel().Add(sel)
+		fe.Show(v, sel)
+	})
+	return nil
+}
+
+// Run executes the ShowPanel command.
+func (c *ShowPanel) Run(w *lime.Window) error {
+	if c.Toggle && w.ActivePanel() == c.Panel {
+		w.HidePanel()
+		return nil
+	}
+	if !w.ShowPanel(c.Panel) {
+		return fmt.Errorf("%s has no panel %s", w, c.Panel)
+	}
+	return nil
+}
+
+// Run executes the HidePanel command.
+func (c *HidePanel) Run(w *lime.Window) error {
+	w.HidePanel()
+	return nil
+}
+
+func init() {
+	register([]lime.Command{
+		&Build{},
+		&Exec{},
+		&NextResult{},
+		&PrevResult{},
+		&ShowPanel{},
+		&HidePanel{},
+	})
+	lime.OnCloseWindow.Add(forgetWindow)
+}
@@ -0,0 +1,230 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/text"
+)
+
+// Waits for the process output in the panel to finish.
+func waitForExec(t *testing.T, p *lime.View) string {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if s := p.Substr(text.Region{A: 0, B: p.Size()}); strings.Contains(s, "[Finished") {
+			return s
+		}
+	}
+	t.Fatalf("Timed out waiting for the exec command to finish")
+	return ""
+}
+
+func TestExec(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+
+	tests := []struct {
+		args lime.Args
+		exp  string
+		code string
+	}{
+		{
+			lime.Args{"shell_cmd": "echo hello"},
+			"hello\n[Finished in ",
+			"",
+		},
+		{
+			lime.Args{"shell_cmd": "echo $LIME_TEST", "env": map[string]interface{}{"LIME_TEST": "env"}},
+			"env\n[Finished in ",
+			"",
+		},
+		{
+			lime.Args{"cmd": []interface{}{"sh", "-c", "echo fail >&2; exit 3"}},
+			"fail\n[Finished in ",
+			"with exit code 3]",
+		},
+	}
+	for i, test := range tests {
+		if err := ed.CommandHandler().RunWindowCommand(w, "exec", test.args); err != nil {
+			t.Errorf("Test %d: Expected no error, but got %s", i, err)
+			continue
+		}
+		p := w.FindOutputPanel("exec")
+		if p == nil {
+			t.Fatalf("Test %d: Expected the exec panel to be created", i)
+		}
+		if a := w.ActivePanel(); a != "output.exec" {
+			t.Errorf("Test %d: Expected the exec panel to be shown, but got %q", i, a)
+		}
+		out := waitForExec(t, p)
+		if !strings.HasPrefix(out, test.exp) || !strings.Contains(out, test.code) {
+			t.Errorf("Test %d: Expected output %q...%q, but got %q", i, test.exp, test.code, out)
+		}
+	}
+}
+
+func TestPanelWriter(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	pw := &panelWriter{v: w.CreateOutputPanel("test")}
+	for _, p := range []string{"a\r", "\nb\xc3", "\xa9\r", "\r\n", "\xe2\x82", "\xac"} {
+		pw.Write([]byte(p))
+	}
+	exp := "a\nb\u00e9\r\n\u20ac"
+	if s := pw.v.Substr(text.Region{A: 0, B: pw.v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	ed := lime.GetEditor()
+	ed.AddBuildSystem("build_test.sublime-build", &lime.BuildSystem{
+		Name:     "build_test",
+		ShellCmd: "echo $file_name",
+		Variants: []*lime.BuildSystem{
+			{Name: "Base", ShellCmd: "echo ${file_base_name}"},
+			{Name: "Dir", ShellCmd: "pwd"},
+		},
+	})
+	w := ed.NewWindow()
+	defer w.Close()
+	w.Settings().Set("build_system", "build_test")
+
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	v.SetFileName(filepath.Join("testdata", "main.go"))
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		variant string
+		exp     string
+	}{
+		{"", "main.go\n"},
+		{"Base", "main\n"},
+		// the working dir defaults to the directory of the file
+		{"Dir", dir + "\n"},
+	}
+	for i, test := range tests {
+		if err := ed.CommandHandler().RunWindowCommand(w, "build", lime.Args{"variant": test.variant}); err != nil {
+			t.Errorf("Test %d: Expected no error, but got %s", i, err)
+			continue
+		}
+		if out := waitForExec(t, w.FindOutputPanel("exec")); !strings.HasPrefix(out, test.exp) {
+			t.Errorf("Test %d: Expected output starting with %q, but got %q", i, test.exp, out)
+		}
+	}
+
+	w.Settings().Set("build_system", "none")
+	if err := ed.CommandHandler().RunWindowCommand(w, "build", nil); err == nil {
+		t.Errorf("Expected an error for an unknown build system")
+	}
+}
+
+func TestExecCloseWindow(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	if err := ed.CommandHandler().RunWindowCommand(w, "exec", lime.Args{"shell_cmd": "sleep 10"}); err != nil {
+		t.Fatalf("Expected no error, but got %s", err)
+	}
+	w.Close()
+
+	procLock.Lock()
+	defer procLock.Unlock()
+	if _, ok := processes[w]; ok {
+		t.Errorf("Expected the process of the closed window to be killed")
+	}
+	if _, ok := results[w]; ok {
+		t.Errorf("Expected the results of the closed window to be forgotten")
+	}
+}
+
+func TestNextPrevResult(t *testing.T) {
+	var fe scfe
+	ed := lime.GetEditor()
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer func() {
+		for _, v := range w.Views() {
+			v.SetScratch(true)
+		}
+		w.Close()
+	}()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := w.CreateOutputPanel("exec")
+	p.Settings().Set("result_file_regex", `^([^:]+):([0-9]+):([0-9]+): (.*)$`)
+	p.Settings().Set("result_base_dir", dir)
+	e := p.BeginEdit()
+	p.Insert(e, 0, "build.go:2:3: first\nbuild.go:5:1: second\n")
+	p.EndEdit(e)
+
+	tests := []struct {
+		cmd       string
+		row, col  int
+		panelLine int
+	}{
+		{"next_result", 1, 2, 0},
+		{"next_result", 4, 0, 20},
+		{"next_result", 1, 2, 0},
+		{"prev_result", 4, 0, 20},
+	}
+	for i, test := range tests {
+		ed.CommandHandler().RunWindowCommand(w, test.cmd, nil)
+		v := w.ActiveView()
+		if v == nil || v.FileName() != filepath.Join(dir, "build.go") {
+			t.Fatalf("Test %d: Expected build.go to be the active view, but got %v", i, v)
+		}
+		pt := v.TextPoint(test.row, test.col)
+		if s := v.Sel().Regions(); len(s) != 1 || s[0] != (text.Region{A: pt, B: pt}) {
+			t.Errorf("Test %d: Expected selection at %d, but got %v", i, pt, s)
+		}
+		if r := p.GetRegions("lime.build.result"); len(r) != 1 || r[0] != p.Line(test.panelLine) {
+			t.Errorf("Test %d: Expected result region %v, but got %v", i, p.Line(test.panelLine), r)
+		}
+	}
+	if n := len(w.Views()); n != 1 {
+		t.Errorf("Expected the result file to be opened once, but got %d views", n)
+	}
+}
+
+func TestShowPanel(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	w.CreateOutputPanel("exec")
+
+	tests := []struct {
+		cmd  string
+		args lime.Args
+		exp  string
+	}{
+		{"show_panel", lime.Args{"panel": "output.exec"}, "output.exec"},
+		{"show_panel", lime.Args{"panel": "output.exec", "toggle": true}, ""},
+		{"show_panel", lime.Args{"panel": "output.exec", "toggle": true}, "output.exec"},
+		{"hide_panel", nil, ""},
+	}
+	for i, test := range tests {
+		ed.CommandHandler().RunWindowCommand(w, test.cmd, test.args)
+		if a := w.ActivePanel(); a != test.exp {
+			t.Errorf("Test %d: Expected active panel %q, but got %q", i, test.exp, a)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// AutoComplete command shows the completion
+	// list at the cursor.
+	AutoComplete struct {
+		lime.BypassUndoCommand
+	}
+
+	// CommitCompletion command inserts the
+	// completion highlighted in the list.
+	CommitCompletion struct {
+		lime.DefaultCommand
+	}
+
+	// HideAutoComplete command hides the
+	// completion list.
+	HideAutoComplete struct {
+		lime.BypassUndoCommand
+	}
+
+	// InsertCompletion command replaces the
+	// prefix before each cursor with the
+	// contents of a completion, which is
+	// inserted as a snippet.
+	InsertCompletion struct {
+		lime.DefaultCommand
+		Contents string
+	}
+)
+
+// Run executes the AutoComplete command.
+func (c *AutoComplete) Run(v *lime.View, e *lime.Edit) error {
+	v.ShowAutoComplete()
+	return nil
+}
+
+// Run executes the CommitCompletion command.
+func (c *CommitCompletion) Run(v *lime.View, e *lime.Edit) error {
+	if q := v.AutoComplete(); q != nil {
+		q.Select(q.Selected)
+	}
+	return nil
+}
+
+// Run executes the HideAutoComplete command.
+func (c *HideAutoComplete) Run(v *lime.View, e *lime.Edit) error {
+	v.HideAutoComplete()
+	return nil
+}
+
+// Run executes the InsertCompletion command.
+func (c *InsertCompletion) Run(v *lime.View, e *lime.Edit) error {
+	v.HideAutoComplete()
+	prefix := func(r text.Region) text.Region {
+		if r.Empty() {
+			return v.CompletionPrefix(r.B)
+		}
+		return text.Region{A: r.B, B: r.B}
+	}
+	if !eraseBeforeSnippet(v, e, prefix) {
+		return nil
+	}
+	return v.InsertSnippet(e, c.Contents)
+}
+
+// Erases the text the snippet replaces before each cursor, the cursors
+// which text can't be erased are dropped from the selection. Returns
+// false if no cursor is left.
+func eraseBeforeSnippet(v *lime.View, e *lime.Edit, toErase func(text.Region) text.Region) bool {
+	sel := v.Sel()
+	var kept []text.Region
+	for i := 0; i < sel.Len(); i++ {
+		if v.Erase(e, toErase(sel.Get(i))) {
+			kept = append(kept, sel.Get(i))
+		}
+	}
+	if len(kept) != sel.Len() {
+		sel.Clear()
+		sel.AddAll(kept)
+	}
+	return len(kept) != 0
+}
+
+func init() {
+	register([]lime.Command{
+		&AutoComplete{},
+		&CommitCompletion{},
+		&HideAutoComplete{},
+		&InsertCompletion{},
+	})
+}
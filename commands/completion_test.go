@@ -0,0 +1,123 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/text"
+)
+
+func TestInsertCompletion(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "fmt.Pr\nfmt.Pr\nx")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().AddAll([]text.Region{{A: 6, B: 6}, {A: 13, B: 13}, {A: 14, B: 15}})
+
+	ed.CommandHandler().RunTextCommand(v, "insert_completion", lime.Args{"contents": "Println"})
+	exp := "fmt.Println\nfmt.Println\nPrintln"
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+	ed.CommandHandler().RunTextCommand(v, "undo", nil)
+	exp = "fmt.Pr\nfmt.Pr\nx"
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected the completion to be undone in one step, but got %q", s)
+	}
+
+	// the contents are a snippet
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{A: 6, B: 6})
+	ed.CommandHandler().RunTextCommand(v, "insert_completion", lime.Args{"contents": "Println(${1:a})"})
+	exp = "fmt.Println(a)\nfmt.Pr\nx"
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+	if sel := v.Sel().Regions(); !reflect.DeepEqual(sel, []text.Region{{A: 12, B: 13}}) {
+		t.Errorf("Expected the field of the completion to be selected, but got %v", sel)
+	}
+
+	// the completion isn't inserted where its prefix is protected
+	ed.CommandHandler().RunTextCommand(v, "undo", nil)
+	v.AddRegions("protected", []text.Region{{A: 11, B: 13}}, "", "", render.PROTECTED)
+	defer v.EraseRegions("protected")
+	v.Sel().Clear()
+	v.Sel().AddAll([]text.Region{{A: 6, B: 6}, {A: 13, B: 13}})
+	ed.CommandHandler().RunTextCommand(v, "insert_completion", lime.Args{"contents": "Println"})
+	exp = "fmt.Println\nfmt.Pr\nx"
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{A: 18, B: 18})
+	ed.CommandHandler().RunTextCommand(v, "insert_completion", lime.Args{"contents": "Println"})
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected nothing to be inserted, but got %q", s)
+	}
+}
+
+func TestAutoComplete(t *testing.T) {
+	var fe scfe
+	ed := lime.GetEditor()
+	ed.SetFrontend(&fe)
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "import\nim")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{A: 9, B: 9})
+
+	ch.RunTextCommand(v, "auto_complete", nil)
+	if !v.IsAutoCompleteVisible() {
+		t.Fatal("Expected the completions to be shown")
+	}
+	ch.RunTextCommand(v, "hide_auto_complete", nil)
+	if v.IsAutoCompleteVisible() {
+		t.Error("Expected the completions to be hidden")
+	}
+
+	ch.RunTextCommand(v, "auto_complete", nil)
+	ch.RunTextCommand(v, "commit_completion", nil)
+	if v.IsAutoCompleteVisible() {
+		t.Error("Expected the completions to be hidden once committed")
+	}
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "import\nimport" {
+		t.Errorf("Expected %q, but got %q", "import\nimport", s)
+	}
+
+	// the list follows the typed text
+	ch.RunTextCommand(v, "insert", lime.Args{"characters": "\nim"})
+	ch.RunTextCommand(v, "auto_complete", nil)
+	ch.RunTextCommand(v, "insert", lime.Args{"characters": "x"})
+	if v.IsAutoCompleteVisible() {
+		t.Error("Expected the completions to be hidden when nothing matches")
+	}
+	ch.RunTextCommand(v, "left_delete", nil)
+	ch.RunTextCommand(v, "auto_complete", nil)
+	ch.RunTextCommand(v, "left_delete", nil)
+	ch.RunTextCommand(v, "left_delete", nil)
+	if v.IsAutoCompleteVisible() {
+		t.Error("Expected the completions to be hidden once the prefix is deleted")
+	}
+}
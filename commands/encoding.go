@@ -0,0 +1,52 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/jxo/lime"
+)
+
+type (
+	// SetEncoding command sets the encoding
+	// the view will be saved in.
+	SetEncoding struct {
+		lime.BypassUndoCommand
+		Encoding string
+	}
+
+	// Reopen command reads the file of the view
+	// again in the given encoding, detecting
+	// it when none is given.
+	Reopen struct {
+		lime.DefaultCommand
+		Encoding string
+	}
+)
+
+// Run executes the SetEncoding command.
+func (c *SetEncoding) Run(v *lime.View, e *lime.Edit) error {
+	v.SetEncoding(c.Encoding)
+	return nil
+}
+
+// Run executes the Reopen command.
+func (c *Reopen) Run(v *lime.View, e *lime.Edit) error {
+	fe := lime.GetEditor().Frontend()
+	if v.IsDirty() && !fe.OkCancelDialog("File has been modified since last save, reopen anyway?", "Reopen") {
+		return nil
+	}
+	if err := v.Reopen(c.Encoding); err != nil {
+		fe.ErrorMessage(err.Error())
+		return err
+	}
+	return nil
+}
+
+func init() {
+	register([]lime.Command{
+		&SetEncoding{},
+		&Reopen{},
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/text"
+)
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lime-reopen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "test.txt")
+	if err := ioutil.WriteFile(file, []byte("h\xc3\xa9llo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var fe scfe
+	ed := lime.GetEditor()
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.OpenFile(file, 0)
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	tests := []struct {
+		args lime.Args
+		exp  string
+	}{
+		{lime.Args{"encoding": "Western (Windows 1252)"}, "hÃ©llo"},
+		{nil, "héllo"},
+	}
+	for i, test := range tests {
+		ed.CommandHandler().RunTextCommand(v, "reopen", test.args)
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+	}
+
+	ed.CommandHandler().RunTextCommand(v, "set_encoding", lime.Args{"encoding": "utf-16 le"})
+	if enc := v.Encoding(); enc != "UTF-16 LE" {
+		t.Errorf("Expected the encoding %q, but got %q", "UTF-16 LE", enc)
+	}
+
+	// the changes are kept when reopening is cancelled
+	e := v.BeginEdit()
+	v.Insert(e, 0, "changed ")
+	v.EndEdit(e)
+	fe.defaultAction = false
+	ed.CommandHandler().RunTextCommand(v, "reopen", nil)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "changed héllo" {
+		t.Errorf("Expected %q, but got %q", "changed héllo", s)
+	}
+}
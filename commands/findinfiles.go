@@ -0,0 +1,120 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/jxo/lime"
+)
+
+type (
+	// FindInFiles command searches the Pattern in
+	// the files of Where, the project folders by
+	// default, writing the matches and Context
+	// lines around them to the "Find Results"
+	// view. The results are then gone through
+	// with next_result and prev_result.
+	FindInFiles struct {
+		lime.DefaultCommand
+		Pattern       string
+		Where         string
+		Regex         bool
+		CaseSensitive bool
+		WholeWord     bool
+		Context       int
+	}
+
+	// ReplaceInFiles command replaces the matches
+	// of find_in_files with Replace, or only shows
+	// them replaced in the "Find Results" view
+	// with Preview set. Each file is replaced as
+	// one edit, undone on its own.
+	ReplaceInFiles struct {
+		lime.DefaultCommand
+		Pattern       string
+		Where         string
+		Regex         bool
+		CaseSensitive bool
+		WholeWord     bool
+		PreserveCase  bool
+		Context       int
+		Replace       string
+		Preview       bool
+	}
+)
+
+// The number of context lines shown by default, as in sublime
+const defaultFindContext = 2
+
+func findFlags(regex, caseSensitive, wholeWord bool) int {
+	flags := 0
+	if !regex {
+		flags |= lime.LITERAL
+	}
+	if !caseSensitive {
+		flags |= lime.IGNORECASE
+	}
+	if wholeWord {
+		flags |= lime.WHOLEWORD
+	}
+	return flags
+}
+
+// Run executes the FindInFiles command.
+func (c *FindInFiles) Run(w *lime.Window) error {
+	r, err := w.FindInFiles(lime.FindInFilesOptions{
+		Pattern: c.Pattern,
+		Flags:   findFlags(c.Regex, c.CaseSensitive, c.WholeWord),
+		Where:   c.Where,
+		Context: c.Context,
+	})
+	if err != nil {
+		return err
+	}
+	setResultsView(w, r.View())
+	return nil
+}
+
+func (c *FindInFiles) Default(key string) interface{} {
+	if key == "context" {
+		return defaultFindContext
+	}
+	return nil
+}
+
+// Run executes the ReplaceInFiles command.
+func (c *ReplaceInFiles) Run(w *lime.Window) error {
+	flags := findFlags(c.Regex, c.CaseSensitive, c.WholeWord)
+	if c.PreserveCase {
+		flags |= lime.PRESERVECASE
+	}
+	r, err := w.FindInFiles(lime.FindInFilesOptions{
+		Pattern:     c.Pattern,
+		Flags:       flags,
+		Where:       c.Where,
+		Context:     c.Context,
+		Replace:     true,
+		Replacement: c.Replace,
+		Preview:     c.Preview,
+	})
+	if err != nil {
+		return err
+	}
+	setResultsView(w, r.View())
+	return nil
+}
+
+func (c *ReplaceInFiles) Default(key string) interface{} {
+	if key == "context" {
+		return defaultFindContext
+	}
+	return nil
+}
+
+func init() {
+	register([]lime.Command{
+		&FindInFiles{},
+		&ReplaceInFiles{},
+	})
+}
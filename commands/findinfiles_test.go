@@ -0,0 +1,103 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/text"
+)
+
+// Waits for the find in files search to be done, returns the results.
+func waitForResults(t *testing.T, w *lime.Window) string {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		v := w.ActiveView()
+		if v == nil || v.Name() != lime.FindResultsName {
+			continue
+		}
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); strings.HasSuffix(s, "file\n") || strings.HasSuffix(s, "files\n") {
+			return s
+		}
+	}
+	t.Fatal("Waiting for the find results took too long")
+	return ""
+}
+
+func TestFindInFiles(t *testing.T) {
+	var fe scfe
+	ed := lime.GetEditor()
+	ed.SetFrontend(&fe)
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer func() {
+		for _, v := range w.Views() {
+			v.SetScratch(true)
+		}
+		w.Close()
+	}()
+
+	dir, err := ioutil.TempDir("", "lime-find")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	ioutil.WriteFile(a, []byte("one\ntwo Needle\n"), 0644)
+	ioutil.WriteFile(b, []byte("needle needle\n"), 0644)
+
+	if err := ch.RunWindowCommand(w, "find_in_files", lime.Args{"pattern": "needle", "where": dir}); err != nil {
+		t.Fatal(err)
+	}
+	exp := "Searching 2 files for \"needle\"\n" +
+		"\n" + a + ":\n    1  one\n    2: two Needle\n" +
+		"\n" + b + ":\n    1: needle needle\n" +
+		"\n3 matches across 2 files\n"
+	if s := waitForResults(t, w); s != exp {
+		t.Errorf("Expected\n%s\nbut got\n%s", exp, s)
+	}
+
+	tests := []struct {
+		cmd  string
+		file string
+		sel  text.Region
+	}{
+		{"next_result", a, text.Region{A: 8, B: 14}},
+		{"next_result", b, text.Region{A: 0, B: 6}},
+		{"next_result", a, text.Region{A: 8, B: 14}},
+		{"prev_result", b, text.Region{A: 0, B: 6}},
+	}
+	for i, test := range tests {
+		if err := ch.RunWindowCommand(w, test.cmd, nil); err != nil {
+			t.Fatalf("Test %d: %s", i, err)
+		}
+		v := w.ActiveView()
+		if v.FileName() != test.file {
+			t.Errorf("Test %d: Expected %s to be opened, but got %s", i, test.file, v.FileName())
+		} else if sel := v.Sel().Regions(); !reflect.DeepEqual(sel, []text.Region{test.sel}) {
+			t.Errorf("Test %d: Expected the match %v to be selected, but got %v", i, test.sel, sel)
+		}
+	}
+
+	// case sensitive regex, replaced in the open files
+	if err := ch.RunWindowCommand(w, "replace_in_files", lime.Args{"pattern": "n[e]+dle", "where": dir, "regex": true, "case_sensitive": true, "replace": "pin"}); err != nil {
+		t.Fatal(err)
+	}
+	if s := waitForResults(t, w); !strings.HasSuffix(s, "2 matches replaced in 1 file\n") {
+		t.Errorf("Expected the replacements to be reported, but got\n%s", s)
+	}
+	if s := w.FindOpenFile(b).Substr(text.Region{A: 0, B: w.FindOpenFile(b).Size()}); s != "pin pin\n" {
+		t.Errorf("Expected %q, but got %q", "pin pin\n", s)
+	}
+	if s := w.FindOpenFile(a).Substr(text.Region{A: 0, B: w.FindOpenFile(a).Size()}); s != "one\ntwo Needle\n" {
+		t.Errorf("Expected %s to be left as is, but got %q", a, s)
+	}
+}
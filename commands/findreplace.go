@@ -6,7 +6,9 @@ package commands
 
 import (
 	"errors"
+
 	"github.com/jxo/lime"
+	"github.com/jxo/lime/render"
 	"github.com/jxo/lime/text"
 )
 
@@ -35,11 +37,14 @@ type (
 		lime.DefaultCommand
 	}
 
+	// FindAll Command selects every match of the search text.
 	FindAll struct {
 		lime.DefaultCommand
 		SearchText []rune
 	}
 
+	// ReplaceAll Command replaces every match of the search text and
+	// selects the last replacement.
 	ReplaceAll struct {
 		lime.DefaultCommand
 		SearchText  []rune
@@ -53,6 +58,9 @@ var (
 	replaceText string
 )
 
+// The regions find_next and replace_next are restricted to
+const findScopeKey = "lime.find.in_selection"
+
 // Run executes the FindUnderExpand command.
 func (c *FindUnderExpand) Run(v *lime.View, e *lime.Edit) error {
 	sel := v.Sel()
@@ -78,54 +86,118 @@ func (c *FindUnderExpand) Run(v *lime.View, e *lime.Edit) error {
 	return nil
 }
 
-func nextSelection(v *lime.View, search string) (text.Region, error) {
-	sel := v.Sel()
-	rs := sel.Regions()
-	last := 0
-	wrap := v.Settings().Bool("find_wrap")
+// Returns the View.Find flags of the find settings of the view:
+// "find_regex", "find_case_sensitive", "find_whole_word" and
+// "find_preserve_case".
+func viewFindFlags(v *lime.View) int {
+	s := v.Settings()
+	flags := findFlags(s.Bool("find_regex", false), s.Bool("find_case_sensitive", false), s.Bool("find_whole_word", false))
+	if s.Bool("find_preserve_case", false) {
+		flags |= lime.PRESERVECASE
+	}
+	return flags
+}
 
+// Returns the regions the search is restricted to with the
+// "find_in_selection" setting, nil for the whole view. The find_next and
+// replace_next commands keep searching the selections they started in,
+// find_all and replace_all search the current ones.
+func findScope(v *lime.View, current bool) []text.Region {
+	if !v.Settings().Bool("find_in_selection", false) {
+		v.EraseRegions(findScopeKey)
+		return nil
+	}
+	if rs := v.GetRegions(findScopeKey); len(rs) != 0 && !current {
+		return rs
+	}
+	var rs []text.Region
+	for _, r := range v.Sel().Regions() {
+		if !r.Empty() {
+			rs = append(rs, r)
+		}
+	}
+	if len(rs) == 0 {
+		v.EraseRegions(findScopeKey)
+		return nil
+	}
+	v.AddRegions(findScopeKey, rs, "", "", render.HIDDEN)
+	return rs
+}
+
+// Returns the matches of the search in the scope, and what they're
+// replaced by given the replacement format.
+func findMatches(v *lime.View, search, format string, current bool) ([]text.Region, []string, error) {
+	rs, reps, err := v.FindReplace(search, viewFindFlags(v), format)
+	if err != nil {
+		return nil, nil, err
+	}
+	scope := findScope(v, current)
+	if scope == nil {
+		return rs, reps, nil
+	}
+	var (
+		inRs   []text.Region
+		inReps []string
+	)
+	for i, r := range rs {
+		for _, sr := range scope {
+			if r.Begin() >= sr.Begin() && r.End() <= sr.End() {
+				inRs = append(inRs, r)
+				inReps = append(inReps, reps[i])
+				break
+			}
+		}
+	}
+	return inRs, inReps, nil
+}
+
+// Returns the index of the match following the selections, wrapping
+// around with the "find_wrap" setting.
+func nextMatch(v *lime.View, matches []text.Region) (int, error) {
+	last := 0
 	// Regions are not sorted, so finding the last one requires a search.
-	for _, r := range rs {
+	for _, r := range v.Sel().Regions() {
 		last = text.Max(last, r.End())
 	}
-
 	// Start the search right after the last selection.
-	start := last
-	r := v.Find(search, start, lime.IGNORECASE|lime.LITERAL)
+	for i, r := range matches {
+		if r.Begin() >= last {
+			return i, nil
+		}
+	}
 	// If not found yet and find_wrap setting is true, search
 	// from the start of the buffer to our original starting point.
-	if r.A == -1 && wrap {
-		r = v.Find(search, 0, lime.IGNORECASE|lime.LITERAL)
+	if len(matches) != 0 && v.Settings().Bool("find_wrap", true) {
+		return 0, nil
 	}
-	// If we found our string, select it.
-	if r.A != -1 {
-		return r, nil
+	return -1, errors.New("Selection not Found")
+}
+
+func nextSelection(v *lime.View, search string) (text.Region, error) {
+	matches, _, err := findMatches(v, search, "", false)
+	if err != nil {
+		return text.Region{A: -1, B: -1}, err
+	}
+	i, err := nextMatch(v, matches)
+	if err != nil {
+		return text.Region{A: -1, B: -1}, err
 	}
-	return text.Region{-1, -1}, errors.New("Selection not Found")
+	return matches[i], nil
 }
 
+// Run executes the FindAll command.
 func (c *FindAll) Run(v *lime.View, e *lime.Edit) error {
 	if len(c.SearchText) == 0 {
 		return nil
 	}
-	/* Original state of find_wrap is stored and then is made false so that nextSection doesn't go into an infinate loop.
-	   Later in the end, it is returned to it's original state.
-	*/
-	wrap := v.Settings().Bool("find_wrap")
-	v.Settings().Set("find_wrap", false)
-	search := string(c.SearchText)
+	matches, _, err := findMatches(v, string(c.SearchText), "", true)
+	if err != nil {
+		return err
+	}
 	sel := v.Sel()
 	sel.Clear()
-	for {
-		selection, err := nextSelection(v, search)
-		if err != nil {
-			break
-		}
-		sel.Add(selection)
-	}
-	v.Settings().Set("find_wrap", wrap)
+	sel.AddAll(matches)
 	return nil
-
 }
 
 // Run executes the FindNext command.
@@ -137,8 +209,8 @@ func (c *FindNext) Run(v *lime.View, e *lime.Edit) error {
 			  search immediately after that.
 			- If the search term is found, clear any existing
 			  selections, and select the newly-found region.
-			- Right now this is doing a case-sensitive search. In ST3
-			  that's a setting.
+			- The search honors the find settings, see
+			  viewFindFlags and findScope.
 	*/
 
 	// If there is no last search term, nothing to do here.
@@ -155,43 +227,50 @@ func (c *FindNext) Run(v *lime.View, e *lime.Edit) error {
 	return nil
 }
 
+// Run executes the ReplaceAll command.
 func (c *ReplaceAll) Run(v *lime.View, e *lime.Edit) error {
 	if len(c.SearchText) == 0 {
 		return nil
 	}
-	search := string(c.SearchText)
-	replace := string(c.ReplaceText)
-	sel := v.Sel()
-	sel.Clear()
-	/* Original state of find_wrap is stored and then is made false so that nextSection doesn't go into an infinate loop.
-	   Later in the end, it is returned to it's original state.
-	*/
-	wrap := v.Settings().Bool("find_wrap")
-	v.Settings().Set("find_wrap", false)
-	for {
-		selection, err := nextSelection(v, string(search))
-		if err != nil {
-			break
+	matches, reps, err := findMatches(v, string(c.SearchText), string(c.ReplaceText), true)
+	if err != nil || len(matches) == 0 {
+		return err
+	}
+	// replaced from the last one so that the replacements don't move
+	// the matches left, the last replacement moves with them
+	last := text.Region{A: -1, B: -1}
+	for i := len(matches) - 1; i >= 0; i-- {
+		r := matches[i]
+		if !v.Replace(e, r, reps[i]) {
+			continue
+		}
+		n := len([]rune(reps[i]))
+		if last.A == -1 {
+			last = text.Region{A: r.Begin(), B: r.Begin() + n}
+		} else {
+			last = text.Region{A: last.A + n - r.Size(), B: last.B + n - r.Size()}
 		}
-		v.Erase(e, selection)
-		v.Insert(e, selection.Begin(), replace)
-		sel := v.Sel()
-		sel.Clear()
-		sel.Add(text.Region{selection.Begin(), selection.Begin() + len(replace)})
 	}
-	v.Settings().Set("find_wrap", wrap)
+	if last.A == -1 {
+		return nil
+	}
+	sel := v.Sel()
+	sel.Clear()
+	sel.Add(last)
 	return nil
 }
 
 // Run executes the ReplaceNext command.
 func (c *ReplaceNext) Run(v *lime.View, e *lime.Edit) error {
-	// use selection function from find.go to get the next region
-	selection, err := nextSelection(v, string(lastSearch))
+	matches, reps, err := findMatches(v, string(lastSearch), replaceText, false)
+	if err != nil {
+		return err
+	}
+	i, err := nextMatch(v, matches)
 	if err != nil {
 		return err
 	}
-	v.Erase(e, selection)
-	v.Insert(e, selection.Begin(), replaceText)
+	v.Replace(e, matches[i], reps[i])
 	return nil
 }
 
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/jxo/lime"
+	"github.com/jxo/lime/render"
 	"github.com/jxo/lime/text"
 )
 
@@ -137,6 +138,29 @@ func TestReplaceAll(t *testing.T) {
 	runReplaceAllTest(tests, t, "replace_all")
 }
 
+func TestReplaceAllProtected(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "aa xx aa yy aa")
+	v.EndEdit(e)
+	v.AddRegions("protected", []text.Region{{0, 2}}, "", "", render.PROTECTED)
+
+	ed.CommandHandler().RunTextCommand(v, "replace_all", lime.Args{"search_text": []rune("aa"), "replace_text": []rune("bbbb")})
+	if out, exp := v.Substr(text.Region{0, v.Size()}), "aa xx bbbb yy bbbb"; out != exp {
+		t.Errorf("Expected %q, but got %q", exp, out)
+	}
+	if rs := v.Sel().Regions(); len(rs) != 1 || rs[0] != (text.Region{14, 18}) {
+		t.Errorf("Expected the last replacement to be selected, but got %v", rs)
+	}
+}
+
 func runReplaceAllTest(tests []replaceAllTest, t *testing.T, commands ...string) {
 	ed := lime.GetEditor()
 	w := ed.NewWindow()
@@ -313,3 +337,147 @@ func TestReplaceNext(t *testing.T) {
 
 	runReplaceTest(tests, t, "find_under_expand", "replace_next")
 }
+
+func TestFindOptions(t *testing.T) {
+	tests := []struct {
+		settings map[string]interface{}
+		in       string
+		sel      []text.Region
+		cmd      string
+		args     lime.Args
+		exp      string
+		expSel   []text.Region
+	}{
+		{
+			map[string]interface{}{"find_regex": true},
+			"foo1 bar foo22",
+			nil,
+			"find_all",
+			lime.Args{"search_text": []rune(`foo\d+`)},
+			"foo1 bar foo22",
+			[]text.Region{{0, 4}, {9, 14}},
+		},
+		{
+			map[string]interface{}{"find_case_sensitive": true},
+			"Abc abc ABC",
+			nil,
+			"find_all",
+			lime.Args{"search_text": []rune("abc")},
+			"Abc abc ABC",
+			[]text.Region{{4, 7}},
+		},
+		{
+			map[string]interface{}{"find_whole_word": true},
+			"abc abcd dabc abc",
+			nil,
+			"find_all",
+			lime.Args{"search_text": []rune("abc")},
+			"abc abcd dabc abc",
+			[]text.Region{{0, 3}, {14, 17}},
+		},
+		{
+			map[string]interface{}{"find_in_selection": true},
+			"abc abc abc abc",
+			[]text.Region{{2, 12}},
+			"find_all",
+			lime.Args{"search_text": []rune("abc")},
+			"abc abc abc abc",
+			[]text.Region{{4, 7}, {8, 11}},
+		},
+		{
+			map[string]interface{}{"find_regex": true},
+			"key = value\nname = lime\n",
+			nil,
+			"replace_all",
+			lime.Args{"search_text": []rune(`(\w+) = (?P<v>\w+)`), "replace_text": []rune(`\U$1\E: ${v}`)},
+			"KEY: value\nNAME: lime\n",
+			[]text.Region{{11, 21}},
+		},
+		{
+			map[string]interface{}{"find_regex": true},
+			"snake_case",
+			nil,
+			"replace_all",
+			lime.Args{"search_text": []rune(`_(\w)`), "replace_text": []rune(`\u\1`)},
+			"snakeCase",
+			[]text.Region{{5, 6}},
+		},
+		{
+			map[string]interface{}{"find_preserve_case": true},
+			"foo Foo FOO",
+			nil,
+			"replace_all",
+			lime.Args{"search_text": []rune("foo"), "replace_text": []rune("bar")},
+			"bar Bar BAR",
+			[]text.Region{{8, 11}},
+		},
+		{
+			map[string]interface{}{"find_in_selection": true},
+			"abc abc abc abc",
+			[]text.Region{{4, 11}},
+			"replace_all",
+			lime.Args{"search_text": []rune("abc"), "replace_text": []rune("x")},
+			"abc x x abc",
+			[]text.Region{{6, 7}},
+		},
+	}
+
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+
+	for i, test := range tests {
+		v := w.NewFile()
+		for k, val := range test.settings {
+			v.Settings().Set(k, val)
+		}
+		e := v.BeginEdit()
+		v.Insert(e, 0, test.in)
+		v.EndEdit(e)
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+
+		ed.CommandHandler().RunTextCommand(v, test.cmd, test.args)
+		if out := v.Substr(text.Region{0, v.Size()}); out != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, out)
+		}
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, test.expSel) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.expSel, sr)
+		}
+		v.SetScratch(true)
+		v.Close()
+	}
+}
+
+func TestFindNextInSelection(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "abc abc abc abc")
+	v.EndEdit(e)
+	v.Settings().Set("find_in_selection", true)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{2, 11})
+	lastSearch = []rune("abc")
+
+	// the search keeps to the selection it started in
+	for i, exp := range []text.Region{{4, 7}, {8, 11}, {4, 7}} {
+		ed.CommandHandler().RunTextCommand(v, "find_next", nil)
+		if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, []text.Region{exp}) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, exp, sr)
+		}
+	}
+
+	v.Settings().Set("find_in_selection", false)
+	ed.CommandHandler().RunTextCommand(v, "find_next", nil)
+	if sr := v.Sel().Regions(); !reflect.DeepEqual(sr, []text.Region{{8, 11}}) {
+		t.Errorf("Expected %v, but got %v", text.Region{8, 11}, sr)
+	}
+}
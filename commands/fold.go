@@ -0,0 +1,74 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/jxo/lime"
+)
+
+type (
+	// Fold command folds the selected text, for an empty
+	// selection the indented block after the cursor's line is folded.
+	Fold struct {
+		lime.BypassUndoCommand
+	}
+
+	// Unfold command unfolds the folds touching the selection.
+	Unfold struct {
+		lime.BypassUndoCommand
+	}
+
+	// FoldByLevel command folds all the blocks at the given
+	// indentation level.
+	FoldByLevel struct {
+		lime.BypassUndoCommand
+		Level int
+	}
+
+	// UnfoldAll command unfolds all the folds of the view.
+	UnfoldAll struct {
+		lime.BypassUndoCommand
+	}
+)
+
+// Run executes the Fold command.
+func (c *Fold) Run(v *lime.View, e *lime.Edit) error {
+	for _, r := range v.Sel().Regions() {
+		if r.Empty() {
+			r = v.IndentedRegion(r.A)
+		}
+		v.Fold(r)
+	}
+	return nil
+}
+
+// Run executes the Unfold command.
+func (c *Unfold) Run(v *lime.View, e *lime.Edit) error {
+	for _, r := range v.Sel().Regions() {
+		v.Unfold(r)
+	}
+	return nil
+}
+
+// Run executes the FoldByLevel command.
+func (c *FoldByLevel) Run(v *lime.View, e *lime.Edit) error {
+	v.FoldByLevel(c.Level)
+	return nil
+}
+
+// Run executes the UnfoldAll command.
+func (c *UnfoldAll) Run(v *lime.View, e *lime.Edit) error {
+	v.UnfoldAll()
+	return nil
+}
+
+func init() {
+	register([]lime.Command{
+		&Fold{},
+		&Unfold{},
+		&FoldByLevel{},
+		&UnfoldAll{},
+	})
+}
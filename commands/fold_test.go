@@ -0,0 +1,49 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/text"
+)
+
+func TestFold(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "a\n\tb\n\tc\nd\n\te\n")
+	v.EndEdit(e)
+
+	tests := []struct {
+		cmd  string
+		args lime.Args
+		sel  []text.Region
+		exp  []text.Region
+	}{
+		{"fold", nil, []text.Region{{0, 0}}, []text.Region{{1, 7}}},
+		{"fold", nil, []text.Region{{9, 11}}, []text.Region{{1, 7}, {9, 11}}},
+		{"unfold", nil, []text.Region{{3, 3}}, []text.Region{{9, 11}}},
+		{"unfold_all", nil, nil, nil},
+		{"fold_by_level", lime.Args{"level": 1}, nil, []text.Region{{1, 7}, {9, 12}}},
+	}
+	for i, test := range tests {
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+		ed.CommandHandler().RunTextCommand(v, test.cmd, test.args)
+		if r := v.FoldedRegions(); !reflect.DeepEqual(r, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, r)
+		}
+	}
+}
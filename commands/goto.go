@@ -0,0 +1,202 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// GotoAnything command opens the project file best matching the
+	// query and goes to the ":line:col", "@symbol" or "#term" the query
+	// ends with. Without a file part the active view is used.
+	GotoAnything struct {
+		lime.DefaultCommand
+		Query string
+	}
+
+	// GotoLine command moves the cursor to the start of the given line.
+	GotoLine struct {
+		lime.BypassUndoCommand
+		Line int
+	}
+
+	// GotoDefinition command goes to the definition of Symbol, or of
+	// the word under the cursor, looking it up in the open files and in
+	// the project symbol index. The definitions are listed in a quick
+	// panel when there are more than one.
+	GotoDefinition struct {
+		lime.DefaultCommand
+		Symbol string
+	}
+)
+
+// Run executes the GotoAnything command.
+func (c *GotoAnything) Run(w *lime.Window) error {
+	q := lime.ParseGotoQuery(c.Query)
+	v := w.ActiveView()
+	if q.File != "" {
+		file := q.File
+		if !filepath.IsAbs(file) {
+			files := w.Project().FileIndex().Find(file)
+			if len(files) == 0 {
+				return fmt.Errorf("No file matches %s", q.File)
+			}
+			file = files[0]
+		}
+		v = openFile(w, file)
+	}
+	if v == nil {
+		return fmt.Errorf("No view to go to %s in", c.Query)
+	}
+	return gotoQuery(v, q)
+}
+
+// Activates the view of the file if it's open, opens it otherwise.
+func openFile(w *lime.Window, file string) *lime.View {
+	if v := w.FindOpenFile(file); v != nil {
+		w.SetActiveView(v)
+		return v
+	}
+	return w.OpenFile(file, 0)
+}
+
+// Selects the position, symbol or term of the query in the view.
+func gotoQuery(v *lime.View, q lime.GotoQuery) error {
+	if v.IsLoading() {
+		// the position may not be loaded yet
+		v.WhenLoaded(func() {
+			if err := gotoQuery(v, q); err != nil {
+				log.Error(err)
+			}
+		})
+		return nil
+	}
+	var r text.Region
+	switch {
+	case q.Line > 0:
+		pt := v.TextPoint(q.Line-1, text.Max(q.Column-1, 0))
+		r = text.Region{A: pt, B: pt}
+	case q.Symbol != "":
+		if r = findSymbol(v, q.Symbol); r.A == -1 {
+			return fmt.Errorf("No symbol %s in %s", q.Symbol, v)
+		}
+	case q.Term != "":
+		if r = v.Find(q.Term, 0, lime.LITERAL|lime.IGNORECASE); r.A == -1 {
+			return fmt.Errorf("%s not found in %s", q.Term, v)
+		}
+	default:
+		return nil
+	}
+	v.Sel().Clear()
+	v.Sel().Add(r)
+	lime.GetEditor().Frontend().Show(v, r)
+	return nil
+}
+
+// Returns the symbol of the view symbol list with the given name, else
+// the first occurrence of it scoped as an entity name or its first
+// occurrence if none is.
+func findSymbol(v *lime.View, sym string) text.Region {
+	for _, s := range v.Symbols() {
+		if v.Substr(s.Region) == sym {
+			return s.Region
+		}
+	}
+	first := text.Region{A: -1, B: -1}
+	for pos := 0; ; {
+		r := v.Find(sym, pos, lime.LITERAL)
+		if r.A == -1 {
+			return first
+		}
+		if v.ScoreSelector(r.A, "entity.name") > 0 {
+			return r
+		}
+		if first.A == -1 {
+			first = r
+		}
+		pos = r.B
+	}
+}
+
+// Run executes the GotoLine command.
+func (c *GotoLine) Run(v *lime.View, e *lime.Edit) error {
+	pt := v.TextPoint(text.Max(c.Line-1, 0), 0)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{A: pt, B: pt})
+	lime.GetEditor().Frontend().Show(v, text.Region{A: pt, B: pt})
+	return nil
+}
+
+// Run executes the GotoDefinition command.
+func (c *GotoDefinition) Run(w *lime.Window) error {
+	sym := c.Symbol
+	if v := w.ActiveView(); sym == "" && v != nil && v.Sel().Len() != 0 {
+		sym = v.Substr(v.Word(v.Sel().Get(0).B))
+	}
+	if sym == "" {
+		return fmt.Errorf("No symbol to go to the definition of")
+	}
+	locs := definitions(w, sym)
+	switch len(locs) {
+	case 0:
+		return fmt.Errorf("No definition of %s found", sym)
+	case 1:
+		return gotoLocation(w, locs[0])
+	}
+	idx := w.Project().FileIndex()
+	items := make([]lime.QuickPanelItem, len(locs))
+	for i, l := range locs {
+		items[i] = lime.QuickPanelItem{
+			Text:    l.Name,
+			Details: []string{fmt.Sprintf("%s:%d", idx.Rel(l.Path), l.Row)},
+		}
+	}
+	q := lime.NewQuickPanel(items, 0, 0, func(i int) {
+		if i != -1 {
+			gotoLocation(w, locs[i])
+		}
+	}, nil)
+	w.ShowOverlay(q)
+	return nil
+}
+
+// Returns the definitions of the symbol in the open files, followed by
+// those of the project index in files which aren't open.
+func definitions(w *lime.Window, sym string) []lime.SymbolLocation {
+	var ret []lime.SymbolLocation
+	for _, l := range w.LookupSymbolInOpenFiles(sym) {
+		if l.Path != "" {
+			ret = append(ret, l)
+		}
+	}
+	for _, l := range w.LookupSymbolInIndex(sym) {
+		if w.FindOpenFile(l.Path) == nil {
+			ret = append(ret, l)
+		}
+	}
+	return ret
+}
+
+func gotoLocation(w *lime.Window, l lime.SymbolLocation) error {
+	v := openFile(w, l.Path)
+	if v == nil {
+		return fmt.Errorf("Couldn't open %s", l.Path)
+	}
+	return gotoQuery(v, lime.GotoQuery{Line: l.Row, Column: l.Col})
+}
+
+func init() {
+	register([]lime.Command{
+		&GotoAnything{},
+		&GotoLine{},
+		&GotoDefinition{},
+	})
+}
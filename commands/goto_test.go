@@ -0,0 +1,219 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/text"
+)
+
+const gotoMain = `package main
+
+func Run() {
+	// TODO: Run
+}
+`
+
+func newGotoWindow(t *testing.T) (*lime.Window, string) {
+	dir, err := ioutil.TempDir("", "lime-goto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "util"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(gotoMain), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "util", "util.go"), []byte("package util\n"), 0644)
+
+	w := lime.GetEditor().NewWindow()
+	w.Project().AddFolder(dir)
+	w.Project().FileIndex().Wait()
+	return w, dir
+}
+
+func closeGotoWindow(w *lime.Window, dir string) {
+	for _, v := range w.Views() {
+		v.SetScratch(true)
+	}
+	w.Close()
+	os.RemoveAll(dir)
+}
+
+func TestGotoAnything(t *testing.T) {
+	var fe scfe
+	ed := lime.GetEditor()
+	ed.SetFrontend(&fe)
+	w, dir := newGotoWindow(t)
+	defer closeGotoWindow(w, dir)
+
+	tests := []struct {
+		query string
+		file  string
+		exp   text.Region
+	}{
+		{"main:3:6", "main.go", text.Region{A: 19, B: 19}},
+		{"util", "util/util.go", text.Region{A: 0, B: 0}},
+		{"main#todo", "main.go", text.Region{A: 31, B: 35}},
+		{"main@Run", "main.go", text.Region{A: 19, B: 22}},
+		{":2", "main.go", text.Region{A: 13, B: 13}},
+	}
+	for i, test := range tests {
+		if err := ed.CommandHandler().RunWindowCommand(w, "goto_anything", lime.Args{"query": test.query}); err != nil {
+			t.Errorf("Test %d: Expected no error, but got %s", i, err)
+			continue
+		}
+		v := w.ActiveView()
+		if exp := filepath.Join(dir, test.file); v == nil || v.FileName() != exp {
+			t.Errorf("Test %d: Expected %s to be the active view, but got %v", i, exp, v)
+			continue
+		}
+		if s := v.Sel().Regions(); len(s) != 1 || s[0] != test.exp {
+			t.Errorf("Test %d: Expected selection %v, but got %v", i, test.exp, s)
+		}
+	}
+	if n := len(w.Views()); n != 2 {
+		t.Errorf("Expected the files to be opened once, but got %d views", n)
+	}
+
+	for _, q := range []string{"xyz", "main@Nothing", "main#nothing"} {
+		if err := ed.CommandHandler().RunWindowCommand(w, "goto_anything", lime.Args{"query": q}); err == nil {
+			t.Errorf("Expected an error for %q", q)
+		}
+	}
+}
+
+func TestGotoLine(t *testing.T) {
+	w := lime.GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, gotoMain)
+	v.EndEdit(e)
+
+	tests := []struct {
+		line int
+		exp  int
+	}{
+		{3, 14},
+		{0, 0},
+		{100, 43},
+	}
+	for i, test := range tests {
+		lime.GetEditor().CommandHandler().RunTextCommand(v, "goto_line", lime.Args{"line": test.line})
+		if s := v.Sel().Regions(); len(s) != 1 || s[0] != (text.Region{A: test.exp, B: test.exp}) {
+			t.Errorf("Test %d: Expected the cursor at %d, but got %v", i, test.exp, s)
+		}
+	}
+}
+
+func TestShowGotoOverlay(t *testing.T) {
+	var fe scfe
+	ed := lime.GetEditor()
+	ed.SetFrontend(&fe)
+	w, dir := newGotoWindow(t)
+	defer closeGotoWindow(w, dir)
+
+	if err := ed.CommandHandler().RunWindowCommand(w, "show_overlay", lime.Args{"overlay": "goto", "text": "main:3"}); err != nil {
+		t.Fatalf("Expected no error, but got %s", err)
+	}
+	q := fe.quickPanel
+	if q == nil {
+		t.Fatal("Expected the goto panel to be shown")
+	}
+	var items []string
+	for _, it := range q.Items {
+		items = append(items, filepath.ToSlash(it.Text))
+	}
+	if len(items) != 2 || items[0] != "main.go" || items[1] != "util/util.go" {
+		t.Fatalf("Expected the project files, but got %v", items)
+	}
+	q.Select(0)
+	v := w.ActiveView()
+	if v == nil || v.FileName() != filepath.Join(dir, "main.go") {
+		t.Fatalf("Expected main.go to be opened, but got %v", v)
+	}
+	if s := v.Sel().Regions(); len(s) != 1 || s[0] != (text.Region{A: 14, B: 14}) {
+		t.Errorf("Expected the cursor at line 3, but got %v", s)
+	}
+}
+
+// Waits for the parse of the view to be done, so the syntax
+// regions added afterwards aren't replaced by it.
+func waitForParse(t *testing.T, v *lime.View) {
+	for start := time.Now(); v.Settings().Int("lime.syntax.updated", -1) != v.ChangeCount(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("Waiting for the parse of %s took too long", v.FileName())
+		}
+	}
+}
+
+func TestGotoDefinition(t *testing.T) {
+	var fe scfe
+	ed := lime.GetEditor()
+	ed.SetFrontend(&fe)
+	w, dir := newGotoWindow(t)
+	defer closeGotoWindow(w, dir)
+
+	const settings = "Packages/Go/Symbol List.tmPreferences"
+	ed.AddSymbolSettings(settings, &lime.SymbolSettings{
+		Scope:                   "entity.name.function",
+		ShowInSymbolList:        true,
+		ShowInIndexedSymbolList: true,
+	})
+	defer ed.AddSymbolSettings(settings, &lime.SymbolSettings{})
+
+	main := w.OpenFile(filepath.Join(dir, "main.go"), 0)
+	waitForParse(t, main)
+	main.AddRegions("lime.syntax source.go entity.name.function.go", []text.Region{{A: 19, B: 22}}, "source.go entity.name.function.go", "", 0)
+
+	if err := ed.CommandHandler().RunWindowCommand(w, "goto_definition", lime.Args{"symbol": "Run"}); err != nil {
+		t.Fatalf("Expected no error, but got %s", err)
+	}
+	if s := main.Sel().Regions(); len(s) != 1 || s[0] != (text.Region{A: 19, B: 19}) {
+		t.Errorf("Expected the cursor at the definition of Run, but got %v", s)
+	}
+
+	util := w.OpenFile(filepath.Join(dir, "util", "util.go"), 0)
+	waitForParse(t, util)
+	e := util.BeginEdit()
+	util.Insert(e, util.Size(), "\nfunc Run() {}\n")
+	util.EndEdit(e)
+	waitForParse(t, util)
+	util.AddRegions("lime.syntax source.go entity.name.function.go", []text.Region{{A: 19, B: 22}}, "source.go entity.name.function.go", "", 0)
+
+	// the word under the cursor with a definition in both files
+	w.SetActiveView(main)
+	main.Sel().Clear()
+	main.Sel().Add(text.Region{A: 38, B: 38})
+	fe.quickPanel = nil
+	if err := ed.CommandHandler().RunWindowCommand(w, "goto_definition", nil); err != nil {
+		t.Fatalf("Expected no error, but got %s", err)
+	}
+	q := fe.quickPanel
+	if q == nil || len(q.Items) != 2 {
+		t.Fatalf("Expected a panel listing the 2 definitions, but got %v", q)
+	}
+	q.Select(1)
+	if v := w.ActiveView(); v != util {
+		t.Fatalf("Expected util.go to be the active view, but got %v", v)
+	}
+	if s := util.Sel().Regions(); len(s) != 1 || s[0] != (text.Region{A: 19, B: 19}) {
+		t.Errorf("Expected the cursor at the definition of Run, but got %v", s)
+	}
+
+	if err := ed.CommandHandler().RunWindowCommand(w, "goto_definition", lime.Args{"symbol": "Nothing"}); err == nil {
+		t.Error("Expected an error for an undefined symbol")
+	}
+}
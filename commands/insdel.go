@@ -52,6 +52,7 @@ func (c *Insert) Run(v *lime.View, e *lime.Edit) error {
 			v.Replace(e, r, c.Characters)
 		}
 	}
+	v.UpdateAutoComplete(c.Characters)
 	return nil
 }
 
@@ -99,6 +100,7 @@ func (c *LeftDelete) Run(v *lime.View, e *lime.Edit) error {
 		}
 		i++
 	}
+	v.UpdateAutoComplete("")
 	return nil
 }
 
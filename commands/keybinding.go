@@ -0,0 +1,41 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"strings"
+
+	"github.com/jxo/lime"
+)
+
+const conflictsPanel = "key_binding_conflicts"
+
+type (
+	// KeyBindingConflicts command lists the key bindings shadowed by
+	// others in an output panel.
+	KeyBindingConflicts struct {
+		lime.DefaultCommand
+	}
+)
+
+// Run executes the KeyBindingConflicts command.
+func (c *KeyBindingConflicts) Run(w *lime.Window) error {
+	report := "No key binding conflicts\n"
+	if conflicts := lime.GetEditor().KeyBindingConflicts(); len(conflicts) != 0 {
+		report = strings.Join(conflicts, "\n") + "\n"
+	}
+	v := w.CreateOutputPanel(conflictsPanel)
+	e := v.BeginEdit()
+	v.Insert(e, 0, report)
+	v.EndEdit(e)
+	w.ShowPanel("output." + conflictsPanel)
+	return nil
+}
+
+func init() {
+	register([]lime.Command{
+		&KeyBindingConflicts{},
+	})
+}
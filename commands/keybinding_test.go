@@ -0,0 +1,37 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/text"
+)
+
+func TestKeyBindingConflicts(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+
+	if err := ed.CommandHandler().RunWindowCommand(w, "key_binding_conflicts", nil); err != nil {
+		t.Fatalf("Expected no error, but got %s", err)
+	}
+	if p := w.ActivePanel(); p != "output."+conflictsPanel {
+		t.Errorf("Expected the conflicts panel to be shown, but got %q", p)
+	}
+	v := w.FindOutputPanel(conflictsPanel)
+	if v == nil {
+		t.Fatal("Expected the conflicts panel to be created")
+	}
+	exp := "No key binding conflicts\n"
+	if conflicts := ed.KeyBindingConflicts(); len(conflicts) != 0 {
+		exp = strings.Join(conflicts, "\n") + "\n"
+	}
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/jxo/lime"
+)
+
+type (
+	// SetLayout command changes the layout of the window,
+	// the args are the same as in sublime: cols, rows and cells.
+	SetLayout struct {
+		lime.DefaultCommand
+		layout lime.Layout
+	}
+
+	// FocusGroup command focuses the given group.
+	FocusGroup struct {
+		lime.DefaultCommand
+		Group int
+	}
+
+	// MoveToGroup command moves the active view
+	// to the end of the given group.
+	MoveToGroup struct {
+		lime.DefaultCommand
+		Group int
+	}
+
+	// NewPane command splits the window adding a new group
+	// on the right and moves the active view into it.
+	NewPane struct {
+		lime.DefaultCommand
+	}
+
+	// ClosePane command removes the active group, its
+	// views are merged into the neighbour group.
+	ClosePane struct {
+		lime.DefaultCommand
+	}
+)
+
+// Init parses the layout from the command args.
+func (c *SetLayout) Init(args lime.Args) error {
+	return c.layout.Set(args)
+}
+
+// Run executes the SetLayout command.
+func (c *SetLayout) Run(w *lime.Window) error {
+	return w.SetLayout(c.layout)
+}
+
+// Run executes the FocusGroup command.
+func (c *FocusGroup) Run(w *lime.Window) error {
+	if c.Group < 0 || c.Group >= w.NumGroups() {
+		return fmt.Errorf("Window{id:%d} has no group %d", w.ID(), c.Group)
+	}
+	w.FocusGroup(c.Group)
+	return nil
+}
+
+// Run executes the MoveToGroup command.
+func (c *MoveToGroup) Run(w *lime.Window) error {
+	v := w.ActiveView()
+	if v == nil {
+		return nil
+	}
+	if c.Group < 0 || c.Group >= w.NumGroups() {
+		return fmt.Errorf("Window{id:%d} has no group %d", w.ID(), c.Group)
+	}
+	w.SetViewIndex(v, c.Group, len(w.ViewsInGroup(c.Group)))
+	w.FocusGroup(c.Group)
+	return nil
+}
+
+// Run executes the NewPane command.
+func (c *NewPane) Run(w *lime.Window) error {
+	if err := w.SetLayout(w.Layout().Split()); err != nil {
+		return err
+	}
+	g := w.NumGroups() - 1
+	if v := w.ActiveView(); v != nil {
+		w.SetViewIndex(v, g, 0)
+	}
+	w.FocusGroup(g)
+	return nil
+}
+
+// Run executes the ClosePane command.
+func (c *ClosePane) Run(w *lime.Window) error {
+	if w.NumGroups() == 1 {
+		return nil
+	}
+	return w.MergeGroup(w.ActiveGroup())
+}
+
+func init() {
+	register([]lime.Command{
+		&SetLayout{},
+		&FocusGroup{},
+		&MoveToGroup{},
+		&NewPane{},
+		&ClosePane{},
+	})
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/jxo/lime"
+)
+
+func TestSetLayout(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+
+	ed.CommandHandler().RunWindowCommand(w, "set_layout", lime.Args{
+		"cols":  []interface{}{0.0, 0.5, 1.0},
+		"rows":  []interface{}{0.0, 1.0},
+		"cells": []interface{}{[]interface{}{0.0, 0.0, 1.0, 1.0}, []interface{}{1.0, 0.0, 2.0, 1.0}},
+	})
+	if w.NumGroups() != 2 {
+		t.Errorf("Expected 2 groups, but got %d", w.NumGroups())
+	}
+
+	ed.CommandHandler().RunWindowCommand(w, "focus_group", lime.Args{"group": 1.0})
+	if w.ActiveGroup() != 1 {
+		t.Errorf("Expected group 1 to be focused, but got %d", w.ActiveGroup())
+	}
+}
+
+func TestMoveToGroup(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+
+	v0 := w.NewFile()
+	defer v0.Close()
+	v1 := w.NewFile()
+	defer v1.Close()
+
+	ed.CommandHandler().RunWindowCommand(w, "new_pane", nil)
+	if w.NumGroups() != 2 {
+		t.Fatalf("Expected 2 groups, but got %d", w.NumGroups())
+	}
+	if g, _ := w.GetViewIndex(v1); g != 1 || w.ActiveGroup() != 1 {
+		t.Errorf("Expected v1 to be moved to the new focused group, but it's in %d", g)
+	}
+
+	ed.CommandHandler().RunWindowCommand(w, "move_to_group", lime.Args{"group": 0.0})
+	if vs := w.ViewsInGroup(0); len(vs) != 2 || vs[1] != v1 {
+		t.Errorf("Expected v1 to be moved back to the end of group 0, but got %v", vs)
+	}
+	if w.ActiveView() != v1 {
+		t.Error("Expected v1 to still be the active view")
+	}
+
+	ed.CommandHandler().RunWindowCommand(w, "focus_group", lime.Args{"group": 1.0})
+	ed.CommandHandler().RunWindowCommand(w, "close_pane", nil)
+	if w.NumGroups() != 1 {
+		t.Errorf("Expected 1 group, but got %d", w.NumGroups())
+	}
+}
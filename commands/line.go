@@ -96,7 +96,9 @@ func (c *JoinLines) Run(v *lime.View, e *lime.Edit) error {
 			}
 			t += " " + strings.TrimLeft(s, " \t")
 		}
-		v.Replace(e, r, t)
+		if !v.Replace(e, r, t) {
+			continue
+		}
 		// Removing the first new line after selection
 		liner := v.FullLine(r.End())
 		line := v.Substr(liner)
@@ -129,8 +131,10 @@ func (c *SwapLineUp) Run(v *lime.View, e *lime.Edit) error {
 		// Select line before region
 		bline := v.Line(r.Begin() - 1)
 		bt := v.Substr(bline)
-		v.Replace(e, r, bt)
-		v.Replace(e, bline, t)
+		if v.Replace(e, r, bt) && !v.Replace(e, bline, t) {
+			// the line before can't be modified, put the lines back
+			v.Replace(e, text.Region{r.Begin(), r.Begin() + bline.Size()}, t)
+		}
 	}
 
 	return nil
@@ -149,8 +153,10 @@ func (c *SwapLineDown) Run(v *lime.View, e *lime.Edit) error {
 		// Select line before region
 		nline := v.Line(r.End() + 1)
 		nt := v.Substr(nline)
-		v.Replace(e, nline, t)
-		v.Replace(e, r, nt)
+		if v.Replace(e, nline, t) && !v.Replace(e, r, nt) {
+			// the lines can't be modified, put the line after back
+			v.Replace(e, text.Region{nline.Begin(), nline.Begin() + r.Size()}, nt)
+		}
 	}
 
 	return nil
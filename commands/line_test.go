@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/jxo/lime"
+	"github.com/jxo/lime/render"
 	"github.com/jxo/lime/text"
 )
 
@@ -323,6 +324,27 @@ func TestSwapLine(t *testing.T) {
 			t.Errorf("Test %d:\nExcepted: '%s'\nbut got: '%s'", i, test.expect, d)
 		}
 	}
+
+	// the lines aren't swapped when one of them is protected
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "a\nb\nc")
+	v.EndEdit(e)
+	v.AddRegions("protected", []text.Region{{2, 3}}, "", "", render.PROTECTED)
+	for _, cmd := range []string{"swap_line_up", "swap_line_down"} {
+		for _, pt := range []int{0, 4} {
+			v.Sel().Clear()
+			v.Sel().Add(text.Region{pt, pt})
+			ed.CommandHandler().RunTextCommand(v, cmd, nil)
+			if d := v.Substr(text.Region{0, v.Size()}); d != "a\nb\nc" {
+				t.Errorf("%s at %d: Expected the lines not to be swapped, but got %q", cmd, pt, d)
+			}
+		}
+	}
 }
 
 func TestSplitToLines(t *testing.T) {
@@ -0,0 +1,31 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/jxo/lime"
+)
+
+type (
+	// SetLineEnding command sets the line endings
+	// the view will be saved with, "windows",
+	// "unix" or "cr".
+	SetLineEnding struct {
+		lime.BypassUndoCommand
+		Type string
+	}
+)
+
+// Run executes the SetLineEnding command.
+func (c *SetLineEnding) Run(v *lime.View, e *lime.Edit) error {
+	v.SetLineEndings(c.Type)
+	return nil
+}
+
+func init() {
+	register([]lime.Command{
+		&SetLineEnding{},
+	})
+}
@@ -0,0 +1,38 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/jxo/lime"
+)
+
+func TestSetLineEnding(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	tests := []struct {
+		typ string
+		exp string
+	}{
+		{"windows", "Windows"},
+		{"unix", "Unix"},
+		{"cr", "CR"},
+		{"klingon", "CR"},
+	}
+	for i, test := range tests {
+		ed.CommandHandler().RunTextCommand(v, "set_line_ending", lime.Args{"type": test.typ})
+		if le := v.LineEndings(); le != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, le)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jxo/lime"
+)
+
+type (
+	// ToggleRecordMacro command starts recording the commands run into
+	// a macro, or stops recording if a macro is being recorded.
+	ToggleRecordMacro struct {
+		lime.DefaultCommand
+	}
+
+	// RunMacro command runs the last macro recorded.
+	RunMacro struct {
+		lime.DefaultCommand
+	}
+
+	// RunMacroFile command runs the macro of the .sublime-macro File.
+	RunMacroFile struct {
+		lime.DefaultCommand
+		File string
+	}
+
+	// SaveMacro command saves the last macro recorded to File, asking
+	// for it when it isn't given.
+	SaveMacro struct {
+		lime.DefaultCommand
+		File string
+	}
+)
+
+// Run executes the ToggleRecordMacro command.
+func (c *ToggleRecordMacro) Run(w *lime.Window) error {
+	ed := lime.GetEditor()
+	if ed.IsRecordingMacro() {
+		ed.StopRecordingMacro()
+		ed.Frontend().StatusMessage("Stopped recording macro")
+	} else {
+		ed.StartRecordingMacro()
+		ed.Frontend().StatusMessage("Recording macro")
+	}
+	return nil
+}
+
+// Run executes the RunMacro command.
+func (c *RunMacro) Run(w *lime.Window) error {
+	return w.RunMacro(lime.GetEditor().Macro())
+}
+
+// IsEnabled returns whether a macro was recorded.
+func (c *RunMacro) IsEnabled() bool {
+	return len(lime.GetEditor().Macro()) != 0
+}
+
+// Run executes the RunMacroFile command.
+func (c *RunMacroFile) Run(w *lime.Window) error {
+	m, err := lime.GetEditor().FindMacro(c.File)
+	if err != nil {
+		return err
+	}
+	return w.RunMacro(m)
+}
+
+// Run executes the SaveMacro command.
+func (c *SaveMacro) Run(w *lime.Window) error {
+	ed := lime.GetEditor()
+	m := ed.Macro()
+	if len(m) == 0 {
+		return fmt.Errorf("No macro to save")
+	}
+	file := c.File
+	if file == "" {
+		files := ed.Frontend().Prompt("Save macro", ed.UserPath(), lime.PROMPT_SAVE_AS)
+		if len(files) == 0 {
+			return nil
+		}
+		file = files[0]
+	}
+	if filepath.Ext(file) != ".sublime-macro" {
+		file += ".sublime-macro"
+	}
+	if err := m.Save(file); err != nil {
+		ed.Frontend().ErrorMessage(fmt.Sprintf("Failed to save macro %s: %s", file, err))
+		return err
+	}
+	ed.AddMacro(file, &m)
+	return nil
+}
+
+// IsEnabled returns whether a macro was recorded.
+func (c *SaveMacro) IsEnabled() bool {
+	return len(lime.GetEditor().Macro()) != 0
+}
+
+func init() {
+	register([]lime.Command{
+		&ToggleRecordMacro{},
+		&RunMacro{},
+		&RunMacroFile{},
+		&SaveMacro{},
+	})
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/text"
+)
+
+func TestRecordAndRunMacro(t *testing.T) {
+	var fe scfe
+	ed := lime.GetEditor()
+	ed.SetFrontend(&fe)
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer w.Close()
+
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	ch.RunWindowCommand(w, "toggle_record_macro", nil)
+	if !ed.IsRecordingMacro() {
+		t.Fatal("Expected a macro to be recorded")
+	}
+	ch.RunTextCommand(v, "insert", lime.Args{"characters": "a"})
+	ch.RunTextCommand(v, "insert", lime.Args{"characters": "b"})
+	ch.RunWindowCommand(w, "toggle_record_macro", nil)
+	if ed.IsRecordingMacro() {
+		t.Fatal("Expected the macro recording to be stopped")
+	}
+	if l := len(ed.Macro()); l != 2 {
+		t.Fatalf("Expected 2 commands in the macro, but got %d", l)
+	}
+
+	if err := ch.RunWindowCommand(w, "run_macro", nil); err != nil {
+		t.Fatalf("Expected no error running the macro, but got %s", err)
+	}
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "abab" {
+		t.Errorf("Expected %q, but got %q", "abab", s)
+	}
+	ch.RunTextCommand(v, "undo", nil)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "ab" {
+		t.Errorf("Expected the macro to be undone in one step, but got %q", s)
+	}
+
+	dir, err := ioutil.TempDir("", "lime-macro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "Add ab")
+	if err := ch.RunWindowCommand(w, "save_macro", lime.Args{"file": file}); err != nil {
+		t.Fatalf("Expected no error saving the macro, but got %s", err)
+	}
+	file += ".sublime-macro"
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("Expected the macro to be saved to %s, but got %s", file, err)
+	}
+	if err := ch.RunWindowCommand(w, "run_macro_file", lime.Args{"file": file}); err != nil {
+		t.Fatalf("Expected no error running %s, but got %s", file, err)
+	}
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "abab" {
+		t.Errorf("Expected %q, but got %q", "abab", s)
+	}
+}
@@ -423,6 +423,7 @@ type scfe struct {
 	show          text.Region
 	defaultAction bool
 	files         []string
+	quickPanel    *lime.QuickPanel
 }
 
 func (f *scfe) StatusMessage(msg string) {}
@@ -446,6 +447,12 @@ func (f *scfe) Show(v *lime.View, r text.Region) {
 func (f *scfe) Prompt(title, dir string, flags int) []string {
 	return f.files
 }
+func (f *scfe) ShowQuickPanel(w *lime.Window, p *lime.QuickPanel) {
+	f.quickPanel = p
+}
+func (f *scfe) ShowInputPanel(w *lime.Window, p *lime.InputPanel) {}
+func (f *scfe) ShowPopupMenu(v *lime.View, p *lime.QuickPanel)    {}
+func (f *scfe) ShowAutoComplete(v *lime.View, p *lime.QuickPanel) {}
 
 func TestScrollLines(t *testing.T) {
 	var fe scfe
@@ -0,0 +1,83 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/jxo/lime"
+)
+
+type (
+	// ShowOverlay command shows the given overlay, "command_palette"
+	// or "goto", with Text as the initial filter.
+	ShowOverlay struct {
+		lime.DefaultCommand
+		Overlay string
+		Text    string
+	}
+)
+
+// Run executes the ShowOverlay command.
+func (c *ShowOverlay) Run(w *lime.Window) error {
+	switch c.Overlay {
+	case "command_palette":
+		showCommandPalette(w, c.Text)
+	case "goto":
+		showGotoAnything(w, c.Text)
+	default:
+		return fmt.Errorf("Unknown overlay: %s", c.Overlay)
+	}
+	return nil
+}
+
+// Lists the command palette entries in a quick panel annotated with
+// their key bindings, running the one selected.
+func showCommandPalette(w *lime.Window, query string) {
+	ed := lime.GetEditor()
+	cmds := ed.CommandPalette()
+	items := make([]lime.QuickPanelItem, len(cmds))
+	for i, c := range cmds {
+		items[i] = lime.QuickPanelItem{
+			Text:       c.Caption,
+			Annotation: ed.KeyBindingHint(c.Command, c.Args),
+		}
+	}
+	q := lime.NewQuickPanel(items, 0, -1, func(i int) {
+		if i != -1 {
+			ed.RunCommand(cmds[i].Command, cmds[i].Args)
+		}
+	}, nil)
+	q.Text = query
+	w.ShowOverlay(q)
+}
+
+// Lists the project files in a quick panel, the file selected is opened
+// at the position, symbol or term the panel text ends with.
+func showGotoAnything(w *lime.Window, query string) {
+	idx := w.Project().FileIndex()
+	files := idx.Files()
+	items := make([]lime.QuickPanelItem, len(files))
+	for i, f := range files {
+		items[i] = lime.QuickPanelItem{Text: idx.Rel(f), Details: []string{f}}
+	}
+	var q *lime.QuickPanel
+	q = lime.NewQuickPanel(items, 0, -1, func(i int) {
+		if i == -1 {
+			return
+		}
+		if v := openFile(w, files[i]); v != nil {
+			gotoQuery(v, lime.ParseGotoQuery(q.Text))
+		}
+	}, nil)
+	q.Text = query
+	w.ShowOverlay(q)
+}
+
+func init() {
+	register([]lime.Command{
+		&ShowOverlay{},
+	})
+}
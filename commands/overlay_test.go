@@ -0,0 +1,54 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/jxo/lime"
+)
+
+func TestShowOverlay(t *testing.T) {
+	var fe scfe
+	ed := lime.GetEditor()
+	ed.SetFrontend(&fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	w.CreateOutputPanel(execPanel)
+
+	const path = "overlay_test.sublime-commands"
+	ed.AddPaletteCommands(path, &lime.PaletteCommands{
+		{Caption: "Overlay Test: Show Exec", Command: "show_panel", Args: lime.Args{"panel": "output.exec"}},
+	})
+	defer ed.AddPaletteCommands(path, &lime.PaletteCommands{})
+
+	if err := ed.CommandHandler().RunWindowCommand(w, "show_overlay", lime.Args{"overlay": "command_palette", "text": "overlay"}); err != nil {
+		t.Fatalf("Expected no error, but got %s", err)
+	}
+	q := fe.quickPanel
+	if q == nil {
+		t.Fatal("Expected the command palette to be shown")
+	}
+	if q.Text != "overlay" {
+		t.Errorf("Expected the filter text %q, but got %q", "overlay", q.Text)
+	}
+	found := -1
+	for _, i := range q.Filter(q.Text) {
+		if q.Items[i].Text == "Overlay Test: Show Exec" {
+			found = i
+		}
+	}
+	if found == -1 {
+		t.Fatalf("Expected the palette entry in %v", q.Items)
+	}
+	q.Select(found)
+	if a := w.ActivePanel(); a != "output.exec" {
+		t.Errorf("Expected the selected command to show the exec panel, but got %q", a)
+	}
+
+	if err := ed.CommandHandler().RunWindowCommand(w, "show_overlay", lime.Args{"overlay": "unknown"}); err == nil {
+		t.Errorf("Expected an error for an unknown overlay")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// InsertSnippet command inserts a snippet,
+	// either its Contents or the snippet file
+	// Name, at each selection and selects its
+	// first field.
+	InsertSnippet struct {
+		lime.DefaultCommand
+		Contents string
+		Name     string
+	}
+
+	// NextField command selects the next field
+	// of the snippet being edited.
+	NextField struct {
+		lime.DefaultCommand
+	}
+
+	// PrevField command selects the previous
+	// field of the snippet being edited.
+	PrevField struct {
+		lime.DefaultCommand
+	}
+
+	// ClearFields command stops editing the
+	// fields of the snippet.
+	ClearFields struct {
+		lime.BypassUndoCommand
+	}
+
+	// InsertBestCompletion command expands the
+	// tab trigger before the cursor, or inserts
+	// the best completion unless Exact is set,
+	// or inserts Default otherwise.
+	InsertBestCompletion struct {
+		lime.DefaultCommand
+		Default string
+		Exact   bool
+	}
+)
+
+// Run executes the InsertSnippet command.
+func (c *InsertSnippet) Run(v *lime.View, e *lime.Edit) error {
+	contents := c.Contents
+	if c.Name != "" {
+		s, err := lime.GetEditor().FindSnippet(c.Name)
+		if err != nil {
+			return err
+		}
+		contents = s.Content
+	}
+	return v.InsertSnippet(e, contents)
+}
+
+// Run executes the NextField command.
+func (c *NextField) Run(v *lime.View, e *lime.Edit) error {
+	v.NextField(e)
+	return nil
+}
+
+// Run executes the PrevField command.
+func (c *PrevField) Run(v *lime.View, e *lime.Edit) error {
+	v.PrevField(e)
+	return nil
+}
+
+// Run executes the ClearFields command.
+func (c *ClearFields) Run(v *lime.View, e *lime.Edit) error {
+	v.ClearFields()
+	return nil
+}
+
+// Run executes the InsertBestCompletion command.
+func (c *InsertBestCompletion) Run(v *lime.View, e *lime.Edit) error {
+	if s, ok := tabTrigger(v); ok {
+		trigger := func(r text.Region) text.Region {
+			_, tr, _ := v.TabTrigger(r.B)
+			return tr
+		}
+		if !eraseBeforeSnippet(v, e, trigger) {
+			return nil
+		}
+		return v.InsertSnippet(e, s.Content)
+	}
+	if !c.Exact {
+		if _, cs := v.QueryCompletions(); len(cs) != 0 {
+			return (&InsertCompletion{Contents: cs[0].Contents}).Run(v, e)
+		}
+	}
+	lime.GetEditor().CommandHandler().RunTextCommand(v, "insert", lime.Args{"characters": c.Default})
+	return nil
+}
+
+// Returns the snippet which tab trigger is typed before every cursor.
+func tabTrigger(v *lime.View) (lime.Snippet, bool) {
+	var s lime.Snippet
+	for i, r := range v.Sel().Regions() {
+		ts, _, ok := v.TabTrigger(r.B)
+		if !ok || !r.Empty() || i > 0 && ts != s {
+			return lime.Snippet{}, false
+		}
+		s = ts
+	}
+	return s, true
+}
+
+func init() {
+	register([]lime.Command{
+		&InsertSnippet{},
+		&NextField{},
+		&PrevField{},
+		&ClearFields{},
+		&InsertBestCompletion{},
+	})
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/text"
+)
+
+func TestInsertSnippet(t *testing.T) {
+	ed := lime.GetEditor()
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	args := lime.Args{"contents": "if ${1:cond} {\n\t${2}\n}$0"}
+	ch.RunTextCommand(v, "insert_snippet", args)
+	exp := "if cond {\n\t\n}"
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+	ch.RunTextCommand(v, "undo", nil)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "" {
+		t.Errorf("Expected the snippet to be undone in one step, but got %q", s)
+	}
+	ch.RunTextCommand(v, "insert_snippet", args)
+	tests := []struct {
+		cmd string
+		exp text.Region
+	}{
+		{"next_field", text.Region{A: 11, B: 11}},
+		{"prev_field", text.Region{A: 3, B: 7}},
+		{"next_field", text.Region{A: 11, B: 11}},
+		{"next_field", text.Region{A: 13, B: 13}},
+	}
+	for i, test := range tests {
+		ch.RunTextCommand(v, test.cmd, nil)
+		if sel := v.Sel().Regions(); !reflect.DeepEqual(sel, []text.Region{test.exp}) {
+			t.Errorf("Test %d: Expected selection %v after %s, but got %v", i, test.exp, test.cmd, sel)
+		}
+	}
+	if v.HasNextField() {
+		t.Error("Expected no more fields")
+	}
+
+	const path = "Packages/Test/test.sublime-snippet"
+	ed.AddSnippet(path, &lime.Snippet{Content: "// ${1:TODO}"})
+	defer ed.AddSnippet(path, &lime.Snippet{})
+	if err := ch.RunTextCommand(v, "insert_snippet", lime.Args{"name": path}); err != nil {
+		t.Fatal(err)
+	}
+	exp = "if cond {\n\t\n}// TODO"
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+	ch.RunTextCommand(v, "clear_fields", nil)
+	if v.HasNextField() {
+		t.Error("Expected the fields to be cleared")
+	}
+}
+
+func TestInsertBestCompletion(t *testing.T) {
+	ed := lime.GetEditor()
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	const path = "Packages/Test/trigger.sublime-snippet"
+	ed.AddSnippet(path, &lime.Snippet{Content: "for ${1:i} {\n}", TabTrigger: "lfor"})
+	defer ed.AddSnippet(path, &lime.Snippet{})
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "lfor\nlfor\nxlfor\nrange ra")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().AddAll([]text.Region{{A: 4, B: 4}, {A: 9, B: 9}})
+
+	args := lime.Args{"default": "\t", "exact": true}
+	ch.RunTextCommand(v, "insert_best_completion", args)
+	exp := "for i {\n}\nfor i {\n}\nxlfor\nrange ra"
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+	if sel := v.Sel().Regions(); !reflect.DeepEqual(sel, []text.Region{{A: 4, B: 5}, {A: 14, B: 15}}) {
+		t.Errorf("Expected the fields of the snippets to be selected, but got %v", sel)
+	}
+
+	// the trigger isn't a word before every cursor, the default is
+	// inserted
+	v.Sel().Clear()
+	v.Sel().AddAll([]text.Region{{A: 0, B: 0}, {A: 25, B: 25}})
+	ch.RunTextCommand(v, "insert_best_completion", args)
+	exp = "\tfor i {\n}\nfor i {\n}\nxlfor\t\nrange ra"
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+
+	// or the best completion when not exact
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{A: v.Size(), B: v.Size()})
+	ch.RunTextCommand(v, "insert_best_completion", lime.Args{"default": "\t"})
+	exp = "\tfor i {\n}\nfor i {\n}\nxlfor\t\nrange range"
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+
+	// the snippet isn't expanded when its trigger is protected
+	ch.RunTextCommand(v, "insert", lime.Args{"characters": "\nlfor"})
+	v.AddRegions("protected", []text.Region{{A: v.Size() - 4, B: v.Size()}}, "", "", render.PROTECTED)
+	defer v.EraseRegions("protected")
+	exp = v.Substr(text.Region{A: 0, B: v.Size()})
+	ch.RunTextCommand(v, "insert_best_completion", args)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+}
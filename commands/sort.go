@@ -106,13 +106,15 @@ func (c *SortLines) Run(v *lime.View, e *lime.Edit) error {
 	for i, r := range regions {
 		r = text.Region{r.A + offset, r.B + offset}
 		if i < len(texts) {
-			v.Replace(e, r, texts[i])
-			offset += len(texts[i]) - r.Size()
+			if v.Replace(e, r, texts[i]) {
+				offset += len(texts[i]) - r.Size()
+			}
 		} else {
 			// Erase the line and its ending
 			fullLine := v.FullLineR(r)
-			v.Erase(e, fullLine)
-			offset -= fullLine.Size()
+			if v.Erase(e, fullLine) {
+				offset -= fullLine.Size()
+			}
 		}
 	}
 
@@ -144,11 +146,13 @@ func (c *SortSelection) Run(v *lime.View, e *lime.Edit) error {
 	for i, r := range regions {
 		r = text.Region{r.A + offset, r.B + offset}
 		if i < len(texts) {
-			v.Replace(e, r, texts[i])
-			offset += len(texts[i]) - r.Size()
+			if v.Replace(e, r, texts[i]) {
+				offset += len(texts[i]) - r.Size()
+			}
 		} else {
-			v.Erase(e, r)
-			offset -= r.Size()
+			if v.Erase(e, r) {
+				offset -= r.Size()
+			}
 		}
 	}
 
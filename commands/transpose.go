@@ -55,11 +55,23 @@ func (c *Transpose) Run(v *lime.View, e *lime.Edit) error {
 		srcr := trs.Regions()[trs.Len()-1]
 		stxt := v.Substr(srcr)
 		slen := srcr.Size()
+		var (
+			done []text.Region
+			orig []string
+		)
 		for i := 0; i < trs.Len(); i++ {
 			r := trs.Regions()[i]
 			dtxt := v.Substr(r)
 			dlen := r.Size()
-			v.Replace(e, r, stxt)
+			if !v.Replace(e, r, stxt) {
+				// the texts are left as they were
+				for j := len(done) - 1; j >= 0; j-- {
+					v.Replace(e, done[j], orig[j])
+				}
+				return nil
+			}
+			done = append(done, text.Region{r.Begin(), r.Begin() + slen})
+			orig = append(orig, dtxt)
 			trs.Adjust(r.Begin()+1, slen-dlen)
 			rsnew.Add(text.Region{r.Begin(), r.Begin() + slen})
 			stxt, slen = dtxt, dlen
@@ -78,8 +90,9 @@ func (c *Transpose) Run(v *lime.View, e *lime.Edit) error {
 			r2 := text.Region{r.A, r.A + 1}
 			s1 := v.Substr(r1)
 			s2 := v.Substr(r2)
-			v.Replace(e, r1, s2)
-			v.Replace(e, r2, s1)
+			if v.Replace(e, r1, s2) && !v.Replace(e, r2, s1) {
+				v.Replace(e, r1, s1)
+			}
 		}
 	}
 
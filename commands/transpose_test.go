@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/jxo/lime"
+	"github.com/jxo/lime/render"
 	"github.com/jxo/lime/text"
 )
 
@@ -131,3 +132,43 @@ func TestTranspose(t *testing.T) {
 		}
 	}
 }
+
+func TestTransposeProtected(t *testing.T) {
+	ed := lime.GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	tests := []struct {
+		start     string
+		regions   []text.Region
+		protected text.Region
+	}{
+		// the second character can't be modified
+		{"one", []text.Region{{1, 1}}, text.Region{1, 2}},
+		// nor can the second of the rotated words
+		{"one two three four", []text.Region{{0, 3}, {4, 7}, {8, 13}}, text.Region{4, 7}},
+		// nor the last one
+		{"one two three four", []text.Region{{0, 3}, {4, 7}, {8, 13}}, text.Region{8, 13}},
+	}
+	for i, test := range tests {
+		v.EraseRegions("protected")
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{0, v.Size()})
+		v.Insert(e, 0, test.start)
+		v.EndEdit(e)
+		v.AddRegions("protected", []text.Region{test.protected}, "", "", render.PROTECTED)
+		v.Sel().Clear()
+		v.Sel().AddAll(test.regions)
+
+		ed.CommandHandler().RunTextCommand(v, "transpose", nil)
+		if b := v.Substr(text.Region{0, v.Size()}); b != test.start {
+			t.Errorf("Test %d: Expected %q; got %q", i, test.start, b)
+		}
+	}
+	v.EraseRegions("protected")
+}
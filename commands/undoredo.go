@@ -5,6 +5,8 @@
 package commands
 
 import (
+	"time"
+
 	"github.com/jxo/lime"
 )
 
@@ -18,6 +20,21 @@ type (
 		lime.BypassUndoCommand
 		hard bool
 	}
+
+	// SwitchUndoBranch command walks to the previous or the next branch
+	// of the undo tree.
+	SwitchUndoBranch struct {
+		lime.BypassUndoCommand
+		dir int
+	}
+
+	// UndoToTime command brings the buffer back to the state it was in
+	// the given Minutes and Seconds ago.
+	UndoToTime struct {
+		lime.BypassUndoCommand
+		Minutes float64
+		Seconds float64
+	}
 )
 
 // Run executes the Undo command.
@@ -32,14 +49,32 @@ func (c *Redo) Run(v *lime.View, e *lime.Edit) error {
 	return nil
 }
 
+// Run executes the SwitchUndoBranch command.
+func (c *SwitchUndoBranch) Run(v *lime.View, e *lime.Edit) error {
+	if !v.UndoStack().SwitchBranch(c.dir) {
+		lime.GetEditor().Frontend().StatusMessage("No other undo branch")
+	}
+	return nil
+}
+
+// Run executes the UndoToTime command.
+func (c *UndoToTime) Run(v *lime.View, e *lime.Edit) error {
+	ago := time.Duration((c.Minutes*60 + c.Seconds) * float64(time.Second))
+	v.UndoStack().UndoToTime(time.Now().Add(-ago))
+	return nil
+}
+
 func init() {
 	register([]lime.Command{
 		&Undo{hard: true},
 		&Redo{hard: true},
+		&UndoToTime{},
 	})
 
 	registerByName([]namedCmd{
 		{"soft_undo", &Undo{}},
 		{"soft_redo", &Redo{}},
+		{"prev_undo_branch", &SwitchUndoBranch{dir: -1}},
+		{"next_undo_branch", &SwitchUndoBranch{dir: 1}},
 	})
 }
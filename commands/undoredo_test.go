@@ -107,3 +107,42 @@ func TestUndoRedos(t *testing.T) {
 		t.Error(v.Substr(text.Region{0, v.Size()}))
 	}
 }
+
+func TestUndoBranchesAndTime(t *testing.T) {
+	var fe scfe
+	ed := lime.GetEditor()
+	ed.SetFrontend(&fe)
+	ch := ed.CommandHandler()
+	w := ed.NewWindow()
+	defer w.Close()
+
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	ch.RunTextCommand(v, "insert", lime.Args{"characters": "a"})
+	ch.RunTextCommand(v, "insert", lime.Args{"characters": "b"})
+	ch.RunTextCommand(v, "undo", nil)
+	ch.RunTextCommand(v, "insert", lime.Args{"characters": "c"})
+
+	tests := []struct {
+		cmd  string
+		args lime.Args
+		exp  string
+	}{
+		{"prev_undo_branch", nil, "ab"},
+		{"prev_undo_branch", nil, "ab"},
+		{"next_undo_branch", nil, "ac"},
+		{"undo_to_time", lime.Args{"minutes": 1}, ""},
+		{"redo", nil, "a"},
+		{"undo_to_time", lime.Args{"seconds": 0}, "ac"},
+	}
+	for i, test := range tests {
+		ch.RunTextCommand(v, test.cmd, test.args)
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+	}
+}
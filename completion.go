@@ -0,0 +1,481 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/rubex"
+	"github.com/jxo/lime/text"
+	"github.com/jxo/lime/util"
+)
+
+// Flags returned by the OnQueryCompletions callbacks, same as in sublime
+const (
+	// Don't add the words of the views to the completions
+	INHIBIT_WORD_COMPLETIONS = 8
+	// Don't add the completions of the .sublime-completions files
+	INHIBIT_EXPLICIT_COMPLETIONS = 16
+)
+
+// Views bigger than this aren't searched for words, unless the
+// "auto_complete_size_limit" setting says otherwise.
+const defaultAutoCompleteSizeLimit = 4 << 20
+
+type (
+	// Completion is an entry of the auto complete list. The Trigger is
+	// what the typed prefix is matched against and Contents is the
+	// snippet inserted in place of the prefix once the completion is
+	// committed.
+	Completion struct {
+		Trigger    string
+		Annotation string
+		Contents   string
+	}
+
+	// Completions is the content of a .sublime-completions file, the
+	// completions are offered where the scope selector matches.
+	Completions struct {
+		Scope       string
+		Completions []Completion
+	}
+)
+
+// NewCompletion returns the completion of the trigger, which can be
+// followed by a tab and the annotation as in sublime.
+func NewCompletion(trigger, contents string) Completion {
+	c := Completion{Trigger: trigger, Contents: contents}
+	if i := strings.Index(trigger, "\t"); i != -1 {
+		c.Trigger, c.Annotation = trigger[:i], trigger[i+1:]
+	}
+	return c
+}
+
+func (c *Completions) UnmarshalJSON(data []byte) error {
+	var f struct {
+		Scope       string
+		Completions []json.RawMessage
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	c.Scope = f.Scope
+	c.Completions = nil
+	for _, raw := range f.Completions {
+		// the completions are either a plain string or a trigger
+		// and its contents
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			c.Completions = append(c.Completions, Completion{Trigger: s, Contents: s})
+			continue
+		}
+		var cmp struct {
+			Trigger  string
+			Contents string
+		}
+		if err := json.Unmarshal(raw, &cmp); err != nil {
+			return err
+		}
+		if cmp.Trigger == "" {
+			return fmt.Errorf("Completion without a trigger: %s", raw)
+		}
+		if cmp.Contents == "" {
+			cmp.Contents = cmp.Trigger
+		}
+		c.Completions = append(c.Completions, NewCompletion(cmp.Trigger, cmp.Contents))
+	}
+	return nil
+}
+
+func (e *Editor) AddCompletions(path string, c *Completions) {
+	edl.Lock()
+	defer edl.Unlock()
+	e.completions[path] = c
+}
+
+// Returns the completions loaded from packages which scope matches at
+// the point of the view, including the tab triggers of the snippets.
+func (e *Editor) explicitCompletions(v *View, point int) []Completion {
+	edl.Lock()
+	paths := make([]string, 0, len(e.completions))
+	for p := range e.completions {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var files []*Completions
+	for _, p := range paths {
+		files = append(files, e.completions[p])
+	}
+	edl.Unlock()
+
+	var ret []Completion
+	for _, c := range files {
+		if c.Scope == "" || v.MatchSelector(point, c.Scope) {
+			ret = append(ret, c.Completions...)
+		}
+	}
+	for _, s := range e.tabTriggerSnippets(v, point) {
+		ret = append(ret, Completion{Trigger: s.TabTrigger, Annotation: s.Description, Contents: s.Content})
+	}
+	return ret
+}
+
+// Words are looked for on every key press, so the compiled
+// "word_separators" are kept. Only a few different ones are expected,
+// past this the separators are taken as a plain list of characters.
+const maxWordSeparators = 64
+
+var (
+	wordSeparators     = make(map[string]*rubex.Regexp)
+	wordSeparatorsLock sync.Mutex
+)
+
+// Returns the compiled separators, nil if they're invalid or if there's
+// no room left to keep them.
+func wordSeparatorsRegex(ws string) (*rubex.Regexp, bool) {
+	wordSeparatorsLock.Lock()
+	defer wordSeparatorsLock.Unlock()
+	if re, ok := wordSeparators[ws]; ok {
+		return re, true
+	}
+	if len(wordSeparators) >= maxWordSeparators {
+		return nil, false
+	}
+	re, err := rubex.Compile(ws)
+	if err != nil {
+		log.Warn("Invalid word_separators %q: %s", ws, err)
+	}
+	// the invalid ones too, not to warn every time
+	wordSeparators[ws] = re
+	return re, true
+}
+
+// Returns a function telling whether a rune is part of a word, i.e
+// neither a space nor one of the "word_separators".
+func (v *View) wordRunes() func(rune) bool {
+	ws := v.Settings().String("word_separators", DEFAULT_SEPARATORS)
+	re, cached := wordSeparatorsRegex(ws)
+	seps := make(map[rune]bool)
+	return func(c rune) bool {
+		if unicode.IsSpace(c) {
+			return false
+		}
+		if !cached {
+			return !strings.ContainsRune(ws, c)
+		}
+		if re == nil {
+			return true
+		}
+		sep, ok := seps[c]
+		if !ok {
+			sep = re.MatchString(string(c))
+			seps[c] = sep
+		}
+		return !sep
+	}
+}
+
+// CompletionPrefix returns the region of the word characters right
+// before the point, which completions replace.
+func (v *View) CompletionPrefix(point int) text.Region {
+	isWord := v.wordRunes()
+	start := point
+	for start > 0 {
+		rs := v.SubstrR(text.Region{A: start - 1, B: start})
+		if len(rs) == 0 || !isWord(rs[0]) {
+			break
+		}
+		start--
+	}
+	return text.Region{A: start, B: point}
+}
+
+// Calls f with every word of the runes and its position.
+func forEachWord(rs []rune, isWord func(rune) bool, f func(s string, start, end int)) {
+	for i := 0; i < len(rs); {
+		if !isWord(rs[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(rs) && isWord(rs[j]) {
+			j++
+		}
+		f(string(rs[i:j]), i, j)
+		i = j
+	}
+}
+
+// The words of a view in the order they first appear, kept until the
+// buffer or the "word_separators" change as the other views are
+// searched for completions on every key press.
+type viewWords struct {
+	changeCount int
+	separators  string
+	words       []string
+}
+
+// Returns the words of the view in the order they first appear.
+func (v *View) words() []string {
+	cc := v.ChangeCount()
+	ws := v.Settings().String("word_separators", DEFAULT_SEPARATORS)
+	v.lock.Lock()
+	if w := v.completionWords; w != nil && w.changeCount == cc && w.separators == ws {
+		v.lock.Unlock()
+		return w.words
+	}
+	v.lock.Unlock()
+
+	var words []string
+	seen := make(map[string]bool)
+	forEachWord(v.SubstrR(text.Region{A: 0, B: v.Size()}), v.wordRunes(), func(s string, start, end int) {
+		if !seen[s] {
+			seen[s] = true
+			words = append(words, s)
+		}
+	})
+	v.lock.Lock()
+	v.completionWords = &viewWords{changeCount: cc, separators: ws, words: words}
+	v.lock.Unlock()
+	return words
+}
+
+// ExtractCompletions returns the words of the view fuzzy matching the
+// prefix, the closest ones to the point first. A negative point keeps
+// the words in the order they appear. The word being typed at the
+// point isn't part of them.
+func (v *View) ExtractCompletions(prefix string, point int) []string {
+	if point < 0 {
+		ret := []string{}
+		for _, s := range v.words() {
+			if _, _, ok := util.FuzzyMatch(prefix, s); ok && s != prefix {
+				ret = append(ret, s)
+			}
+		}
+		return ret
+	}
+
+	type word struct {
+		s    string
+		dist int
+	}
+	var words []word
+	seen := make(map[string]int)
+	forEachWord(v.SubstrR(text.Region{A: 0, B: v.Size()}), v.wordRunes(), func(s string, start, end int) {
+		if (point >= start && point <= end) || s == prefix {
+			return
+		}
+		if _, _, ok := util.FuzzyMatch(prefix, s); !ok {
+			return
+		}
+		dist := point - end
+		if start > point {
+			dist = start - point
+		}
+		if k, ok := seen[s]; ok {
+			if dist < words[k].dist {
+				words[k].dist = dist
+			}
+			return
+		}
+		seen[s] = len(words)
+		words = append(words, word{s, dist})
+	})
+	sort.SliceStable(words, func(i, j int) bool { return words[i].dist < words[j].dist })
+	ret := make([]string, len(words))
+	for i, w := range words {
+		ret[i] = w.s
+	}
+	return ret
+}
+
+// QueryCompletions returns the completions of the prefix before the
+// first cursor of the view, best matches first, and the prefix. These
+// are the completions of the OnQueryCompletions callbacks, of the
+// .sublime-completions files which scope matches and the words of the
+// view and the other open views, unless the callbacks inhibit them.
+func (v *View) QueryCompletions() (string, []Completion) {
+	sel := v.Sel().Regions()
+	if len(sel) == 0 {
+		return "", nil
+	}
+	locations := make([]int, len(sel))
+	for i, r := range sel {
+		locations[i] = r.B
+	}
+	pt := locations[0]
+	prefix := v.Substr(v.CompletionPrefix(pt))
+
+	cs, flags := OnQueryCompletions.Call(v, prefix, locations)
+	if flags&INHIBIT_EXPLICIT_COMPLETIONS == 0 {
+		cs = append(cs, GetEditor().explicitCompletions(v, typedPoint(pt))...)
+	}
+	if flags&INHIBIT_WORD_COMPLETIONS == 0 {
+		for _, w := range v.ExtractCompletions(prefix, pt) {
+			cs = append(cs, Completion{Trigger: w, Contents: escapeSnippet(w)})
+		}
+		limit := v.Settings().Int("auto_complete_size_limit", defaultAutoCompleteSizeLimit)
+		for _, w := range GetEditor().Windows() {
+			for _, ov := range w.Views() {
+				if ov == v || ov.Size() > limit {
+					continue
+				}
+				for _, w := range ov.ExtractCompletions(prefix, -1) {
+					cs = append(cs, Completion{Trigger: w, Contents: escapeSnippet(w)})
+				}
+			}
+		}
+	}
+
+	type match struct {
+		c     Completion
+		score int
+	}
+	var matches []match
+	seen := make(map[string]bool)
+	for _, c := range cs {
+		if seen[c.Trigger+"\t"+c.Contents] {
+			continue
+		}
+		seen[c.Trigger+"\t"+c.Contents] = true
+		if score, _, ok := util.FuzzyMatch(prefix, c.Trigger); ok {
+			matches = append(matches, match{c, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	ret := make([]Completion, len(matches))
+	for i, m := range matches {
+		ret[i] = m.c
+	}
+	return prefix, ret
+}
+
+// ShowAutoComplete has the frontend show the completions at the cursor
+// of the view, replacing the list shown if any. Choosing one runs the
+// "insert_completion" command. The list is hidden if there are no
+// completions.
+func (v *View) ShowAutoComplete() *QuickPanel {
+	prefix, cs := v.QueryCompletions()
+	if len(cs) == 0 {
+		v.HideAutoComplete()
+		return nil
+	}
+	items := make([]QuickPanelItem, len(cs))
+	for i, c := range cs {
+		items[i] = QuickPanelItem{Text: c.Trigger, Annotation: c.Annotation}
+	}
+	q := NewQuickPanel(items, 0, 0, func(i int) {
+		if i < 0 {
+			return
+		}
+		GetEditor().CommandHandler().RunTextCommand(v, "insert_completion", Args{"contents": cs[i].Contents})
+	}, nil)
+	q.Text = prefix
+
+	v.lock.Lock()
+	v.autoComplete = q
+	v.lock.Unlock()
+	if fe := GetEditor().Frontend(); fe != nil {
+		fe.ShowAutoComplete(v, q)
+	}
+	return q
+}
+
+// AutoComplete returns the completion list shown in the view, nil if
+// none is.
+func (v *View) AutoComplete() *QuickPanel {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.autoComplete == nil || v.autoComplete.Done() {
+		return nil
+	}
+	return v.autoComplete
+}
+
+// IsAutoCompleteVisible returns whether the completion list is shown.
+func (v *View) IsAutoCompleteVisible() bool {
+	return v.AutoComplete() != nil
+}
+
+// HideAutoComplete cancels the completion list shown if any.
+func (v *View) HideAutoComplete() {
+	v.lock.Lock()
+	q := v.autoComplete
+	v.autoComplete = nil
+	v.lock.Unlock()
+	if q != nil {
+		q.Cancel()
+	}
+}
+
+// UpdateAutoComplete is called once the characters were typed in the
+// view. The completion list is refreshed when it's shown. Otherwise
+// it's shown, with the "auto_complete" setting on, when a word is
+// being typed where the "auto_complete_selector" setting matches, or
+// when the characters and selector of one of the
+// "auto_complete_triggers" match.
+func (v *View) UpdateAutoComplete(typed string) {
+	if v.IsAutoCompleteVisible() {
+		pt := v.Sel().Get(0).B
+		if v.CompletionPrefix(pt).Empty() && !v.autoCompleteTriggered(typed, pt) {
+			v.HideAutoComplete()
+		} else {
+			v.ShowAutoComplete()
+		}
+		return
+	}
+	s := v.Settings()
+	if !s.Bool("auto_complete", true) || s.Bool("is_widget", false) || v.Sel().Len() == 0 || typed == "" {
+		return
+	}
+	pt := v.Sel().Get(0).B
+	if v.autoCompleteTriggered(typed, pt) {
+		v.ShowAutoComplete()
+		return
+	}
+	sel := s.String("auto_complete_selector", "source - comment")
+	if !v.CompletionPrefix(pt).Empty() && strings.IndexFunc(typed, v.wordRunes()) != -1 && v.MatchSelector(typedPoint(pt), sel) {
+		v.ShowAutoComplete()
+	}
+}
+
+// Returns the point of the character typed before the cursor at pt, the
+// selectors are matched against its scope as the end of the buffer has
+// none.
+func typedPoint(pt int) int {
+	if pt > 0 {
+		return pt - 1
+	}
+	return pt
+}
+
+// Returns whether the last typed character is one of the characters of
+// an "auto_complete_triggers" entry which selector matches at the point.
+func (v *View) autoCompleteTriggered(typed string, pt int) bool {
+	if typed == "" {
+		return false
+	}
+	rs := []rune(typed)
+	last := string(rs[len(rs)-1])
+	triggers, _ := v.Settings().Get("auto_complete_triggers", nil).([]interface{})
+	for _, t := range triggers {
+		m, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		chars, _ := m["characters"].(string)
+		sel, _ := m["selector"].(string)
+		if strings.Contains(chars, last) && (sel == "" || v.MatchSelector(typedPoint(pt), sel)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,314 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jxo/lime/rubex"
+	"github.com/jxo/lime/text"
+	"github.com/jxo/lime/util"
+)
+
+func init() {
+	// answers only the views asking for it so the other tests aren't
+	// affected
+	OnQueryCompletions.Add(func(v *View, prefix string, locations []int) ([]Completion, int) {
+		if !v.Settings().Bool("test_completions", false) {
+			return nil, 0
+		}
+		return []Completion{NewCompletion("hex\tplugin", "hex()")}, v.Settings().Int("test_completions_flags", 0)
+	})
+}
+
+func TestCompletionsUnmarshal(t *testing.T) {
+	data := `{
+		"scope": "source.go",
+		"completions": [
+			"func",
+			{"trigger": "fmt\tpackage", "contents": "fmt.Println($1)"},
+			{"trigger": "iota"}
+		]
+	}`
+	var c Completions
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatal(err)
+	}
+	exp := Completions{
+		Scope: "source.go",
+		Completions: []Completion{
+			{Trigger: "func", Contents: "func"},
+			{Trigger: "fmt", Annotation: "package", Contents: "fmt.Println($1)"},
+			{Trigger: "iota", Contents: "iota"},
+		},
+	}
+	if !reflect.DeepEqual(c, exp) {
+		t.Errorf("Expected %+v, but got %+v", exp, c)
+	}
+	if err := json.Unmarshal([]byte(`{"completions": [{"contents": "x"}]}`), &c); err == nil {
+		t.Error("Expected an error for a completion without a trigger")
+	}
+}
+
+func TestExtractCompletions(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "hello(help) world.helium he\nheap hello")
+	v.EndEdit(e)
+
+	tests := []struct {
+		prefix string
+		point  int
+		exp    []string
+	}{
+		{"he", 27, []string{"heap", "helium", "hello", "help"}},
+		{"he", -1, []string{"hello", "help", "helium", "heap"}},
+		{"hlo", 27, []string{"hello"}},
+		{"", 0, []string{"help", "world", "helium", "he", "heap", "hello"}},
+		{"xyz", 27, []string{}},
+	}
+	for i, test := range tests {
+		if cs := v.ExtractCompletions(test.prefix, test.point); !reflect.DeepEqual(cs, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, cs)
+		}
+	}
+}
+
+func TestViewWords(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "one two one")
+	v.EndEdit(e)
+
+	if ws := v.words(); !reflect.DeepEqual(ws, []string{"one", "two"}) {
+		t.Errorf("Expected %v, but got %v", []string{"one", "two"}, ws)
+	}
+	cached := v.completionWords
+	v.words()
+	if v.completionWords != cached {
+		t.Error("Expected the words to be kept while the buffer doesn't change")
+	}
+	e = v.BeginEdit()
+	v.Insert(e, v.Size(), " three")
+	v.EndEdit(e)
+	if ws := v.words(); !reflect.DeepEqual(ws, []string{"one", "two", "three"}) {
+		t.Errorf("Expected %v, but got %v", []string{"one", "two", "three"}, ws)
+	}
+	v.Settings().Set("word_separators", "[w]")
+	if ws := v.words(); !reflect.DeepEqual(ws, []string{"one", "t", "o", "three"}) {
+		t.Errorf("Expected %v, but got %v", []string{"one", "t", "o", "three"}, ws)
+	}
+}
+
+func TestWordSeparatorsCache(t *testing.T) {
+	re1, ok1 := wordSeparatorsRegex(DEFAULT_SEPARATORS)
+	re2, ok2 := wordSeparatorsRegex(DEFAULT_SEPARATORS)
+	if !ok1 || !ok2 || re1 == nil || re1 != re2 {
+		t.Errorf("Expected the separators to be compiled once, but got %p, %p", re1, re2)
+	}
+
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer v.Close()
+	// past the limit the separators are a list of characters
+	wordSeparatorsLock.Lock()
+	saved := wordSeparators
+	wordSeparators = make(map[string]*rubex.Regexp)
+	for i := 0; i < maxWordSeparators; i++ {
+		wordSeparators[fmt.Sprint(i)] = nil
+	}
+	wordSeparatorsLock.Unlock()
+	defer func() {
+		wordSeparatorsLock.Lock()
+		wordSeparators = saved
+		wordSeparatorsLock.Unlock()
+	}()
+	v.Settings().Set("word_separators", "./")
+	isWord := v.wordRunes()
+	for _, c := range "./ " {
+		if isWord(c) {
+			t.Errorf("Expected %q not to be part of a word", c)
+		}
+	}
+	if !isWord('a') {
+		t.Error("Expected 'a' to be part of a word")
+	}
+}
+
+func TestCompletionPrefix(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "fmt.Pri x_y")
+	v.EndEdit(e)
+
+	tests := []struct {
+		point int
+		exp   text.Region
+	}{
+		{7, text.Region{A: 4, B: 7}},
+		{4, text.Region{A: 4, B: 4}},
+		{3, text.Region{A: 0, B: 3}},
+		{11, text.Region{A: 8, B: 11}},
+		{0, text.Region{A: 0, B: 0}},
+	}
+	for i, test := range tests {
+		if r := v.CompletionPrefix(test.point); r != test.exp {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, r)
+		}
+	}
+}
+
+func TestQueryCompletions(t *testing.T) {
+	ed := GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	ov := w.NewFile()
+	defer func() {
+		for _, v := range []*View{v, ov} {
+			v.SetScratch(true)
+			v.Close()
+		}
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "hexagon hex")
+	v.EndEdit(e)
+	e = ov.BeginEdit()
+	ov.Insert(e, 0, "hexane other")
+	ov.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{A: 11, B: 11})
+	v.Settings().Set("test_completions", true)
+
+	ed.AddCompletions("test.sublime-completions", &Completions{
+		Completions: []Completion{{Trigger: "hexdump", Contents: "hexdump"}},
+	})
+	defer func() {
+		edl.Lock()
+		delete(ed.completions, "test.sublime-completions")
+		edl.Unlock()
+	}()
+
+	tests := []struct {
+		flags int
+		exp   []Completion
+	}{
+		{
+			0,
+			[]Completion{
+				{Trigger: "hex", Annotation: "plugin", Contents: "hex()"},
+				{Trigger: "hexdump", Contents: "hexdump"},
+				{Trigger: "hexagon", Contents: "hexagon"},
+				{Trigger: "hexane", Contents: "hexane"},
+			},
+		},
+		{
+			INHIBIT_WORD_COMPLETIONS,
+			[]Completion{
+				{Trigger: "hex", Annotation: "plugin", Contents: "hex()"},
+				{Trigger: "hexdump", Contents: "hexdump"},
+			},
+		},
+		{
+			INHIBIT_WORD_COMPLETIONS | INHIBIT_EXPLICIT_COMPLETIONS,
+			[]Completion{
+				{Trigger: "hex", Annotation: "plugin", Contents: "hex()"},
+			},
+		},
+	}
+	for i, test := range tests {
+		v.Settings().Set("test_completions_flags", test.flags)
+		prefix, cs := v.QueryCompletions()
+		if prefix != "hex" {
+			t.Errorf("Test %d: Expected prefix %q, but got %q", i, "hex", prefix)
+		}
+		if !reflect.DeepEqual(cs, test.exp) {
+			t.Errorf("Test %d: Expected %+v, but got %+v", i, test.exp, cs)
+		}
+	}
+}
+
+func TestUpdateAutoComplete(t *testing.T) {
+	ed := GetEditor()
+	ed.SetFrontend(&dummyFrontend{})
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	addSetSyntax(t, v.Settings(), "testdata/Symbols.tmLanguage")
+	e := v.BeginEdit()
+	v.Insert(e, 0, "def first\ndef fi")
+	v.EndEdit(e)
+	for start := time.Now(); v.Settings().Int("lime.syntax.updated", -1) != v.ChangeCount(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Waiting for 'lime.syntax.updated' took too long")
+		}
+	}
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{A: v.Size(), B: v.Size()})
+
+	v.Settings().Set("auto_complete", false)
+	v.UpdateAutoComplete("i")
+	if v.IsAutoCompleteVisible() {
+		t.Error("Expected no completions with auto_complete off")
+	}
+	v.Settings().Set("auto_complete", true)
+	v.UpdateAutoComplete("i")
+	q := v.AutoComplete()
+	if q == nil {
+		t.Fatal("Expected the completions to be shown")
+	}
+	if len(q.Items) != 1 || q.Items[0].Text != "first" || q.Text != "fi" {
+		t.Errorf("Expected the completion of %q, but got %+v", "fi", q)
+	}
+	if r := OnQueryContext.Call(v, "auto_complete_visible", util.OpEqual, true, false); r != True {
+		t.Errorf("Expected the auto_complete_visible context to hold, but got %v", r)
+	}
+
+	v.HideAutoComplete()
+	if v.IsAutoCompleteVisible() || !q.Done() {
+		t.Error("Expected the completions to be hidden")
+	}
+
+	// where the selector doesn't match only the triggers show them
+	v.Settings().Set("auto_complete_selector", "comment")
+	v.UpdateAutoComplete("i")
+	if v.IsAutoCompleteVisible() {
+		t.Error("Expected no completions where the selector doesn't match")
+	}
+	v.Settings().Set("auto_complete_triggers", []interface{}{
+		map[string]interface{}{"selector": "source", "characters": "i."},
+	})
+	v.UpdateAutoComplete("i")
+	if !v.IsAutoCompleteVisible() {
+		t.Error("Expected the trigger to show the completions")
+	}
+}
@@ -6,6 +6,7 @@ package lime
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/jxo/lime/text"
 )
@@ -25,6 +26,8 @@ type (
 		args       Args
 		v          *View
 		bypassUndo bool
+		// When the edit was begun
+		created time.Time
 	}
 )
 
@@ -32,6 +35,7 @@ func newEdit(v *View) *Edit {
 	ret := &Edit{
 		v:          v,
 		savedCount: v.ChangeCount(),
+		created:    time.Now(),
 	}
 	for _, r := range v.Sel().Regions() {
 		ret.savedSel.Add(r)
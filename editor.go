@@ -10,7 +10,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/jxo/lime/clipboard"
 	"github.com/jxo/lime/keys"
@@ -45,10 +48,18 @@ type Editor struct {
 	userKB           *keys.HasKeyBindings
 	defaultPath      string
 	userPath         string
+	localPath        string
 	pkgsPaths        []string
 	colorSchemes     map[string]ColorScheme
 	syntaxes         map[string]Syntax
 	filetypes        map[string]string
+	buildSystems     map[string]*BuildSystem
+	paletteCommands  map[string]*PaletteCommands
+	symbolSettings   map[string]*SymbolSettings
+	macros           map[string]*Macro
+	completions      map[string]*Completions
+	snippets         map[string]*Snippet
+	shellVariables   map[string]*ShellVariables
 }
 
 var (
@@ -82,6 +93,13 @@ func GetEditor() *Editor {
 			colorSchemes:     make(map[string]ColorScheme),
 			syntaxes:         make(map[string]Syntax),
 			filetypes:        make(map[string]string),
+			buildSystems:     make(map[string]*BuildSystem),
+			paletteCommands:  make(map[string]*PaletteCommands),
+			symbolSettings:   make(map[string]*SymbolSettings),
+			macros:           make(map[string]*Macro),
+			completions:      make(map[string]*Completions),
+			snippets:         make(map[string]*Snippet),
+			shellVariables:   make(map[string]*ShellVariables),
 		}
 		var err error
 		if ed.Watcher, err = watch.NewWatcher(); err != nil {
@@ -143,6 +161,11 @@ func (e *Editor) Init() {
 	log.Info("Initializing")
 	OnInit.call()
 	OnPackagesPathAdd.Add(packages.Scan)
+	if e.Settings().Bool("hot_exit", true) {
+		if err := e.LoadSession(); err != nil {
+			log.Error(err)
+		}
+	}
 }
 
 func (e *Editor) loadDefaultKeyBindings(dir string) {
@@ -167,6 +190,33 @@ func (e *Editor) loadUserKeyBindings(dir string) {
 	packages.LoadJSON(p, e.KeyBindings())
 }
 
+// KeyBindingConflicts returns a line for each key binding shadowed in
+// the key bindings hierarchy, i.e "ctrl+k: d (Default) is shadowed
+// by u (User)".
+func (e *Editor) KeyBindingConflicts() []string {
+	var ret []string
+	for _, c := range e.KeyBindings().Conflicts() {
+		ret = append(ret, fmt.Sprintf("%s: %s (%s) is shadowed by %s (%s)", c.Shadowed.KeysString(),
+			c.Shadowed.Command, e.keyBindingsName(c.In), c.By.Command, e.keyBindingsName(c.ByIn)))
+	}
+	return ret
+}
+
+// Returns the name of the key bindings of the hierarchy.
+func (e *Editor) keyBindingsName(kb *keys.KeyBindings) string {
+	switch kb {
+	case e.KeyBindings():
+		return "User (" + e.Plat() + ")"
+	case e.userKB.KeyBindings():
+		return "User"
+	case e.platformKB.KeyBindings():
+		return "Default (" + e.Plat() + ")"
+	case e.defaultKB.KeyBindings():
+		return "Default"
+	}
+	return "Packages"
+}
+
 func (e *Editor) loadDefaultSettings(dir string) {
 	log.Fine("Loading editor default settings")
 	p := path.Join(dir, "Preferences.sublime-settings")
@@ -279,8 +329,13 @@ func (e *Editor) HandleInput(kp keys.KeyPress) {
 
 func (e *Editor) inputThread() {
 	pc := 0
-	var lastBindings keys.KeyBindings
-	doinput := func(kp keys.KeyPress) {
+	var (
+		// The key presses of the key sequence being typed
+		pending []keys.KeyPress
+		timeout <-chan time.Time
+	)
+	// kp is nil once waiting for the next key press timed out
+	doinput := func(kp *keys.KeyPress) {
 		defer func() {
 			if r := recover(); r != nil {
 				log.Error("Panic in inputThread: %v\n%s", r, string(debug.Stack()))
@@ -293,51 +348,130 @@ func (e *Editor) inputThread() {
 		p := util.Prof.Enter("hi")
 		defer p.Exit()
 
-		lvl := log.FINE
-		if e.logInput {
-			lvl++
+		if kp != nil {
+			lvl := log.FINE
+			if e.logInput {
+				lvl++
+			}
+			log.Logf(lvl, "Key: %v", *kp)
+			pending = append(pending, *kp)
 		}
-		log.Logf(lvl, "Key: %v", kp)
-		if lastBindings.SeqIndex() == 0 {
-			lastBindings = *e.KeyBindings()
+		// timeout is reset first so a panic doesn't leave it set
+		timeout = nil
+		if pending = e.resolveKeys(pending, kp == nil); len(pending) != 0 {
+			timeout = time.After(e.keySequenceTimeout())
 		}
-	try_again:
-		possible_actions := lastBindings.Filter(kp)
-		lastBindings = possible_actions
-
-		// TODO?
-		var (
-			wnd *Window
-			v   *View
-		)
-		if wnd = e.ActiveWindow(); wnd != nil {
-			v = wnd.ActiveView()
+	}
+	for {
+		select {
+		case kp := <-e.keyInput:
+			doinput(&kp)
+		case <-timeout:
+			doinput(nil)
 		}
+	}
+}
 
+// Runs the actions bound to the key presses, returning the key presses
+// of the key sequence still being typed. The longest key sequence bound
+// is run first and the key presses left unbound are inserted if they are
+// characters. With flush set the key sequence isn't waited on anymore,
+// i.e the wait for its next key press timed out.
+func (e *Editor) resolveKeys(kps []keys.KeyPress, flush bool) []keys.KeyPress {
+	for len(kps) != 0 {
+		var v *View
+		if wnd := e.ActiveWindow(); wnd != nil {
+			v = wnd.ActiveView()
+		}
 		qc := func(key string, operator util.Op, operand interface{}, match_all bool) bool {
 			return OnQueryContext.Call(v, key, operator, operand, match_all) == True
 		}
 
-		if action := possible_actions.Action(qc); action != nil {
-			p2 := util.Prof.Enter("hi.perform")
-			e.RunCommand(action.Command, action.Args)
-			p2.Exit()
-		} else if possible_actions.SeqIndex() > 1 {
-			// TODO: this disables having keyBindings with more than 2 key sequence
-			lastBindings = *e.KeyBindings()
-			goto try_again
-		} else if kp.IsCharacter() {
-			p2 := util.Prof.Enter("hi.character")
-			log.Finest("[editor.inputThread] kp: |%s|, pos: %v", kp.Text, possible_actions)
-			if err := e.CommandHandler().RunTextCommand(v, "insert", Args{"characters": kp.Text}); err != nil {
-				log.Debug("Couldn't run textcommand: %s", err)
+		if b := e.filterKeys(kps); !flush && b.Pending(qc) {
+			e.statusMessage(fmt.Sprintf("%s was pressed, waiting for the next key", keysString(kps)))
+			return kps
+		}
+
+		n := len(kps)
+		for ; n > 0; n-- {
+			b := e.filterKeys(kps[:n])
+			if action := b.Action(qc); action != nil {
+				p := util.Prof.Enter("hi.perform")
+				e.RunCommand(action.Command, bindingArgs(action, kps[:n]))
+				p.Exit()
+				break
 			}
-			p2.Exit()
 		}
+		if n == 0 {
+			n = 1
+			if kps[0].IsCharacter() {
+				p := util.Prof.Enter("hi.character")
+				log.Finest("[editor.inputThread] kp: |%s|", kps[0].Text)
+				if err := e.CommandHandler().RunTextCommand(v, "insert", Args{"characters": kps[0].Text}); err != nil {
+					log.Debug("Couldn't run textcommand: %s", err)
+				}
+				p.Exit()
+			}
+		}
+		kps = kps[n:]
 	}
-	for kp := range e.keyInput {
-		doinput(kp)
+	return nil
+}
+
+// Returns the args of the key binding matching the key presses, with the
+// "character" arg set to the character typed for its "<character>" key.
+func bindingArgs(b *keys.KeyBinding, kps []keys.KeyPress) Args {
+	args := Args(b.Args)
+	for i, k := range b.Keys {
+		if k.Key != keys.Any || i >= len(kps) {
+			continue
+		}
+		// the binding args are shared, the character goes to a copy
+		ret := make(Args, len(args)+1)
+		for k, v := range args {
+			ret[k] = v
+		}
+		ret["character"] = kps[i].Text
+		args = ret
 	}
+	return args
+}
+
+// Returns the key bindings of the key sequences starting with the key
+// presses. Filtering drops the shorter bindings so it's done over for
+// every key sequence.
+func (e *Editor) filterKeys(kps []keys.KeyPress) keys.KeyBindings {
+	b := *e.KeyBindings()
+	for _, kp := range kps {
+		b = b.Filter(kp)
+	}
+	return b
+}
+
+// Returns how long to wait for the next key press of a key sequence,
+// set in milliseconds by the key_sequence_timeout setting.
+func (e *Editor) keySequenceTimeout() time.Duration {
+	s := e.Settings()
+	if wnd := e.ActiveWindow(); wnd != nil {
+		if v := wnd.ActiveView(); v != nil {
+			s = v.Settings()
+		}
+	}
+	return time.Duration(s.Int("key_sequence_timeout", 1000)) * time.Millisecond
+}
+
+func (e *Editor) statusMessage(msg string) {
+	if fe := e.Frontend(); fe != nil {
+		fe.StatusMessage(msg)
+	}
+}
+
+func keysString(kps []keys.KeyPress) string {
+	s := make([]string, len(kps))
+	for i, kp := range kps {
+		s[i] = kp.String()
+	}
+	return strings.Join(s, ", ")
 }
 
 func (e *Editor) LogInput(l bool) {
@@ -428,6 +562,12 @@ func (e *Editor) UserPath() string {
 	return e.userPath
 }
 
+// LocalPath returns the directory where the state kept between sessions,
+// like the undo journals, is stored.
+func (e *Editor) LocalPath() string {
+	return e.localPath
+}
+
 func (e *Editor) SetDefaultPath(p string) {
 	e.defaultPath = p
 	OnDefaultPathAdd.call(p)
@@ -438,6 +578,10 @@ func (e *Editor) SetUserPath(p string) {
 	OnUserPathAdd.call(p)
 }
 
+func (e *Editor) SetLocalPath(p string) {
+	e.localPath = p
+}
+
 func (e *Editor) AddColorScheme(path string, cs ColorScheme) {
 	e.colorSchemes[path] = cs
 }
@@ -458,6 +602,8 @@ func (e *Editor) GetColorScheme(path string) ColorScheme {
 func (e *Editor) ColorSchemes() {}
 
 func (e *Editor) AddSyntax(path string, s Syntax) {
+	edl.Lock()
+	defer edl.Unlock()
 	e.syntaxes[path] = s
 	for _, t := range s.FileTypes() {
 		e.filetypes[t] = path
@@ -465,10 +611,16 @@ func (e *Editor) AddSyntax(path string, s Syntax) {
 }
 
 func (e *Editor) GetSyntax(path string) Syntax {
+	edl.Lock()
+	defer edl.Unlock()
 	return e.syntaxes[path]
 }
 
+// Returns the syntax of the file type, it's used by the symbol index
+// goroutines too.
 func (e *Editor) fileTypeSyntax(ext string) string {
+	edl.Lock()
+	defer edl.Unlock()
 	return e.filetypes[ext]
 }
 
@@ -476,3 +628,25 @@ func (e *Editor) fileTypeSyntax(ext string) string {
 // the name in the menu should come from defined name inside syntax file or
 // the syntax file name
 func (e *Editor) Syntaxes() {}
+
+func (e *Editor) AddBuildSystem(path string, b *BuildSystem) {
+	edl.Lock()
+	defer edl.Unlock()
+	e.buildSystems[path] = b
+}
+
+// Returns the build systems loaded from packages sorted by path.
+func (e *Editor) BuildSystems() []*BuildSystem {
+	edl.Lock()
+	defer edl.Unlock()
+	paths := make([]string, 0, len(e.buildSystems))
+	for p := range e.buildSystems {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	ret := make([]*BuildSystem, len(paths))
+	for i, p := range paths {
+		ret[i] = e.buildSystems[p]
+	}
+	return ret
+}
@@ -6,9 +6,12 @@ package lime
 
 import (
 	"path"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/jxo/lime/keys"
+	"github.com/jxo/lime/loaders"
 )
 
 func TestGetEditor(t *testing.T) {
@@ -160,6 +163,165 @@ func TestHandleInput(t *testing.T) {
 	}
 }
 
+type (
+	chordTestCommand struct {
+		DefaultCommand
+		Name string
+		ran  *[]string
+	}
+
+	chordInsertCommand struct {
+		DefaultCommand
+		Characters string
+		ran        *[]string
+	}
+
+	chordCharacterCommand struct {
+		DefaultCommand
+		Name      string
+		Character string
+		ran       *[]string
+	}
+)
+
+func (c *chordTestCommand) Run(v *View, e *Edit) error {
+	*c.ran = append(*c.ran, c.Name)
+	return nil
+}
+
+func (c *chordInsertCommand) Run(v *View, e *Edit) error {
+	*c.ran = append(*c.ran, "insert "+c.Characters)
+	return nil
+}
+
+func (c *chordCharacterCommand) Run(v *View, e *Edit) error {
+	*c.ran = append(*c.ran, c.Name+" "+c.Character)
+	return nil
+}
+
+func TestResolveKeys(t *testing.T) {
+	ed := GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	var ran []string
+	ch := ed.CommandHandler()
+	ch.Register("chord_test", &chordTestCommand{ran: &ran})
+	ch.Register("insert", &chordInsertCommand{ran: &ran})
+	ch.Register("chord_character", &chordCharacterCommand{ran: &ran})
+	defer func() {
+		ch.Unregister("chord_test")
+		ch.Unregister("insert")
+		ch.Unregister("chord_character")
+	}()
+
+	var kb keys.KeyBindings
+	if err := loaders.LoadJSON([]byte(`[
+		{ "keys": ["ctrl+k", "ctrl+c", "x"], "command": "chord_test", "args": {"name": "kcx"} },
+		{ "keys": ["ctrl+k", "ctrl+c"], "command": "chord_test", "args": {"name": "kc"} },
+		{ "keys": ["j", "j"], "command": "chord_test", "args": {"name": "jj"} },
+		{ "keys": ["ctrl+d"], "command": "chord_test", "args": {"name": "d"} },
+		{ "keys": ["ctrl+e", "<character>"], "command": "chord_character", "args": {"name": "e"} },
+	]`), &kb); err != nil {
+		t.Fatal(err)
+	}
+	old := *ed.KeyBindings()
+	*ed.KeyBindings() = kb
+	defer func() {
+		*ed.KeyBindings() = old
+	}()
+
+	ctrlK := keys.KeyPress{Key: 'k', Ctrl: true}
+	ctrlC := keys.KeyPress{Key: 'c', Ctrl: true}
+	ctrlD := keys.KeyPress{Key: 'd', Ctrl: true}
+	ctrlE := keys.KeyPress{Key: 'e', Ctrl: true}
+	char := func(r rune) keys.KeyPress {
+		return keys.KeyPress{Key: keys.Key(r), Text: string(r)}
+	}
+	tests := []struct {
+		kps     []keys.KeyPress
+		flush   bool
+		ran     []string
+		pending int
+	}{
+		{[]keys.KeyPress{ctrlK}, false, nil, 1},
+		{[]keys.KeyPress{ctrlK, ctrlC}, false, nil, 2},
+		{[]keys.KeyPress{ctrlK, ctrlC, char('x')}, false, []string{"kcx"}, 0},
+		{[]keys.KeyPress{ctrlK, ctrlC}, true, []string{"kc"}, 0},
+		{[]keys.KeyPress{ctrlK, ctrlC, char('y')}, false, []string{"kc", "insert y"}, 0},
+		{[]keys.KeyPress{ctrlK}, true, nil, 0},
+		{[]keys.KeyPress{char('j'), char('k')}, false, []string{"insert j", "insert k"}, 0},
+		{[]keys.KeyPress{char('j'), char('j')}, false, []string{"jj"}, 0},
+		{[]keys.KeyPress{char('j')}, true, []string{"insert j"}, 0},
+		{[]keys.KeyPress{char('j'), ctrlK}, false, []string{"insert j"}, 1},
+		{[]keys.KeyPress{ctrlD}, false, []string{"d"}, 0},
+		{[]keys.KeyPress{ctrlE, char('z')}, false, []string{"e z"}, 0},
+		{[]keys.KeyPress{ctrlE, char('j')}, false, []string{"e j"}, 0},
+	}
+	for i, test := range tests {
+		ran = nil
+		pending := ed.resolveKeys(test.kps, test.flush)
+		if len(pending) != test.pending {
+			t.Errorf("Test %d: Expected %d pending key presses, but got %v", i, test.pending, pending)
+		}
+		if !reflect.DeepEqual(ran, test.ran) {
+			t.Errorf("Test %d: Expected %v to run, but got %v", i, test.ran, ran)
+		}
+	}
+}
+
+func TestKeySequenceTimeout(t *testing.T) {
+	ed := GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	if d := ed.keySequenceTimeout(); d != time.Second {
+		t.Errorf("Expected the default timeout of 1s, but got %s", d)
+	}
+	v.Settings().Set("key_sequence_timeout", 250)
+	if d := ed.keySequenceTimeout(); d != 250*time.Millisecond {
+		t.Errorf("Expected a timeout of 250ms, but got %s", d)
+	}
+}
+
+func TestKeyBindingConflicts(t *testing.T) {
+	ed := GetEditor()
+	user, userPlat := ed.userKB.KeyBindings(), ed.KeyBindings()
+	oldUser, oldUserPlat := *user, *userPlat
+	defer func() {
+		*user, *userPlat = oldUser, oldUserPlat
+	}()
+	for kb, d := range map[*keys.KeyBindings]string{
+		user:     `[{ "keys": ["ctrl+k", "ctrl+x"], "command": "user_command" }]`,
+		userPlat: `[{ "keys": ["ctrl+k", "ctrl+x"], "command": "platform_command" }]`,
+	} {
+		if err := loaders.LoadJSON([]byte(d), kb); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := "ctrl+k, ctrl+x: user_command (User) is shadowed by platform_command (User (" + ed.Plat() + "))"
+	found := false
+	for _, c := range ed.KeyBindingConflicts() {
+		if c == exp {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected %q to be reported in %v", exp, ed.KeyBindingConflicts())
+	}
+}
+
 func TestAddColorScheme(t *testing.T) {
 	csPath := "testdata/Monokai.tmTheme"
 	cs := newDummyColorScheme(t, csPath)
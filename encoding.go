@@ -0,0 +1,295 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/jxo/lime/log"
+)
+
+// The encodings files are read and written in, named the way sublime
+// names them.
+const (
+	encodingUTF8        = "UTF-8"
+	encodingUTF8BOM     = "UTF-8 with BOM"
+	encodingUTF16LE     = "UTF-16 LE"
+	encodingUTF16LEBOM  = "UTF-16 LE with BOM"
+	encodingUTF16BE     = "UTF-16 BE"
+	encodingUTF16BEBOM  = "UTF-16 BE with BOM"
+	encodingLatin1      = "Western (ISO 8859-1)"
+	encodingWindows1252 = "Western (Windows 1252)"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+
+	// The characters of the 0x80-0x9f range of Windows 1252, the bytes
+	// it doesn't define being mapped to the same code points as in
+	// ISO 8859-1.
+	windows1252 = [32]rune{
+		0x20ac, 0x81, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+		0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0x8d, 0x017d, 0x8f,
+		0x90, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+		0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0x9d, 0x017e, 0x0178,
+	}
+	windows1252Bytes = make(map[rune]byte)
+
+	// The other names the encodings are known by, lower cased and
+	// without blanks, dashes and underscores.
+	encodingAliases = map[string]string{
+		"utf8":                 encodingUTF8,
+		"utf8withbom":          encodingUTF8BOM,
+		"utf8bom":              encodingUTF8BOM,
+		"utf8sig":              encodingUTF8BOM,
+		"utf16le":              encodingUTF16LE,
+		"utf16lewithbom":       encodingUTF16LEBOM,
+		"utf16":                encodingUTF16LEBOM,
+		"utf16be":              encodingUTF16BE,
+		"utf16bewithbom":       encodingUTF16BEBOM,
+		"latin1":               encodingLatin1,
+		"iso88591":             encodingLatin1,
+		"western(iso88591)":    encodingLatin1,
+		"cp1252":               encodingWindows1252,
+		"windows1252":          encodingWindows1252,
+		"western(windows1252)": encodingWindows1252,
+	}
+)
+
+func init() {
+	for i, r := range windows1252 {
+		windows1252Bytes[r] = byte(0x80 + i)
+	}
+}
+
+// Returns the name of the encoding, an empty string if it isn't known.
+func normalizeEncoding(name string) string {
+	key := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '_' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+	return encodingAliases[key]
+}
+
+// Returns the encoding of the data, the fallback encoding being used when
+// it isn't any of UTF-8 and UTF-16.
+func detectEncoding(data []byte, fallback string) string {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return encodingUTF8BOM
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return encodingUTF16LEBOM
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return encodingUTF16BEBOM
+	}
+	if enc := detectUTF16(data); enc != "" {
+		return enc
+	}
+	if utf8.Valid(data) {
+		return encodingUTF8
+	}
+	if fallback = normalizeEncoding(fallback); fallback == encodingLatin1 {
+		// the C1 control characters are unlikely to be found in text,
+		// they rather are the characters Windows 1252 puts there
+		for _, b := range data {
+			if b >= 0x80 && b < 0xa0 && windows1252[b-0x80] > 0xff {
+				return encodingWindows1252
+			}
+		}
+	}
+	if fallback == "" {
+		return encodingWindows1252
+	}
+	return fallback
+}
+
+// Guesses whether the data is UTF-16 text without a BOM from the zero
+// bytes the ASCII characters have in it.
+func detectUTF16(data []byte) string {
+	if len(data) < 2 || len(data)%2 != 0 {
+		return ""
+	}
+	if len(data) > detectEncodingSize {
+		data = data[:detectEncodingSize]
+	}
+	var even, odd int
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			even++
+		}
+		if data[i+1] == 0 {
+			odd++
+		}
+	}
+	n := len(data) / 2
+	switch {
+	case odd*10 >= n*4 && even*10 < n:
+		return encodingUTF16LE
+	case even*10 >= n*4 && odd*10 < n:
+		return encodingUTF16BE
+	}
+	return ""
+}
+
+// Decodes the data in the encoding, the BOM of the encodings having one
+// being skipped.
+func decodeText(data []byte, enc string) (string, error) {
+	switch normalizeEncoding(enc) {
+	case encodingUTF8:
+		return string(data), nil
+	case encodingUTF8BOM:
+		return string(bytes.TrimPrefix(data, bomUTF8)), nil
+	case encodingUTF16LE:
+		return decodeUTF16(data, false), nil
+	case encodingUTF16LEBOM:
+		return decodeUTF16(bytes.TrimPrefix(data, bomUTF16LE), false), nil
+	case encodingUTF16BE:
+		return decodeUTF16(data, true), nil
+	case encodingUTF16BEBOM:
+		return decodeUTF16(bytes.TrimPrefix(data, bomUTF16BE), true), nil
+	case encodingLatin1:
+		r := make([]rune, len(data))
+		for i, b := range data {
+			r[i] = rune(b)
+		}
+		return string(r), nil
+	case encodingWindows1252:
+		r := make([]rune, len(data))
+		for i, b := range data {
+			if b >= 0x80 && b < 0xa0 {
+				r[i] = windows1252[b-0x80]
+			} else {
+				r[i] = rune(b)
+			}
+		}
+		return string(r), nil
+	}
+	return "", fmt.Errorf("Unsupported encoding %s", enc)
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	u := make([]uint16, len(data)/2)
+	for i := range u {
+		if bigEndian {
+			u[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			u[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(u))
+}
+
+// Encodes the text in the encoding, writing the BOM of the encodings
+// having one. Returns an error if the text has characters the encoding
+// can't represent.
+func encodeText(s, enc string) ([]byte, error) {
+	switch e := normalizeEncoding(enc); e {
+	case encodingUTF8:
+		return []byte(s), nil
+	case encodingUTF8BOM:
+		return append(append([]byte(nil), bomUTF8...), s...), nil
+	case encodingUTF16LE, encodingUTF16LEBOM, encodingUTF16BE, encodingUTF16BEBOM:
+		var ret []byte
+		be := e == encodingUTF16BE || e == encodingUTF16BEBOM
+		switch e {
+		case encodingUTF16LEBOM:
+			ret = append(ret, bomUTF16LE...)
+		case encodingUTF16BEBOM:
+			ret = append(ret, bomUTF16BE...)
+		}
+		for _, u := range utf16.Encode([]rune(s)) {
+			if be {
+				ret = append(ret, byte(u>>8), byte(u))
+			} else {
+				ret = append(ret, byte(u), byte(u>>8))
+			}
+		}
+		return ret, nil
+	case encodingLatin1:
+		ret := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r >= 0x100 {
+				return nil, fmt.Errorf("%q can't be encoded in %s", r, e)
+			}
+			ret = append(ret, byte(r))
+		}
+		return ret, nil
+	case encodingWindows1252:
+		ret := make([]byte, 0, len(s))
+		for _, r := range s {
+			if b, ok := windows1252Bytes[r]; ok {
+				ret = append(ret, b)
+			} else if r < 0x80 || (r >= 0xa0 && r < 0x100) {
+				ret = append(ret, byte(r))
+			} else {
+				return nil, fmt.Errorf("%q can't be encoded in %s", r, e)
+			}
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("Unsupported encoding %s", enc)
+}
+
+// Reads the file, decoding it in the given encoding or in the one detected
+// when it's empty. Returns the content, its line breaks converted to "\n",
+// along with its encoding and line endings.
+func (v *View) readFile(filename, enc string) (string, string, string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", "", "", err
+	}
+	d := v.newChunkDecoder(enc)
+	s, err := d.decode(data, true)
+	if err != nil {
+		return "", "", "", err
+	}
+	return s, d.enc, d.le, nil
+}
+
+// Encoding returns the encoding the view is saved in, the one its file was
+// read in or the "default_encoding" setting for new files.
+func (v *View) Encoding() string {
+	if enc := v.Settings().String("encoding", ""); enc != "" {
+		return enc
+	}
+	return v.Settings().String("default_encoding", encodingUTF8)
+}
+
+// SetEncoding sets the encoding the view is to be saved in, i.e "UTF-8",
+// "UTF-8 with BOM", "UTF-16 LE" or "Western (Windows 1252)".
+func (v *View) SetEncoding(enc string) {
+	if e := normalizeEncoding(enc); e != "" {
+		enc = e
+	} else {
+		log.Warn("Unsupported encoding %s set on %s", enc, v)
+	}
+	v.Settings().Set("encoding", enc)
+}
+
+// Reopen reads the file of the view again in the encoding, or detecting it
+// when it's empty. The view content is replaced in a single edit.
+func (v *View) Reopen(enc string) error {
+	if v.FileName() == "" {
+		return fmt.Errorf("%s has no file to reopen", v)
+	}
+	s, enc, le, err := v.readFile(v.FileName(), enc)
+	if err != nil {
+		return fmt.Errorf("Couldn't reopen %s: %s", v.FileName(), err)
+	}
+	v.setContent(s)
+	v.Settings().Set("encoding", enc)
+	v.Settings().Set("line_endings", le)
+	v.Settings().Set("lime.last_save_change_count", v.ChangeCount())
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jxo/lime/text"
+)
+
+func TestNormalizeEncoding(t *testing.T) {
+	tests := []struct {
+		name, exp string
+	}{
+		{"utf-8", encodingUTF8},
+		{"UTF-8 with BOM", encodingUTF8BOM},
+		{"utf_16_le", encodingUTF16LE},
+		{"UTF-16 BE with BOM", encodingUTF16BEBOM},
+		{"latin-1", encodingLatin1},
+		{"cp1252", encodingWindows1252},
+		{"Western (Windows 1252)", encodingWindows1252},
+		{"klingon", ""},
+	}
+	for i, test := range tests {
+		if enc := normalizeEncoding(test.name); enc != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, enc)
+		}
+	}
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		data     []byte
+		fallback string
+		exp      string
+	}{
+		{[]byte("hello"), "", encodingUTF8},
+		{[]byte("h\xc3\xa9llo"), "", encodingUTF8},
+		{[]byte("\xef\xbb\xbfhello"), "", encodingUTF8BOM},
+		{[]byte("\xff\xfeh\x00i\x00"), "", encodingUTF16LEBOM},
+		{[]byte("\xfe\xff\x00h\x00i"), "", encodingUTF16BEBOM},
+		{[]byte("h\x00e\x00l\x00l\x00o\x00"), "", encodingUTF16LE},
+		{[]byte("\x00h\x00e\x00l\x00l\x00o"), "", encodingUTF16BE},
+		{[]byte("h\xe9llo"), "", encodingWindows1252},
+		{[]byte("h\xe9llo"), "latin1", encodingLatin1},
+		{[]byte("\x93hello\x94"), "latin1", encodingWindows1252},
+	}
+	for i, test := range tests {
+		if enc := detectEncoding(test.data, test.fallback); enc != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, enc)
+		}
+	}
+}
+
+func TestEncodeDecodeText(t *testing.T) {
+	tests := []struct {
+		enc  string
+		text string
+		data []byte
+	}{
+		{encodingUTF8, "héllo", []byte("h\xc3\xa9llo")},
+		{encodingUTF8BOM, "héllo", []byte("\xef\xbb\xbfh\xc3\xa9llo")},
+		{encodingUTF16LE, "hé", []byte("h\x00\xe9\x00")},
+		{encodingUTF16LEBOM, "hé", []byte("\xff\xfeh\x00\xe9\x00")},
+		{encodingUTF16BE, "h\U0001f600", []byte("\x00h\xd8\x3d\xde\x00")},
+		{encodingUTF16BEBOM, "hé", []byte("\xfe\xff\x00h\x00\xe9")},
+		{encodingLatin1, "héllo", []byte("h\xe9llo")},
+		{encodingWindows1252, "“héllo” €", []byte("\x93h\xe9llo\x94 \x80")},
+	}
+	for i, test := range tests {
+		if data, err := encodeText(test.text, test.enc); err != nil {
+			t.Errorf("Test %d: Expected no error, but got %s", i, err)
+		} else if !bytes.Equal(data, test.data) {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.data, data)
+		}
+		if s, err := decodeText(test.data, test.enc); err != nil {
+			t.Errorf("Test %d: Expected no error, but got %s", i, err)
+		} else if s != test.text {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.text, s)
+		}
+	}
+
+	for i, enc := range []string{encodingLatin1, encodingWindows1252, "klingon"} {
+		if _, err := encodeText("€ and ✓", enc); err == nil {
+			t.Errorf("Test %d: Expected an error encoding in %s", i, enc)
+		}
+	}
+}
+
+func TestViewEncoding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lime-encoding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ed := GetEditor()
+	ed.SetFrontend(&dummyFrontend{})
+	w := ed.NewWindow()
+	defer w.Close()
+
+	file := filepath.Join(dir, "test.txt")
+	utf16 := []byte("\xff\xfeh\x00\xe9\x00")
+	if err := ioutil.WriteFile(file, utf16, 0644); err != nil {
+		t.Fatal(err)
+	}
+	v := w.OpenFile(file, 0)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "hé" {
+		t.Errorf("Expected %q, but got %q", "hé", s)
+	}
+	if enc := v.Encoding(); enc != encodingUTF16LEBOM {
+		t.Errorf("Expected the encoding %q, but got %q", encodingUTF16LEBOM, enc)
+	}
+
+	e := v.BeginEdit()
+	v.Insert(e, v.Size(), "!")
+	v.EndEdit(e)
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	exp := append(utf16, '!', 0)
+	if data, _ := ioutil.ReadFile(file); !bytes.Equal(data, exp) {
+		t.Errorf("Expected %q to be saved, but got %q", exp, data)
+	}
+
+	v.SetEncoding("utf-8 with bom")
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	exp = []byte("\xef\xbb\xbfh\xc3\xa9!")
+	if data, _ := ioutil.ReadFile(file); !bytes.Equal(data, exp) {
+		t.Errorf("Expected %q to be saved, but got %q", exp, data)
+	}
+
+	if err := v.Reopen(encodingLatin1); err != nil {
+		t.Fatal(err)
+	}
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "ï»¿hÃ©!" {
+		t.Errorf("Expected %q, but got %q", "ï»¿hÃ©!", s)
+	}
+	if v.IsDirty() {
+		t.Error("Expected the reopened view not to be dirty")
+	}
+
+	v.SetEncoding(encodingLatin1)
+	e = v.BeginEdit()
+	v.Insert(e, 0, "€")
+	v.EndEdit(e)
+	if err := v.Save(); err == nil {
+		t.Error("Expected an error saving a character Latin-1 can't encode")
+	}
+	if fis, _ := ioutil.ReadDir(dir); len(fis) != 1 {
+		t.Errorf("Expected the temporary directory of the save to be removed, but got %d files", len(fis))
+	}
+	v.SetScratch(true)
+	v.Close()
+}
@@ -5,9 +5,14 @@
 package lime
 
 import (
+	"fmt"
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/rubex"
+	"github.com/jxo/lime/text"
 	"github.com/jxo/lime/util"
 )
 
@@ -38,6 +43,15 @@ type (
 	// A QueryContextEvent is simply a bunch of QueryContextCallbacks.
 	QueryContextEvent []QueryContextCallback
 
+	// The completions are queried when the auto complete list is shown, locations
+	// are the points of the cursors and prefix is the text typed before the first one.
+	// The callback returns its completions and the INHIBIT_* flags to leave out the
+	// word and the explicit completions.
+	QueryCompletionsCallback func(v *View, prefix string, locations []int) ([]Completion, int)
+
+	// A QueryCompletionsEvent is simply a bunch of QueryCompletionsCallbacks.
+	QueryCompletionsEvent []QueryCompletionsCallback
+
 	// A WindowEventCallback deals with Window events.
 	WindowEventCallback func(w *Window)
 	// A WindowEvent is simply a bunch of WindowEventCallbacks.
@@ -120,6 +134,23 @@ func (qe QueryContextEvent) Call(v *View, key string, operator util.Op, operand
 	return Unknown
 }
 
+// Add the provided QueryCompletionsCallback to the QueryCompletionsEvent.
+func (qe *QueryCompletionsEvent) Add(cb QueryCompletionsCallback) {
+	*qe = append(*qe, cb)
+}
+
+// Returns the completions of all the callbacks in order of registration,
+// together with their flags.
+func (qe QueryCompletionsEvent) Call(v *View, prefix string, locations []int) (ret []Completion, flags int) {
+	log.Fine("Query completions: %q, %v", prefix, locations)
+	for i := range qe {
+		cs, f := qe[i](v, prefix, locations)
+		ret = append(ret, cs...)
+		flags |= f
+	}
+	return
+}
+
 // Add the provided WindowEventCallback to this WindowEvent.
 // TODO(.): Support removing WindowEventCallbacks?
 func (we *WindowEvent) Add(cb WindowEventCallback) {
@@ -171,10 +202,14 @@ var (
 	OnStatusChanged     ViewEvent //< Called when a view's status has changed.
 
 	OnNewWindow      WindowEvent //< Called when a new window has been created.
+	OnCloseWindow    WindowEvent //< Called when a window has been closed.
 	OnProjectChanged WindowEvent
+	OnPanelChanged   WindowEvent //< Called when a panel has been shown or hidden.
 
 	OnQueryContext QueryContextEvent //< Called when context is being queried.
 
+	OnQueryCompletions QueryCompletionsEvent //< Called when the completions are being queried.
+
 	OnInit InitEvent //< Called once at program startup
 
 	OnPackagesPathAdd    PathEvent
@@ -201,7 +236,9 @@ var (
 	}
 	wevNames = map[*WindowEvent]string{
 		&OnNewWindow:      "OnNewWindow",
+		&OnCloseWindow:    "OnCloseWindow",
 		&OnProjectChanged: "OnProjectChanged",
+		&OnPanelChanged:   "OnPanelChanged",
 	}
 	pkgPathevNames = map[*PathEvent]string{
 		&OnPackagesPathAdd:    "OnPackagesPathAdd",
@@ -214,34 +251,213 @@ var (
 )
 
 func init() {
-	// Register functionality dealing with a couple of built in contexts
-	OnQueryContext.Add(func(v *View, key string, operator util.Op, operand interface{}, match_all bool) QueryContextReturn {
-		if strings.HasPrefix(key, "setting.") && operator == util.OpEqual {
-			if v.Settings().Bool(key[8:]) {
-				return True
+	// Register functionality dealing with the built in contexts
+	OnQueryContext.Add(builtinContext)
+
+	OnLoad.Add(func(v *View) {
+		GetEditor().Watch(v.FileName(), v)
+	})
+
+	// Keeps the project symbol index, if there is one, up to date
+	OnPostSave.Add(func(v *View) {
+		if w := v.Window(); w != nil && w.project != nil && w.project.symbols != nil {
+			w.project.symbols.Update(v.FileName())
+		}
+	})
+}
+
+// The built in contexts evaluated for each selection, returning the
+// value of the selection the operand is compared with.
+var selectionContexts = map[string]func(v *View, r text.Region) interface{}{
+	"selection_empty": func(v *View, r text.Region) interface{} {
+		return r.Empty()
+	},
+	"preceding_text": func(v *View, r text.Region) interface{} {
+		return v.Substr(text.Region{A: v.Line(r.B).A, B: r.B})
+	},
+	"following_text": func(v *View, r text.Region) interface{} {
+		return v.Substr(text.Region{A: r.B, B: v.Line(r.B).B})
+	},
+	"text": func(v *View, r text.Region) interface{} {
+		return v.Substr(r)
+	},
+}
+
+// Answers the built in contexts. The selection contexts hold when the
+// comparison holds for any of the selections, or for all of them with
+// match_all set.
+func builtinContext(v *View, key string, operator util.Op, operand interface{}, match_all bool) QueryContextReturn {
+	if v == nil {
+		return Unknown
+	}
+	if f, ok := selectionContexts[key]; ok {
+		var err error
+		ret := querySelections(v, match_all, func(r text.Region) bool {
+			ok, e := compareContext(f(v, r), operator, operand)
+			if e != nil {
+				err = e
 			}
-			return False
-		} else if key == "num_selections" {
-			opf, _ := operand.(float64)
-			op := int(opf)
-
-			switch operator {
-			case util.OpEqual:
-				if op == v.Sel().Len() {
-					return True
-				}
-				return False
-			case util.OpNotEqual:
-				if op != v.Sel().Len() {
-					return True
-				}
-				return False
+			return ok
+		})
+		if err != nil {
+			log.Warn("Couldn't query context %s: %s", key, err)
+			return Unknown
+		}
+		return contextReturn(ret)
+	}
+
+	var value interface{}
+	switch key {
+	case "selector", "eol_selector":
+		if operator != util.OpEqual && operator != util.OpNotEqual {
+			return Unknown
+		}
+		sel, _ := operand.(string)
+		return contextReturn(querySelections(v, match_all, func(r text.Region) bool {
+			pt := r.B
+			if key == "eol_selector" {
+				pt = v.Line(r.B).B
 			}
+			return v.MatchSelector(pt, sel) == (operator == util.OpEqual)
+		}))
+	case "num_selections":
+		value = v.Sel().Len()
+	case "auto_complete_visible":
+		value = v.IsAutoCompleteVisible()
+	case "has_next_field":
+		value = v.HasNextField()
+	case "has_prev_field":
+		value = v.HasPrevField()
+	case "overlay_visible", "overlay_has_focus":
+		value = v.Window() != nil && v.Window().Overlay() != nil
+	case "panel_visible":
+		value = v.Window() != nil && v.Window().ActivePanel() != ""
+	case "panel":
+		value = ""
+		if w := v.Window(); w != nil {
+			value = w.ActivePanel()
+		}
+	default:
+		if !strings.HasPrefix(key, "setting.") {
+			return Unknown
 		}
+		value = v.Settings().Get(key[8:])
+	}
+	ret, err := compareContext(value, operator, operand)
+	if err != nil {
+		log.Warn("Couldn't query context %s: %s", key, err)
 		return Unknown
-	})
+	}
+	return contextReturn(ret)
+}
 
-	OnLoad.Add(func(v *View) {
-		GetEditor().Watch(v.FileName(), v)
-	})
+// Returns whether the value compares to the operand with the operator.
+// The regex operators match the operand regex against the value text,
+// the whole of it for OpRegexMatch and any part of it for
+// OpRegexContains.
+func compareContext(value interface{}, operator util.Op, operand interface{}) (bool, error) {
+	switch operator {
+	case util.OpEqual:
+		return contextEqual(value, operand), nil
+	case util.OpNotEqual:
+		return !contextEqual(value, operand), nil
+	}
+	pattern, ok := operand.(string)
+	if !ok {
+		return false, fmt.Errorf("Expected a regex operand, not %v", operand)
+	}
+	if operator == util.OpRegexMatch || operator == util.OpNotRegexMatch {
+		pattern = `\A(?:` + pattern + `)\z`
+	}
+	re, cached, err := contextRegex(pattern)
+	if err != nil {
+		return false, err
+	}
+	if !cached {
+		defer re.Free()
+	}
+	s := ""
+	if value != nil {
+		s = fmt.Sprint(value)
+	}
+	match := re.MatchString(s)
+	if operator == util.OpNotRegexMatch || operator == util.OpNotRegexContains {
+		return !match, nil
+	}
+	return match, nil
+}
+
+// The context is queried for every selection on every key press, so the
+// compiled regexes of the operands are kept. The operands come from the
+// key bindings, only so many of them are expected.
+const maxContextRegexes = 256
+
+var (
+	contextRegexes     = make(map[string]*rubex.Regexp)
+	contextRegexesLock sync.Mutex
+)
+
+// Returns the compiled regex of the pattern, and whether it's cached.
+// Once there's no room left in the cache the caller has to free it.
+func contextRegex(pattern string) (*rubex.Regexp, bool, error) {
+	contextRegexesLock.Lock()
+	defer contextRegexesLock.Unlock()
+	if re, ok := contextRegexes[pattern]; ok {
+		return re, true, nil
+	}
+	re, err := rubex.Compile(pattern)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(contextRegexes) >= maxContextRegexes {
+		return re, false, nil
+	}
+	contextRegexes[pattern] = re
+	return re, true, nil
+}
+
+// Returns whether the value equals the operand, the operands being
+// decoded from json numbers are floats and missing values are false.
+func contextEqual(value, operand interface{}) bool {
+	switch op := operand.(type) {
+	case bool:
+		b, _ := value.(bool)
+		return b == op
+	case float64:
+		switch v := value.(type) {
+		case int:
+			return float64(v) == op
+		case int64:
+			return float64(v) == op
+		case float64:
+			return v == op
+		}
+		return false
+	case int:
+		return contextEqual(value, float64(op))
+	case string:
+		s, ok := value.(string)
+		return ok && s == op
+	case nil:
+		return value == nil
+	}
+	return reflect.DeepEqual(value, operand)
+}
+
+func contextReturn(b bool) QueryContextReturn {
+	if b {
+		return True
+	}
+	return False
+}
+
+// Returns whether f holds for all the selections of the view when
+// matchAll is set, for any of them otherwise.
+func querySelections(v *View, matchAll bool, f func(text.Region) bool) bool {
+	for _, r := range v.Sel().Regions() {
+		if f(r) != matchAll {
+			return !matchAll
+		}
+	}
+	return matchAll && v.Sel().Len() != 0
 }
@@ -8,8 +8,10 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	. "github.com/jxo/lime/text"
+	"github.com/jxo/lime/util"
 )
 
 func TestOnSelectionModified(t *testing.T) {
@@ -143,3 +145,146 @@ func TestOnNewWindow(t *testing.T) {
 		t.Fatalf("%d != 1", callCount)
 	}
 }
+
+func TestSelectorContext(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	addSetSyntax(t, v.Settings(), "testdata/Symbols.tmLanguage")
+	e := v.BeginEdit()
+	v.Insert(e, 0, "def first\ndef second\n")
+	v.EndEdit(e)
+	for start := time.Now(); v.Settings().Int("lime.syntax.updated", -1) != v.ChangeCount(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Waiting for 'lime.syntax.updated' took too long")
+		}
+	}
+
+	v.Sel().Clear()
+	v.Sel().AddAll([]Region{{5, 5}, {1, 1}})
+	tests := []struct {
+		operator util.Op
+		operand  string
+		matchAll bool
+		exp      QueryContextReturn
+	}{
+		{util.OpEqual, "entity.name", false, True},
+		{util.OpEqual, "entity.name", true, False},
+		{util.OpEqual, "source", true, True},
+		{util.OpNotEqual, "entity.name", false, True},
+		{util.OpNotEqual, "entity.name", true, False},
+		{util.OpNotEqual, "comment", true, True},
+		{util.OpRegexMatch, "source", false, Unknown},
+	}
+	for i, test := range tests {
+		if r := OnQueryContext.Call(v, "selector", test.operator, test.operand, test.matchAll); r != test.exp {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, r)
+		}
+	}
+}
+
+func TestBuiltinContexts(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "abc def\nxyz")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().AddAll([]Region{{A: 4, B: 4}, {A: 8, B: 11}})
+	v.Settings().Set("foo", true)
+	v.Settings().Set("size", 4)
+
+	tests := []struct {
+		key      string
+		operator util.Op
+		operand  interface{}
+		matchAll bool
+		exp      QueryContextReturn
+	}{
+		{"selection_empty", util.OpEqual, true, false, True},
+		{"selection_empty", util.OpEqual, true, true, False},
+		{"selection_empty", util.OpNotEqual, true, false, True},
+		{"preceding_text", util.OpRegexContains, "abc", false, True},
+		{"preceding_text", util.OpRegexContains, "abc", true, False},
+		{"preceding_text", util.OpRegexMatch, "abc", false, False},
+		{"preceding_text", util.OpRegexMatch, "abc ", false, True},
+		{"preceding_text", util.OpNotRegexMatch, "abc ", true, False},
+		{"preceding_text", util.OpNotRegexContains, "q", true, True},
+		{"following_text", util.OpEqual, "def", false, True},
+		{"following_text", util.OpEqual, "", true, False},
+		{"text", util.OpEqual, "xyz", false, True},
+		{"text", util.OpEqual, "xyz", true, False},
+		{"text", util.OpNotRegexContains, "y", false, True},
+		{"text", util.OpRegexMatch, "(", false, Unknown},
+		{"eol_selector", util.OpEqual, "comment", false, False},
+		{"num_selections", util.OpEqual, 2.0, false, True},
+		{"num_selections", util.OpNotEqual, 2.0, false, False},
+		{"num_selections", util.OpRegexMatch, "[0-9]", false, True},
+		{"setting.foo", util.OpEqual, true, false, True},
+		{"setting.foo", util.OpNotEqual, true, false, False},
+		{"setting.bar", util.OpEqual, false, false, True},
+		{"setting.size", util.OpEqual, 4.0, false, True},
+		{"setting.size", util.OpRegexContains, "4", false, True},
+		{"auto_complete_visible", util.OpEqual, false, false, True},
+		{"overlay_visible", util.OpEqual, true, false, False},
+		{"panel_visible", util.OpEqual, true, false, False},
+		{"no_such_context", util.OpEqual, true, false, Unknown},
+	}
+	for i, test := range tests {
+		if r := OnQueryContext.Call(v, test.key, test.operator, test.operand, test.matchAll); r != test.exp {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, r)
+		}
+	}
+}
+
+func TestContextRegexCache(t *testing.T) {
+	re1, cached, err := contextRegex("cache_test")
+	if err != nil || !cached {
+		t.Fatalf("Expected the regex to be cached, but got %v, %v", cached, err)
+	}
+	if re2, _, _ := contextRegex("cache_test"); re2 != re1 {
+		t.Errorf("Expected the cached regex to be reused")
+	}
+	if _, _, err := contextRegex("("); err == nil {
+		t.Errorf("Expected an error for an invalid regex")
+	}
+}
+
+func TestPanelContexts(t *testing.T) {
+	ed := GetEditor()
+	ed.SetFrontend(&dummyFrontend{})
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	w.CreateOutputPanel("test")
+	w.ShowPanel("output.test")
+	if r := OnQueryContext.Call(v, "panel_visible", util.OpEqual, true, false); r != True {
+		t.Errorf("Expected the panel to be visible, but got %v", r)
+	}
+	if r := OnQueryContext.Call(v, "panel", util.OpEqual, "output.test", false); r != True {
+		t.Errorf("Expected the output.test panel, but got %v", r)
+	}
+
+	q := w.ShowQuickPanel([]QuickPanelItem{{Text: "a"}}, 0, 0, nil, nil)
+	if r := OnQueryContext.Call(v, "overlay_visible", util.OpEqual, true, false); r != True {
+		t.Errorf("Expected the overlay to be visible, but got %v", r)
+	}
+	q.Cancel()
+	if r := OnQueryContext.Call(v, "overlay_visible", util.OpEqual, true, false); r != False {
+		t.Errorf("Expected the overlay to be hidden, but got %v", r)
+	}
+}
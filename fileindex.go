@@ -0,0 +1,364 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/util"
+)
+
+// FileIndex is the list of the files in the project folders. It's built
+// in the background honoring the folders include and exclude patterns,
+// and kept up to date through the editor watcher.
+type FileIndex struct {
+	project *Project
+	files   map[string]*Folder
+	// The watched directories and the folder they're in
+	dirs map[string]*Folder
+	// Incremented on every rebuild so outdated walks are dropped
+	gen int
+	// The number of walks and updates in progress
+	pending int
+	done    *sync.Cond
+	// When set, receives the path of each watcher event once the index
+	// is updated
+	handled chan<- string
+	lock    sync.Mutex
+}
+
+func newFileIndex(p *Project) *FileIndex {
+	i := &FileIndex{
+		project: p,
+		files:   make(map[string]*Folder),
+		dirs:    make(map[string]*Folder),
+	}
+	i.done = sync.NewCond(&i.lock)
+	return i
+}
+
+// Rebuild drops the index and walks the project folders
+// again in the background.
+func (i *FileIndex) Rebuild() {
+	dirs := i.reset()
+	for _, d := range dirs {
+		GetEditor().UnWatch(d, i)
+	}
+	i.lock.Lock()
+	gen := i.gen
+	folders := make([]*Folder, len(i.project.folders))
+	copy(folders, i.project.folders)
+	i.pending++
+	i.lock.Unlock()
+
+	go func() {
+		defer i.finish()
+		for _, f := range folders {
+			root, err := filepath.Abs(f.Path)
+			if err != nil {
+				log.Warn("Couldn't index folder %s: %s", f.Path, err)
+				continue
+			}
+			i.walk(gen, f, root, root, make(map[string]bool))
+		}
+	}()
+}
+
+// Close stops watching the indexed directories.
+func (i *FileIndex) Close() {
+	i.lock.Lock()
+	i.handled = nil
+	i.lock.Unlock()
+	for _, d := range i.reset() {
+		GetEditor().UnWatch(d, i)
+	}
+}
+
+// Empties the index, returning the directories that were watched.
+func (i *FileIndex) reset() []string {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	dirs := make([]string, 0, len(i.dirs))
+	for d := range i.dirs {
+		dirs = append(dirs, d)
+	}
+	i.files = make(map[string]*Folder)
+	i.dirs = make(map[string]*Folder)
+	i.gen++
+	return dirs
+}
+
+// Wait blocks until the indexing in progress is done.
+func (i *FileIndex) Wait() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	for i.pending != 0 {
+		i.done.Wait()
+	}
+}
+
+func (i *FileIndex) finish() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.pending--; i.pending == 0 {
+		i.done.Broadcast()
+	}
+}
+
+// Files returns the absolute paths of the indexed files, sorted.
+func (i *FileIndex) Files() []string {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	ret := make([]string, 0, len(i.files))
+	for f := range i.files {
+		ret = append(ret, f)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Rel returns the path of the file relative to the project folder it's
+// in, prefixed by the folder name if it has one.
+func (i *FileIndex) Rel(path string) string {
+	i.lock.Lock()
+	f := i.files[path]
+	i.lock.Unlock()
+	if f == nil {
+		return path
+	}
+	root, err := filepath.Abs(f.Path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	if f.Name != "" {
+		return filepath.Join(f.Name, rel)
+	}
+	return rel
+}
+
+// Find returns the indexed files which path relative to their folder
+// fuzzy matches the pattern, best matches first. Files scoring the same
+// are sorted by the length of their relative path.
+func (i *FileIndex) Find(pattern string) []string {
+	type match struct {
+		path, rel string
+		score     int
+	}
+	var matches []match
+	for _, f := range i.Files() {
+		rel := i.Rel(f)
+		if score, _, ok := util.FuzzyMatch(pattern, rel); ok {
+			matches = append(matches, match{f, rel, score})
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].score != matches[b].score {
+			return matches[a].score > matches[b].score
+		}
+		return len(matches[a].rel) < len(matches[b].rel)
+	})
+	ret := make([]string, len(matches))
+	for j, m := range matches {
+		ret[j] = m.path
+	}
+	return ret
+}
+
+// Indexes the files of dir recursively, seen holds the real paths of the
+// directories walked so symlink loops are only followed once.
+func (i *FileIndex) walk(gen int, f *Folder, root, dir string, seen map[string]bool) {
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		if seen[real] {
+			return
+		}
+		seen[real] = true
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Warn("Couldn't index directory %s: %s", dir, err)
+		return
+	}
+	var files, dirs []string
+	for _, fi := range fis {
+		path := filepath.Join(dir, fi.Name())
+		isDir := fi.IsDir()
+		if fi.Mode()&os.ModeSymlink != 0 {
+			st, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if isDir = st.IsDir(); isDir && !f.FollowSymlinks {
+				continue
+			}
+		}
+		if isDir && includeDir(f, root, path) {
+			dirs = append(dirs, path)
+		} else if !isDir && includeFile(f, root, path) {
+			files = append(files, path)
+		}
+	}
+
+	i.lock.Lock()
+	if i.gen != gen {
+		i.lock.Unlock()
+		return
+	}
+	i.dirs[dir] = f
+	for _, p := range files {
+		i.files[p] = f
+	}
+	i.lock.Unlock()
+	if err := GetEditor().Watch(dir, i); err != nil {
+		log.Warn("Couldn't watch directory %s: %s", dir, err)
+	}
+
+	for _, d := range dirs {
+		i.walk(gen, f, root, d, seen)
+	}
+}
+
+// Folder exclude patterns apply to all the directories under the folder,
+// include patterns only to the top level ones.
+func includeDir(f *Folder, root, path string) bool {
+	if matchPatterns(f.ExcludePatterns, root, path) {
+		return false
+	}
+	if len(f.IncludePatterns) != 0 && filepath.Dir(path) == root {
+		return matchPatterns(f.IncludePatterns, root, path)
+	}
+	return true
+}
+
+func includeFile(f *Folder, root, path string) bool {
+	if matchPatterns(f.FileExcludePatterns, root, path) {
+		return false
+	}
+	if len(f.FileIncludePatterns) != 0 {
+		return matchPatterns(f.FileIncludePatterns, root, path)
+	}
+	return true
+}
+
+// Patterns are matched against the base name of the path, or against the
+// path relative to the folder root when they contain a separator.
+func matchPatterns(patterns []string, root, path string) bool {
+	name := filepath.Base(path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+	for _, p := range patterns {
+		s := name
+		if strings.Contains(p, "/") {
+			s = rel
+		}
+		if ok, _ := filepath.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Watcher callbacks, these are called with the watcher locked
+// so the index is updated from another goroutine.
+
+func (i *FileIndex) FileCreated(path string) {
+	i.lock.Lock()
+	f, ok := i.dirs[filepath.Dir(path)]
+	gen := i.gen
+	if ok {
+		i.pending++
+	}
+	i.lock.Unlock()
+	if !ok {
+		i.handledEvent(path)
+		return
+	}
+	go func() {
+		defer i.finish()
+		i.created(gen, f, path)
+		i.handledEvent(path)
+	}()
+}
+
+func (i *FileIndex) FileRemoved(path string) {
+	i.lock.Lock()
+	var dirs []string
+	prefix := path + string(filepath.Separator)
+	delete(i.files, path)
+	for p := range i.files {
+		if strings.HasPrefix(p, prefix) {
+			delete(i.files, p)
+		}
+	}
+	for d := range i.dirs {
+		if d == path || strings.HasPrefix(d, prefix) {
+			delete(i.dirs, d)
+			dirs = append(dirs, d)
+		}
+	}
+	if len(dirs) != 0 {
+		i.pending++
+	}
+	i.lock.Unlock()
+	if len(dirs) == 0 {
+		i.handledEvent(path)
+		return
+	}
+	go func() {
+		defer i.finish()
+		for _, d := range dirs {
+			GetEditor().UnWatch(d, i)
+		}
+		i.handledEvent(path)
+	}()
+}
+
+// End of watcher callbacks
+
+func (i *FileIndex) handledEvent(path string) {
+	i.lock.Lock()
+	c := i.handled
+	i.lock.Unlock()
+	if c != nil {
+		c <- path
+	}
+}
+
+func (i *FileIndex) created(gen int, f *Folder, path string) {
+	root, err := filepath.Abs(f.Path)
+	if err != nil {
+		return
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if st.IsDir() {
+		if includeDir(f, root, path) {
+			i.walk(gen, f, root, path, make(map[string]bool))
+		}
+		return
+	}
+	if !includeFile(f, root, path) {
+		return
+	}
+	i.lock.Lock()
+	if i.gen == gen {
+		i.files[path] = f
+	}
+	i.lock.Unlock()
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newIndexTree(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "lime-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{
+		"main.go",
+		"README.md",
+		filepath.Join("util", "util.go"),
+		filepath.Join("util", "util_test.go"),
+		filepath.Join(".git", "config"),
+	} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func relFiles(idx *FileIndex) []string {
+	var ret []string
+	for _, f := range idx.Files() {
+		ret = append(ret, filepath.ToSlash(idx.Rel(f)))
+	}
+	return ret
+}
+
+// Waits for the index to handle the watcher events of the paths.
+func waitForEvents(t *testing.T, handled <-chan string, paths ...string) {
+	left := make(map[string]bool)
+	for _, p := range paths {
+		left[p] = true
+	}
+	timeout := time.After(5 * time.Second)
+	for len(left) != 0 {
+		select {
+		case p := <-handled:
+			delete(left, p)
+		case <-timeout:
+			t.Fatalf("Expected the events of %v to be handled", left)
+		}
+	}
+}
+
+func TestFileIndexWatch(t *testing.T) {
+	dir := newIndexTree(t)
+	defer os.RemoveAll(dir)
+
+	p := newProject(nil)
+	p.folders = Folders{{Path: dir, ExcludePatterns: []string{".git"}, FileExcludePatterns: []string{"*.md"}}}
+	idx := p.FileIndex()
+	defer p.closeIndex()
+	idx.Wait()
+	handled := make(chan string, 64)
+	idx.lock.Lock()
+	idx.handled = handled
+	idx.lock.Unlock()
+
+	newGo, news := filepath.Join(dir, "util", "new.go"), filepath.Join(dir, "NEWS.md")
+	if err := ioutil.WriteFile(newGo, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(news, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents(t, handled, newGo, news)
+	exp := []string{"main.go", "util/new.go", "util/util.go", "util/util_test.go"}
+	if files := relFiles(idx); !reflect.DeepEqual(files, exp) {
+		t.Errorf("Expected indexed files %v, but got %v", exp, files)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "main.go")); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents(t, handled, filepath.Join(dir, "main.go"))
+	exp = []string{"util/new.go", "util/util.go", "util/util_test.go"}
+	if files := relFiles(idx); !reflect.DeepEqual(files, exp) {
+		t.Errorf("Expected indexed files %v, but got %v", exp, files)
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "util")); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents(t, handled, filepath.Join(dir, "util"))
+	if files := relFiles(idx); len(files) != 0 {
+		t.Errorf("Expected no indexed files, but got %v", files)
+	}
+}
+
+func TestFileIndex(t *testing.T) {
+	dir := newIndexTree(t)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		folder Folder
+		exp    []string
+	}{
+		{
+			Folder{ExcludePatterns: []string{".git"}},
+			[]string{"README.md", "main.go", "util/util.go", "util/util_test.go"},
+		},
+		{
+			Folder{Name: "src", ExcludePatterns: []string{".git"}, FileExcludePatterns: []string{"*_test.go"}},
+			[]string{"src/README.md", "src/main.go", "src/util/util.go"},
+		},
+		{
+			Folder{FileIncludePatterns: []string{"*.go"}, IncludePatterns: []string{"util"}},
+			[]string{"main.go", "util/util.go", "util/util_test.go"},
+		},
+		{
+			Folder{FileIncludePatterns: []string{"util/*.go"}},
+			[]string{"util/util.go", "util/util_test.go"},
+		},
+	}
+	for i, test := range tests {
+		p := newProject(nil)
+		f := test.folder
+		f.Path = dir
+		p.folders = Folders{&f}
+		idx := p.FileIndex()
+		idx.Wait()
+		if files := relFiles(idx); !reflect.DeepEqual(files, test.exp) {
+			t.Errorf("Test %d: Expected indexed files %v, but got %v", i, test.exp, files)
+		}
+		p.closeIndex()
+	}
+}
+
+func TestFileIndexFind(t *testing.T) {
+	dir := newIndexTree(t)
+	defer os.RemoveAll(dir)
+
+	p := newProject(nil)
+	p.folders = Folders{{Path: dir, ExcludePatterns: []string{".git"}}}
+	idx := p.FileIndex()
+	defer p.closeIndex()
+	idx.Wait()
+
+	tests := []struct {
+		pattern string
+		exp     []string
+	}{
+		{"util", []string{"util/util.go", "util/util_test.go"}},
+		{"ut", []string{"util/util.go", "util/util_test.go"}},
+		{"main", []string{"main.go"}},
+		{"rdm", []string{"README.md"}},
+		{"xyz", nil},
+	}
+	for i, test := range tests {
+		var res []string
+		for _, f := range idx.Find(test.pattern) {
+			res = append(res, filepath.ToSlash(idx.Rel(f)))
+		}
+		if !reflect.DeepEqual(res, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, res)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/text"
+)
+
+// Flags of Find, FindAll and FindReplace
+const (
+	// The pattern is literal text rather than a regular expression,
+	// the replacements are then literal too
+	LITERAL = 1 << iota
+	IGNORECASE
+	// Only matches not surrounded by word characters
+	WHOLEWORD
+	// The replacements take the case of the text they replace: upper
+	// case, capitalized or as is
+	PRESERVECASE
+)
+
+type (
+	// A compiled pattern of Find.
+	finder struct {
+		re    *regexp.Regexp
+		flags int
+	}
+
+	// A match of a finder, the region counts runes while the submatches
+	// are byte indexes in the text searched.
+	findMatch struct {
+		region     text.Region
+		submatches []int
+	}
+)
+
+func newFinder(pat string, flags int) (*finder, error) {
+	if flags&LITERAL != 0 {
+		pat = regexp.QuoteMeta(pat)
+	}
+	if flags&IGNORECASE != 0 {
+		pat = "(?im)" + pat
+	} else {
+		pat = "(?m)" + pat
+	}
+	// Using regexp instead of rubex because rubex doesn't
+	// support flag for treating pattern as a literal text
+	re, err := regexp.Compile(pat)
+	if err != nil {
+		return nil, err
+	}
+	return &finder{re: re, flags: flags}, nil
+}
+
+func isFindWord(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// Returns whether there's a word boundary at the byte index i of s.
+func isWordBoundary(s string, i int) bool {
+	before, after := false, false
+	if c, n := utf8.DecodeLastRuneInString(s[:i]); n != 0 {
+		before = isFindWord(c)
+	}
+	if c, n := utf8.DecodeRuneInString(s[i:]); n != 0 {
+		after = isFindWord(c)
+	}
+	return before != after
+}
+
+// Returns the non empty matches in s.
+func (f *finder) find(s string) []findMatch {
+	return f.findFrom(s, 0, -1)
+}
+
+// Returns the first n non empty matches in s starting at the byte index
+// from or after, all of them if n is negative. The whole of s is matched
+// so that the anchors and word boundaries see the text before from.
+func (f *finder) findFrom(s string, from, n int) []findMatch {
+	// regexp can't start matching at an index, the matches are looked
+	// for in more and more of s until there are n of them after from
+	limit := -1
+	if n >= 0 {
+		limit = 16
+	}
+	for {
+		all := f.re.FindAllStringSubmatchIndex(s, limit)
+		ret := f.matchesFrom(s, all, from, n)
+		if limit < 0 || len(all) < limit || len(ret) == n {
+			return ret
+		}
+		limit *= 2
+	}
+}
+
+// Returns the first n non empty matches of all starting at the byte
+// index from or after, all of them if n is negative.
+func (f *finder) matchesFrom(s string, all [][]int, from, n int) []findMatch {
+	var (
+		ret    []findMatch
+		last   = from
+		offset = utf8.RuneCountInString(s[:from])
+	)
+	for _, m := range all {
+		if m[0] < from || m[0] == m[1] {
+			continue
+		}
+		if f.flags&WHOLEWORD != 0 && (!isWordBoundary(s, m[0]) || !isWordBoundary(s, m[1])) {
+			continue
+		}
+		offset += utf8.RuneCountInString(s[last:m[0]])
+		a := offset
+		offset += utf8.RuneCountInString(s[m[0]:m[1]])
+		last = m[1]
+		ret = append(ret, findMatch{region: text.Region{A: a, B: offset}, submatches: m})
+		if len(ret) == n {
+			break
+		}
+	}
+	return ret
+}
+
+// Returns the replacement of the match m in s. The format of regular
+// expressions can refer to the groups as $1, ${1}, \1, $name or ${name}
+// and change their case with \u, \l, \U, \L and \E.
+func (f *finder) expand(s string, m findMatch, format string) string {
+	matched := s[m.submatches[0]:m.submatches[1]]
+	ret := format
+	if f.flags&LITERAL == 0 {
+		ret = expandFormat(f.re, s, m.submatches, format)
+	}
+	if f.flags&PRESERVECASE != 0 {
+		ret = preserveCase(matched, ret)
+	}
+	return ret
+}
+
+// Replaces the matches in s, returns the text once replaced and the rune
+// regions of the replacements in it.
+func (f *finder) replaceAll(s, format string) (string, []text.Region) {
+	var (
+		buf     strings.Builder
+		regions []text.Region
+		last    int
+		delta   int
+	)
+	for _, m := range f.find(s) {
+		rep := f.expand(s, m, format)
+		buf.WriteString(s[last:m.submatches[0]])
+		buf.WriteString(rep)
+		last = m.submatches[1]
+		n := utf8.RuneCountInString(rep)
+		regions = append(regions, text.Region{A: m.region.A + delta, B: m.region.A + delta + n})
+		delta += n - m.region.Size()
+	}
+	buf.WriteString(s[last:])
+	return buf.String(), regions
+}
+
+// Returns the format with the groups of the match m in s expanded.
+func expandFormat(re *regexp.Regexp, s string, m []int, format string) string {
+	var (
+		w formatWriter
+		f = []rune(format)
+	)
+	group := func(name string) string {
+		i, err := strconv.Atoi(name)
+		if err != nil {
+			i = re.SubexpIndex(name)
+		}
+		if i >= 0 && 2*i+1 < len(m) && m[2*i] != -1 {
+			return s[m[2*i]:m[2*i+1]]
+		}
+		return ""
+	}
+	isName := func(c rune) bool {
+		return c == '_' || c < utf8.RuneSelf && (unicode.IsLetter(c) || unicode.IsDigit(c))
+	}
+	for i := 0; i < len(f); i++ {
+		c := f[i]
+		switch {
+		case c == '\\' && i+1 < len(f):
+			i++
+			if c = f[i]; c >= '0' && c <= '9' {
+				w.write(group(string(c)))
+			} else if !w.escape(c) {
+				w.write(string(c))
+			}
+		case c == '$' && i+1 < len(f) && f[i+1] == '$':
+			w.write("$")
+			i++
+		case c == '$' && i+1 < len(f) && f[i+1] == '{':
+			end := i + 2
+			for end < len(f) && f[end] != '}' {
+				end++
+			}
+			if end == len(f) {
+				w.write(string(c))
+				continue
+			}
+			w.write(group(string(f[i+2 : end])))
+			i = end
+		case c == '$' && i+1 < len(f) && isName(f[i+1]):
+			j := i + 1
+			if unicode.IsDigit(f[j]) {
+				for j < len(f) && unicode.IsDigit(f[j]) {
+					j++
+				}
+			} else {
+				for j < len(f) && isName(f[j]) {
+					j++
+				}
+			}
+			w.write(group(string(f[i+1 : j])))
+			i = j - 1
+		default:
+			w.write(string(c))
+		}
+	}
+	return w.String()
+}
+
+// Returns the replacement in the case of the text it replaces.
+func preserveCase(matched, replacement string) string {
+	upper, lower := 0, 0
+	for _, c := range matched {
+		switch {
+		case unicode.IsUpper(c):
+			upper++
+		case unicode.IsLower(c):
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return replacement
+	case lower == 0 && upper > 1:
+		return strings.ToUpper(replacement)
+	}
+	first, _ := utf8.DecodeRuneInString(matched)
+	if unicode.IsUpper(first) {
+		c, n := utf8.DecodeRuneInString(replacement)
+		return string(unicode.ToUpper(c)) + replacement[n:]
+	}
+	return replacement
+}
+
+// Find returns the first match of the pattern starting at pos or after,
+// or (-1, -1) if there's none.
+func (v *View) Find(pat string, pos int, flags int) text.Region {
+	f, err := newFinder(pat, flags)
+	if err != nil {
+		log.Error(err)
+		return text.Region{A: -1, B: -1}
+	}
+	s := v.Substr(text.Region{A: 0, B: v.Size()})
+	from := len(v.Substr(text.Region{A: 0, B: text.Clamp(0, v.Size(), pos)}))
+	if ms := f.findFrom(s, from, 1); len(ms) != 0 {
+		return ms[0].region
+	}
+	return text.Region{A: -1, B: -1}
+}
+
+// FindAll returns the matches of the pattern in the view.
+func (v *View) FindAll(pat string, flags int) []text.Region {
+	f, err := newFinder(pat, flags)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	ms := f.find(v.Substr(text.Region{A: 0, B: v.Size()}))
+	ret := make([]text.Region, len(ms))
+	for i, m := range ms {
+		ret[i] = m.region
+	}
+	return ret
+}
+
+// FindReplace returns the matches of the pattern in the view and what
+// each is replaced by given the replacement format, see the flags.
+func (v *View) FindReplace(pat string, flags int, format string) ([]text.Region, []string, error) {
+	f, err := newFinder(pat, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := v.Substr(text.Region{A: 0, B: v.Size()})
+	ms := f.find(s)
+	regions := make([]text.Region, len(ms))
+	replacements := make([]string, len(ms))
+	for i, m := range ms {
+		regions[i] = m.region
+		replacements[i] = f.expand(s, m, format)
+	}
+	return regions, replacements, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jxo/lime/text"
+)
+
+func TestFindFlags(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "héllo wörld\nHello World\n")
+	v.EndEdit(e)
+
+	tests := []struct {
+		pat   string
+		pos   int
+		flags int
+		exp   text.Region
+	}{
+		{"hello", 0, LITERAL, text.Region{A: -1, B: -1}},
+		{"Hello", 0, LITERAL, text.Region{A: 12, B: 17}},
+		{"wörld", 0, LITERAL | IGNORECASE, text.Region{A: 6, B: 11}},
+		{"world", 7, LITERAL | IGNORECASE, text.Region{A: 18, B: 23}},
+		{"w.rld", 0, 0, text.Region{A: 6, B: 11}},
+		{"^H", 0, 0, text.Region{A: 12, B: 13}},
+		// the text before pos is seen by the anchors and word boundaries
+		{"^llo", 2, 0, text.Region{A: -1, B: -1}},
+		{`\bllo`, 14, 0, text.Region{A: -1, B: -1}},
+		{`\bw`, 7, IGNORECASE, text.Region{A: 18, B: 19}},
+		{"(", 0, 0, text.Region{A: -1, B: -1}},
+	}
+	for i, test := range tests {
+		if r := v.Find(test.pat, test.pos, test.flags); r != test.exp {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, r)
+		}
+	}
+}
+
+func TestFinderFindFrom(t *testing.T) {
+	f, err := newFinder("ab", LITERAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := strings.Repeat("ab ", 100)
+	if ms := f.findFrom(s, 151, 2); len(ms) != 2 || ms[0].region != (text.Region{A: 153, B: 155}) || ms[1].region != (text.Region{A: 156, B: 158}) {
+		t.Errorf("Expected the 2 matches after 151, but got %v", ms)
+	}
+	if ms := f.findFrom(s, 151, -1); len(ms) != 49 {
+		t.Errorf("Expected 49 matches, but got %d", len(ms))
+	}
+	if ms := f.findFrom(s, 298, 1); len(ms) != 0 {
+		t.Errorf("Expected no match, but got %v", ms)
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "foo foobar ba_foo föo foo\n")
+	v.EndEdit(e)
+
+	tests := []struct {
+		pat   string
+		flags int
+		exp   []text.Region
+	}{
+		{"foo", LITERAL, []text.Region{{A: 0, B: 3}, {A: 4, B: 7}, {A: 14, B: 17}, {A: 22, B: 25}}},
+		{"foo", LITERAL | WHOLEWORD, []text.Region{{A: 0, B: 3}, {A: 22, B: 25}}},
+		{"f.o", WHOLEWORD, []text.Region{{A: 0, B: 3}, {A: 18, B: 21}, {A: 22, B: 25}}},
+		{"x*", 0, []text.Region{}},
+	}
+	for i, test := range tests {
+		if r := v.FindAll(test.pat, test.flags); !reflect.DeepEqual(r, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, r)
+		}
+	}
+}
+
+func TestFindReplace(t *testing.T) {
+	tests := []struct {
+		in     string
+		pat    string
+		flags  int
+		format string
+		exp    []string
+	}{
+		{"a.b a-b", "a.b", LITERAL, `$0\1`, []string{`$0\1`}},
+		{"key=value", `(\w+)=(\w+)`, 0, `$2=$1`, []string{"value=key"}},
+		{"key=value", `(\w+)=(\w+)`, 0, `\2=\1`, []string{"value=key"}},
+		{"key=value", `(?P<k>\w+)=(?P<v>\w+)`, 0, `${v}_$k`, []string{"value_key"}},
+		{"key=value", `(\w+)=(\w+)`, 0, `${1}0 $$1`, []string{"key0 $1"}},
+		{"key=value", `(\w+)=(\w+)`, 0, `\u$2 \U$1\E $1`, []string{"Value KEY key"}},
+		{"KEY=VALUE", `(\w+)=(\w+)`, 0, `\l$2 \L$1\E`, []string{"vALUE key"}},
+		{"a b", `(\w) (\w)`, 0, `$2\t$1\n`, []string{"b\ta\n"}},
+		{"foo Foo FOO fOo", "foo", LITERAL | IGNORECASE | PRESERVECASE, "bar", []string{"bar", "Bar", "BAR", "bar"}},
+		{"ölja Ölja", "(ö)lja", IGNORECASE | PRESERVECASE, "${1}l", []string{"öl", "Öl"}},
+	}
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	for i, test := range tests {
+		e := v.BeginEdit()
+		v.Replace(e, text.Region{A: 0, B: v.Size()}, test.in)
+		v.EndEdit(e)
+		_, reps, err := v.FindReplace(test.pat, test.flags, test.format)
+		if err != nil {
+			t.Errorf("Test %d: Unexpected error %s", i, err)
+		} else if !reflect.DeepEqual(reps, test.exp) {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, reps)
+		}
+	}
+
+	if _, _, err := v.FindReplace("(", 0, ""); err == nil {
+		t.Error("Expected an error on an invalid pattern")
+	}
+}
+
+func TestFinderReplaceAll(t *testing.T) {
+	f, err := newFinder(`é(\w)`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, rs := f.replaceAll("aéb céd", `\U$1$1`)
+	if exp := "aBB cDD"; s != exp {
+		t.Errorf("Expected %q, but got %q", exp, s)
+	}
+	if exp := []text.Region{{A: 1, B: 3}, {A: 5, B: 7}}; !reflect.DeepEqual(rs, exp) {
+		t.Errorf("Expected %v, but got %v", exp, rs)
+	}
+}
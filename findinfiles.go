@@ -0,0 +1,541 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/text"
+)
+
+const (
+	// FindResultsName is the name of the view find in files writes its
+	// results to.
+	FindResultsName = "Find Results"
+	// FindResultsSyntax is the syntax of the Find Results view.
+	FindResultsSyntax = "Packages/Default/Find Results.hidden-tmLanguage"
+	// The "Where" of find in files searching the project folders and the
+	// views open in the window.
+	WhereProject   = "<project>"
+	WhereOpenFiles = "<open files>"
+
+	// The Find Results view settings holding the pattern searched and
+	// its flags, to find the match of a result line.
+	FindPatternKey = "lime.find_in_files.pattern"
+	FindFlagsKey   = "lime.find_in_files.flags"
+	// The key of the regions of the matches in the Find Results view
+	findResultsRegions = "lime.find_in_files.matches"
+)
+
+type (
+	// FindInFilesOptions is a search across files, see Window.FindInFiles.
+	FindInFilesOptions struct {
+		Pattern string
+		// The flags of View.Find
+		Flags int
+		// A comma separated list of what to search: folders, files,
+		// WhereProject, WhereOpenFiles, include patterns like "*.go" and
+		// exclude ones like "-*_test.go". The project is searched when
+		// it's empty or holds only patterns.
+		Where string
+		// The number of lines shown around the matches
+		Context int
+		// With Replace set the matches are replaced by the Replacement
+		// in the files, or only shown replaced with Preview set. The
+		// Replacement is expanded as for View.FindReplace.
+		Replace     bool
+		Replacement string
+		Preview     bool
+	}
+
+	// FindMatch is a match of a find in files search, Line and Column
+	// start from 1.
+	FindMatch struct {
+		File   string
+		Line   int
+		Column int
+	}
+
+	// FindResults is a find in files search in progress, its matches are
+	// written to the view as they're found.
+	FindResults struct {
+		view    *View
+		matches []FindMatch
+		done    chan struct{}
+		cancel  chan struct{}
+		once    sync.Once
+		lock    sync.Mutex
+	}
+
+	// What a find in files search looks into: a file, or the buffer of
+	// an open view which takes precedence over the file on disk.
+	findTarget struct {
+		name string
+		view *View
+	}
+
+	// The matches found in a target, by line.
+	findFileResult struct {
+		target  findTarget
+		lines   []string
+		matches map[int][]text.Region // the rune regions of each line
+		count   int
+	}
+)
+
+// View returns the Find Results view.
+func (r *FindResults) View() *View {
+	return r.view
+}
+
+// Wait blocks until the search is done.
+func (r *FindResults) Wait() {
+	<-r.done
+}
+
+// Cancel stops the search and waits until it's done.
+func (r *FindResults) Cancel() {
+	r.once.Do(func() { close(r.cancel) })
+	r.Wait()
+}
+
+// Returns whether the search was cancelled.
+func (r *FindResults) cancelled() bool {
+	select {
+	case <-r.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// Matches returns the matches found so far, in the order of the files
+// and of the matches in them.
+func (r *FindResults) Matches() []FindMatch {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]FindMatch(nil), r.matches...)
+}
+
+// FindInFiles searches the files concurrently, the results are written
+// to the "Find Results" view as sublime does: the files matching then
+// their matching lines, numbered and surrounded by the context lines.
+// The view's result_file_regex and result_line_regex settings match
+// them, see BuildResults.
+//
+// Replacing is done in the views of the files, opened if they aren't, so
+// that each file has its own undo entry and is saved by the user. The
+// files which can't be modified are reported as skipped.
+//
+// The search running in the window if any is cancelled.
+func (w *Window) FindInFiles(f FindInFilesOptions) (*FindResults, error) {
+	if f.Pattern == "" {
+		return nil, fmt.Errorf("Nothing to find")
+	}
+	fi, err := newFinder(f.Pattern, f.Flags)
+	if err != nil {
+		return nil, err
+	}
+	targets := w.findTargets(f.Where)
+
+	w.cancelFindInFiles()
+	v := w.findResultsView()
+	v.Settings().Set(FindPatternKey, f.Pattern)
+	v.Settings().Set(FindFlagsKey, f.Flags)
+	header := fmt.Sprintf("Searching %d files for %q", len(targets), f.Pattern)
+	if f.Replace {
+		header += fmt.Sprintf(" (replace with %q)", f.Replacement)
+	}
+	appendView(v, header+"\n")
+
+	r := &FindResults{view: v, done: make(chan struct{}), cancel: make(chan struct{})}
+	w.lock.Lock()
+	w.findResults = r
+	w.lock.Unlock()
+	go func() {
+		defer close(r.done)
+		r.search(w, f, fi, targets)
+	}()
+	return r, nil
+}
+
+// Searches the targets concurrently, the results are written in the
+// order of the targets.
+func (r *FindResults) search(w *Window, f FindInFilesOptions, fi *finder, targets []findTarget) {
+	var (
+		results = make([]chan *findFileResult, len(targets))
+		queue   = make(chan int)
+	)
+	for i := range results {
+		results[i] = make(chan *findFileResult, 1)
+	}
+	for n := 0; n < runtime.NumCPU(); n++ {
+		go func() {
+			for i := range queue {
+				results[i] <- searchTarget(targets[i], fi)
+			}
+		}()
+	}
+	go func() {
+		defer close(queue)
+		for i := range targets {
+			select {
+			case queue <- i:
+			case <-r.cancel:
+				return
+			}
+		}
+	}()
+
+	var (
+		matches, files int
+		regions        []text.Region
+		skipped        []string
+	)
+	for _, c := range results {
+		var res *findFileResult
+		select {
+		case res = <-c:
+		case <-r.cancel:
+			return
+		}
+		if res == nil || res.count == 0 {
+			continue
+		}
+		if f.Replace && !f.Preview {
+			n, ok := r.replaceInTarget(w, res.target, fi, f.Replacement)
+			if r.cancelled() {
+				return
+			}
+			if !ok {
+				skipped = append(skipped, res.target.name)
+			}
+			if n == 0 {
+				continue
+			}
+			matches += n
+		} else {
+			matches += res.count
+		}
+		files++
+		regions = append(regions, r.write(res, f, fi)...)
+		r.view.AddRegions(findResultsRegions, regions, "text", "", render.DRAW_NO_FILL)
+	}
+
+	what := "matches"
+	if matches == 1 {
+		what = "match"
+	}
+	if f.Replace && !f.Preview {
+		what += " replaced"
+	}
+	where := "across %d files"
+	if files == 1 {
+		where = "in %d file"
+	}
+	summary := fmt.Sprintf("\n%d %s "+where+"\n", matches, what, files)
+	if len(skipped) != 0 {
+		summary += fmt.Sprintf("\nSkipped %d files which couldn't be modified:\n", len(skipped))
+		for _, name := range skipped {
+			summary += "  " + name + "\n"
+		}
+	}
+	appendView(r.view, summary)
+}
+
+// Writes the matches of a file to the view, returns the regions of the
+// matches in it.
+func (r *FindResults) write(res *findFileResult, f FindInFilesOptions, fi *finder) []text.Region {
+	var rows []int
+	for l := range res.matches {
+		rows = append(rows, l)
+	}
+	sort.Ints(rows)
+
+	// the lines shown, the matches and their context
+	shown := make(map[int]bool)
+	for _, l := range rows {
+		for c := l - f.Context; c <= l+f.Context; c++ {
+			if c >= 0 && c < len(res.lines) {
+				shown[c] = true
+			}
+		}
+	}
+	var lines []int
+	for l := range shown {
+		lines = append(lines, l)
+	}
+	sort.Ints(lines)
+
+	var (
+		buf     []rune
+		regions []text.Region
+		start   = r.view.Size()
+	)
+	buf = append(buf, []rune("\n"+res.target.name+":\n")...)
+	r.lock.Lock()
+	for i, l := range lines {
+		if i > 0 && l != lines[i-1]+1 {
+			buf = append(buf, []rune("  ..\n")...)
+		}
+		line, sep := res.lines[l], " "
+		ms := res.matches[l]
+		if len(ms) != 0 {
+			sep = ":"
+			for _, m := range ms {
+				r.matches = append(r.matches, FindMatch{File: res.target.name, Line: l + 1, Column: m.A + 1})
+			}
+		}
+		if len(ms) != 0 && f.Replace {
+			// the line once replaced, with the replacements highlighted
+			line, ms = fi.replaceAll(line, f.Replacement)
+		}
+		prefix := []rune(fmt.Sprintf("%5d%s ", l+1, sep))
+		for _, m := range ms {
+			at := start + len(buf) + len(prefix)
+			regions = append(regions, text.Region{A: at + m.A, B: at + m.B})
+		}
+		buf = append(buf, prefix...)
+		buf = append(buf, []rune(line)...)
+		buf = append(buf, '\n')
+	}
+	r.lock.Unlock()
+	appendView(r.view, string(buf))
+	return regions
+}
+
+// Searches the target, returns nil if it couldn't be read or is binary.
+func searchTarget(t findTarget, fi *finder) *findFileResult {
+	var s string
+	if t.view != nil {
+		s = t.view.Substr(text.Region{A: 0, B: t.view.Size()})
+	} else {
+		d, err := ioutil.ReadFile(t.name)
+		if err != nil {
+			log.Warn("Couldn't search %s: %s", t.name, err)
+			return nil
+		}
+		if bytes.IndexByte(d, 0) != -1 {
+			return nil
+		}
+		s = strings.Replace(string(d), "\r\n", "\n", -1)
+	}
+	res := &findFileResult{
+		target:  t,
+		lines:   strings.Split(strings.TrimSuffix(s, "\n"), "\n"),
+		matches: make(map[int][]text.Region),
+	}
+	// the matches by line, the ones spanning lines are shown on their
+	// first line
+	row, lineStart := 0, 0
+	for _, fm := range fi.find(s) {
+		m := fm.region
+		for row < len(res.lines)-1 && lineStart+utf8.RuneCountInString(res.lines[row]) < m.A {
+			lineStart += utf8.RuneCountInString(res.lines[row]) + 1
+			row++
+		}
+		a := m.A - lineStart
+		b := text.Min(m.B-lineStart, utf8.RuneCountInString(res.lines[row]))
+		res.matches[row] = append(res.matches[row], text.Region{A: a, B: b})
+		res.count++
+	}
+	return res
+}
+
+// Replaces the matches in the view of the target as one edit, once its
+// file is loaded. Returns the number of matches replaced, and false if
+// some couldn't be or if the view was closed while loading.
+func (r *FindResults) replaceInTarget(w *Window, t findTarget, fi *finder, replacement string) (int, bool) {
+	v := t.view
+	if v == nil {
+		if v = w.FindOpenFile(t.name); v == nil {
+			v = w.OpenFile(t.name, 0)
+		}
+	}
+	type replaced struct {
+		n  int
+		ok bool
+	}
+	done := make(chan replaced, 1)
+	v.WhenLoaded(func() {
+		if r.cancelled() {
+			done <- replaced{}
+			return
+		}
+		s := v.Substr(text.Region{A: 0, B: v.Size()})
+		matches := fi.find(s)
+		res := replaced{ok: true}
+		e := v.BeginEdit()
+		for i := len(matches) - 1; i >= 0; i-- {
+			if v.Replace(e, matches[i].region, fi.expand(s, matches[i], replacement)) {
+				res.n++
+			} else {
+				res.ok = false
+			}
+		}
+		v.EndEdit(e)
+		done <- res
+	})
+	select {
+	case res := <-done:
+		return res.n, res.ok
+	case <-r.cancel:
+		return 0, false
+	case <-v.loadingDone():
+		// the functions waiting for the file are called before
+		select {
+		case res := <-done:
+			return res.n, res.ok
+		default:
+			return 0, false
+		}
+	}
+}
+
+// Cancels the find in files search running in the window if any.
+func (w *Window) cancelFindInFiles() {
+	w.lock.Lock()
+	r := w.findResults
+	w.findResults = nil
+	w.lock.Unlock()
+	if r != nil {
+		r.Cancel()
+	}
+}
+
+// Returns the Find Results view of the window, emptied, creating it if
+// there's none.
+func (w *Window) findResultsView() *View {
+	var v *View
+	for _, ov := range w.Views() {
+		if ov.Name() == FindResultsName && ov.FileName() == "" {
+			v = ov
+			break
+		}
+	}
+	if v == nil {
+		v = w.NewFile()
+		v.SetName(FindResultsName)
+		v.SetScratch(true)
+		s := v.Settings()
+		s.Set("syntax", FindResultsSyntax)
+		s.Set("result_file_regex", `^([^ \t].*):$`)
+		s.Set("result_line_regex", `^ +([0-9]+):`)
+		s.Set("word_wrap", false)
+		s.Set("line_numbers", false)
+		s.Set("gutter", false)
+	}
+	v.EraseRegions(findResultsRegions)
+	e := v.BeginEdit()
+	v.Erase(e, text.Region{A: 0, B: v.Size()})
+	v.EndEdit(e)
+	w.SetActiveView(v)
+	return v
+}
+
+func appendView(v *View, s string) {
+	e := v.BeginEdit()
+	v.Insert(e, v.Size(), s)
+	v.EndEdit(e)
+}
+
+// Returns what to search for the "Where" of find in files, sorted by
+// name.
+func (w *Window) findTargets(where string) []findTarget {
+	var (
+		includes, excludes []string
+		paths              []string
+		project, open      bool
+	)
+	for _, p := range strings.Split(where, ",") {
+		switch p = strings.TrimSpace(p); {
+		case p == "":
+		case p == WhereProject:
+			project = true
+		case p == WhereOpenFiles:
+			open = true
+		case strings.HasPrefix(p, "-"):
+			excludes = append(excludes, p[1:])
+		case strings.ContainsAny(p, "*?[") && !strings.Contains(p, string(filepath.Separator)):
+			includes = append(includes, p)
+		default:
+			paths = append(paths, p)
+		}
+	}
+	if !project && !open && len(paths) == 0 {
+		project = true
+	}
+
+	names := make(map[string]bool)
+	if project {
+		idx := w.Project().FileIndex()
+		idx.Wait()
+		for _, f := range idx.Files() {
+			names[f] = true
+		}
+	}
+	for _, p := range paths {
+		p, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+		filepath.Walk(p, func(path string, fi os.FileInfo, err error) error {
+			if err == nil && fi.Mode().IsRegular() {
+				names[path] = true
+			}
+			return nil
+		})
+	}
+
+	// the open views are searched rather than their files
+	views := make(map[string]*View)
+	for _, v := range w.Views() {
+		if v.Name() == FindResultsName && v.FileName() == "" {
+			continue
+		}
+		name := v.FileName()
+		if name == "" {
+			if !open {
+				continue
+			}
+			name = fmt.Sprintf("<untitled %d>", v.ID())
+		}
+		views[name] = v
+		if open {
+			names[name] = true
+		}
+	}
+
+	var ret []findTarget
+	for name := range names {
+		base := filepath.Base(name)
+		if matchFindPatterns(excludes, base) || len(includes) != 0 && !matchFindPatterns(includes, base) {
+			continue
+		}
+		ret = append(ret, findTarget{name: name, view: views[name]})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].name < ret[j].name })
+	return ret
+}
+
+func matchFindPatterns(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
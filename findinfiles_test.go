@@ -0,0 +1,273 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jxo/lime/text"
+)
+
+func newFindTree(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "lime-find")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f, data := range map[string]string{
+		"a.go":                       "package a\n\nfunc Foo() {}\n// foo bar\n",
+		"b.txt":                      "nothing\nFOO\n",
+		"bin.dat":                    "foo\x00",
+		filepath.Join("sub", "c.go"): "package c\n",
+	} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFindInFiles(t *testing.T) {
+	dir := newFindTree(t)
+	defer os.RemoveAll(dir)
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	w.Project().AddFolder(dir)
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.txt")
+
+	tests := []struct {
+		opts    FindInFilesOptions
+		exp     string
+		matches []FindMatch
+	}{
+		{
+			FindInFilesOptions{Pattern: "foo", Flags: LITERAL | IGNORECASE, Context: 1},
+			"Searching 4 files for \"foo\"\n" +
+				"\n" + a + ":\n    2  \n    3: func Foo() {}\n    4: // foo bar\n" +
+				"\n" + b + ":\n    1  nothing\n    2: FOO\n" +
+				"\n3 matches across 2 files\n",
+			[]FindMatch{{a, 3, 6}, {a, 4, 4}, {b, 2, 1}},
+		},
+		{
+			FindInFilesOptions{Pattern: "f.o", Where: "*.go", Context: 0},
+			"Searching 2 files for \"f.o\"\n" +
+				"\n" + a + ":\n    4: // foo bar\n" +
+				"\n1 match in 1 file\n",
+			[]FindMatch{{a, 4, 4}},
+		},
+		{
+			FindInFilesOptions{Pattern: "package|bar", Where: "-*.txt, " + filepath.Join(dir, "sub")},
+			"Searching 1 files for \"package|bar\"\n" +
+				"\n" + filepath.Join(dir, "sub", "c.go") + ":\n    1: package c\n" +
+				"\n1 match in 1 file\n",
+			nil,
+		},
+		{
+			FindInFilesOptions{Pattern: "xyz", Flags: LITERAL},
+			"Searching 4 files for \"xyz\"\n\n0 matches across 0 files\n",
+			nil,
+		},
+	}
+	for i, test := range tests {
+		r, err := w.FindInFiles(test.opts)
+		if err != nil {
+			t.Fatalf("Test %d: %s", i, err)
+		}
+		r.Wait()
+		v := r.View()
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != test.exp {
+			t.Errorf("Test %d: Expected\n%s\nbut got\n%s", i, test.exp, s)
+		}
+		if test.matches != nil && !reflect.DeepEqual(r.Matches(), test.matches) {
+			t.Errorf("Test %d: Expected matches %v, but got %v", i, test.matches, r.Matches())
+		}
+		if v.Name() != FindResultsName || v.Settings().String("syntax", "") != FindResultsSyntax || w.ActiveView() != v {
+			t.Errorf("Test %d: Expected the active %s view, but got %s", i, FindResultsName, v.Name())
+		}
+	}
+
+	// the results are reused and can be gone through
+	var n int
+	for _, v := range w.Views() {
+		if v.Name() == FindResultsName {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("Expected a single %s view, but got %d", FindResultsName, n)
+	}
+	r, _ := w.FindInFiles(FindInFilesOptions{Pattern: "foo", Flags: LITERAL | IGNORECASE})
+	r.Wait()
+	results := BuildResults(r.View())
+	exp := []struct {
+		file string
+		line int
+	}{{a, 3}, {a, 4}, {b, 2}}
+	if len(results) != len(exp) {
+		t.Fatalf("Expected %d results, but got %+v", len(exp), results)
+	}
+	for i, e := range exp {
+		if results[i].File != e.file || results[i].Line != e.line {
+			t.Errorf("Expected result %d at %s:%d, but got %s:%d", i, e.file, e.line, results[i].File, results[i].Line)
+		}
+	}
+
+	if _, err := w.FindInFiles(FindInFilesOptions{Pattern: "(", Where: WhereOpenFiles}); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestFindInOpenFiles(t *testing.T) {
+	dir := newFindTree(t)
+	defer os.RemoveAll(dir)
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	a := filepath.Join(dir, "a.go")
+	v := w.OpenFile(a, 0)
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "// unsaved foo\n")
+	v.EndEdit(e)
+
+	r, err := w.FindInFiles(FindInFilesOptions{Pattern: "unsaved", Where: WhereOpenFiles})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+	if exp := []FindMatch{{a, 1, 4}}; !reflect.DeepEqual(r.Matches(), exp) {
+		t.Errorf("Expected the buffer of the open file to be searched, but got %v", r.Matches())
+	}
+}
+
+func TestReplaceInFiles(t *testing.T) {
+	dir := newFindTree(t)
+	defer os.RemoveAll(dir)
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	w.Project().AddFolder(dir)
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.txt")
+	opts := FindInFilesOptions{Pattern: "foo", Flags: LITERAL | IGNORECASE, Replace: true, Replacement: "baz", Preview: true}
+
+	r, err := w.FindInFiles(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+	s := r.View().Substr(text.Region{A: 0, B: r.View().Size()})
+	if !strings.Contains(s, "    3: func baz() {}\n") || !strings.Contains(s, "    2: baz\n") {
+		t.Errorf("Expected the matches to be shown replaced, but got\n%s", s)
+	}
+	if w.FindOpenFile(a) != nil {
+		t.Error("Expected the files not to be opened for a preview")
+	}
+
+	opts.Preview = false
+	if r, err = w.FindInFiles(opts); err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+	if s := r.View().Substr(text.Region{A: 0, B: r.View().Size()}); !strings.HasSuffix(s, "3 matches replaced across 2 files\n") {
+		t.Errorf("Expected the replacements to be reported, but got\n%s", s)
+	}
+	for file, exp := range map[string]string{
+		a: "package a\n\nfunc baz() {}\n// baz bar\n",
+		b: "nothing\nbaz\n",
+	} {
+		v := w.FindOpenFile(file)
+		if v == nil {
+			t.Fatalf("Expected %s to be opened", file)
+		}
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+			t.Errorf("Expected %s to be %q, but got %q", file, exp, s)
+		}
+		if d, _ := ioutil.ReadFile(file); strings.Contains(string(d), "baz") {
+			t.Errorf("Expected %s not to be saved", file)
+		}
+		// the replacements of a file are undone at once
+		v.UndoStack().Undo(true)
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); strings.Contains(s, "baz") || !v.IsDirty() && s == exp {
+			t.Errorf("Expected the replacements of %s to be undone, but got %q", file, s)
+		}
+		v.SetScratch(true)
+	}
+}
+
+func TestReplaceInLoadingFiles(t *testing.T) {
+	dir := newFindTree(t)
+	defer os.RemoveAll(dir)
+	ed := GetEditor()
+	ed.Settings().Set("async_load_size", 1)
+	defer ed.Settings().Erase("async_load_size")
+	w := ed.NewWindow()
+	defer func() {
+		for _, v := range w.Views() {
+			v.SetScratch(true)
+		}
+		w.Close()
+	}()
+	w.Project().AddFolder(dir)
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.txt")
+	vb := w.OpenFile(b, 0)
+	waitLoading(t, vb)
+	vb.SetReadOnly(true)
+
+	r, err := w.FindInFiles(FindInFilesOptions{Pattern: "foo", Flags: LITERAL | IGNORECASE, Replace: true, Replacement: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+	exp := "\n2 matches replaced in 1 file\n\nSkipped 1 files which couldn't be modified:\n  " + b + "\n"
+	if s := r.View().Substr(text.Region{A: 0, B: r.View().Size()}); !strings.HasSuffix(s, exp) {
+		t.Errorf("Expected the summary %q, but got\n%s", exp, s)
+	}
+	va := w.FindOpenFile(a)
+	if va == nil {
+		t.Fatalf("Expected %s to be opened", a)
+	}
+	if s, exp := va.Substr(text.Region{A: 0, B: va.Size()}), "package a\n\nfunc baz() {}\n// baz bar\n"; s != exp {
+		t.Errorf("Expected %q once loaded, but got %q", exp, s)
+	}
+	if s := vb.Substr(text.Region{A: 0, B: vb.Size()}); s != "nothing\nFOO\n" {
+		t.Errorf("Expected the read only view not to be modified, but got %q", s)
+	}
+}
+
+func TestFindInFilesCancel(t *testing.T) {
+	dir := newFindTree(t)
+	defer os.RemoveAll(dir)
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	w.Project().AddFolder(dir)
+
+	r1, err := w.FindInFiles(FindInFilesOptions{Pattern: "foo", Flags: LITERAL | IGNORECASE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := w.FindInFiles(FindInFilesOptions{Pattern: "xyz", Flags: LITERAL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-r1.done:
+	default:
+		t.Error("Expected the first search to be done")
+	}
+	r2.Wait()
+	exp := "Searching 4 files for \"xyz\"\n\n0 matches across 0 files\n"
+	if s := r2.View().Substr(text.Region{A: 0, B: r2.View().Size()}); s != exp {
+		t.Errorf("Expected only the results of the last search, but got\n%s", s)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"sort"
+
+	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/text"
+)
+
+// The key of the view regions holding the folds, being regular view
+// regions they're adjusted on buffer changes like any other.
+const foldsKey = "lime.folds"
+
+// Fold folds the given regions, hiding their text. Returns false
+// if all of them were already folded.
+func (v *View) Fold(regions ...text.Region) bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	vr := v.regions[foldsKey]
+	added := false
+	for _, r := range regions {
+		if r.Empty() || vr.Regions.Contains(r) {
+			continue
+		}
+		vr.Regions.Add(text.Region{A: r.Begin(), B: r.End()})
+		added = true
+	}
+	if added {
+		vr.Flags = render.FOLDED
+		v.regions[foldsKey] = vr
+	}
+	return added
+}
+
+// Unfold unfolds the folds intersecting the given region, returning
+// the regions that were unfolded.
+func (v *View) Unfold(r text.Region) (ret []text.Region) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	vr, ok := v.regions[foldsKey]
+	if !ok {
+		return nil
+	}
+	var keep []text.Region
+	for _, f := range vr.Regions.Regions() {
+		if f.Intersects(r) || f.Contains(r.A) || f.Contains(r.B) {
+			ret = append(ret, f)
+		} else {
+			keep = append(keep, f)
+		}
+	}
+	v.setFoldsLocked(keep)
+	return
+}
+
+// UnfoldAll unfolds all the folds of the view, returning the
+// regions that were unfolded.
+func (v *View) UnfoldAll() []text.Region {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	vr := v.regions[foldsKey]
+	ret := vr.Regions.Regions()
+	delete(v.regions, foldsKey)
+	return ret
+}
+
+// FoldedRegions returns the folded regions of the view sorted by position.
+func (v *View) FoldedRegions() (ret []text.Region) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	vr := v.regions[foldsKey]
+	for _, r := range vr.Regions.Regions() {
+		// a fold which text got erased is gone
+		if !r.Empty() {
+			ret = append(ret, r)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].A < ret[j].A })
+	return
+}
+
+// IsFolded returns whether the region is entirely hidden inside a fold.
+func (v *View) IsFolded(r text.Region) bool {
+	for _, f := range v.FoldedRegions() {
+		if f.Covers(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Note that it's presumed that the function calling this function
+// has locked the view!
+func (v *View) setFoldsLocked(folds []text.Region) {
+	if len(folds) == 0 {
+		delete(v.regions, foldsKey)
+		return
+	}
+	vr := render.ViewRegions{Flags: render.FOLDED}
+	vr.Regions.AddAll(folds)
+	v.regions[foldsKey] = vr
+}
+
+// IndentedRegion returns the region that would be folded by indentation
+// at the given point: from the end of its line to the end of the last of
+// the following lines indented more than it. The region is empty if
+// the next line isn't indented more.
+func (v *View) IndentedRegion(point int) text.Region {
+	tabSize := v.Settings().Int("tab_size", 4)
+	line := v.Line(point)
+	ind, blank := v.indentation(line, tabSize)
+	if blank {
+		return text.Region{A: line.End(), B: line.End()}
+	}
+	end := line.End()
+	for l := line; l.End() < v.Size(); {
+		l = v.Line(l.End() + 1)
+		if i, blank := v.indentation(l, tabSize); blank {
+			continue
+		} else if i <= ind {
+			break
+		}
+		end = l.End()
+	}
+	return text.Region{A: line.End(), B: end}
+}
+
+// FoldByLevel folds all the blocks indented at the given level,
+// level 1 folding the blocks of the lines that aren't indented.
+func (v *View) FoldByLevel(level int) {
+	if level < 1 {
+		return
+	}
+	tabSize := v.Settings().Int("tab_size", 4)
+	var folds []text.Region
+	for _, l := range v.Lines(text.Region{A: 0, B: v.Size()}) {
+		if ind, blank := v.indentation(l, tabSize); blank || ind/tabSize != level-1 {
+			continue
+		}
+		if r := v.IndentedRegion(l.A); !r.Empty() {
+			folds = append(folds, r)
+		}
+	}
+	v.Fold(folds...)
+}
+
+// FoldByScope folds the text which syntax scope matches the selector
+// and spans over more than one line, leaving its first line visible.
+// Returns the regions that were folded.
+func (v *View) FoldByScope(scope string) (ret []text.Region) {
+	for _, r := range v.FindBySelector(scope) {
+		f := text.Region{A: v.Line(r.A).End(), B: r.B}
+		if v.Substr(text.Region{A: f.B - 1, B: f.B}) == "\n" {
+			f.B--
+		}
+		if f.B > f.A {
+			ret = append(ret, f)
+		}
+	}
+	v.Fold(ret...)
+	return
+}
+
+// Returns the indentation width of the line and whether
+// it's blank, tabs being tabSize wide.
+func (v *View) indentation(line text.Region, tabSize int) (ind int, blank bool) {
+	for _, c := range v.Substr(line) {
+		switch c {
+		case ' ':
+			ind++
+		case '\t':
+			ind += tabSize - ind%tabSize
+		default:
+			return ind, false
+		}
+	}
+	return ind, true
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jxo/lime/text"
+)
+
+const foldText = `func a() {
+	if b {
+		c()
+
+	}
+}
+func d() {
+}
+`
+
+func TestViewIndentedRegion(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, foldText)
+	v.EndEdit(e)
+	v.Settings().Set("tab_size", 4)
+
+	tests := []struct {
+		point int
+		exp   text.Region
+	}{
+		{0, text.Region{A: 10, B: 28}},
+		{12, text.Region{A: 18, B: 24}},
+		{20, text.Region{A: 24, B: 24}},
+		{35, text.Region{A: 41, B: 41}},
+	}
+	for i, test := range tests {
+		if r := v.IndentedRegion(test.point); r != test.exp {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, r)
+		}
+	}
+}
+
+func TestViewFold(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, foldText)
+	v.EndEdit(e)
+	v.Settings().Set("tab_size", 4)
+
+	if !v.Fold(text.Region{A: 18, B: 24}) {
+		t.Error("Expected the region to be folded")
+	}
+	if v.Fold(text.Region{A: 20, B: 23}) {
+		t.Error("Expected a region inside a fold not to be folded again")
+	}
+	if !v.IsFolded(text.Region{A: 20, B: 23}) || v.IsFolded(text.Region{A: 0, B: 5}) {
+		t.Error("IsFolded doesn't match the folds")
+	}
+
+	// folds are adjusted like any other region
+	e = v.BeginEdit()
+	v.Insert(e, 0, "//\n")
+	v.EndEdit(e)
+	if r := v.FoldedRegions(); !reflect.DeepEqual(r, []text.Region{{21, 27}}) {
+		t.Errorf("Expected the fold to be adjusted, but got %v", r)
+	}
+
+	if r := v.Unfold(text.Region{A: 25, B: 25}); !reflect.DeepEqual(r, []text.Region{{21, 27}}) {
+		t.Errorf("Expected the fold to be unfolded, but got %v", r)
+	}
+	if len(v.FoldedRegions()) != 0 {
+		t.Errorf("Expected no folds, but got %v", v.FoldedRegions())
+	}
+}
+
+func TestViewFoldByLevel(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, foldText)
+	v.EndEdit(e)
+	v.Settings().Set("tab_size", 4)
+
+	v.FoldByLevel(2)
+	if r := v.FoldedRegions(); !reflect.DeepEqual(r, []text.Region{{18, 24}}) {
+		t.Errorf("Expected %v, but got %v", []text.Region{{18, 24}}, r)
+	}
+	v.FoldByLevel(1)
+	if r := v.FoldedRegions(); !reflect.DeepEqual(r, []text.Region{{10, 28}}) {
+		t.Errorf("Expected %v, but got %v", []text.Region{{10, 28}}, r)
+	}
+	if r := v.UnfoldAll(); len(r) != 1 || len(v.FoldedRegions()) != 0 {
+		t.Errorf("Expected all folds to be unfolded, but got %v", v.FoldedRegions())
+	}
+}
+
+func TestViewFoldByScope(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, foldText)
+	v.EndEdit(e)
+	v.Settings().Set("tab_size", 4)
+
+	// stands in for the regions of a parse
+	v.AddRegions("lime.syntax.source comment", []text.Region{{11, 20}}, "source comment", "", 0)
+	v.AddRegions("lime.syntax.source comment punctuation", []text.Region{{20, 25}}, "source comment punctuation", "", 0)
+	v.AddRegions("lime.syntax.source comment.line", []text.Region{{31, 35}}, "source comment.line", "", 0)
+	// a scope which name only contains the selector doesn't match it
+	v.AddRegions("lime.syntax.source nocomment", []text.Region{{35, 44}}, "source nocomment", "", 0)
+
+	exp := []text.Region{{18, 24}}
+	if r := v.FoldByScope("comment"); !reflect.DeepEqual(r, exp) {
+		t.Errorf("Expected %v, but got %v", exp, r)
+	}
+	if r := v.FoldedRegions(); !reflect.DeepEqual(r, exp) {
+		t.Errorf("Expected %v, but got %v", exp, r)
+	}
+}
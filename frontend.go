@@ -36,6 +36,25 @@ type Frontend interface {
 	// Displays file dialog, returns the selected files.
 	// folder is the path file dialog will show.
 	Prompt(title, folder string, flags int) []string
+
+	// Displays the quick panel in the window, the frontend filters
+	// the items as the user types and reports the highlighted and
+	// chosen items through the panel.
+	ShowQuickPanel(w *Window, p *QuickPanel)
+
+	// Displays the input panel in the window, the text is
+	// edited in the panel view. The frontend calls the panel
+	// Done or Cancel once the user is finished.
+	ShowInputPanel(w *Window, p *InputPanel)
+
+	// Displays a popup menu at the cursor of the view, the
+	// chosen item is reported through the panel.
+	ShowPopupMenu(v *View, p *QuickPanel)
+
+	// Displays the completion list at the cursor of the view,
+	// the frontend highlights the item to be committed and
+	// reports the chosen one through the panel.
+	ShowAutoComplete(v *View, p *QuickPanel)
 }
 
 const (
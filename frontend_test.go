@@ -38,3 +38,7 @@ func (fe *dummyFrontend) VisibleRegion(v *View) text.Region {
 func (fe *dummyFrontend) Prompt(title, folder string, flags int) []string {
 	return nil
 }
+func (fe *dummyFrontend) ShowQuickPanel(w *Window, p *QuickPanel) {}
+func (fe *dummyFrontend) ShowInputPanel(w *Window, p *InputPanel) {}
+func (fe *dummyFrontend) ShowPopupMenu(v *View, p *QuickPanel)    {}
+func (fe *dummyFrontend) ShowAutoComplete(v *View, p *QuickPanel) {}
@@ -0,0 +1,58 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"strconv"
+	"strings"
+)
+
+// GotoQuery is a Goto Anything query, a file pattern optionally followed
+// by a ":line:col" position, an "@symbol" or a "#term" to search for.
+type GotoQuery struct {
+	File   string
+	Line   int
+	Column int
+	Symbol string
+	Term   string
+}
+
+// ParseGotoQuery splits the query in its file pattern and suffix,
+// i.e "main.go:12:4", "main.go@Run" or "#TODO". Line and column
+// are 1 based, 0 when not given.
+func ParseGotoQuery(query string) GotoQuery {
+	var q GotoQuery
+	if i := strings.IndexAny(query, "@#"); i != -1 {
+		q.File = query[:i]
+		if query[i] == '@' {
+			q.Symbol = query[i+1:]
+		} else {
+			q.Term = query[i+1:]
+		}
+		return q
+	}
+	q.File = query
+	// the position is read backward so drive letters aren't taken for it
+	nums := make([]int, 0, 2)
+	for len(nums) < 2 {
+		i := strings.LastIndex(q.File, ":")
+		if i == -1 {
+			break
+		}
+		n, err := strconv.Atoi(q.File[i+1:])
+		if err != nil || n < 0 {
+			break
+		}
+		nums = append(nums, n)
+		q.File = q.File[:i]
+	}
+	switch len(nums) {
+	case 1:
+		q.Line = nums[0]
+	case 2:
+		q.Line, q.Column = nums[1], nums[0]
+	}
+	return q
+}
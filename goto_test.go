@@ -0,0 +1,30 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import "testing"
+
+func TestParseGotoQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		exp   GotoQuery
+	}{
+		{"main.go", GotoQuery{File: "main.go"}},
+		{"main.go:12", GotoQuery{File: "main.go", Line: 12}},
+		{"main.go:12:4", GotoQuery{File: "main.go", Line: 12, Column: 4}},
+		{":12", GotoQuery{Line: 12}},
+		{`C:\main.go:3`, GotoQuery{File: `C:\main.go`, Line: 3}},
+		{"main.go:x", GotoQuery{File: "main.go:x"}},
+		{"main.go@Run", GotoQuery{File: "main.go", Symbol: "Run"}},
+		{"@Run", GotoQuery{Symbol: "Run"}},
+		{"view#TODO", GotoQuery{File: "view", Term: "TODO"}},
+		{"", GotoQuery{}},
+	}
+	for i, test := range tests {
+		if q := ParseGotoQuery(test.query); q != test.exp {
+			t.Errorf("Test %d: Expected %+v, but got %+v", i, test.exp, q)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+// Package headless implements a lime.Frontend without any user interface,
+// useful for tests and for running the editor from scripts. Messages are
+// logged, dialogs are answered with preset values and the shown panels
+// are kept so they can be driven programmatically.
+package headless
+
+import (
+	"sync"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/text"
+)
+
+type Frontend struct {
+	// Answer of OkCancelDialog
+	DefaultAction bool
+	// Files returned by Prompt
+	Files []string
+
+	status       string
+	quickPanel   *lime.QuickPanel
+	inputPanel   *lime.InputPanel
+	popupMenu    *lime.QuickPanel
+	autoComplete *lime.QuickPanel
+	lock         sync.Mutex
+}
+
+func (f *Frontend) VisibleRegion(v *lime.View) text.Region {
+	return text.Region{A: 0, B: v.Size()}
+}
+
+func (f *Frontend) Show(v *lime.View, r text.Region) {}
+
+func (f *Frontend) StatusMessage(msg string) {
+	log.Info(msg)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.status = msg
+}
+
+func (f *Frontend) ErrorMessage(msg string) { log.Error(msg) }
+
+func (f *Frontend) MessageDialog(msg string) { log.Info(msg) }
+
+func (f *Frontend) OkCancelDialog(msg string, okname string) bool {
+	log.Info(msg)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.DefaultAction
+}
+
+func (f *Frontend) Prompt(title, folder string, flags int) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.Files
+}
+
+// ShowQuickPanel cancels the quick panel being shown if any and keeps
+// the new one, the first item is highlighted unless the panel says
+// otherwise.
+func (f *Frontend) ShowQuickPanel(w *lime.Window, p *lime.QuickPanel) {
+	f.lock.Lock()
+	prev := f.quickPanel
+	f.quickPanel = p
+	f.lock.Unlock()
+	if prev != nil {
+		prev.Cancel()
+	}
+	if p.Selected >= 0 {
+		p.Highlight(p.Selected)
+	} else {
+		p.Highlight(0)
+	}
+}
+
+// ShowInputPanel cancels the input panel being shown if any
+// and keeps the new one.
+func (f *Frontend) ShowInputPanel(w *lime.Window, p *lime.InputPanel) {
+	f.lock.Lock()
+	prev := f.inputPanel
+	f.inputPanel = p
+	f.lock.Unlock()
+	if prev != nil {
+		prev.Cancel()
+	}
+}
+
+// ShowPopupMenu cancels the popup menu being shown if any
+// and keeps the new one.
+func (f *Frontend) ShowPopupMenu(v *lime.View, p *lime.QuickPanel) {
+	f.lock.Lock()
+	prev := f.popupMenu
+	f.popupMenu = p
+	f.lock.Unlock()
+	if prev != nil {
+		prev.Cancel()
+	}
+}
+
+// ShowAutoComplete cancels the completion list being shown
+// if any and keeps the new one.
+func (f *Frontend) ShowAutoComplete(v *lime.View, p *lime.QuickPanel) {
+	f.lock.Lock()
+	prev := f.autoComplete
+	f.autoComplete = p
+	f.lock.Unlock()
+	if prev != nil {
+		prev.Cancel()
+	}
+}
+
+// Status returns the last status message.
+func (f *Frontend) Status() string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.status
+}
+
+// QuickPanel returns the last quick panel shown.
+func (f *Frontend) QuickPanel() *lime.QuickPanel {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.quickPanel
+}
+
+// InputPanel returns the last input panel shown.
+func (f *Frontend) InputPanel() *lime.InputPanel {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.inputPanel
+}
+
+// PopupMenu returns the last popup menu shown.
+func (f *Frontend) PopupMenu() *lime.QuickPanel {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.popupMenu
+}
+
+// AutoComplete returns the last completion list shown.
+func (f *Frontend) AutoComplete() *lime.QuickPanel {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.autoComplete
+}
+
+// Type types the text at the end of the input panel,
+// as if the user typed it.
+func (f *Frontend) Type(s string) {
+	p := f.InputPanel()
+	if p == nil {
+		return
+	}
+	v := p.View()
+	e := v.BeginEdit()
+	v.Insert(e, v.Size(), s)
+	v.EndEdit(e)
+}
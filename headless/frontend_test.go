@@ -0,0 +1,145 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package headless
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jxo/lime"
+	_ "github.com/jxo/lime/commands"
+	"github.com/jxo/lime/text"
+)
+
+func TestQuickPanel(t *testing.T) {
+	fe := &Frontend{}
+	ed := lime.GetEditor()
+	ed.SetFrontend(fe)
+	w := ed.NewWindow()
+	defer w.Close()
+
+	var selected, highlighted []int
+	items := []lime.QuickPanelItem{{Text: "a"}, {Text: "b"}, {Text: "c"}}
+	w.ShowQuickPanel(items, 0, 1, func(i int) {
+		selected = append(selected, i)
+	}, func(i int) {
+		highlighted = append(highlighted, i)
+	})
+	q := fe.QuickPanel()
+	if q == nil {
+		t.Fatalf("Expected the quick panel to be shown")
+	}
+	q.Highlight(2)
+	q.Select(2)
+	if exp := []int{1, 2}; !reflect.DeepEqual(highlighted, exp) {
+		t.Errorf("Expected highlighted %v, but got %v", exp, highlighted)
+	}
+
+	// showing another panel cancels the previous one
+	w.ShowQuickPanel(items, 0, -1, func(i int) {
+		selected = append(selected, i)
+	}, nil)
+	w.ShowQuickPanel(items, 0, -1, nil, nil)
+	if exp := []int{2, -1}; !reflect.DeepEqual(selected, exp) {
+		t.Errorf("Expected selected %v, but got %v", exp, selected)
+	}
+}
+
+func TestInputPanel(t *testing.T) {
+	fe := &Frontend{}
+	ed := lime.GetEditor()
+	ed.SetFrontend(fe)
+	w := ed.NewWindow()
+	defer w.Close()
+
+	var (
+		changes   []string
+		done      string
+		cancelled bool
+	)
+	v := w.ShowInputPanel("Goto Line:", "1", func(s string) {
+		done = s
+	}, func(s string) {
+		changes = append(changes, s)
+	}, func() {
+		cancelled = true
+	})
+	if !v.Settings().Bool("is_widget", false) {
+		t.Errorf("Expected the input panel view to be a widget")
+	}
+	p := fe.InputPanel()
+	if p == nil || p.View() != v || p.Caption != "Goto Line:" {
+		t.Fatalf("Expected the input panel to be shown, but got %v", p)
+	}
+	fe.Type("2")
+	fe.Type("3")
+	p.Done()
+	p.Cancel()
+	fe.Type("4")
+	if exp := []string{"12", "123"}; !reflect.DeepEqual(changes, exp) {
+		t.Errorf("Expected changes %v, but got %v", exp, changes)
+	}
+	if done != "123" {
+		t.Errorf("Expected done with %q, but got %q", "123", done)
+	}
+	if cancelled {
+		t.Errorf("Expected the input panel not to be cancelled once done")
+	}
+}
+
+func TestPopupMenu(t *testing.T) {
+	fe := &Frontend{}
+	ed := lime.GetEditor()
+	ed.SetFrontend(fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	chosen := -2
+	v.ShowPopupMenu([]string{"copy", "paste"}, func(i int) { chosen = i }, 0)
+	p := fe.PopupMenu()
+	if p == nil || len(p.Items) != 2 || p.Items[1].Text != "paste" {
+		t.Fatalf("Expected the popup menu to be shown, but got %v", p)
+	}
+	p.Select(1)
+	if chosen != 1 {
+		t.Errorf("Expected item 1 to be chosen, but got %d", chosen)
+	}
+}
+
+func TestAutoComplete(t *testing.T) {
+	fe := &Frontend{}
+	ed := lime.GetEditor()
+	ed.SetFrontend(fe)
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "headless he")
+	v.EndEdit(e)
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{A: 11, B: 11})
+
+	q := v.ShowAutoComplete()
+	if p := fe.AutoComplete(); p == nil || p != q || p.Items[0].Text != "headless" {
+		t.Fatalf("Expected the completions to be shown, but got %v", p)
+	}
+	v.ShowAutoComplete()
+	if !q.Done() {
+		t.Error("Expected the previous completions to be cancelled")
+	}
+	fe.AutoComplete().Select(0)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "headless headless" {
+		t.Errorf("Expected %q, but got %q", "headless headless", s)
+	}
+}
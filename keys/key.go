@@ -12,22 +12,15 @@ type (
 	Key rune
 )
 
+// The keys without a character are mapped past the unicode range, so
+// they never collide with a character typed.
 const (
-	Left Key = 0x2190 + iota
+	Left Key = unicode.MaxRune + 1 + iota
 	Up
 	Right
 	Down
 
-	Enter       = '\n'
-	Escape      = 0x001B
-	Backspace   = 0x0008
-	Delete      = 0x007F
-	KeypadEnter = '\n'
-)
-
-const (
-	// map to dingbats...
-	F1 Key = 0x2701 + iota
+	F1
 	F2
 	F3
 	F4
@@ -39,97 +32,175 @@ const (
 	F10
 	F11
 	F12
+	F13
+	F14
+	F15
+	F16
+	F17
+	F18
+	F19
+	F20
+	F21
+	F22
+	F23
+	F24
 	Insert
 	PageUp
 	PageDown
 	Home
 	End
 	Break
-	Any Key = unicode.MaxRune
+	Pause
+	Clear
+	SysReq
+	ContextMenu
+
+	Keypad0
+	Keypad1
+	Keypad2
+	Keypad3
+	Keypad4
+	Keypad5
+	Keypad6
+	Keypad7
+	Keypad8
+	Keypad9
+	KeypadPeriod
+	KeypadDivide
+	KeypadMultiply
+	KeypadMinus
+	KeypadPlus
+	KeypadEnter
+
+	BrowserBack
+	BrowserForward
+	BrowserRefresh
+	BrowserStop
+	BrowserSearch
+	BrowserFavorites
+	BrowserHome
+	MediaPlayPause
+	MediaStop
+	MediaNextTrack
+	MediaPreviousTrack
+	VolumeUp
+	VolumeDown
+	VolumeMute
+
+	// Any is the <character> wildcard, matching any character typed
+	Any
 )
 
 const (
-	shift = (1 << (29 - iota))
+	Enter     = '\n'
+	Escape    = 0x001B
+	Backspace = 0x0008
+	Delete    = 0x007F
+)
+
+// The modifiers are stored past the key in the key press index, the
+// keys fit in keyBits as they're all below 2^21.
+const keyBits = 21
+
+const (
+	shift = 1 << (keyBits + iota)
 	ctrl
 	alt
 	super
+	meta
 )
 
 var keylut = map[string]Key{
-	"up":            Up,
-	"left":          Left,
-	"right":         Right,
-	"down":          Down,
-	"enter":         Enter,
-	"tab":           '\t',
-	"escape":        Escape,
-	"space":         ' ',
-	"f1":            F1,
-	"f2":            F2,
-	"f3":            F3,
-	"f4":            F4,
-	"f5":            F5,
-	"f6":            F6,
-	"f7":            F7,
-	"f8":            F8,
-	"f9":            F9,
-	"f10":           F10,
-	"f11":           F11,
-	"f12":           F12,
-	"backspace":     Backspace,
-	"delete":        Delete,
-	"keypad_enter":  KeypadEnter,
-	"insert":        Insert,
-	"pageup":        PageUp,
-	"pagedown":      PageDown,
-	"home":          Home,
-	"end":           End,
-	"break":         Break,
-	"forward_slash": '/',
-	"backquote":     '`',
-	"\\\"":          '"',
-	"plus":          '+',
-	"minus":         '-',
-	"equals":        '=',
-	"<character>":   Any,
+	"up":                   Up,
+	"left":                 Left,
+	"right":                Right,
+	"down":                 Down,
+	"enter":                Enter,
+	"tab":                  '\t',
+	"escape":               Escape,
+	"space":                ' ',
+	"f1":                   F1,
+	"f2":                   F2,
+	"f3":                   F3,
+	"f4":                   F4,
+	"f5":                   F5,
+	"f6":                   F6,
+	"f7":                   F7,
+	"f8":                   F8,
+	"f9":                   F9,
+	"f10":                  F10,
+	"f11":                  F11,
+	"f12":                  F12,
+	"f13":                  F13,
+	"f14":                  F14,
+	"f15":                  F15,
+	"f16":                  F16,
+	"f17":                  F17,
+	"f18":                  F18,
+	"f19":                  F19,
+	"f20":                  F20,
+	"f21":                  F21,
+	"f22":                  F22,
+	"f23":                  F23,
+	"f24":                  F24,
+	"backspace":            Backspace,
+	"delete":               Delete,
+	"insert":               Insert,
+	"pageup":               PageUp,
+	"pagedown":             PageDown,
+	"home":                 Home,
+	"end":                  End,
+	"break":                Break,
+	"pause":                Pause,
+	"clear":                Clear,
+	"sysreq":               SysReq,
+	"context_menu":         ContextMenu,
+	"keypad0":              Keypad0,
+	"keypad1":              Keypad1,
+	"keypad2":              Keypad2,
+	"keypad3":              Keypad3,
+	"keypad4":              Keypad4,
+	"keypad5":              Keypad5,
+	"keypad6":              Keypad6,
+	"keypad7":              Keypad7,
+	"keypad8":              Keypad8,
+	"keypad9":              Keypad9,
+	"keypad_period":        KeypadPeriod,
+	"keypad_divide":        KeypadDivide,
+	"keypad_multiply":      KeypadMultiply,
+	"keypad_minus":         KeypadMinus,
+	"keypad_plus":          KeypadPlus,
+	"keypad_enter":         KeypadEnter,
+	"browser_back":         BrowserBack,
+	"browser_forward":      BrowserForward,
+	"browser_refresh":      BrowserRefresh,
+	"browser_stop":         BrowserStop,
+	"browser_search":       BrowserSearch,
+	"browser_favorites":    BrowserFavorites,
+	"browser_home":         BrowserHome,
+	"media_play_pause":     MediaPlayPause,
+	"media_stop":           MediaStop,
+	"media_next_track":     MediaNextTrack,
+	"media_previous_track": MediaPreviousTrack,
+	"volume_up":            VolumeUp,
+	"volume_down":          VolumeDown,
+	"volume_mute":          VolumeMute,
+	"forward_slash":        '/',
+	"backquote":            '`',
+	"\\\"":                 '"',
+	"plus":                 '+',
+	"minus":                '-',
+	"equals":               '=',
+	"<character>":          Any,
 }
 
-var rkeylut = map[Key]string{
-	Up:        "up",
-	Left:      "left",
-	Right:     "right",
-	Down:      "down",
-	Enter:     "enter",
-	'\t':      "tab",
-	Escape:    "escape",
-	' ':       "space",
-	F1:        "f1",
-	F2:        "f2",
-	F3:        "f3",
-	F4:        "f4",
-	F5:        "f5",
-	F6:        "f6",
-	F7:        "f7",
-	F8:        "f8",
-	F9:        "f9",
-	F10:       "f10",
-	F11:       "f11",
-	F12:       "f12",
-	Backspace: "backspace",
-	Delete:    "delete",
-	Insert:    "insert",
-	PageUp:    "pageup",
-	PageDown:  "pagedown",
-	Home:      "home",
-	End:       "end",
-	Break:     "break",
-	'/':       "forward_slash",
-	'`':       "backquote",
-	'"':       "\\\"",
-	'+':       "plus",
-	'-':       "minus",
-	'=':       "equals",
-	Any:       "<character>",
+// The names of the keys, the reverse of keylut
+var rkeylut = make(map[Key]string, len(keylut))
+
+func init() {
+	for name, k := range keylut {
+		rkeylut[k] = name
+	}
 }
 
 func (k Key) String() string {
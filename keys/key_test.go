@@ -18,4 +18,14 @@ func TestKeyString(t *testing.T) {
 	if k.String() != "home" {
 		t.Errorf("Expected %q but got %q", "home", k.String())
 	}
+
+	k = Key(F24)
+	if k.String() != "f24" {
+		t.Errorf("Expected %q but got %q", "f24", k.String())
+	}
+
+	k = Key(MediaPlayPause)
+	if k.String() != "media_play_pause" {
+		t.Errorf("Expected %q but got %q", "media_play_pause", k.String())
+	}
 }
@@ -8,7 +8,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"sort"
+	"strings"
 
 	. "github.com/jxo/lime/util"
 )
@@ -40,6 +42,19 @@ type (
 		Bindings []*KeyBinding
 		seqIndex int // The index we are in a multiple key sequence keybinding
 		parent   KeyBindingsInterface
+		// The bindings by the index of their key press at seqIndex,
+		// built on the first lookup and dropped as Bindings change
+		index        map[int][]*KeyBinding
+		indexedCount int
+	}
+
+	// A KeyBindingConflict is a key binding which never runs, as
+	// another one bound to the same keys with a subset of its context
+	// is always chosen over it. In and ByIn are the KeyBindings of the
+	// hierarchy defining them.
+	KeyBindingConflict struct {
+		Shadowed, By *KeyBinding
+		In, ByIn     *KeyBindings
 	}
 )
 
@@ -70,6 +85,7 @@ func (k *KeyBindings) DropLessEqualKeys(count int) {
 			if len(k.Bindings[i].Keys) <= count {
 				k.Bindings[i] = k.Bindings[len(k.Bindings)-1]
 				k.Bindings = k.Bindings[:len(k.Bindings)-1]
+				k.index = nil
 			} else {
 				i++
 			}
@@ -89,6 +105,7 @@ func (k *KeyBindings) UnmarshalJSON(d []byte) error {
 	for i := range k.Bindings {
 		k.Bindings[i].priority = i
 	}
+	k.index = nil
 	k.DropLessEqualKeys(0)
 	return nil
 }
@@ -97,20 +114,31 @@ func (k *KeyBindings) SetParent(p KeyBindingsInterface) {
 	k.parent = p
 	// All parents and childs seqIndex must be equal
 	p.KeyBindings().seqIndex = k.seqIndex
+	p.KeyBindings().index = nil
 }
 
 func (k *KeyBindings) Parent() KeyBindingsInterface {
 	return k.parent
 }
 
+// Returns the bindings whose key press at seqIndex has the index ki.
+func (k *KeyBindings) lookup(ki int) []*KeyBinding {
+	if k.index == nil || k.indexedCount != len(k.Bindings) {
+		k.index = make(map[int][]*KeyBinding)
+		for _, b := range k.Bindings {
+			if len(b.Keys) > k.seqIndex {
+				i := b.Keys[k.seqIndex].Index()
+				k.index[i] = append(k.index[i], b)
+			}
+		}
+		k.indexedCount = len(k.Bindings)
+	}
+	return k.index[ki]
+}
+
 func (k *KeyBindings) filter(ki int, ret *KeyBindings) {
 	for {
-		idx := sort.Search(k.Len(), func(i int) bool {
-			return k.Bindings[i].Keys[k.seqIndex].Index() >= ki
-		})
-		for i := idx; i < len(k.Bindings) && k.Bindings[i].Keys[k.seqIndex].Index() == ki; i++ {
-			ret.Bindings = append(ret.Bindings, k.Bindings[i])
-		}
+		ret.Bindings = append(ret.Bindings, k.lookup(ki)...)
 		if k.parent == nil {
 			break
 		}
@@ -157,15 +185,12 @@ func (k *KeyBindings) Action(qc func(key string, operator Op, operand interface{
 				// the user has only pressed ['a','b'] so far.
 				continue
 			}
-			for _, c := range k.Bindings[i].Context {
-				if !qc(c.Key, c.Operator, c.Operand, c.MatchAll) {
-					goto skip
-				}
+			if !k.Bindings[i].matchContext(qc) {
+				continue
 			}
 			if kb == nil || kb.priority < k.Bindings[i].priority {
 				kb = k.Bindings[i]
 			}
-		skip:
 		}
 		if kb != nil || k.parent == nil {
 			break
@@ -175,6 +200,147 @@ func (k *KeyBindings) Action(qc func(key string, operator Op, operand interface{
 	return
 }
 
+// Returns whether any of the KeyBindings, as determined by context, is
+// of a key sequence longer than the key presses filtered so far, i.e
+// more key presses are needed to complete it.
+func (k *KeyBindings) Pending(qc func(key string, operator Op, operand interface{}, match_all bool) bool) bool {
+	for {
+		for _, b := range k.Bindings {
+			if len(b.Keys) > k.seqIndex && b.matchContext(qc) {
+				return true
+			}
+		}
+		if k.parent == nil {
+			return false
+		}
+		k = k.parent.KeyBindings()
+	}
+}
+
+func (k *KeyBinding) matchContext(qc func(key string, operator Op, operand interface{}, match_all bool) bool) bool {
+	for _, c := range k.Context {
+		if !qc(c.Key, c.Operator, c.Operand, c.MatchAll) {
+			return false
+		}
+	}
+	return true
+}
+
+// Conflicts returns the key bindings shadowed by others in the
+// hierarchy. A binding is shadowed by one bound to the same keys whose
+// context conditions are all among its own, either in KeyBindings
+// looked up before, or in the same KeyBindings and defined after it.
+func (k *KeyBindings) Conflicts() (ret []KeyBindingConflict) {
+	var levels []*KeyBindings
+	for kb := k; ; kb = kb.parent.KeyBindings() {
+		levels = append(levels, kb)
+		if kb.parent == nil {
+			break
+		}
+	}
+	for i, in := range levels {
+		for _, b := range in.Bindings {
+			for _, by := range levels[:i+1] {
+				if c := by.shadowing(b); c != nil {
+					ret = append(ret, KeyBindingConflict{Shadowed: b, By: c, In: in, ByIn: by})
+					break
+				}
+			}
+		}
+	}
+	return
+}
+
+// Returns the binding of k shadowing b, nil if there's none.
+func (k *KeyBindings) shadowing(b *KeyBinding) (kb *KeyBinding) {
+	for _, o := range k.Bindings {
+		if o == b || !o.shadows(b) {
+			continue
+		}
+		if kb == nil || kb.priority < o.priority {
+			kb = o
+		}
+	}
+	// within the same bindings only those of higher priority shadow b
+	if kb != nil && kb.priority < b.priority && k.contains(b) {
+		return nil
+	}
+	return
+}
+
+func (k *KeyBindings) contains(b *KeyBinding) bool {
+	for _, o := range k.Bindings {
+		if o == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns whether k is bound to the same keys as o, with its context
+// conditions all among those of o.
+func (k *KeyBinding) shadows(o *KeyBinding) bool {
+	if len(k.Keys) != len(o.Keys) {
+		return false
+	}
+	for i := range k.Keys {
+		if k.Keys[i].Index() != o.Keys[i].Index() {
+			return false
+		}
+	}
+	for _, c := range k.Context {
+		found := false
+		for _, oc := range o.Context {
+			if reflect.DeepEqual(c, oc) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the binding of the given command with the given args, nil if
+// the command isn't bound. The bindings are looked up before their
+// parents, nil and empty args are the same.
+func (k *KeyBindings) Lookup(command string, args map[string]interface{}) (kb *KeyBinding) {
+	for {
+		for _, b := range k.Bindings {
+			if b.Command != command || !sameArgs(b.Args, args) {
+				continue
+			}
+			if kb == nil || kb.priority < b.priority {
+				kb = b
+			}
+		}
+		if kb != nil || k.parent == nil {
+			break
+		}
+		k = k.parent.KeyBindings()
+	}
+	return
+}
+
+func sameArgs(a, b map[string]interface{}) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Returns the keys of the binding the way they're written
+// in keymaps, i.e "ctrl+k, ctrl+u".
+func (k *KeyBinding) KeysString() string {
+	keys := make([]string, len(k.Keys))
+	for i, kp := range k.Keys {
+		keys[i] = kp.String()
+	}
+	return strings.Join(keys, ", ")
+}
+
 func (k *KeyBindings) SeqIndex() int {
 	return k.seqIndex
 }
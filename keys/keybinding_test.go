@@ -215,6 +215,126 @@ func TestKeyBindingsAction(t *testing.T) {
 	}
 }
 
+func TestKeyBindingsLookup(t *testing.T) {
+	var (
+		bindings KeyBindings
+		p        HasKeyBindings
+	)
+	for fn, kb := range map[string]*KeyBindings{
+		"testdata/Default.sublime-keymap": &bindings,
+		"testdata/test.sublime-keymap":    p.KeyBindings(),
+	} {
+		d, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := loaders.LoadJSON(d, kb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bindings.SetParent(&p)
+
+	tests := []struct {
+		command string
+		args    map[string]interface{}
+		exp     string
+	}{
+		{"test1", nil, "ctrl+i, j"},
+		{"test4", map[string]interface{}{}, "ctrl+d"},
+		{"t1", nil, "ctrl+d, ctrl+k"},
+		{"test3", map[string]interface{}{"a": 1}, ""},
+		{"none", nil, ""},
+	}
+	for i, test := range tests {
+		kb := bindings.Lookup(test.command, test.args)
+		if kb == nil {
+			if test.exp != "" {
+				t.Errorf("Test %d: Expected a binding for %s", i, test.command)
+			}
+		} else if s := kb.KeysString(); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+	}
+}
+
+func TestKeyBindingsPending(t *testing.T) {
+	var (
+		bindings KeyBindings
+		p        HasKeyBindings
+	)
+	for fn, kb := range map[string]*KeyBindings{
+		"testdata/Default.sublime-keymap": &bindings,
+		"testdata/test.sublime-keymap":    p.KeyBindings(),
+	} {
+		d, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := loaders.LoadJSON(d, kb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bindings.SetParent(&p)
+
+	tests := []struct {
+		kp  KeyPress
+		ck  string
+		exp bool
+	}{
+		{KeyPress{Key: 'd', Ctrl: true}, "t1", true},
+		{KeyPress{Key: 'd', Ctrl: true}, "test4", false},
+		{KeyPress{Key: 'i', Ctrl: true}, "test2", true},
+		{KeyPress{Key: 'p'}, "t2", false},
+	}
+	for i, test := range tests {
+		qc := func(key string, operator util.Op, operand interface{}, match_all bool) bool {
+			return key == test.ck
+		}
+		b := bindings.Filter(test.kp)
+		if p := b.Pending(qc); p != test.exp {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, p)
+		}
+	}
+}
+
+func TestKeyBindingsConflicts(t *testing.T) {
+	var (
+		bindings KeyBindings
+		p        HasKeyBindings
+	)
+	for fn, kb := range map[string]*KeyBindings{
+		"testdata/conflicts_user.sublime-keymap":    &bindings,
+		"testdata/conflicts_default.sublime-keymap": p.KeyBindings(),
+	} {
+		d, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := loaders.LoadJSON(d, kb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bindings.SetParent(&p)
+
+	exp := map[string]string{
+		"d1": "u1",
+		"d2": "u2",
+		"d4": "d5",
+	}
+	conflicts := bindings.Conflicts()
+	if len(conflicts) != len(exp) {
+		t.Errorf("Expected %d conflicts, but got %d", len(exp), len(conflicts))
+	}
+	for _, c := range conflicts {
+		if by := exp[c.Shadowed.Command]; by != c.By.Command {
+			t.Errorf("Expected %s to be shadowed by %q, but got %s", c.Shadowed.Command, by, c.By.Command)
+		}
+		if c.In != p.KeyBindings() {
+			t.Errorf("Expected %s to be shadowed in the parent bindings", c.Shadowed.Command)
+		}
+	}
+}
+
 func TestSeqIndex(t *testing.T) {
 	var bd KeyBindings
 	bd.seqIndex = 3
@@ -16,14 +16,14 @@ import (
 // Note that Key does not distinguish between capital and non-capital letters;
 // use the Text property for this purpose.
 type KeyPress struct {
-	Text                    string // the text representation of the key
-	Key                     Key    // the code for the key that was pressed
-	Shift, Super, Alt, Ctrl bool   // true if modifier key was pressed
+	Text                          string // the text representation of the key
+	Key                           Key    // the code for the key that was pressed
+	Shift, Super, Alt, Ctrl, Meta bool   // true if modifier key was pressed
 }
 
-// Returns an index used for sorting key presses.
-// TODO(.): This is in no way a unique index with quite a lot of collisions and potentially resulting
-// in bad lookups.
+// Returns an index identifying the key press, used for sorting and
+// looking up key presses. The index is distinct for every key and
+// combination of modifiers.
 func (k KeyPress) Index() (ret int) {
 	ret = int(k.Key)
 	if k.Shift {
@@ -38,12 +38,15 @@ func (k KeyPress) Index() (ret int) {
 	if k.Super {
 		ret += super
 	}
+	if k.Meta {
+		ret += meta
+	}
 	return
 }
 
 // Returns whether this KeyPress is a print character or not.
 func (k KeyPress) IsCharacter() bool {
-	return unicode.IsPrint(rune(k.Key)) && !k.Super && !k.Ctrl
+	return unicode.IsPrint(rune(k.Key)) && !k.Super && !k.Ctrl && !k.Meta
 }
 
 // Modifies the KeyPress so that it's Key is a unicode lower case
@@ -70,6 +73,8 @@ func (k *KeyPress) UnmarshalJSON(d []byte) error {
 			k.Alt = true
 		case "shift":
 			k.Shift = true
+		case "meta":
+			k.Meta = true
 		default:
 			if v, ok := keylut[lower]; ok {
 				k.Key = v
@@ -79,7 +84,7 @@ func (k *KeyPress) UnmarshalJSON(d []byte) error {
 					log.Warn("Unknown key value with %d bytes: %s", len(c), c)
 					return nil
 				}
-				k.Key = Key(c[0])
+				k.Key = r[0]
 				k.fix()
 			}
 		}
@@ -97,6 +102,9 @@ func (k KeyPress) String() (ret string) {
 	if k.Alt {
 		ret += "alt+"
 	}
+	if k.Meta {
+		ret += "meta+"
+	}
 	if k.Shift {
 		ret += "shift+"
 	}
@@ -6,6 +6,7 @@ package keys
 
 import (
 	"testing"
+	"unicode"
 )
 
 func TestKeyPressIndex(t *testing.T) {
@@ -42,6 +43,34 @@ func TestKeyPressIndex(t *testing.T) {
 	}
 }
 
+func TestKeyPressIndexCollisions(t *testing.T) {
+	kps := []KeyPress{
+		{Key: Left},
+		{Key: 0x2190},
+		{Key: F1},
+		{Key: 0x2701},
+		{Key: Enter},
+		{Key: KeypadEnter},
+		{Key: F24},
+		{Key: Keypad0},
+		{Key: '0'},
+		{Key: Any},
+		{Key: unicode.MaxRune},
+		{Key: 'a', Super: true},
+		{Key: 'a', Meta: true},
+		{Key: 'a', Ctrl: true},
+		{Key: 'a', Super: true, Meta: true},
+		{Key: VolumeMute, Shift: true, Super: true, Alt: true, Ctrl: true, Meta: true},
+	}
+	seen := make(map[int]KeyPress)
+	for i, kp := range kps {
+		if o, ok := seen[kp.Index()]; ok {
+			t.Errorf("Test %d: Expected %v and %v to have distinct indices", i, kp, o)
+		}
+		seen[kp.Index()] = kp
+	}
+}
+
 func TestKeyPressIsCharacter(t *testing.T) {
 	tests := []struct {
 		kp  KeyPress
@@ -63,10 +92,18 @@ func TestKeyPressIsCharacter(t *testing.T) {
 			KeyPress{Key: 'a', Shift: false, Super: false, Alt: false, Ctrl: true},
 			false,
 		},
-		// {
-		// 	KeyPress{Key: F1, Shift: false, Super: false, Alt: false, Ctrl: false},
-		// 	false,
-		// },
+		{
+			KeyPress{Key: 'a', Meta: true},
+			false,
+		},
+		{
+			KeyPress{Key: F1, Shift: false, Super: false, Alt: false, Ctrl: false},
+			false,
+		},
+		{
+			KeyPress{Key: Left},
+			false,
+		},
 	}
 
 	for i, test := range tests {
@@ -77,7 +114,7 @@ func TestKeyPressIsCharacter(t *testing.T) {
 }
 
 func TestKeyPressFix(t *testing.T) {
-	k := KeyPress{"A", 'A', false, false, false, false}
+	k := KeyPress{"A", 'A', false, false, false, false, false}
 	k.fix()
 	if k.Key != 'a' {
 		t.Errorf("Expected the key to be %q, but it was %q", 'a', k.Key)
@@ -94,16 +131,43 @@ func TestKeyPressUnmarshalJSON(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+
+	tests := []struct {
+		in  string
+		exp KeyPress
+	}{
+		{`"meta+keypad5"`, KeyPress{Key: Keypad5, Meta: true}},
+		{`"super+f24"`, KeyPress{Key: F24, Super: true}},
+		{`"keypad_enter"`, KeyPress{Key: KeypadEnter}},
+		{`"ctrl+volume_up"`, KeyPress{Key: VolumeUp, Ctrl: true}},
+		{`"alt+λ"`, KeyPress{Key: 'λ', Alt: true}},
+		{`"<character>"`, KeyPress{Key: Any}},
+	}
+	for i, test := range tests {
+		var k KeyPress
+		if err := k.UnmarshalJSON([]byte(test.in)); err != nil {
+			t.Errorf("Test %d: %s", i, err)
+		} else if k != test.exp {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, k)
+		}
+	}
 }
 
 func TestKeyPressString(t *testing.T) {
-	k1 := KeyPress{"a", 'a', true, true, false, false}
+	k1 := KeyPress{"a", 'a', true, true, false, false, false}
 	if k1.String() != "super+shift+a" {
 		t.Errorf("Expected %q, but got %q", "super+shift+a", k1.String())
 	}
 
-	k2 := KeyPress{"b", 'b', true, false, true, true}
+	k2 := KeyPress{"b", 'b', true, false, true, true, false}
 	if k2.String() != "ctrl+alt+shift+b" {
 		t.Errorf("Expected %q, but got %q", "ctrl+alt+shift+b", k2.String())
 	}
 }
+
+func TestKeyPressStringMeta(t *testing.T) {
+	k := KeyPress{Key: KeypadEnter, Ctrl: true, Meta: true}
+	if k.String() != "ctrl+meta+keypad_enter" {
+		t.Errorf("Expected %q, but got %q", "ctrl+meta+keypad_enter", k.String())
+	}
+}
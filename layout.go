@@ -0,0 +1,180 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jxo/lime/text"
+)
+
+// Layout describes how a window is split into groups, it's the same
+// model sublime uses: Cols and Rows are the split positions in the range
+// [0, 1] and each cell is a group placed at the [x1, y1, x2, y2] indices
+// of Cols and Rows.
+type Layout struct {
+	Cols  []float64
+	Rows  []float64
+	Cells [][4]int
+}
+
+// The layout of a new window, a single group covering the whole window.
+func defaultLayout() Layout {
+	return Layout{
+		Cols:  []float64{0, 1},
+		Rows:  []float64{0, 1},
+		Cells: [][4]int{{0, 0, 1, 1}},
+	}
+}
+
+// Validate returns an error if the layout isn't usable.
+func (l *Layout) Validate() error {
+	if len(l.Cols) < 2 || len(l.Rows) < 2 {
+		return fmt.Errorf("Layout needs at least 2 cols and 2 rows")
+	}
+	if len(l.Cells) == 0 {
+		return fmt.Errorf("Layout needs at least 1 cell")
+	}
+	for i, c := range l.Cells {
+		if c[0] < 0 || c[2] >= len(l.Cols) || c[0] >= c[2] ||
+			c[1] < 0 || c[3] >= len(l.Rows) || c[1] >= c[3] {
+			return fmt.Errorf("Invalid layout cell %d: %v", i, c)
+		}
+	}
+	return nil
+}
+
+func (l Layout) clone() Layout {
+	return Layout{
+		Cols:  append([]float64(nil), l.Cols...),
+		Rows:  append([]float64(nil), l.Rows...),
+		Cells: append([][4]int(nil), l.Cells...),
+	}
+}
+
+// Set implements CustomSet, so the layout can be given as a command
+// argument the way sublime does: {"cols": [...], "rows": [...], "cells": [[...], ...]}
+func (l *Layout) Set(v interface{}) error {
+	m := reflect.ValueOf(v)
+	if m.Kind() != reflect.Map || m.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("Expected layout to be a map, not %T", v)
+	}
+	get := func(key string) interface{} {
+		if e := m.MapIndex(reflect.ValueOf(key).Convert(m.Type().Key())); e.IsValid() {
+			return e.Interface()
+		}
+		return nil
+	}
+	var (
+		nl  Layout
+		err error
+	)
+	if nl.Cols, err = toFloats(get("cols")); err != nil {
+		return fmt.Errorf("Invalid layout cols: %s", err)
+	}
+	if nl.Rows, err = toFloats(get("rows")); err != nil {
+		return fmt.Errorf("Invalid layout rows: %s", err)
+	}
+	cells := reflect.ValueOf(get("cells"))
+	if cells.Kind() != reflect.Slice {
+		return fmt.Errorf("Expected layout cells to be a list, not %T", get("cells"))
+	}
+	for i := 0; i < cells.Len(); i++ {
+		c, err := toFloats(cells.Index(i).Interface())
+		if err != nil || len(c) != 4 {
+			return fmt.Errorf("Invalid layout cell %d: %v", i, cells.Index(i).Interface())
+		}
+		nl.Cells = append(nl.Cells, [4]int{int(c[0]), int(c[1]), int(c[2]), int(c[3])})
+	}
+	if err := nl.Validate(); err != nil {
+		return err
+	}
+	*l = nl
+	return nil
+}
+
+func toFloats(v interface{}) ([]float64, error) {
+	s := reflect.ValueOf(v)
+	if s.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("expected a list, not %T", v)
+	}
+	ret := make([]float64, s.Len())
+	for i := range ret {
+		e := reflect.ValueOf(s.Index(i).Interface())
+		switch e.Kind() {
+		case reflect.Float32, reflect.Float64:
+			ret[i] = e.Float()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			ret[i] = float64(e.Int())
+		default:
+			return nil, fmt.Errorf("expected a number, not %v", s.Index(i).Interface())
+		}
+	}
+	return ret, nil
+}
+
+// Split returns the layout with a new column added on the right, spanning
+// all the rows. The new cell is the last one.
+func (l Layout) Split() Layout {
+	nl := l.clone()
+	n := float64(len(nl.Cols) - 1)
+	for i := range nl.Cols {
+		nl.Cols[i] *= n / (n + 1)
+	}
+	nl.Cols = append(nl.Cols, 1)
+	nl.Cells = append(nl.Cells, [4]int{len(nl.Cols) - 2, 0, len(nl.Cols) - 1, len(nl.Rows) - 1})
+	return nl
+}
+
+// Merge returns the layout with the given cell removed and the index in
+// the new layout of the neighbour cell grown to take its space, -1 if
+// there's no neighbour sharing a whole edge with it.
+func (l Layout) Merge(cell int) (Layout, int) {
+	nl := l.clone()
+	r := nl.Cells[cell]
+	nl.Cells = append(nl.Cells[:cell], nl.Cells[cell+1:]...)
+	into := -1
+	for i, c := range nl.Cells {
+		if c[1] == r[1] && c[3] == r[3] && (c[2] == r[0] || c[0] == r[2]) {
+			nl.Cells[i][0], nl.Cells[i][2] = text.Min(c[0], r[0]), text.Max(c[2], r[2])
+		} else if c[0] == r[0] && c[2] == r[2] && (c[3] == r[1] || c[1] == r[3]) {
+			nl.Cells[i][1], nl.Cells[i][3] = text.Min(c[1], r[1]), text.Max(c[3], r[3])
+		} else {
+			continue
+		}
+		into = i
+		break
+	}
+	nl.Cols = compact(nl.Cols, nl.Cells, 0)
+	nl.Rows = compact(nl.Rows, nl.Cells, 1)
+	return nl, into
+}
+
+// Removes the splits no cell starts or ends at and stretches the remaining
+// ones back to [0, 1]. off is 0 for the cols and 1 for the rows.
+func compact(splits []float64, cells [][4]int, off int) []float64 {
+	used := make([]bool, len(splits))
+	for _, c := range cells {
+		used[c[off]], used[c[off+2]] = true, true
+	}
+	idx := make([]int, len(splits))
+	var ret []float64
+	for i, s := range splits {
+		idx[i] = len(ret)
+		if used[i] {
+			ret = append(ret, s)
+		}
+	}
+	for i := range cells {
+		cells[i][off], cells[i][off+2] = idx[cells[i][off]], idx[cells[i][off+2]]
+	}
+	if a, b := ret[0], ret[len(ret)-1]; b > a {
+		for i := range ret {
+			ret[i] = (ret[i] - a) / (b - a)
+		}
+	}
+	return ret
+}
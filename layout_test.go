@@ -0,0 +1,110 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayoutSet(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		exp Layout
+		err bool
+	}{
+		{
+			Args{"cols": []interface{}{0.0, 1.0}, "rows": []interface{}{0.0, 1.0}, "cells": []interface{}{[]interface{}{0.0, 0.0, 1.0, 1.0}}},
+			defaultLayout(),
+			false,
+		},
+		{
+			map[string]interface{}{"cols": []interface{}{0, 0.5, 1}, "rows": []interface{}{0, 1}, "cells": []interface{}{[]interface{}{0, 0, 1, 1}, []interface{}{1, 0, 2, 1}}},
+			Layout{Cols: []float64{0, 0.5, 1}, Rows: []float64{0, 1}, Cells: [][4]int{{0, 0, 1, 1}, {1, 0, 2, 1}}},
+			false,
+		},
+		{
+			Args{"cols": []interface{}{0, 1}, "rows": []interface{}{0, 1}, "cells": []interface{}{[]interface{}{0, 0, 1}}},
+			Layout{},
+			true,
+		},
+		{
+			Args{"cols": []interface{}{0, 1}, "rows": []interface{}{0, 1}, "cells": []interface{}{[]interface{}{0, 0, 1, 2}}},
+			Layout{},
+			true,
+		},
+		{
+			"layout",
+			Layout{},
+			true,
+		},
+	}
+	for i, test := range tests {
+		var l Layout
+		if err := l.Set(test.in); (err != nil) != test.err {
+			t.Errorf("Test %d: Expected error %v, but got %v", i, test.err, err)
+		} else if !test.err && !reflect.DeepEqual(l, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, l)
+		}
+	}
+}
+
+func TestLayoutSplit(t *testing.T) {
+	l := defaultLayout().Split()
+	exp := Layout{
+		Cols:  []float64{0, 0.5, 1},
+		Rows:  []float64{0, 1},
+		Cells: [][4]int{{0, 0, 1, 1}, {1, 0, 2, 1}},
+	}
+	if !reflect.DeepEqual(l, exp) {
+		t.Errorf("Expected %v, but got %v", exp, l)
+	}
+}
+
+func TestLayoutMerge(t *testing.T) {
+	grid := Layout{
+		Cols:  []float64{0, 0.5, 1},
+		Rows:  []float64{0, 0.5, 1},
+		Cells: [][4]int{{0, 0, 1, 1}, {1, 0, 2, 1}, {0, 1, 1, 2}, {1, 1, 2, 2}},
+	}
+	tests := []struct {
+		in   Layout
+		cell int
+		exp  Layout
+		into int
+	}{
+		{
+			defaultLayout().Split(),
+			1,
+			defaultLayout(),
+			0,
+		},
+		{
+			defaultLayout().Split(),
+			0,
+			defaultLayout(),
+			0,
+		},
+		{
+			grid,
+			1,
+			Layout{
+				Cols:  []float64{0, 0.5, 1},
+				Rows:  []float64{0, 0.5, 1},
+				Cells: [][4]int{{0, 0, 2, 1}, {0, 1, 1, 2}, {1, 1, 2, 2}},
+			},
+			0,
+		},
+	}
+	for i, test := range tests {
+		l, into := test.in.Merge(test.cell)
+		if into != test.into {
+			t.Errorf("Test %d: Expected cell %d to grow, but got %d", i, test.into, into)
+		}
+		if !reflect.DeepEqual(l, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, l)
+		}
+	}
+}
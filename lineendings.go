@@ -0,0 +1,96 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/jxo/lime/log"
+)
+
+// The line endings of files, named the way sublime names them. The buffer
+// of a view always uses "\n", files are converted on load and on save.
+const (
+	lineEndingsUnix    = "Unix"
+	lineEndingsWindows = "Windows"
+	lineEndingsCR      = "CR"
+)
+
+// Returns the name of the line endings, an empty string if they aren't
+// known.
+func normalizeLineEndings(name string) string {
+	switch strings.ToLower(name) {
+	case "unix", "lf", "\n":
+		return lineEndingsUnix
+	case "windows", "crlf", "\r\n":
+		return lineEndingsWindows
+	case "cr", "mac", "\r":
+		return lineEndingsCR
+	case "system":
+		if runtime.GOOS == "windows" {
+			return lineEndingsWindows
+		}
+		return lineEndingsUnix
+	}
+	return ""
+}
+
+// Returns the line endings of the first line break of s, or the fallback
+// when s has none.
+func detectLineEndings(s, fallback string) string {
+	if i := strings.IndexAny(s, "\r\n"); i != -1 {
+		switch {
+		case s[i] == '\n':
+			return lineEndingsUnix
+		case i+1 < len(s) && s[i+1] == '\n':
+			return lineEndingsWindows
+		default:
+			return lineEndingsCR
+		}
+	}
+	if le := normalizeLineEndings(fallback); le != "" {
+		return le
+	}
+	return normalizeLineEndings("system")
+}
+
+// Replaces all the line breaks of s, whichever their style, by "\n".
+func unixLineEndings(s string) string {
+	if strings.IndexByte(s, '\r') == -1 {
+		return s
+	}
+	return strings.Replace(strings.Replace(s, "\r\n", "\n", -1), "\r", "\n", -1)
+}
+
+// Converts the "\n" line breaks of s to the line endings.
+func convertLineEndings(s, le string) string {
+	switch normalizeLineEndings(le) {
+	case lineEndingsWindows:
+		return strings.Replace(s, "\n", "\r\n", -1)
+	case lineEndingsCR:
+		return strings.Replace(s, "\n", "\r", -1)
+	}
+	return s
+}
+
+// LineEndings returns the line endings the view is saved with, "Unix",
+// "Windows" or "CR". They are the ones of its file or the ones of the
+// "default_line_ending" setting for new files.
+func (v *View) LineEndings() string {
+	if le := normalizeLineEndings(v.Settings().String("line_endings", "")); le != "" {
+		return le
+	}
+	return detectLineEndings("", v.Settings().String("default_line_ending", "system"))
+}
+
+// SetLineEndings sets the line endings the view is to be saved with.
+func (v *View) SetLineEndings(le string) {
+	if l := normalizeLineEndings(le); l != "" {
+		v.Settings().Set("line_endings", l)
+	} else {
+		log.Warn("Unsupported line endings %s set on %s", le, v)
+	}
+}
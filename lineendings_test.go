@@ -0,0 +1,112 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jxo/lime/text"
+)
+
+func TestDetectLineEndings(t *testing.T) {
+	tests := []struct {
+		text, fallback, exp string
+	}{
+		{"a\nb\r\n", "", lineEndingsUnix},
+		{"a\r\nb\n", "", lineEndingsWindows},
+		{"a\rb\r", "", lineEndingsCR},
+		{"a\r", "", lineEndingsCR},
+		{"ab", "windows", lineEndingsWindows},
+		{"ab", "unix", lineEndingsUnix},
+		{"ab", "klingon", normalizeLineEndings("system")},
+	}
+	for i, test := range tests {
+		if le := detectLineEndings(test.text, test.fallback); le != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, le)
+		}
+	}
+}
+
+func TestConvertLineEndings(t *testing.T) {
+	tests := []struct {
+		text, le, exp string
+	}{
+		{"a\nb\n", lineEndingsUnix, "a\nb\n"},
+		{"a\nb\n", lineEndingsWindows, "a\r\nb\r\n"},
+		{"a\nb\n", "cr", "a\rb\r"},
+	}
+	for i, test := range tests {
+		if s := convertLineEndings(test.text, test.le); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+		if s := unixLineEndings(test.exp); s != test.text {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.text, s)
+		}
+	}
+	if s := unixLineEndings("a\r\nb\rc\n"); s != "a\nb\nc\n" {
+		t.Errorf("Expected mixed line endings to be normalized, but got %q", s)
+	}
+}
+
+func TestViewLineEndings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lime-lineendings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ed := GetEditor()
+	ed.SetFrontend(&dummyFrontend{})
+	w := ed.NewWindow()
+	defer w.Close()
+
+	tests := []struct {
+		data string
+		le   string
+	}{
+		{"first\nsecond\n", lineEndingsUnix},
+		{"first\r\nsecond\r\n", lineEndingsWindows},
+		{"first\rsecond\r", lineEndingsCR},
+	}
+	for i, test := range tests {
+		file := filepath.Join(dir, "test.txt")
+		if err := ioutil.WriteFile(file, []byte(test.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		v := w.OpenFile(file, 0)
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "first\nsecond\n" {
+			t.Errorf("Test %d: Expected the buffer to be normalized, but got %q", i, s)
+		}
+		if le := v.LineEndings(); le != test.le {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.le, le)
+		}
+		if r, c := v.RowCol(v.Line(0).End()); r != 0 || c != 5 {
+			t.Errorf("Test %d: Expected the first line to end at 0:5, but got %d:%d", i, r, c)
+		}
+
+		e := v.BeginEdit()
+		v.Insert(e, v.Size(), "third\n")
+		v.EndEdit(e)
+		if err := v.Save(); err != nil {
+			t.Fatal(err)
+		}
+		exp := convertLineEndings("first\nsecond\nthird\n", test.le)
+		if data, _ := ioutil.ReadFile(file); string(data) != exp {
+			t.Errorf("Test %d: Expected %q to be saved, but got %q", i, exp, data)
+		}
+
+		v.SetLineEndings("windows")
+		if err := v.Save(); err != nil {
+			t.Fatal(err)
+		}
+		exp = "first\r\nsecond\r\nthird\r\n"
+		if data, _ := ioutil.ReadFile(file); string(data) != exp {
+			t.Errorf("Test %d: Expected %q to be saved, but got %q", i, exp, data)
+		}
+		v.Close()
+	}
+}
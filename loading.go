@@ -0,0 +1,347 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/text"
+)
+
+const (
+	// The size in bytes of the first chunk files loaded in the background
+	// are read in.
+	loadChunkSize = 1 << 20
+	// Files bigger than this are loaded in the background, unless the
+	// "async_load_size" setting says otherwise.
+	defaultAsyncLoadSize = 8 << 20
+	// The status key of the loading progress.
+	loadingStatusKey = "lime.loading"
+	// The size of the beginning of a file its encoding is detected from.
+	detectEncodingSize = 4096
+)
+
+// Decodes a file read in chunks. The bytes of the characters and the "\r"
+// of the line breaks which may continue in the next chunk are held back
+// until it's read.
+type chunkDecoder struct {
+	// The encoding of the file, detected from the first chunk when empty
+	enc string
+	// The line endings of the file, detected from the first line break
+	le string
+	// The fallbacks used for the detection
+	fallbackEnc, fallbackLE string
+	// Whether the encoding was detected rather than given
+	detected    bool
+	started, cr bool
+	pending     []byte
+}
+
+// Returned by the decoder when a chunk isn't valid in the encoding
+// detected from the first one, the file has to be decoded over from its
+// beginning in the new encoding of the decoder.
+var errEncodingChanged = errors.New("The encoding changed")
+
+// Returns the length of the beginning of data which doesn't end in the
+// middle of a UTF-8 encoded character.
+func utf8Boundary(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				break
+			}
+			return i
+		}
+	}
+	return len(data)
+}
+
+// Returns the length of the beginning of data which can be decoded
+// without the bytes following it.
+func (d *chunkDecoder) boundary(data []byte) int {
+	switch d.enc {
+	case encodingUTF8, encodingUTF8BOM:
+		return utf8Boundary(data)
+	case encodingUTF16LE, encodingUTF16LEBOM, encodingUTF16BE, encodingUTF16BEBOM:
+		n := len(data) &^ 1
+		if n < 2 {
+			return n
+		}
+		u := uint16(data[n-2]) | uint16(data[n-1])<<8
+		if d.enc == encodingUTF16BE || d.enc == encodingUTF16BEBOM {
+			u = uint16(data[n-2])<<8 | uint16(data[n-1])
+		}
+		// the first half of a surrogate pair
+		if utf16.IsSurrogate(rune(u)) && u < 0xdc00 {
+			n -= 2
+		}
+		return n
+	}
+	return len(data)
+}
+
+// Decodes the chunk, eof being true for the last one. The text returned
+// has its line breaks converted to "\n".
+func (d *chunkDecoder) decode(p []byte, eof bool) (string, error) {
+	data := append(d.pending, p...)
+	enc := d.enc
+	if !d.started {
+		if enc == "" && !eof && len(data) < detectEncodingSize {
+			// not enough to tell the encoding yet
+			d.pending = data
+			return "", nil
+		} else if enc == "" {
+			probe := data
+			if !eof {
+				// UTF-16 comes in pairs of bytes while a UTF-8
+				// character can be cut anywhere
+				if probe = data[:len(data)&^1]; detectUTF16(probe) == "" {
+					probe = data[:utf8Boundary(data)]
+				}
+			}
+			enc = detectEncoding(probe, d.fallbackEnc)
+			d.detected = true
+		} else if enc = normalizeEncoding(enc); enc == "" {
+			return "", fmt.Errorf("Unsupported encoding %s", d.enc)
+		}
+		d.enc = enc
+	} else {
+		// only the first chunk starts with the BOM
+		switch enc {
+		case encodingUTF8BOM:
+			enc = encodingUTF8
+		case encodingUTF16LEBOM:
+			enc = encodingUTF16LE
+		case encodingUTF16BEBOM:
+			enc = encodingUTF16BE
+		}
+	}
+	n := len(data)
+	if !eof {
+		n = d.boundary(data)
+	}
+	if d.detected && d.started && enc == encodingUTF8 && !utf8.Valid(data[:n]) {
+		d.enc = detectEncoding(data[:n], d.fallbackEnc)
+		return "", errEncodingChanged
+	}
+	s, err := decodeText(data[:n], enc)
+	if err != nil {
+		return "", err
+	}
+	d.started = d.started || n > 0
+	d.pending = append([]byte(nil), data[n:]...)
+
+	if d.cr {
+		s = "\r" + s
+		d.cr = false
+	}
+	if !eof && strings.HasSuffix(s, "\r") {
+		s = s[:len(s)-1]
+		d.cr = true
+	}
+	if d.le == "" && strings.ContainsAny(s, "\r\n") {
+		d.le = detectLineEndings(s, d.fallbackLE)
+	} else if d.le == "" && eof {
+		d.le = detectLineEndings("", d.fallbackLE)
+	}
+	return unixLineEndings(s), nil
+}
+
+func (v *View) newChunkDecoder(enc string) *chunkDecoder {
+	return &chunkDecoder{
+		enc:         enc,
+		fallbackEnc: v.Settings().String("fallback_encoding", encodingWindows1252),
+		fallbackLE:  v.Settings().String("default_line_ending", "system"),
+	}
+}
+
+// IsLoading returns whether the file of the view is still being loaded
+// in the background. The view can't be modified until it's done.
+func (v *View) IsLoading() bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.loading
+}
+
+// Returns a channel closed once the file of the view is loaded, or once
+// it stopped loading.
+func (v *View) loadingDone() <-chan struct{} {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.loadDone == nil {
+		v.loadDone = make(chan struct{})
+		close(v.loadDone)
+	}
+	return v.loadDone
+}
+
+// Stops loading the file of the view, waiting for the chunk being
+// inserted if any.
+func (v *View) stopLoading() {
+	v.loadLock.Lock()
+	defer v.loadLock.Unlock()
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.loading = false
+	v.whenLoaded = nil
+}
+
+// WhenLoaded calls f once the file of the view is loaded, right away
+// unless it's being loaded in the background.
+func (v *View) WhenLoaded(f func()) {
+	v.lock.Lock()
+	if v.loading {
+		v.whenLoaded = append(v.whenLoaded, f)
+		v.lock.Unlock()
+		return
+	}
+	v.lock.Unlock()
+	f()
+}
+
+// Loads the file of the view on a background goroutine, inserting it in
+// chunks in the buffer. OnLoad is called once the whole file is loaded,
+// unless the view was closed in between.
+func (v *View) loadAsync(filename string) {
+	v.lock.Lock()
+	v.loading = true
+	v.loadDone = make(chan struct{})
+	buf, done := v.buffer, v.loadDone
+	v.lock.Unlock()
+
+	go func() {
+		defer close(done)
+		err := v.streamFile(buf, filename)
+		v.EraseStatus(loadingStatusKey)
+		if !v.IsLoading() {
+			log.Fine("Stopped loading %s", filename)
+			return
+		}
+		if err != nil {
+			log.Error("Couldn't load file %s: %s", filename, err)
+		}
+		v.loaded()
+	}()
+}
+
+func (v *View) streamFile(buf text.Buffer, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var size int64
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+
+	d := v.newChunkDecoder("")
+	defer func() {
+		v.Settings().Set("encoding", d.enc)
+		v.Settings().Set("line_endings", d.le)
+	}()
+	var (
+		chunk    []byte
+		read     int64
+		progress = -1
+	)
+	for {
+		// the buffer is rebalanced on every insertion, the chunks grow
+		// with the part of the file already read to keep their number low
+		if n := int(read); n > len(chunk) || chunk == nil {
+			if n < loadChunkSize {
+				n = loadChunkSize
+			}
+			chunk = make([]byte, n)
+		}
+		n, err := io.ReadFull(f, chunk)
+		eof := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !eof {
+			return err
+		}
+		read += int64(n)
+		s, err := d.decode(chunk[:n], eof)
+		if err == errEncodingChanged {
+			// start over in the fallback encoding
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			if !v.clearChunks(buf) {
+				return nil
+			}
+			d = v.newChunkDecoder(d.enc)
+			read = 0
+			continue
+		} else if err != nil {
+			return err
+		}
+		if !v.insertChunk(buf, s) {
+			return nil
+		}
+		if eof {
+			return nil
+		}
+		if size > 0 {
+			if p := int(read * 100 / size); p != progress {
+				progress = p
+				v.SetStatus(loadingStatusKey, fmt.Sprintf("Loading %d%%", p))
+			}
+		}
+	}
+}
+
+// Appends the text to the buffer, returns false if the view stopped
+// loading.
+func (v *View) insertChunk(buf text.Buffer, s string) bool {
+	v.loadLock.Lock()
+	defer v.loadLock.Unlock()
+	if !v.IsLoading() {
+		return false
+	}
+	if s != "" {
+		buf.Insert(buf.Size(), s)
+	}
+	return true
+}
+
+// Erases the text inserted so far, returns false if the view stopped
+// loading.
+func (v *View) clearChunks(buf text.Buffer) bool {
+	v.loadLock.Lock()
+	defer v.loadLock.Unlock()
+	if !v.IsLoading() {
+		return false
+	}
+	return buf.Erase(0, buf.Size()) == nil
+}
+
+// Finishes opening the view once its file is loaded, then calls OnLoad
+// and the functions waiting for the file with WhenLoaded.
+func (v *View) loaded() {
+	v.Settings().Set("lime.last_save_change_count", v.ChangeCount())
+	v.SetScratch(false)
+	if !isWritable(v.FileName()) {
+		v.SetReadOnly(true)
+	}
+	v.loadUndoJournal()
+	v.reparse(false)
+
+	v.lock.Lock()
+	v.loading = false
+	fs := v.whenLoaded
+	v.whenLoaded = nil
+	v.lock.Unlock()
+	OnLoad.Call(v)
+	for _, f := range fs {
+		f()
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxo/lime/text"
+)
+
+func TestChunkDecoder(t *testing.T) {
+	const s = "héllo\r\nwörld \U0001f600\r\n€\rend"
+	tests := []struct {
+		enc, le string
+	}{
+		{encodingUTF8, lineEndingsWindows},
+		{encodingUTF8BOM, lineEndingsWindows},
+		{encodingUTF16LEBOM, lineEndingsWindows},
+		{encodingUTF16BE, lineEndingsWindows},
+	}
+	for i, test := range tests {
+		data, err := encodeText(s, test.enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := unixLineEndings(s)
+		// split the data at every position to cut characters and line
+		// breaks in two
+		for j := 0; j <= len(data); j++ {
+			d := chunkDecoder{enc: test.enc}
+			if j == 0 {
+				d.enc = ""
+			}
+			s1, err1 := d.decode(data[:j], false)
+			s2, err2 := d.decode(data[j:], true)
+			if err1 != nil || err2 != nil {
+				t.Errorf("Test %d, %d: Expected no error, but got %v, %v", i, j, err1, err2)
+			} else if s1+s2 != exp {
+				t.Errorf("Test %d, %d: Expected %q, but got %q", i, j, exp, s1+s2)
+			}
+			if d.enc != test.enc || d.le != test.le {
+				t.Errorf("Test %d, %d: Expected %s, %s, but got %s, %s", i, j, test.enc, test.le, d.enc, d.le)
+			}
+		}
+	}
+}
+
+func TestChunkDecoderDetect(t *testing.T) {
+	be, err := encodeText(strings.Repeat("lorem ipsum ", detectEncodingSize/10), encodingUTF16BE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first chunk ends in the middle of a UTF-16 code unit
+	d := chunkDecoder{}
+	if _, err := d.decode(be[:detectEncodingSize+1], false); err != nil {
+		t.Fatal(err)
+	} else if d.enc != encodingUTF16BE {
+		t.Errorf("Expected %s, but got %s", encodingUTF16BE, d.enc)
+	}
+
+	// invalid UTF-8 after the first chunk
+	d = chunkDecoder{fallbackEnc: encodingWindows1252}
+	first := []byte(strings.Repeat("a", detectEncodingSize))
+	if s, err := d.decode(first, false); err != nil || s != string(first) {
+		t.Fatalf("Expected %q, but got %q, %v", first, s, err)
+	} else if d.enc != encodingUTF8 {
+		t.Errorf("Expected %s, but got %s", encodingUTF8, d.enc)
+	}
+	if _, err := d.decode([]byte("caf\xe9"), true); err != errEncodingChanged {
+		t.Errorf("Expected %v, but got %v", errEncodingChanged, err)
+	}
+	if d.enc != encodingWindows1252 {
+		t.Errorf("Expected %s, but got %s", encodingWindows1252, d.enc)
+	}
+
+	// an encoding given isn't changed
+	d = chunkDecoder{enc: encodingUTF8}
+	d.decode(first, false)
+	if _, err := d.decode([]byte("caf\xe9"), true); err != nil {
+		t.Errorf("Expected no error, but got %v", err)
+	}
+}
+
+func waitLoading(t *testing.T, v *View) {
+	for i := 0; v.IsLoading(); i++ {
+		if i == 1000 {
+			t.Fatalf("Expected %s to be loaded", v.FileName())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOpenFileAsync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lime-loading")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ed := GetEditor()
+	ed.SetFrontend(&dummyFrontend{})
+	ed.Settings().Set("async_load_size", 1)
+	defer ed.Settings().Erase("async_load_size")
+	w := ed.NewWindow()
+	defer w.Close()
+
+	line := strings.Repeat("lorem ipsum ", 10) + "\r\n"
+	data := strings.Repeat(line, 3*loadChunkSize/len(line))
+	file := filepath.Join(dir, "big.txt")
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := w.OpenFile(file, 0)
+	if !v.IsLoading() {
+		t.Error("Expected the file to be loading")
+	}
+	waitLoading(t, v)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != unixLineEndings(data) {
+		t.Errorf("Expected the whole file to be loaded, but got %d characters", len(s))
+	}
+	if le := v.LineEndings(); le != lineEndingsWindows {
+		t.Errorf("Expected %q, but got %q", lineEndingsWindows, le)
+	}
+	if v.IsScratch() || v.IsDirty() {
+		t.Error("Expected the loaded view to be neither scratch nor dirty")
+	}
+	if st := v.GetStatus(loadingStatusKey); st != "" {
+		t.Errorf("Expected the loading status to be erased, but got %q", st)
+	}
+	if p := v.UndoStack().Position(); p != 0 {
+		t.Errorf("Expected the loading not to be undoable, but got position %d", p)
+	}
+	v.Close()
+
+	// invalid UTF-8 after the first chunk is loaded in the fallback encoding
+	latin := []byte(data)
+	latin = append(latin[:len(latin)-2], 0xe9)
+	if err := ioutil.WriteFile(file, latin, 0644); err != nil {
+		t.Fatal(err)
+	}
+	v = w.OpenFile(file, 0)
+	waitLoading(t, v)
+	if s, exp := v.Substr(text.Region{A: 0, B: v.Size()}), unixLineEndings(data[:len(data)-2])+"é"; s != exp {
+		t.Errorf("Expected the file to be decoded in the fallback encoding, but got %q", s[len(s)-10:])
+	}
+	if enc := v.Encoding(); enc != encodingWindows1252 {
+		t.Errorf("Expected %s, but got %s", encodingWindows1252, enc)
+	}
+	v.Close()
+
+	// closing the view stops loading it
+	v = w.OpenFile(file, 0)
+	v.Close()
+	if v.IsLoading() {
+		t.Error("Expected the closed view to stop loading")
+	}
+}
+
+func TestWhenLoaded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lime-loading")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ed := GetEditor()
+	ed.SetFrontend(&dummyFrontend{})
+	ed.Settings().Set("async_load_size", 1)
+	defer ed.Settings().Erase("async_load_size")
+	w := ed.NewWindow()
+	defer w.Close()
+
+	line := strings.Repeat("lorem ipsum ", 10) + "\n"
+	n := 3 * loadChunkSize / len(line)
+	file := filepath.Join(dir, "big.txt")
+	if err := ioutil.WriteFile(file, []byte(strings.Repeat(line, n)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := w.OpenFile(file, 0)
+	defer v.Close()
+	if !v.IsLoading() {
+		t.Fatal("Expected the file to be loading")
+	}
+	// the selection set while loading is kept
+	v.Sel().Clear()
+	v.Sel().Add(text.Region{A: 5, B: 5})
+	done := make(chan text.Region, 1)
+	v.WhenLoaded(func() {
+		if v.IsLoading() {
+			t.Error("Expected the file to be loaded")
+		}
+		done <- text.Region{A: v.TextPoint(n-1, 0), B: v.Size()}
+	})
+	var exp text.Region
+	select {
+	case exp = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the function to be called once the file is loaded")
+	}
+	if exp.A != exp.B-len(line) {
+		t.Errorf("Expected the last line to be loaded, but got %v", exp)
+	}
+	if rs := v.Sel().Regions(); len(rs) != 1 || rs[0] != (text.Region{A: 5, B: 5}) {
+		t.Errorf("Expected the selection to be kept, but got %v", rs)
+	}
+
+	called := false
+	v.WhenLoaded(func() { called = true })
+	if !called {
+		t.Error("Expected the function to be called right away")
+	}
+}
+
+func TestLoadingReadOnly(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "hello")
+	v.EndEdit(e)
+
+	v.loading = true
+	e = v.BeginEdit()
+	v.Insert(e, 0, "a")
+	v.Erase(e, text.Region{A: 0, B: 1})
+	v.Replace(e, text.Region{A: 0, B: 5}, "bye")
+	v.EndEdit(e)
+	if err := v.Save(); err == nil {
+		t.Error("Expected an error saving a view which is loading")
+	}
+	v.loading = false
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "hello" {
+		t.Errorf("Expected the loading view not to be modified, but got %q", s)
+	}
+}
+
+func BenchmarkOpenFile(b *testing.B) {
+	dir, err := ioutil.TempDir("", "lime-loading")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ed := GetEditor()
+	ed.SetFrontend(&dummyFrontend{})
+	w := ed.NewWindow()
+	defer w.Close()
+
+	line := strings.Repeat("lorem ipsum ", 10) + "\n"
+	for _, mb := range []int{16, 64, 256} {
+		file := filepath.Join(dir, fmt.Sprintf("%dMB.txt", mb))
+		data := strings.Repeat(line, (mb<<20)/len(line))
+		if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+			b.Fatal(err)
+		}
+		data = ""
+		for _, async := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%dMB/async=%v", mb, async), func(b *testing.B) {
+				size := 0
+				if async {
+					size = 1
+				}
+				ed.Settings().Set("async_load_size", size)
+				for i := 0; i < b.N; i++ {
+					v := w.OpenFile(file, 0)
+					for v.IsLoading() {
+						time.Sleep(time.Millisecond)
+					}
+					v.Close()
+				}
+			})
+		}
+		os.Remove(file)
+	}
+	ed.Settings().Erase("async_load_size")
+}
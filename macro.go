@@ -0,0 +1,206 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type (
+	// MacroCommand is a command of a macro along with its args.
+	MacroCommand struct {
+		Command string `json:"command"`
+		Args    Args   `json:"args,omitempty"`
+	}
+
+	// Macro is a list of commands run in order, the content of a
+	// .sublime-macro file.
+	Macro []MacroCommand
+
+	// Records the text and window commands run while recording a
+	// macro. Only the commands run directly are recorded, not those
+	// run by other commands.
+	macroRecorder struct {
+		recording bool
+		// The macro being recorded and the last one recorded
+		recorded Macro
+		macro    Macro
+		// How deeply nested the commands running are
+		depth int
+		// Set when the command running isn't to be recorded
+		skip bool
+		lock sync.Mutex
+	}
+)
+
+func (m *Macro) UnmarshalJSON(data []byte) error {
+	var cmds []MacroCommand
+	if err := json.Unmarshal(data, &cmds); err != nil {
+		return err
+	}
+	*m = cmds
+	return nil
+}
+
+// Save writes the macro to the file in the .sublime-macro format.
+func (m Macro) Save(path string) error {
+	if m == nil {
+		m = Macro{}
+	}
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Called before running a command, returns whether the command is to
+// be recorded once it's done.
+func (r *macroRecorder) begin() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.depth++
+	if r.depth == 1 {
+		r.skip = false
+	}
+	return r.recording && r.depth == 1
+}
+
+// Called once the command is done, ok being whether it ran.
+func (r *macroRecorder) end(record bool, name string, args Args, ok bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.depth--
+	if record && ok && r.recording && !r.skip {
+		r.recorded = append(r.recorded, MacroCommand{Command: name, Args: args})
+	}
+}
+
+// Records the commands of the macro in place of the command running it.
+func (r *macroRecorder) replay(m Macro) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.recording && r.depth == 1 && !r.skip {
+		r.recorded = append(r.recorded, m...)
+		r.skip = true
+	}
+}
+
+// StartRecordingMacro starts recording the commands run into a new macro.
+func (e *Editor) StartRecordingMacro() {
+	r := &e.cmdHandler.macro
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.recording = true
+	r.recorded = nil
+}
+
+// StopRecordingMacro stops recording, the macro recorded becoming the
+// one returned by Macro.
+func (e *Editor) StopRecordingMacro() {
+	r := &e.cmdHandler.macro
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.recording {
+		return
+	}
+	r.recording = false
+	r.macro = r.recorded
+	r.recorded = nil
+}
+
+// IsRecordingMacro returns whether a macro is being recorded.
+func (e *Editor) IsRecordingMacro() bool {
+	r := &e.cmdHandler.macro
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.recording
+}
+
+// Macro returns the last macro recorded.
+func (e *Editor) Macro() Macro {
+	r := &e.cmdHandler.macro
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.macro
+}
+
+func (e *Editor) AddMacro(path string, m *Macro) {
+	edl.Lock()
+	defer edl.Unlock()
+	e.macros[path] = m
+}
+
+// FindMacro returns the macro loaded from the file, which can be given
+// relative to the packages directory, i.e "Packages/Default/Delete
+// Line.sublime-macro". Macros not loaded from packages are read from
+// the file.
+func (e *Editor) FindMacro(file string) (Macro, error) {
+	file = filepath.FromSlash(strings.TrimPrefix(file, "res://"))
+	edl.Lock()
+	paths := make([]string, 0, len(e.macros))
+	for p := range e.macros {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if p == file || strings.HasSuffix(p, string(filepath.Separator)+strings.TrimPrefix(file, "Packages"+string(filepath.Separator))) {
+			m := *e.macros[p]
+			edl.Unlock()
+			return m, nil
+		}
+	}
+	edl.Unlock()
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't find macro %s", file)
+	}
+	var m Macro
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("Couldn't load macro %s: %s", file, err)
+	}
+	return m, nil
+}
+
+// RunMacro runs the commands of the macro, text commands in the active
+// view and the others in the window. The changes made to the view
+// active when the macro starts are undone in a single step.
+func (w *Window) RunMacro(m Macro) error {
+	ch := &GetEditor().cmdHandler
+	ch.macro.replay(m)
+	v := w.ActiveView()
+	mark := -1
+	if v != nil {
+		mark = v.UndoStack().Position()
+	}
+	defer func() {
+		if v != nil && v.UndoStack().Position()-mark > 1 {
+			v.UndoStack().GlueFrom(mark)
+		}
+	}()
+	for _, c := range m {
+		var err error
+		if av := w.ActiveView(); av != nil && ch.TextCommands[c.Command] != nil {
+			err = ch.RunTextCommand(av, c.Command, c.Args)
+		} else if ch.WindowCommands[c.Command] != nil {
+			err = ch.RunWindowCommand(w, c.Command, c.Args)
+		} else if ch.ApplicationCommands[c.Command] != nil {
+			err = ch.RunApplicationCommand(c.Command, c.Args)
+		} else {
+			err = fmt.Errorf("No such command: %s", c.Command)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMacroRecorder(t *testing.T) {
+	var r macroRecorder
+	r.recording = true
+
+	// a command running another one
+	rec := r.begin()
+	nested := r.begin()
+	r.end(nested, "nested", nil, true)
+	r.end(rec, "outer", Args{"a": 1}, true)
+
+	// a command which failed
+	rec = r.begin()
+	r.end(rec, "failed", nil, false)
+
+	// a command running a macro
+	rec = r.begin()
+	r.replay(Macro{{Command: "first"}, {Command: "second"}})
+	nested = r.begin()
+	r.end(nested, "first", nil, true)
+	r.end(rec, "run_macro", nil, true)
+
+	exp := Macro{
+		{Command: "outer", Args: Args{"a": 1}},
+		{Command: "first"},
+		{Command: "second"},
+	}
+	if !reflect.DeepEqual(r.recorded, exp) {
+		t.Errorf("Expected %+v, but got %+v", exp, r.recorded)
+	}
+	if r.depth != 0 {
+		t.Errorf("Expected depth 0, but got %d", r.depth)
+	}
+}
+
+func TestEditorRecordMacro(t *testing.T) {
+	ed := GetEditor()
+	ed.StartRecordingMacro()
+	if !ed.IsRecordingMacro() {
+		t.Fatal("Expected a macro to be recorded")
+	}
+	rec := ed.cmdHandler.macro.begin()
+	ed.cmdHandler.macro.end(rec, "test", nil, true)
+	ed.StopRecordingMacro()
+	if ed.IsRecordingMacro() {
+		t.Error("Expected the macro recording to be stopped")
+	}
+	exp := Macro{{Command: "test"}}
+	if m := ed.Macro(); !reflect.DeepEqual(m, exp) {
+		t.Errorf("Expected %+v, but got %+v", exp, m)
+	}
+}
+
+func TestMacroSaveAndFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lime-macro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := Macro{
+		{Command: "move_to", Args: Args{"to": "eol"}},
+		{Command: "insert", Args: Args{"characters": "\n"}},
+	}
+	path := filepath.Join(dir, "test.sublime-macro")
+	if err := m.Save(path); err != nil {
+		t.Fatalf("Expected no error saving %s, but got %s", path, err)
+	}
+	ed := GetEditor()
+	got, err := ed.FindMacro(path)
+	if err != nil {
+		t.Fatalf("Expected no error finding %s, but got %s", path, err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("Expected %+v, but got %+v", m, got)
+	}
+
+	loaded := Macro{{Command: "loaded"}}
+	ed.AddMacro(filepath.Join(dir, "Packages", "Test", "Loaded.sublime-macro"), &loaded)
+	if got, err := ed.FindMacro("res://Packages/Test/Loaded.sublime-macro"); err != nil {
+		t.Errorf("Expected no error finding the loaded macro, but got %s", err)
+	} else if !reflect.DeepEqual(got, loaded) {
+		t.Errorf("Expected %+v, but got %+v", loaded, got)
+	}
+
+	if _, err := ed.FindMacro(filepath.Join(dir, "missing.sublime-macro")); err == nil {
+		t.Error("Expected an error finding a missing macro")
+	}
+}
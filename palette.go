@@ -0,0 +1,142 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+type (
+	// PaletteCommand is an entry of the command palette.
+	PaletteCommand struct {
+		Caption string `json:"caption"`
+		Command string `json:"command"`
+		Args    Args   `json:"args,omitempty"`
+	}
+
+	// PaletteCommands is the content of a .sublime-commands file.
+	PaletteCommands []PaletteCommand
+)
+
+func (p *PaletteCommands) UnmarshalJSON(data []byte) error {
+	var cmds []PaletteCommand
+	if err := json.Unmarshal(data, &cmds); err != nil {
+		return err
+	}
+	*p = cmds
+	return nil
+}
+
+func (e *Editor) AddPaletteCommands(path string, p *PaletteCommands) {
+	edl.Lock()
+	defer edl.Unlock()
+	e.paletteCommands[path] = p
+}
+
+// CommandPalette returns the entries of the command palette sorted by
+// caption. Those are the entries loaded from packages which command is
+// visible and enabled, plus the visible registered commands no entry
+// refers to which have a description, captioned by it.
+func (e *Editor) CommandPalette() []PaletteCommand {
+	edl.Lock()
+	paths := make([]string, 0, len(e.paletteCommands))
+	for p := range e.paletteCommands {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var entries []PaletteCommand
+	for _, p := range paths {
+		entries = append(entries, *e.paletteCommands[p]...)
+	}
+	edl.Unlock()
+
+	var ret []PaletteCommand
+	listed := make(map[string]bool)
+	for _, pc := range entries {
+		c := e.cmdHandler.command(pc.Command)
+		if c == nil {
+			continue
+		}
+		listed[pc.Command] = true
+		// the registered command is shared, the args go to a copy
+		c = copyCommand(c)
+		if c == nil || e.cmdHandler.init(c, pc.Args) != nil || !c.IsVisible() || !c.IsEnabled() {
+			continue
+		}
+		ret = append(ret, pc)
+	}
+	for _, name := range e.cmdHandler.names() {
+		if listed[name] {
+			continue
+		}
+		// these aren't initialized as they may require args
+		c := e.cmdHandler.command(name)
+		if !c.IsVisible() {
+			continue
+		}
+		if desc := c.Description(); desc != "" {
+			ret = append(ret, PaletteCommand{Caption: desc, Command: name})
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Caption < ret[j].Caption
+	})
+	return ret
+}
+
+// Returns a copy of the command, nil if it isn't a pointer to a struct
+// as the commands initialized with args are.
+func copyCommand(c Command) Command {
+	v := reflect.ValueOf(c)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	cp := reflect.New(v.Elem().Type())
+	cp.Elem().Set(v.Elem())
+	return cp.Interface().(Command)
+}
+
+// KeyBindingHint returns the keys bound to the command with the given
+// args, i.e "ctrl+k, ctrl+u", or an empty string if it has no binding.
+func (e *Editor) KeyBindingHint(command string, args Args) string {
+	if kb := e.KeyBindings().Lookup(command, args); kb != nil {
+		return kb.KeysString()
+	}
+	return ""
+}
+
+// Returns the command registered with the given name, text commands
+// shadowing window commands which shadow application commands.
+func (ch *commandHandler) command(name string) Command {
+	if c := ch.TextCommands[name]; c != nil {
+		return c
+	}
+	if c := ch.WindowCommands[name]; c != nil {
+		return c
+	}
+	if c := ch.ApplicationCommands[name]; c != nil {
+		return c
+	}
+	return nil
+}
+
+// Returns the sorted names of the registered commands, skipping the
+// unregistered ones which are kept as nil.
+func (ch *commandHandler) names() []string {
+	seen := make(map[string]bool)
+	var ret []string
+	for _, m := range []map[string]Command{ch.TextCommands, ch.WindowCommands, ch.ApplicationCommands} {
+		for name, c := range m {
+			if c != nil && !seen[name] {
+				seen[name] = true
+				ret = append(ret, name)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jxo/lime/keys"
+)
+
+type (
+	paletteTestCommand struct {
+		DefaultCommand
+		Hidden bool
+	}
+
+	describedTestCommand struct {
+		DefaultCommand
+	}
+)
+
+func (c *paletteTestCommand) Run(w *Window) error {
+	return nil
+}
+
+func (c *paletteTestCommand) IsVisible() bool {
+	return !c.Hidden
+}
+
+func (c *describedTestCommand) Run(w *Window) error {
+	return nil
+}
+
+func (c *describedTestCommand) Description() string {
+	return "Palette Described"
+}
+
+func TestCommandPalette(t *testing.T) {
+	ed := GetEditor()
+	ch := ed.CommandHandler()
+	registered := &paletteTestCommand{}
+	ch.Register("palette_test", registered)
+	ch.Register("palette_described", &describedTestCommand{})
+	defer func() {
+		ch.Unregister("palette_test")
+		ch.Unregister("palette_described")
+	}()
+
+	const path = "palette_test.sublime-commands"
+	ed.AddPaletteCommands(path, &PaletteCommands{
+		{Caption: "Palette: Shown", Command: "palette_test"},
+		{Caption: "Palette: Hidden", Command: "palette_test", Args: Args{"hidden": true}},
+		{Caption: "Palette: Unknown", Command: "palette_unknown"},
+	})
+	defer func() {
+		edl.Lock()
+		delete(ed.paletteCommands, path)
+		edl.Unlock()
+	}()
+
+	var res []PaletteCommand
+	for _, pc := range ed.CommandPalette() {
+		if strings.HasPrefix(pc.Command, "palette_") {
+			res = append(res, pc)
+		}
+	}
+	exp := []PaletteCommand{
+		{Caption: "Palette Described", Command: "palette_described"},
+		{Caption: "Palette: Shown", Command: "palette_test"},
+	}
+	if !reflect.DeepEqual(res, exp) {
+		t.Errorf("Expected %v, but got %v", exp, res)
+	}
+	if registered.Hidden {
+		t.Errorf("Expected the registered command to be left as is")
+	}
+}
+
+func TestKeyBindingHint(t *testing.T) {
+	kb := GetEditor().KeyBindings()
+	old := kb.Bindings
+	defer func() { kb.Bindings = old }()
+	kb.Bindings = append(kb.Bindings[:len(old):len(old)], &keys.KeyBinding{
+		Keys:    []keys.KeyPress{{Key: 'k', Ctrl: true}, {Key: 'u', Ctrl: true}},
+		Command: "palette_test",
+		Args:    map[string]interface{}{"hidden": false},
+	})
+
+	tests := []struct {
+		args Args
+		exp  string
+	}{
+		{Args{"hidden": false}, "ctrl+k, ctrl+u"},
+		{Args{"hidden": true}, ""},
+		{nil, ""},
+	}
+	for i, test := range tests {
+		if hint := GetEditor().KeyBindingHint("palette_test", test.args); hint != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, hint)
+		}
+	}
+}
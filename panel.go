@@ -0,0 +1,144 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jxo/lime/text"
+)
+
+// Output panels are named with this prefix when shown, the same
+// way sublime does, i.e the "exec" panel is shown as "output.exec".
+const outputPanelPrefix = "output."
+
+// CreateOutputPanel returns the output panel with the given name,
+// creating it if it doesn't exist yet. The contents of an existing
+// panel are erased.
+func (w *Window) CreateOutputPanel(name string) *View {
+	if v := w.FindOutputPanel(name); v != nil {
+		e := v.BeginEdit()
+		v.Erase(e, text.Region{A: 0, B: v.Size()})
+		v.EndEdit(e)
+		v.Sel().Clear()
+		v.Sel().Add(text.Region{A: 0, B: 0})
+		return v
+	}
+
+	v := newView(w)
+	v.setBuffer(text.NewBuffer())
+	v.SetScratch(true)
+	v.Settings().Set("is_widget", true)
+	v.selection.Clear()
+	v.selection.Add(text.Region{A: 0, B: 0})
+
+	w.lock.Lock()
+	if w.panels == nil {
+		w.panels = make(map[string]*View)
+	}
+	w.panels[name] = v
+	w.lock.Unlock()
+
+	OnNew.Call(v)
+	return v
+}
+
+// FindOutputPanel returns the output panel with the given name or nil.
+func (w *Window) FindOutputPanel(name string) *View {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.panels[name]
+}
+
+// DestroyOutputPanel closes the output panel with the given name,
+// hiding it first if it's the shown one.
+func (w *Window) DestroyOutputPanel(name string) {
+	v := w.FindOutputPanel(name)
+	if v == nil {
+		return
+	}
+	if w.ActivePanel() == outputPanelPrefix+name {
+		w.HidePanel()
+	}
+	v.Close()
+}
+
+// Panels returns the names of the output panels as they're
+// shown, i.e "output.exec", sorted.
+func (w *Window) Panels() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	ret := make([]string, 0, len(w.panels))
+	for name := range w.panels {
+		ret = append(ret, outputPanelPrefix+name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// ActivePanel returns the name of the shown panel, empty if none is.
+func (w *Window) ActivePanel() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.active_panel
+}
+
+// ShowPanel shows the panel with the given name, output panels are
+// named with the "output." prefix. Returns false if there's no such
+// output panel, other panels are left to the frontend.
+func (w *Window) ShowPanel(name string) bool {
+	w.lock.Lock()
+	if strings.HasPrefix(name, outputPanelPrefix) {
+		if _, ok := w.panels[strings.TrimPrefix(name, outputPanelPrefix)]; !ok {
+			w.lock.Unlock()
+			return false
+		}
+	}
+	changed := w.active_panel != name
+	w.active_panel = name
+	w.lock.Unlock()
+	if changed {
+		OnPanelChanged.Call(w)
+	}
+	return true
+}
+
+// HidePanel hides the shown panel.
+func (w *Window) HidePanel() {
+	w.lock.Lock()
+	changed := w.active_panel != ""
+	w.active_panel = ""
+	w.lock.Unlock()
+	if changed {
+		OnPanelChanged.Call(w)
+	}
+}
+
+// Removes the panel from the window returning true if v is a panel.
+func (w *Window) removePanel(v *View) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for name, p := range w.panels {
+		if p == v {
+			delete(w.panels, name)
+			return true
+		}
+	}
+	return false
+}
+
+// Destroys all the output panels of the window.
+func (w *Window) destroyPanels() {
+	w.lock.Lock()
+	names := make([]string, 0, len(w.panels))
+	for name := range w.panels {
+		names = append(names, name)
+	}
+	w.lock.Unlock()
+	for _, name := range names {
+		w.DestroyOutputPanel(name)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jxo/lime/text"
+)
+
+func TestCreateOutputPanel(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	p := w.CreateOutputPanel("exec")
+	if !p.IsScratch() || !p.Settings().Bool("is_widget", false) {
+		t.Errorf("Expected the panel to be a scratch widget")
+	}
+	if len(w.Views()) != 0 {
+		t.Errorf("Expected panels not to be in the window views, but got %v", w.Views())
+	}
+	e := p.BeginEdit()
+	p.Insert(e, 0, "output")
+	p.EndEdit(e)
+
+	if p2 := w.CreateOutputPanel("exec"); p2 != p {
+		t.Errorf("Expected to get the same panel, but got %v", p2)
+	} else if p.Size() != 0 {
+		t.Errorf("Expected the panel to be erased, but got %q", p.Substr(text.Region{A: 0, B: p.Size()}))
+	}
+	if f := w.FindOutputPanel("exec"); f != p {
+		t.Errorf("Expected to find %v, but got %v", p, f)
+	}
+	if f := w.FindOutputPanel("other"); f != nil {
+		t.Errorf("Expected not to find a panel, but got %v", f)
+	}
+}
+
+func TestPanels(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	w.CreateOutputPanel("exec")
+	w.CreateOutputPanel("find")
+	if exp := []string{"output.exec", "output.find"}; !reflect.DeepEqual(w.Panels(), exp) {
+		t.Errorf("Expected panels %v, but got %v", exp, w.Panels())
+	}
+
+	tests := []struct {
+		show   string
+		ok     bool
+		active string
+	}{
+		{"output.exec", true, "output.exec"},
+		{"output.none", false, "output.exec"},
+		{"console", true, "console"},
+		{"output.find", true, "output.find"},
+	}
+	for i, test := range tests {
+		if ok := w.ShowPanel(test.show); ok != test.ok {
+			t.Errorf("Test %d: Expected showing %s to return %v, but got %v", i, test.show, test.ok, ok)
+		}
+		if a := w.ActivePanel(); a != test.active {
+			t.Errorf("Test %d: Expected active panel %q, but got %q", i, test.active, a)
+		}
+	}
+
+	w.DestroyOutputPanel("find")
+	if a := w.ActivePanel(); a != "" {
+		t.Errorf("Expected destroying the shown panel to hide it, but got %q", a)
+	}
+	if exp := []string{"output.exec"}; !reflect.DeepEqual(w.Panels(), exp) {
+		t.Errorf("Expected panels %v, but got %v", exp, w.Panels())
+	}
+}
+
+func TestPanelChangedEvent(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	calls := 0
+	OnPanelChanged.Add(func(ww *Window) {
+		if ww == w {
+			calls++
+		}
+	})
+	w.CreateOutputPanel("exec")
+	w.ShowPanel("output.exec")
+	w.ShowPanel("output.exec")
+	w.HidePanel()
+	w.HidePanel()
+	if calls != 2 {
+		t.Errorf("Expected OnPanelChanged to be called 2 times, but got %d", calls)
+	}
+}
@@ -30,6 +30,18 @@ type Parser interface {
 	Parse() (*Node, error)
 }
 
+// The IncrementalParser interface is implemented by parsers which keep
+// enough state from their last parse to only parse again the part of
+// the data affected by a change.
+type IncrementalParser interface {
+	Parser
+	// Reparse updates the Node structure of the last Parse or Reparse
+	// for the changed "data". Returns the new root node and the Region
+	// of "data" which has actually been parsed again, outside of it the
+	// nodes are the old ones adjusted to the change.
+	Reparse(data []rune) (*Node, text.Region, error)
+}
+
 // The SyntaxHighlighter interface is responsible for
 // identifying the extent and name of code scopes given
 // a position in the code buffer this specific SyntaxHighlighter
@@ -87,6 +99,12 @@ func NewSyntaxHighlighter(p Parser) (SyntaxHighlighter, error) {
 	}
 }
 
+// Creates a new default implementation of SyntaxHighlighter operating
+// on an already parsed tree.
+func NewNodeHighlighter(root *Node) SyntaxHighlighter {
+	return &nodeHighlighter{rootNode: root}
+}
+
 // Given a text region, returns the innermost node covering that region.
 // Side-effects: Writes to nh.lastScopeBuf...
 func (nh *nodeHighlighter) findScope(search text.Region, node *Node) *Node {
@@ -156,29 +174,35 @@ func (nh *nodeHighlighter) ScopeName(point int) string {
 	return nh.lastScopeName
 }
 
-func (nh *nodeHighlighter) flatten(vrmap render.ViewRegionMap, scopename string, node *Node) {
+// Adds the parts of the node scopes within "clip" to vrmap.
+func flatten(vrmap render.ViewRegionMap, scopename string, node *Node, clip text.Region) {
 	scopename += " " + node.Name
 	cur := node.Range
 
 	for _, c := range node.Children {
 		if cur.A <= c.Range.A {
-			reg := vrmap[scopename]
-			reg.Flags |= render.DRAW_TEXT
-			reg.Scope = scopename
-			reg.Regions.Add(text.Region{A: cur.A, B: c.Range.A})
-			vrmap[scopename] = reg
+			addScope(vrmap, scopename, text.Region{A: text.Max(cur.A, clip.A), B: text.Min(c.Range.A, clip.B)})
 		}
 		cur.A = c.Range.B
-		nh.flatten(vrmap, scopename, c)
+		if c.Range.B >= clip.A && c.Range.A <= clip.B {
+			flatten(vrmap, scopename, c, clip)
+		}
 	}
 	// Just add the last region if it's not zero sized
 	if cur.A != cur.B {
-		reg := vrmap[scopename]
-		reg.Flags |= render.DRAW_TEXT
-		reg.Scope = scopename
-		reg.Regions.Add(text.Region{A: cur.A, B: cur.B})
-		vrmap[scopename] = reg
+		addScope(vrmap, scopename, text.Region{A: text.Max(cur.A, clip.A), B: text.Min(cur.B, clip.B)})
+	}
+}
+
+func addScope(vrmap render.ViewRegionMap, scopename string, r text.Region) {
+	if r.A > r.B {
+		return
 	}
+	reg := vrmap[scopename]
+	reg.Flags |= render.DRAW_TEXT
+	reg.Scope = scopename
+	reg.Regions.Add(r)
+	vrmap[scopename] = reg
 }
 
 func (nh *nodeHighlighter) Adjust(position, delta int) {
@@ -187,6 +211,14 @@ func (nh *nodeHighlighter) Adjust(position, delta int) {
 
 func (nh *nodeHighlighter) Flatten() (ret render.ViewRegionMap) {
 	ret = make(render.ViewRegionMap)
-	nh.flatten(ret, "lime.syntax", nh.rootNode)
+	flatten(ret, "lime.syntax", nh.rootNode, text.Region{A: 0, B: int(^uint(0) >> 1)})
+	return
+}
+
+// FlattenRegion is like SyntaxHighlighter.Flatten but only includes
+// the parts of the scopes of the tree "root" within Region "r".
+func FlattenRegion(root *Node, r text.Region) (ret render.ViewRegionMap) {
+	ret = make(render.ViewRegionMap)
+	flatten(ret, "lime.syntax", root, r)
 	return
 }
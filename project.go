@@ -18,10 +18,12 @@ import (
 
 type Project struct {
 	util.HasSettings
-	window   *Window
-	filename string
-	folders  Folders
-	// TODO: build_systems
+	window       *Window
+	filename     string
+	folders      Folders
+	buildSystems []*BuildSystem
+	index        *FileIndex
+	symbols      *SymbolIndex
 }
 
 // Represents each folder in sublime-project file
@@ -43,6 +45,7 @@ func newProject(w *Window) *Project {
 
 func (p *Project) Close() {
 	GetEditor().UnWatch(p.FileName(), p)
+	p.closeIndex()
 	*p = *newProject(p.Window())
 	OnProjectChanged.Call(p.Window())
 }
@@ -66,6 +69,7 @@ func (p *Project) SaveAs(name string) error {
 func (p *Project) AddFolder(path string) {
 	log.Fine("Adding folder %s to project %s", path, p.FileName())
 	p.folders = append(p.folders, &Folder{Path: path})
+	p.reindex()
 	OnAddFolder.call(p.Window(), path)
 }
 
@@ -76,6 +80,7 @@ func (p *Project) RemoveFolder(path string) {
 			p.folders[i] = p.folders[len(p.folders)-1]
 			p.folders[len(p.folders)-1] = nil
 			p.folders = p.folders[:len(p.folders)-1]
+			p.reindex()
 			OnRemoveFolder.call(p.Window(), path)
 			break
 		}
@@ -99,6 +104,49 @@ func (p *Project) Folder(path string) *Folder {
 	return nil
 }
 
+// FileIndex returns the index of the files in the project folders,
+// the folders are indexed in the background on the first call.
+func (p *Project) FileIndex() *FileIndex {
+	if p.index == nil {
+		p.index = newFileIndex(p)
+		p.index.Rebuild()
+	}
+	return p.index
+}
+
+// SymbolIndex returns the index of the symbols defined in the project
+// files, the files are indexed in the background on the first call.
+func (p *Project) SymbolIndex() *SymbolIndex {
+	if p.symbols == nil {
+		p.symbols = newSymbolIndex(p)
+		p.symbols.Rebuild()
+	}
+	return p.symbols
+}
+
+// Rebuilds the file and symbol indexes if there are as the folders changed.
+func (p *Project) reindex() {
+	if p.index != nil {
+		p.index.Rebuild()
+	}
+	if p.symbols != nil {
+		p.symbols.Rebuild()
+	}
+}
+
+func (p *Project) closeIndex() {
+	if p.index != nil {
+		p.index.Close()
+		p.index = nil
+	}
+	p.symbols = nil
+}
+
+// Returns the build systems defined in the project.
+func (p *Project) BuildSystems() []*BuildSystem {
+	return p.buildSystems
+}
+
 func (p *Project) FileName() string {
 	return p.filename
 }
@@ -114,13 +162,16 @@ func (p *Project) Window() *Window {
 
 func (p *Project) UnmarshalJSON(data []byte) error {
 	med := struct {
-		Folders  Folders
-		Settings util.Settings
+		Folders      Folders
+		Settings     util.Settings
+		BuildSystems []*BuildSystem `json:"build_systems"`
 	}{}
 	if err := json.Unmarshal(data, &med); err != nil {
 		return err
 	}
 	p.folders = med.Folders
+	p.buildSystems = med.BuildSystems
+	p.reindex()
 	if data, err := json.Marshal(&med.Settings); err != nil {
 		return err
 	} else if err = json.Unmarshal(data, p.Settings()); err != nil {
@@ -158,6 +209,13 @@ func (p *Project) MarshalJSON() ([]byte, error) {
 		buf.WriteString("\n\t\t}")
 	}
 	buf.WriteString("\n\t]")
+	if len(p.buildSystems) != 0 {
+		data, err := json.MarshalIndent(p.buildSystems, "\t", "\t")
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(buf, ",\n\t\"build_systems\":\n\t%s", data)
+	}
 	if data, err := json.MarshalIndent(p.Settings(), "", "\t"); err != nil {
 		return nil, err
 	} else if str := string(data); str != "{}" {
@@ -0,0 +1,294 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jxo/lime/text"
+	"github.com/jxo/lime/util"
+)
+
+// Quick panel flags, same as in sublime
+const (
+	// Show the items in a monospace font
+	MONOSPACE_FONT = 1 << iota
+	// Don't cancel the panel when it loses focus
+	KEEP_OPEN_ON_FOCUS_LOST
+)
+
+type (
+	// QuickPanelItem is a row of the quick panel, Details are the
+	// additional lines shown below the Text and Annotation is shown
+	// aside, i.e the key binding of a command.
+	QuickPanelItem struct {
+		Text       string
+		Details    []string
+		Annotation string
+	}
+
+	// QuickPanel is a list of items the user chooses from, filtering
+	// them by typing. The frontend reports back through Highlight and
+	// Select, the panel is done once an item is selected or the panel
+	// is cancelled.
+	QuickPanel struct {
+		Items []QuickPanelItem
+		Flags int
+		// Index of the item initially highlighted, -1 for none
+		Selected int
+		// The filter text, kept up to date by the frontend
+		Text        string
+		onSelect    func(int)
+		onHighlight func(int)
+		done        bool
+		lock        sync.Mutex
+	}
+
+	// InputPanel is a single line text input, the text is edited in
+	// the panel View.
+	InputPanel struct {
+		Caption  string
+		view     *View
+		onDone   func(string)
+		onChange func(string)
+		onCancel func()
+		done     bool
+		lock     sync.Mutex
+	}
+)
+
+// NewQuickPanel returns a quick panel of the given items, any
+// of the callbacks can be nil.
+func NewQuickPanel(items []QuickPanelItem, flags, selected int, onSelect, onHighlight func(int)) *QuickPanel {
+	if selected >= len(items) {
+		selected = -1
+	}
+	return &QuickPanel{
+		Items:       items,
+		Flags:       flags,
+		Selected:    selected,
+		onSelect:    onSelect,
+		onHighlight: onHighlight,
+	}
+}
+
+// Filter returns the indices of the items fuzzy matching the query,
+// best matches first. Items scoring the same are sorted by length,
+// an empty query matches all the items in order.
+func (q *QuickPanel) Filter(query string) []int {
+	if strings.TrimSpace(query) == "" {
+		ret := make([]int, len(q.Items))
+		for i := range ret {
+			ret[i] = i
+		}
+		return ret
+	}
+	type match struct {
+		i, score int
+	}
+	var matches []match
+	for i, it := range q.Items {
+		if score, _, ok := util.FuzzyMatch(query, it.Text); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return len(q.Items[a.i].Text) < len(q.Items[b.i].Text)
+	})
+	var ret []int
+	for _, m := range matches {
+		ret = append(ret, m.i)
+	}
+	return ret
+}
+
+// Matches returns the indices of the runes of the item text matched by
+// the query, so the frontend can highlight them.
+func (q *QuickPanel) Matches(i int, query string) []int {
+	if i < 0 || i >= len(q.Items) {
+		return nil
+	}
+	_, matches, _ := util.FuzzyMatch(query, q.Items[i].Text)
+	return matches
+}
+
+// Highlight is called by the frontend when the item at index i
+// is highlighted.
+func (q *QuickPanel) Highlight(i int) {
+	q.lock.Lock()
+	if q.done || i < 0 || i >= len(q.Items) {
+		q.lock.Unlock()
+		return
+	}
+	q.Selected = i
+	q.lock.Unlock()
+	if q.onHighlight != nil {
+		q.onHighlight(i)
+	}
+}
+
+// Select is called by the frontend when the item at index i is chosen,
+// -1 meaning the panel was cancelled. Only the first call has an effect.
+func (q *QuickPanel) Select(i int) {
+	q.lock.Lock()
+	if q.done {
+		q.lock.Unlock()
+		return
+	}
+	if i < 0 || i >= len(q.Items) {
+		i = -1
+	}
+	q.done = true
+	q.lock.Unlock()
+	if q.onSelect != nil {
+		q.onSelect(i)
+	}
+}
+
+// Cancel closes the panel without choosing an item.
+func (q *QuickPanel) Cancel() {
+	q.Select(-1)
+}
+
+// Done returns whether an item was selected or the panel cancelled.
+func (q *QuickPanel) Done() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.done
+}
+
+// View returns the widget view the text is edited in.
+func (p *InputPanel) View() *View {
+	return p.view
+}
+
+// Text returns the current text of the panel.
+func (p *InputPanel) Text() string {
+	return p.view.Substr(text.Region{A: 0, B: p.view.Size()})
+}
+
+// Done is called by the frontend when the input is confirmed.
+func (p *InputPanel) Done() {
+	s, ok := p.finish()
+	if ok && p.onDone != nil {
+		p.onDone(s)
+	}
+}
+
+// Cancel is called by the frontend when the input is cancelled.
+func (p *InputPanel) Cancel() {
+	if _, ok := p.finish(); ok && p.onCancel != nil {
+		p.onCancel()
+	}
+}
+
+// Marks the panel as done and closes its view, returns the text it had
+// or false if it already was done.
+func (p *InputPanel) finish() (string, bool) {
+	p.lock.Lock()
+	if p.done {
+		p.lock.Unlock()
+		return "", false
+	}
+	p.done = true
+	p.view.buffer.RemoveObserver(p)
+	p.lock.Unlock()
+	s := p.Text()
+	p.view.Close()
+	return s, true
+}
+
+// BufferObserver
+
+func (p *InputPanel) Erased(changed_buffer text.Buffer, region_removed text.Region, data_removed []rune) {
+	p.changed()
+}
+
+func (p *InputPanel) Inserted(changed_buffer text.Buffer, region_inserted text.Region, data_inserted []rune) {
+	p.changed()
+}
+
+// End of Buffer Observer
+
+func (p *InputPanel) changed() {
+	if p.onChange != nil {
+		p.onChange(p.Text())
+	}
+}
+
+// ShowQuickPanel shows a quick panel of the items in the window,
+// onSelect is called with the index of the chosen item or -1 if
+// the panel is cancelled, onHighlight with the index of the item
+// highlighted. Both callbacks can be nil.
+func (w *Window) ShowQuickPanel(items []QuickPanelItem, flags, selected int, onSelect, onHighlight func(int)) *QuickPanel {
+	q := NewQuickPanel(items, flags, selected, onSelect, onHighlight)
+	w.ShowOverlay(q)
+	return q
+}
+
+// ShowOverlay has the frontend show the quick panel in the window,
+// keeping track of it until it's done.
+func (w *Window) ShowOverlay(q *QuickPanel) {
+	w.lock.Lock()
+	w.overlay = q
+	w.lock.Unlock()
+	GetEditor().Frontend().ShowQuickPanel(w, q)
+}
+
+// Overlay returns the quick panel shown in the window, nil if none is.
+func (w *Window) Overlay() *QuickPanel {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.overlay == nil || w.overlay.Done() {
+		return nil
+	}
+	return w.overlay
+}
+
+// ShowInputPanel shows the input panel with the initial text in the
+// window, returning the panel view. onChange is called every time the
+// text changes, onDone once the input is confirmed and onCancel if it's
+// cancelled, any of them can be nil.
+func (w *Window) ShowInputPanel(caption, initial string, onDone, onChange func(string), onCancel func()) *View {
+	v := newView(w)
+	v.setBuffer(text.NewBuffer())
+	v.SetScratch(true)
+	v.Settings().Set("is_widget", true)
+	e := v.BeginEdit()
+	v.Insert(e, 0, initial)
+	v.EndEdit(e)
+	v.selection.Clear()
+	v.selection.Add(text.Region{A: v.Size(), B: v.Size()})
+
+	p := &InputPanel{
+		Caption:  caption,
+		view:     v,
+		onDone:   onDone,
+		onChange: onChange,
+		onCancel: onCancel,
+	}
+	v.buffer.AddObserver(p)
+	GetEditor().Frontend().ShowInputPanel(w, p)
+	return v
+}
+
+// ShowPopupMenu shows a menu of the items at the cursor of the view,
+// onDone is called with the index of the chosen item or -1 if the
+// menu is cancelled.
+func (v *View) ShowPopupMenu(items []string, onDone func(int), flags int) *QuickPanel {
+	qi := make([]QuickPanelItem, len(items))
+	for i, it := range items {
+		qi[i].Text = it
+	}
+	q := NewQuickPanel(qi, flags, -1, onDone, nil)
+	GetEditor().Frontend().ShowPopupMenu(v, q)
+	return q
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuickPanelFilter(t *testing.T) {
+	q := NewQuickPanel([]QuickPanelItem{
+		{Text: "Set Syntax: Go"},
+		{Text: "Toggle Comment"},
+		{Text: "Goto Line"},
+		{Text: "Sort Lines", Details: []string{"sorts the selected lines"}},
+	}, 0, -1, nil, nil)
+
+	tests := []struct {
+		query string
+		exp   []int
+	}{
+		{"", []int{0, 1, 2, 3}},
+		{"go", []int{2, 0, 1}},
+		{"GOTO", []int{2}},
+		{"tc", []int{1}},
+		{"s l", []int{3}},
+		{"xyz", nil},
+	}
+	for i, test := range tests {
+		if res := q.Filter(test.query); !reflect.DeepEqual(res, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, res)
+		}
+	}
+}
+
+func TestQuickPanelMatches(t *testing.T) {
+	q := NewQuickPanel([]QuickPanelItem{{Text: "Toggle Comment"}}, 0, -1, nil, nil)
+
+	tests := []struct {
+		i     int
+		query string
+		exp   []int
+	}{
+		{0, "tc", []int{0, 7}},
+		{0, "com", []int{7, 8, 9}},
+		{0, "", nil},
+		{0, "xyz", nil},
+		{1, "tc", nil},
+	}
+	for i, test := range tests {
+		if res := q.Matches(test.i, test.query); !reflect.DeepEqual(res, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, res)
+		}
+	}
+}
+
+func TestQuickPanelSelect(t *testing.T) {
+	var selected, highlighted []int
+	q := NewQuickPanel([]QuickPanelItem{{Text: "a"}, {Text: "b"}}, 0, 5,
+		func(i int) { selected = append(selected, i) },
+		func(i int) { highlighted = append(highlighted, i) })
+	if q.Selected != -1 {
+		t.Errorf("Expected an out of range selected index to be -1, but got %d", q.Selected)
+	}
+
+	q.Highlight(1)
+	q.Highlight(2)
+	q.Select(0)
+	q.Select(1)
+	q.Cancel()
+	q.Highlight(0)
+	if exp := []int{1}; !reflect.DeepEqual(highlighted, exp) {
+		t.Errorf("Expected highlighted %v, but got %v", exp, highlighted)
+	}
+	if exp := []int{0}; !reflect.DeepEqual(selected, exp) {
+		t.Errorf("Expected selected %v, but got %v", exp, selected)
+	}
+	if !q.Done() {
+		t.Errorf("Expected the panel to be done")
+	}
+
+	selected = nil
+	q = NewQuickPanel([]QuickPanelItem{{Text: "a"}}, 0, -1, func(i int) { selected = append(selected, i) }, nil)
+	q.Cancel()
+	if exp := []int{-1}; !reflect.DeepEqual(selected, exp) {
+		t.Errorf("Expected cancelling to select %v, but got %v", exp, selected)
+	}
+}
+
+type inputFrontend struct {
+	dummyFrontend
+	panel *InputPanel
+}
+
+func (fe *inputFrontend) ShowInputPanel(w *Window, p *InputPanel) { fe.panel = p }
+
+func TestInputPanelClose(t *testing.T) {
+	ed := GetEditor()
+	old := ed.Frontend()
+	defer ed.SetFrontend(old)
+	w := ed.NewWindow()
+	defer w.Close()
+
+	for i, cancel := range []bool{false, true} {
+		var fe inputFrontend
+		ed.SetFrontend(&fe)
+		var done string
+		v := w.ShowInputPanel("", "text", func(s string) { done = s }, nil, nil)
+		if cancel {
+			fe.panel.Cancel()
+		} else {
+			fe.panel.Done()
+		}
+		if !cancel && done != "text" {
+			t.Errorf("Test %d: Expected done with %q, but got %q", i, "text", done)
+		}
+		v.lock.Lock()
+		closed := v.reparseChan == nil
+		v.lock.Unlock()
+		if !closed {
+			t.Errorf("Test %d: Expected the input panel view to be closed", i)
+		}
+	}
+}
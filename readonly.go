@@ -0,0 +1,92 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"os"
+
+	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/text"
+)
+
+// IsReadOnly returns whether the view is read only. Files which can't be
+// written are opened read only.
+func (v *View) IsReadOnly() bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.readOnly
+}
+
+// SetReadOnly sets whether the view is read only, Insert, Erase and
+// Replace then leave it unchanged.
+func (v *View) SetReadOnly(ro bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.readOnly = ro
+}
+
+// Returns whether the file can be written by us, a file which doesn't
+// exist yet is created once saved.
+func isWritable(filename string) bool {
+	fi, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return true
+	} else if err != nil || fi.Mode().Perm()&0222 == 0 {
+		return false
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY, 0)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// Returns whether the region of the view can be modified, r being empty
+// for insertions. The user is told why it can't otherwise.
+//
+// The view can't be modified while it's loading or when it's read only,
+// nor can the regions added with the PROTECTED flag. Text can still be
+// inserted at their beginning or end.
+func (v *View) canModify(r text.Region) bool {
+	v.lock.Lock()
+	loading, ro := v.loading, v.readOnly
+	protected := false
+	for _, vr := range v.regions {
+		if vr.Flags&render.PROTECTED == 0 {
+			continue
+		}
+		for _, p := range vr.Regions.Regions() {
+			if !p.Empty() && p.Begin() < r.End() && r.Begin() < p.End() {
+				protected = true
+				break
+			}
+		}
+	}
+	v.lock.Unlock()
+
+	var msg string
+	switch {
+	case loading:
+		msg = "The view can't be modified while it's loading"
+	case ro:
+		msg = "The view is read only"
+	case protected:
+		msg = "The text is protected"
+	default:
+		return true
+	}
+	GetEditor().statusMessage(msg)
+	return false
+}
+
+// Replaces the whole content of the view in a single edit, regardless of
+// it being read only or having protected regions. Used when the content
+// comes from the file.
+func (v *View) setContent(s string) {
+	e := v.BeginEdit()
+	e.composite.AddExec(text.NewReplaceAction(v.buffer, text.Region{A: 0, B: v.Size()}, s))
+	v.EndEdit(e)
+}
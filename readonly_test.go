@@ -0,0 +1,172 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/text"
+)
+
+func TestReadOnly(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "hello")
+	v.EndEdit(e)
+
+	v.SetReadOnly(true)
+	if !v.IsReadOnly() {
+		t.Error("Expected the view to be read only")
+	}
+	e = v.BeginEdit()
+	if n := v.Insert(e, 0, "a"); n != 0 {
+		t.Errorf("Expected nothing to be inserted, but got %d", n)
+	}
+	if v.Erase(e, text.Region{A: 0, B: 1}) {
+		t.Error("Expected the erase to be rejected")
+	}
+	if v.Replace(e, text.Region{A: 0, B: 5}, "bye") {
+		t.Error("Expected the replace to be rejected")
+	}
+	v.EndEdit(e)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "hello" {
+		t.Errorf("Expected the read only view not to be modified, but got %q", s)
+	}
+
+	v.SetReadOnly(false)
+	e = v.BeginEdit()
+	if !v.Replace(e, text.Region{A: 0, B: 5}, "bye") {
+		t.Error("Expected the replace to be applied")
+	}
+	v.EndEdit(e)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "bye" {
+		t.Errorf("Expected %q, but got %q", "bye", s)
+	}
+}
+
+func TestProtectedRegions(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.Insert(e, 0, "header\nbody")
+	v.EndEdit(e)
+	v.AddRegions("generated", []text.Region{{A: 0, B: 6}}, "", "", render.PROTECTED|render.HIDDEN)
+
+	tests := []struct {
+		insert bool
+		r      text.Region
+		value  string
+		exp    string
+	}{
+		{true, text.Region{A: 3, B: 3}, "x", "header\nbody"},
+		{false, text.Region{A: 5, B: 8}, "", "header\nbody"},
+		{false, text.Region{A: 0, B: 11}, "new", "header\nbody"},
+		{true, text.Region{A: 6, B: 6}, "!", "header!\nbody"},
+		{false, text.Region{A: 7, B: 8}, "", "header!body"},
+		{true, text.Region{A: 0, B: 0}, "// ", "// header!body"},
+		{false, text.Region{A: 5, B: 7}, "", "// header!body"},
+	}
+	for i, test := range tests {
+		e := v.BeginEdit()
+		if test.insert {
+			v.Insert(e, test.r.A, test.value)
+		} else {
+			v.Replace(e, test.r, test.value)
+		}
+		v.EndEdit(e)
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+	}
+	// the protected region follows the edits
+	if r := v.GetRegions("generated"); !reflect.DeepEqual(r, []text.Region{{A: 3, B: 10}}) {
+		t.Errorf("Expected the protected region to be adjusted, but got %v", r)
+	}
+
+	v.EraseRegions("generated")
+	e = v.BeginEdit()
+	v.Erase(e, text.Region{A: 0, B: 3})
+	v.EndEdit(e)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "header!body" {
+		t.Errorf("Expected %q, but got %q", "header!body", s)
+	}
+}
+
+func TestOpenReadOnlyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lime-readonly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fe := &dummyFrontend{}
+	fe.SetDefaultAction(true)
+	ed := GetEditor()
+	ed.SetFrontend(fe)
+	w := ed.NewWindow()
+	defer w.Close()
+
+	file := filepath.Join(dir, "readonly.txt")
+	if err := ioutil.WriteFile(file, []byte("hello"), 0444); err != nil {
+		t.Fatal(err)
+	}
+	v := w.OpenFile(file, 0)
+	defer v.Close()
+	if !v.IsReadOnly() {
+		t.Error("Expected a file which can't be written to be opened read only")
+	}
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "hello" {
+		t.Errorf("Expected %q, but got %q", "hello", s)
+	}
+
+	// the file is still reloaded
+	if err := os.Chmod(file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v.FileChanged(file)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "changed" {
+		t.Errorf("Expected %q, but got %q", "changed", s)
+	}
+
+	if w.OpenFile(file, 0).IsReadOnly() {
+		t.Error("Expected a file which can be written not to be opened read only")
+	}
+
+	// a file which doesn't exist yet is opened to be created
+	nv := w.OpenFile(filepath.Join(dir, "new.txt"), 0)
+	defer func() {
+		nv.SetScratch(true)
+		nv.Close()
+	}()
+	if nv.IsReadOnly() {
+		t.Error("Expected a missing file not to be opened read only")
+	}
+	e := nv.BeginEdit()
+	if n := nv.Insert(e, 0, "new"); n != 3 {
+		t.Errorf("Expected 3 characters to be inserted, but got %d", n)
+	}
+	nv.EndEdit(e)
+	if s := nv.Substr(text.Region{A: 0, B: nv.Size()}); s != "new" {
+		t.Errorf("Expected %q, but got %q", "new", s)
+	}
+}
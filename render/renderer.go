@@ -65,18 +65,36 @@ type (
 //
 // The final output, the Recipe, contains a mapping of all unique Flavours and that Flavour's
 // associated RegionSet.
+//
+// The text of the ViewRegions flagged as FOLDED is cut out of all the other regions, the folded
+// regions themselves end up under a Flavour with the FOLDED flag set so that the Renderer
+// can draw a fold marker in their place.
 func Transform(scheme ColourScheme, data ViewRegionMap, viewport text.Region) Recipe {
 	pe := util.Prof.Enter("render.Transform")
 	defer pe.Exit()
 
 	data.Cull(viewport)
+	var folded []text.Region
+	for _, v := range data {
+		if v.Flags&FOLDED != 0 {
+			folded = append(folded, v.Regions.Regions()...)
+		}
+	}
 	recipe := make(Recipe)
 	for _, v := range data {
 		k := scheme.Spice(&v)
-		rs := recipe[k]
 		a := util.Prof.Enter("render.Transform.(Regions)")
 		r := v.Regions.Regions()
 		a.Exit()
+		if v.Flags&FOLDED != 0 {
+			k.Flags |= FOLDED
+		} else if len(folded) > 0 {
+			r = elide(r, folded)
+			if len(r) == 0 {
+				continue
+			}
+		}
+		rs := recipe[k]
 		a = util.Prof.Enter("render.Transform.(AddAll)")
 		rs.AddAll(r)
 		a.Exit()
@@ -85,6 +103,34 @@ func Transform(scheme ColourScheme, data ViewRegionMap, viewport text.Region) Re
 	return recipe
 }
 
+// Cuts the folded parts out of the regions, empty regions
+// are kept unless they're strictly inside a fold.
+func elide(regions, folded []text.Region) []text.Region {
+	for _, f := range folded {
+		a, b := f.Begin(), f.End()
+		var ret []text.Region
+		for _, r := range regions {
+			switch {
+			case r.Empty():
+				if r.A <= a || r.A >= b {
+					ret = append(ret, r)
+				}
+			case r.End() <= a || r.Begin() >= b:
+				ret = append(ret, r)
+			default:
+				if r.Begin() < a {
+					ret = append(ret, text.Region{A: r.Begin(), B: a})
+				}
+				if r.End() > b {
+					ret = append(ret, text.Region{A: b, B: r.End()})
+				}
+			}
+		}
+		regions = ret
+	}
+	return regions
+}
+
 // Transcribing the Recipe creates a linear step-by-step
 // representation of it, which might or might not
 // make it easier for Renderers to work with.
@@ -160,3 +160,40 @@ func TestRecipeTranscribe(t *testing.T) {
 		}
 	}
 }
+
+func TestTransformFolded(t *testing.T) {
+	vrmap := ViewRegionMap{
+		"A": {Scope: "A"},
+		"B": {Scope: "B"},
+		"F": {Flags: FOLDED},
+	}
+	for k, r := range map[string][]text.Region{
+		"A": {{0, 10}},
+		"B": {{4, 6}, {20, 25}},
+		"F": {{3, 8}},
+	} {
+		vr := vrmap[k]
+		vr.Regions.AddAll(r)
+		vrmap[k] = vr
+	}
+	recipe := Transform(dummyColourScheme{}, vrmap, text.Region{A: 0, B: 30})
+
+	tests := []struct {
+		flav Flavour
+		exp  []text.Region
+	}{
+		{flavourA, []text.Region{{0, 3}, {8, 10}}},
+		{flavourB, []text.Region{{20, 25}}},
+		{Flavour{Background: flavourDef.Background, Flags: FOLDED}, []text.Region{{3, 8}}},
+	}
+	for i, test := range tests {
+		rs, ok := recipe[test.flav]
+		if !ok {
+			t.Errorf("Test %d: Expected flavour %v in the recipe, but it wasn't", i, test.flav)
+			continue
+		}
+		if r := rs.Regions(); !reflect.DeepEqual(r, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, r)
+		}
+	}
+}
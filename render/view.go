@@ -23,6 +23,8 @@ const (
 	SELECTION                                             // This Region is part of selected text
 	HIGHLIGHT                                             // This Region is part of highlighted text
 	DRAW_TEXT                                             // The actual text contained in the region should be rendered
+	FOLDED                                                // Region is folded, its text is elided from the other regions
+	PROTECTED                                             // Region can't be modified
 	DEFAULT                 ViewRegionFlags = 0           // No flags at all, only draw the region itself and not the text
 )
 
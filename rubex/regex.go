@@ -387,6 +387,23 @@ func (re *Regexp) FindStringSubmatchIndex(s string) []int {
 	return re.FindSubmatchIndex(b)
 }
 
+// FindStringSubmatchIndexFrom is like FindStringSubmatchIndex but starts the
+// search at offset. Unlike searching s[offset:], anchors and look-behinds
+// still see the text before offset. The returned indexes are relative to s.
+func (re *Regexp) FindStringSubmatchIndexFrom(s string, offset int) []int {
+	if offset < 0 || offset > len(s) {
+		return nil
+	}
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	re.ClearMatchData()
+	match := re.find([]byte(s), len(s), offset)
+	if len(match) == 0 {
+		return nil
+	}
+	return match
+}
+
 func (re *Regexp) FindAllSubmatchIndex(b []byte, n int) [][]int {
 	matches := re.findAll(b, n)
 	if len(matches) == 0 {
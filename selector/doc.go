@@ -0,0 +1,8 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+// Parses and scores TextMate and Sublime Text scope selectors, as used by
+// themes, preferences and key binding contexts to refer to the syntax
+// scopes of the text.
+package selector
@@ -0,0 +1,360 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package selector
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type (
+	// Selector is a compiled scope selector.
+	Selector struct {
+		src  string
+		root node
+	}
+
+	// A node of the selector expression tree, returning the score of
+	// the scope elements.
+	node interface {
+		score(elems []string) int
+		String() string
+	}
+
+	// Scopes which must be found in order in the scope name, i.e
+	// "source.go string".
+	path []string
+
+	// Two selectors joined by an operator, "," and "|" matching either
+	// of them, "&" both of them and "-" the left one without the right.
+	binary struct {
+		op          byte
+		left, right node
+	}
+
+	// A negated selector, i.e "-comment".
+	negation struct {
+		n node
+	}
+
+	// A parenthesized selector.
+	group struct {
+		n node
+	}
+)
+
+// The number of dotted scope parts counted per scope name element and
+// the number of innermost scope name elements scored, keeping the score
+// in a 32 bit int.
+const (
+	partBits = 3
+	maxParts = 1<<partBits - 1
+	maxElems = 10
+)
+
+// The number of compiled selectors Score keeps, the cache is emptied
+// once it's full.
+const maxCached = 1024
+
+var (
+	cache     = make(map[string]*Selector)
+	cacheLock sync.Mutex
+)
+
+// Compile parses the selector. The selector is a list of alternatives
+// separated by ",", each being scope paths joined by the "|" (or), "&"
+// (and) and "-" (without) operators, evaluated left to right. A path can
+// be negated by a leading "-" and grouped within parentheses. The "L:",
+// "R:" and "B:" side prefixes are accepted and ignored.
+func Compile(s string) (*Selector, error) {
+	p := parser{src: s}
+	n, err := p.alternatives()
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.pos < len(p.src) {
+		return nil, fmt.Errorf("Unexpected %q at %d in selector %q", p.src[p.pos], p.pos, s)
+	}
+	return &Selector{src: s, root: n}, nil
+}
+
+// MustCompile is like Compile but panics if the selector can't be parsed.
+func MustCompile(s string) *Selector {
+	sel, err := Compile(s)
+	if err != nil {
+		panic(err)
+	}
+	return sel
+}
+
+// Score returns how well the selector matches the space separated scope
+// name, 0 meaning it doesn't match. Compiled selectors are cached and
+// invalid ones never match.
+func Score(selector, scope string) int {
+	cacheLock.Lock()
+	sel, ok := cache[selector]
+	if !ok {
+		if len(cache) >= maxCached {
+			cache = make(map[string]*Selector)
+		}
+		sel, _ = Compile(selector)
+		cache[selector] = sel
+	}
+	cacheLock.Unlock()
+	if sel == nil {
+		return 0
+	}
+	return sel.Score(scope)
+}
+
+// Match returns whether the selector matches the scope name.
+func Match(selector, scope string) bool {
+	return Score(selector, scope) > 0
+}
+
+// Score returns how well the selector matches the space separated scope
+// name, 0 meaning it doesn't. Matching a deeper scope name element scores
+// higher, then matching more of its dotted parts, ties being broken by
+// the same rules applied to the outer elements. An empty selector matches
+// any scope with the lowest score.
+func (s *Selector) Score(scope string) int {
+	if s.root == nil {
+		return 1
+	}
+	return s.root.score(strings.Fields(scope))
+}
+
+// Match returns whether the selector matches the scope name.
+func (s *Selector) Match(scope string) bool {
+	return s.Score(scope) > 0
+}
+
+func (s *Selector) String() string {
+	return s.src
+}
+
+func (p path) score(elems []string) int {
+	parts := make([]int, len(elems))
+	// the scopes are matched from the innermost one outwards
+	j := len(elems) - 1
+	for i := len(p) - 1; i >= 0; i-- {
+		for ; j >= 0; j-- {
+			if n := matchScope(p[i], elems[j]); n > 0 {
+				parts[j] = n
+				break
+			}
+		}
+		if j < 0 {
+			return 0
+		}
+		j--
+	}
+	score := 0
+	for i, n := range parts {
+		if d := len(elems) - 1 - i; d < maxElems {
+			score |= n << uint(partBits*(maxElems-1-d))
+		}
+	}
+	if score == 0 {
+		// only outer elements beyond those scored matched
+		return 1
+	}
+	return score
+}
+
+func (p path) String() string {
+	return strings.Join(p, " ")
+}
+
+// Returns the number of dotted parts of the scope name element the
+// selector scope matches, 0 if it doesn't.
+func matchScope(sel, elem string) int {
+	if sel == "*" {
+		return 1
+	}
+	if !strings.HasPrefix(elem, sel) || (len(elem) > len(sel) && elem[len(sel)] != '.') {
+		return 0
+	}
+	if n := strings.Count(sel, ".") + 1; n < maxParts {
+		return n
+	}
+	return maxParts
+}
+
+func (b *binary) score(elems []string) int {
+	l := b.left.score(elems)
+	switch b.op {
+	case '|', ',':
+		if r := b.right.score(elems); r > l {
+			return r
+		}
+		return l
+	case '&':
+		if l == 0 {
+			return 0
+		}
+		if r := b.right.score(elems); r == 0 {
+			return 0
+		} else if r > l {
+			return r
+		}
+		return l
+	case '-':
+		if l == 0 || b.right.score(elems) != 0 {
+			return 0
+		}
+		return l
+	}
+	return 0
+}
+
+func (b *binary) String() string {
+	if b.op == ',' {
+		return fmt.Sprintf("%s, %s", b.left, b.right)
+	}
+	return fmt.Sprintf("%s %c %s", b.left, b.op, b.right)
+}
+
+func (n *negation) score(elems []string) int {
+	if n.n.score(elems) != 0 {
+		return 0
+	}
+	return 1
+}
+
+func (n *negation) String() string {
+	return "-" + n.n.String()
+}
+
+func (g *group) score(elems []string) int {
+	return g.n.score(elems)
+}
+
+func (g *group) String() string {
+	return "(" + g.n.String() + ")"
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) skip() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) != -1 {
+		p.pos++
+	}
+}
+
+// Returns the next non blank character without consuming it.
+func (p *parser) peek() byte {
+	if p.skip(); p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) alternatives() (node, error) {
+	var ret node
+	for {
+		n, err := p.composite()
+		if err != nil {
+			return nil, err
+		}
+		if ret == nil {
+			ret = n
+		} else if n != nil {
+			ret = &binary{',', ret, n}
+		}
+		if p.peek() != ',' {
+			return ret, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) composite() (node, error) {
+	left, err := p.expression()
+	if err != nil || left == nil {
+		return left, err
+	}
+	for {
+		op := p.peek()
+		if op != '|' && op != '&' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if right == nil {
+			return nil, fmt.Errorf("Expected a selector after %q at %d in selector %q", op, p.pos, p.src)
+		}
+		left = &binary{op, left, right}
+	}
+}
+
+func (p *parser) expression() (node, error) {
+	switch c := p.peek(); {
+	case c == '-':
+		p.pos++
+		n, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			return nil, fmt.Errorf("Expected a selector after '-' at %d in selector %q", p.pos, p.src)
+		}
+		return &negation{n}, nil
+	case c == '(':
+		p.pos++
+		n, err := p.alternatives()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("Expected ')' at %d in selector %q", p.pos, p.src)
+		}
+		p.pos++
+		if n == nil {
+			return nil, fmt.Errorf("Empty group in selector %q", p.src)
+		}
+		return &group{n}, nil
+	case (c == 'L' || c == 'R' || c == 'B') && p.pos+1 < len(p.src) && p.src[p.pos+1] == ':':
+		p.pos += 2
+		return p.expression()
+	}
+	return p.path(), nil
+}
+
+// Returns the scopes up to the next operator, nil if there are none.
+func (p *parser) path() node {
+	var ret path
+	for {
+		// a leading dash is the without operator
+		if p.skip(); p.pos < len(p.src) && p.src[p.pos] == '-' {
+			break
+		}
+		start := p.pos
+		for p.pos < len(p.src) && isScopeChar(p.src[p.pos]) {
+			p.pos++
+		}
+		if start == p.pos {
+			break
+		}
+		ret = append(ret, p.src[start:p.pos])
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// Scope names can contain dashes, so the "-" operator has to be preceded
+// by a blank, i.e "source.c++ - comment.line-number".
+func isScopeChar(c byte) bool {
+	return c != ' ' && c != '\t' && c != '\r' && c != '\n' && strings.IndexByte(",|&()", c) == -1
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package selector
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	const scope = "source.go meta.function.go string.quoted.double.go punctuation.definition.string.begin.go"
+	tests := []struct {
+		selector string
+		exp      bool
+	}{
+		{"", true},
+		{"string", true},
+		{"string.quoted", true},
+		{"string.quot", false},
+		{"string.quoted.single", false},
+		{"source string", true},
+		{"string source", false},
+		{"source.go punctuation", true},
+		{"source.python punctuation", false},
+		{"comment, string", true},
+		{"comment | string", true},
+		{"comment & string", false},
+		{"source & string", true},
+		{"string - punctuation", false},
+		{"source - comment", true},
+		{"source -comment", true},
+		{"-comment", true},
+		{"-string", false},
+		{"source - (comment | string)", false},
+		{"source - (comment | keyword)", true},
+		{"(comment, keyword), meta", true},
+		{"L:source string", true},
+		{"*", true},
+		{"keyword.operator-like", false},
+	}
+	for i, test := range tests {
+		if res := Match(test.selector, scope); res != test.exp {
+			t.Errorf("Test %d: Expected %q matching to be %t, but got %t", i, test.selector, test.exp, res)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	const scope = "source.go meta.function.go string.quoted.double.go"
+	// each selector scores higher than the previous one
+	tests := []string{
+		"source",
+		"source.go",
+		"meta",
+		"source meta",
+		"meta.function",
+		"string",
+		"source string",
+		"meta string",
+		"string.quoted",
+		"string.quoted.double",
+		"meta string.quoted.double",
+	}
+	last := 0
+	for i, sel := range tests {
+		s := Score(sel, scope)
+		if s <= last {
+			t.Errorf("Test %d: Expected %q to score higher than %d, but got %d", i, sel, last, s)
+		}
+		last = s
+	}
+	if a, b := Score("comment, string", scope), Score("string", scope); a != b {
+		t.Errorf("Expected the best alternative score %d, but got %d", b, a)
+	}
+}
+
+func TestScoreDeep(t *testing.T) {
+	elems := make([]string, 30)
+	for i := range elems {
+		elems[i] = fmt.Sprintf("a%d.b.c.d.e.f.g.h", i)
+	}
+	scope := strings.Join(elems, " ")
+	last := 0
+	for i := len(elems) - 1; i >= len(elems)-maxElems; i-- {
+		s := Score(elems[i], scope)
+		if s <= 0 || s > math.MaxInt32 {
+			t.Errorf("Expected %q to score within an int32, but got %d", elems[i], s)
+		}
+		if i != len(elems)-1 && s >= last {
+			t.Errorf("Expected %q to score lower than %d, but got %d", elems[i], last, s)
+		}
+		last = s
+	}
+	if s := Score(elems[0], scope); s != 1 {
+		t.Errorf("Expected an outer element beyond those scored to score 1, but got %d", s)
+	}
+}
+
+func TestScoreCache(t *testing.T) {
+	for i := 0; i < 2*maxCached; i++ {
+		Score(fmt.Sprintf("source.s%d", i), "source.go")
+	}
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if len(cache) > maxCached {
+		t.Errorf("Expected at most %d cached selectors, but got %d", maxCached, len(cache))
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	for i, sel := range []string{"(source", "source)", "source |", "source - ", "()", "-"} {
+		if _, err := Compile(sel); err == nil {
+			t.Errorf("Test %d: Expected an error compiling %q", i, sel)
+		}
+		if Match(sel, "source") {
+			t.Errorf("Test %d: Expected the invalid %q not to match", i, sel)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	sel := MustCompile("source.go - (comment | string), text")
+	if s := sel.String(); s != "source.go - (comment | string), text" {
+		t.Errorf("Expected the selector source, but got %q", s)
+	}
+	if s := sel.root.String(); s != "source.go - (comment | string), text" {
+		t.Errorf("Expected %q, but got %q", "source.go - (comment | string), text", s)
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// The state of the editor saved on exit and restored on Init when
+	// the "hot_exit" setting is on, so that the views which weren't
+	// saved survive restarts.
+	session struct {
+		Windows      []sessionWindow `json:"windows"`
+		ActiveWindow int             `json:"active_window"`
+	}
+
+	sessionWindow struct {
+		// The project file, or the project itself when it wasn't saved
+		ProjectFile string          `json:"project_file,omitempty"`
+		Project     json.RawMessage `json:"project,omitempty"`
+		Layout      Layout          `json:"layout"`
+		Views       []sessionView   `json:"views"`
+		ActiveView  int             `json:"active_view"`
+	}
+
+	sessionView struct {
+		FileName string `json:"file_name,omitempty"`
+		Name     string `json:"name,omitempty"`
+		Scratch  bool   `json:"scratch,omitempty"`
+		Dirty    bool   `json:"dirty,omitempty"`
+		// The content of the view when it isn't the one of its file
+		Contents *string `json:"contents,omitempty"`
+		Group    int     `json:"group"`
+		// Whether the view is the active one of its group
+		Active    bool             `json:"active,omitempty"`
+		Selection []text.Region    `json:"selection"`
+		Scroll    int              `json:"scroll"`
+		Settings  json.RawMessage  `json:"settings,omitempty"`
+		Regions   []sessionRegions `json:"regions,omitempty"`
+		Undo      *undoJournal     `json:"undo,omitempty"`
+	}
+
+	// The regions added to a view with the PERSISTENT flag.
+	sessionRegions struct {
+		Key     string                 `json:"key"`
+		Scope   string                 `json:"scope,omitempty"`
+		Icon    string                 `json:"icon,omitempty"`
+		Flags   render.ViewRegionFlags `json:"flags"`
+		Regions []text.Region          `json:"regions"`
+	}
+)
+
+// Returns the path of the session file, an empty string when there's no
+// local path to store it in.
+func (e *Editor) sessionPath() string {
+	if e.LocalPath() == "" {
+		return ""
+	}
+	return filepath.Join(e.LocalPath(), "Session.sublime_session")
+}
+
+// Exit is to be called by the frontend before it exits, the session is
+// saved when the "hot_exit" setting is on.
+func (e *Editor) Exit() {
+	if !e.Settings().Bool("hot_exit", true) {
+		return
+	}
+	if err := e.SaveSession(); err != nil {
+		log.Error(err)
+	}
+}
+
+// SaveSession writes the windows, their projects and their views to the
+// session file in the local path. The content of the views which weren't
+// saved is stored along with their undo history.
+func (e *Editor) SaveSession() error {
+	path := e.sessionPath()
+	if path == "" {
+		return fmt.Errorf("No local path to save the session in")
+	}
+	var s session
+	for i, w := range e.Windows() {
+		if w == e.ActiveWindow() {
+			s.ActiveWindow = i
+		}
+		s.Windows = append(s.Windows, w.session())
+	}
+	data, err := json.Marshal(&s)
+	if err != nil {
+		return fmt.Errorf("Couldn't save the session: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("Couldn't save the session: %s", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("Couldn't save the session: %s", err)
+	}
+	return nil
+}
+
+// LoadSession opens the windows and views of the session file in the
+// local path, if there's one.
+func (e *Editor) LoadSession() error {
+	path := e.sessionPath()
+	if path == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Couldn't read the session %s: %s", path, err)
+	}
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("Couldn't load the session %s: %s", path, err)
+	}
+	log.Fine("Restoring session %s", path)
+	windows := make([]*Window, len(s.Windows))
+	for i, sw := range s.Windows {
+		windows[i] = e.NewWindow()
+		windows[i].restoreSession(sw)
+	}
+	if a := s.ActiveWindow; a >= 0 && a < len(windows) {
+		e.SetActiveWindow(windows[a])
+	}
+	return nil
+}
+
+func (w *Window) session() sessionWindow {
+	sw := sessionWindow{Layout: w.Layout()}
+	if p := w.Project(); p.FileName() != "" {
+		sw.ProjectFile = p.FileName()
+	} else if len(p.Folders()) != 0 {
+		if data, err := json.Marshal(p); err != nil {
+			log.Warn("Couldn't save the project of %s in the session: %s", w, err)
+		} else {
+			sw.Project = data
+		}
+	}
+	active := w.ActiveView()
+	for i, v := range w.Views() {
+		if v == active {
+			sw.ActiveView = i
+		}
+		sv := v.session()
+		sv.Group, _ = w.GetViewIndex(v)
+		sv.Active = w.ActiveViewInGroup(sv.Group) == v
+		sw.Views = append(sw.Views, sv)
+	}
+	return sw
+}
+
+func (w *Window) restoreSession(sw sessionWindow) {
+	if sw.ProjectFile != "" {
+		w.OpenProject(sw.ProjectFile)
+	} else if len(sw.Project) != 0 {
+		if err := json.Unmarshal(sw.Project, w.Project()); err != nil {
+			log.Warn("Couldn't restore the project of %s: %s", w, err)
+		}
+	}
+	if len(sw.Layout.Cells) != 0 {
+		if err := w.SetLayout(sw.Layout); err != nil {
+			log.Warn("Couldn't restore the layout of %s: %s", w, err)
+		}
+	}
+	views := make([]*View, len(sw.Views))
+	for i, sv := range sw.Views {
+		if sv.Group >= 0 && sv.Group < w.NumGroups() {
+			w.FocusGroup(sv.Group)
+		}
+		var v *View
+		if sv.FileName != "" {
+			v = w.OpenFile(sv.FileName, 0)
+		} else {
+			v = w.NewFile()
+		}
+		v.restoreSession(sv)
+		views[i] = v
+	}
+	for i, sv := range sw.Views {
+		if sv.Active {
+			w.SetActiveView(views[i])
+		}
+	}
+	if a := sw.ActiveView; a >= 0 && a < len(views) {
+		w.SetActiveView(views[a])
+	}
+}
+
+func (v *View) session() sessionView {
+	sv := sessionView{
+		FileName:  v.FileName(),
+		Name:      v.Name(),
+		Scratch:   v.IsScratch(),
+		Dirty:     v.IsDirty(),
+		Selection: v.Sel().Regions(),
+	}
+	if sv.FileName == "" || sv.Dirty {
+		c := v.Substr(text.Region{A: 0, B: v.Size()})
+		sv.Contents = &c
+	}
+	if fe := GetEditor().Frontend(); fe != nil {
+		sv.Scroll = fe.VisibleRegion(v).Begin()
+	}
+	if data, err := json.Marshal(v.Settings()); err != nil {
+		log.Warn("Couldn't save the settings of %s in the session: %s", v, err)
+	} else {
+		sv.Settings = data
+	}
+
+	v.lock.Lock()
+	keys := make([]string, 0, len(v.regions))
+	for k, vr := range v.regions {
+		if vr.Flags&render.PERSISTENT != 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vr := v.regions[k]
+		sv.Regions = append(sv.Regions, sessionRegions{
+			Key:     k,
+			Scope:   vr.Scope,
+			Icon:    vr.Icon,
+			Flags:   vr.Flags,
+			Regions: vr.Regions.Regions(),
+		})
+	}
+	v.lock.Unlock()
+
+	if j, ok := v.newUndoJournal(); ok {
+		j.limit(v.Settings().Int("persistent_undo_max_size", defaultUndoJournalSize))
+		sv.Undo = j
+	}
+	return sv
+}
+
+func (v *View) restoreSession(sv sessionView) {
+	if len(sv.Settings) != 0 {
+		if err := json.Unmarshal(sv.Settings, v.Settings()); err != nil {
+			log.Warn("Couldn't restore the settings of %s: %s", v, err)
+		}
+	}
+	if sv.Name != "" {
+		v.SetName(sv.Name)
+	}
+	// the file has to be loaded for the content and the positions to
+	// be restored
+	v.WhenLoaded(func() { v.restoreSessionContent(sv) })
+}
+
+func (v *View) restoreSessionContent(sv sessionView) {
+	saved := v.ChangeCount()
+	if sv.Contents != nil {
+		// the content is restored without being added to the undo stack
+		v.SetScratch(true)
+		v.setContent(*sv.Contents)
+	}
+	if !sv.Dirty {
+		saved = v.ChangeCount()
+	}
+	v.Settings().Set("lime.last_save_change_count", saved)
+	v.SetScratch(sv.Scratch)
+
+	if sv.Undo != nil {
+		v.restoreUndoJournal(sv.Undo)
+	}
+	for _, r := range sv.Regions {
+		v.AddRegions(r.Key, r.Regions, r.Scope, r.Icon, r.Flags)
+	}
+	v.Sel().Clear()
+	size := v.Size()
+	for _, r := range sv.Selection {
+		v.Sel().Add(text.Region{A: text.Clamp(0, size, r.A), B: text.Clamp(0, size, r.B)})
+	}
+	if fe := GetEditor().Frontend(); fe != nil && sv.Scroll > 0 {
+		fe.Show(v, text.Region{A: sv.Scroll, B: sv.Scroll})
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/text"
+)
+
+func TestSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lime-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ed := GetEditor()
+	ed.SetFrontend(&dummyFrontend{})
+	ed.SetLocalPath(dir)
+	defer ed.SetLocalPath("")
+
+	clean, dirty := filepath.Join(dir, "clean.txt"), filepath.Join(dir, "dirty.txt")
+	for _, f := range []string{clean, dirty} {
+		if err := ioutil.WriteFile(f, []byte("file content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := ed.NewWindow()
+	w.Project().AddFolder(dir)
+	if err := w.SetLayout(Layout{
+		Cols:  []float64{0, 0.5, 1},
+		Rows:  []float64{0, 1},
+		Cells: [][4]int{{0, 0, 1, 1}, {1, 0, 2, 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	v1 := w.OpenFile(clean, 0)
+	v1.Sel().Clear()
+	v1.Sel().Add(text.Region{A: 2, B: 4})
+
+	v2 := w.OpenFile(dirty, 0)
+	e := v2.BeginEdit()
+	v2.Insert(e, 0, "new ")
+	v2.EndEdit(e)
+	v2.Settings().Set("tab_size", 2)
+
+	w.FocusGroup(1)
+	v3 := w.NewFile()
+	e = v3.BeginEdit()
+	v3.Insert(e, 0, "unsaved")
+	v3.EndEdit(e)
+	e = v3.BeginEdit()
+	v3.Insert(e, 7, " text")
+	v3.EndEdit(e)
+	v3.AddRegions("persistent", []text.Region{{A: 0, B: 3}}, "comment", "dot", render.PERSISTENT)
+	v3.AddRegions("volatile", []text.Region{{A: 0, B: 3}}, "comment", "", 0)
+	w.SetActiveView(v2)
+
+	if err := ed.SaveSession(); err != nil {
+		t.Fatalf("Expected no error saving the session, but got %s", err)
+	}
+	for _, v := range w.Views() {
+		v.SetScratch(true)
+	}
+	w.Close()
+
+	before := len(ed.Windows())
+	if err := ed.LoadSession(); err != nil {
+		t.Fatalf("Expected no error loading the session, but got %s", err)
+	}
+	windows := ed.Windows()
+	if len(windows) != before+1 {
+		t.Fatalf("Expected %d windows, but got %d", before+1, len(windows))
+	}
+	w = windows[len(windows)-1]
+	defer func() {
+		for _, v := range w.Views() {
+			v.SetScratch(true)
+		}
+		w.Close()
+	}()
+
+	if folders := w.Project().Folders(); !reflect.DeepEqual(folders, []string{dir}) {
+		t.Errorf("Expected the project folders %v, but got %v", []string{dir}, folders)
+	}
+	if n := w.NumGroups(); n != 2 {
+		t.Errorf("Expected 2 groups, but got %d", n)
+	}
+	views := w.Views()
+	if len(views) != 3 {
+		t.Fatalf("Expected 3 views, but got %d", len(views))
+	}
+	if w.ActiveView() != views[1] {
+		t.Errorf("Expected the second view to be active, but got %s", w.ActiveView())
+	}
+
+	tests := []struct {
+		file    string
+		content string
+		dirty   bool
+		group   int
+		sel     []text.Region
+	}{
+		{clean, "file content", false, 0, []text.Region{{A: 2, B: 4}}},
+		{dirty, "new file content", true, 0, []text.Region{{A: 4, B: 4}}},
+		{"", "unsaved text", true, 1, []text.Region{{A: 12, B: 12}}},
+	}
+	for i, test := range tests {
+		v := views[i]
+		if fn := v.FileName(); fn != test.file {
+			t.Errorf("Test %d: Expected the file %q, but got %q", i, test.file, fn)
+		}
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != test.content {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.content, s)
+		}
+		if d := v.IsDirty(); d != test.dirty {
+			t.Errorf("Test %d: Expected dirty %v, but got %v", i, test.dirty, d)
+		}
+		if g, _ := w.GetViewIndex(v); g != test.group {
+			t.Errorf("Test %d: Expected the group %d, but got %d", i, test.group, g)
+		}
+		if sel := v.Sel().Regions(); !reflect.DeepEqual(sel, test.sel) {
+			t.Errorf("Test %d: Expected the selection %v, but got %v", i, test.sel, sel)
+		}
+	}
+
+	if ts := views[1].Settings().Int("tab_size", 4); ts != 2 {
+		t.Errorf("Expected tab_size 2, but got %d", ts)
+	}
+	v3 = views[2]
+	if r := v3.GetRegions("persistent"); !reflect.DeepEqual(r, []text.Region{{A: 0, B: 3}}) {
+		t.Errorf("Expected the persistent regions to be restored, but got %v", r)
+	}
+	if r := v3.GetRegions("volatile"); len(r) != 0 {
+		t.Errorf("Expected the regions which aren't persistent not to be restored, but got %v", r)
+	}
+	v3.UndoStack().Undo(true)
+	if s := v3.Substr(text.Region{A: 0, B: v3.Size()}); s != "unsaved" {
+		t.Errorf("Expected the undo history to be restored, but got %q", s)
+	}
+}
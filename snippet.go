@@ -0,0 +1,891 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jxo/lime/rubex"
+	"github.com/jxo/lime/selector"
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// Snippet is a template inserted by the insert_snippet command, or
+	// in place of its TabTrigger where the Scope selector matches. The
+	// Content can hold fields the user moves between with next_field and
+	// prev_field:
+	//
+	//   $1, ${2:placeholder}  fields, the repeated ones are mirrored
+	//   $0                    where the cursor ends, the end by default
+	//   ${1/regex/format/gi}  the text of field 1 transformed
+	//   $TM_FILENAME, ${TM_SELECTED_TEXT:default}, ${VAR/regex/format/}
+	//                         variables, including the shell variables of
+	//                         the scope
+	Snippet struct {
+		Content     string
+		TabTrigger  string
+		Scope       string
+		Description string
+	}
+
+	// ShellVariables are the variables defined in tmPreferences files for
+	// the scopes matching the Scope selector, snippets refer to them.
+	ShellVariables struct {
+		Scope     string
+		Variables map[string]string
+	}
+
+	// A part of a parsed snippet, either literal text or a field. Fields
+	// without a placeholder mirror the first one having one and fields
+	// with a transform hold the transformed text of the field.
+	snippetPart struct {
+		text           string
+		field          int
+		hasPlaceholder bool
+		placeholder    []snippetPart
+		transform      *snippetTransform
+	}
+
+	// A "regex/format/flags" substitution of a snippet, the format can
+	// refer to the captures as $n or ${n}, change their case with \u, \l,
+	// \U, \L and \E and hold conditionals as (?n:if:else).
+	snippetTransform struct {
+		re     *rubex.Regexp
+		format string
+		global bool
+	}
+
+	snippetParser struct {
+		rs   []rune
+		pos  int
+		vars func(string) (string, bool)
+	}
+
+	// The fields of the snippet being edited in a view. The fields are
+	// in navigation order, the last one being where the cursor ends, and
+	// each holds the regions of its mirrors.
+	snippetFields struct {
+		fields     [][]text.Region
+		transforms []snippetFieldTransform
+		current    int
+	}
+
+	snippetFieldTransform struct {
+		field     int
+		region    text.Region
+		transform *snippetTransform
+	}
+)
+
+func (e *Editor) AddSnippet(path string, s *Snippet) {
+	edl.Lock()
+	defer edl.Unlock()
+	e.snippets[path] = s
+}
+
+// FindSnippet returns the snippet loaded from the file, which can be
+// given relative to the packages directory, i.e
+// "Packages/Go/func.sublime-snippet".
+func (e *Editor) FindSnippet(file string) (Snippet, error) {
+	file = filepath.FromSlash(strings.TrimPrefix(file, "res://"))
+	edl.Lock()
+	defer edl.Unlock()
+	paths := make([]string, 0, len(e.snippets))
+	for p := range e.snippets {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if p == file || strings.HasSuffix(p, string(filepath.Separator)+strings.TrimPrefix(file, "Packages"+string(filepath.Separator))) {
+			return *e.snippets[p], nil
+		}
+	}
+	return Snippet{}, fmt.Errorf("Couldn't find snippet %s", file)
+}
+
+// Returns the snippets which have a tab trigger and which scope matches
+// at the point, sorted by path.
+func (e *Editor) tabTriggerSnippets(v *View, point int) []Snippet {
+	edl.Lock()
+	paths := make([]string, 0, len(e.snippets))
+	for p, s := range e.snippets {
+		if s.TabTrigger != "" {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	snippets := make([]Snippet, len(paths))
+	for i, p := range paths {
+		snippets[i] = *e.snippets[p]
+	}
+	edl.Unlock()
+
+	var ret []Snippet
+	for _, s := range snippets {
+		if s.Scope == "" || v.MatchSelector(point, s.Scope) {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+func (e *Editor) AddShellVariables(path string, s *ShellVariables) {
+	edl.Lock()
+	defer edl.Unlock()
+	e.shellVariables[path] = s
+}
+
+// Returns the shell variables applying to the scope, each variable is
+// taken from the best scoring selector defining it.
+func (e *Editor) resolveShellVariables(scope string) map[string]string {
+	edl.Lock()
+	defer edl.Unlock()
+	ret := make(map[string]string)
+	scores := make(map[string]int)
+	for _, s := range e.shellVariables {
+		score := selector.Score(s.Scope, scope)
+		if score == 0 {
+			continue
+		}
+		for k, val := range s.Variables {
+			if score > scores[k] {
+				ret[k], scores[k] = val, score
+			}
+		}
+	}
+	return ret
+}
+
+// Escapes the text so that it's inserted as is as a snippet.
+func escapeSnippet(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `$`, `\$`).Replace(s)
+}
+
+// Parses the snippet, the variables are expanded with the values vars
+// returns.
+func parseSnippet(s string, vars func(string) (string, bool)) ([]snippetPart, error) {
+	p := &snippetParser{rs: []rune(s), vars: vars}
+	return p.parse(false)
+}
+
+// Parses up to the end of the snippet, or up to the closing brace of the
+// placeholder being parsed.
+func (p *snippetParser) parse(inner bool) ([]snippetPart, error) {
+	var (
+		parts []snippetPart
+		buf   []rune
+	)
+	flush := func() {
+		if len(buf) != 0 {
+			parts = append(parts, snippetPart{text: string(buf), field: -1})
+			buf = nil
+		}
+	}
+	for p.pos < len(p.rs) {
+		c := p.rs[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.rs) && strings.ContainsRune(`\$}`, p.rs[p.pos+1]):
+			buf = append(buf, p.rs[p.pos+1])
+			p.pos += 2
+		case c == '}' && inner:
+			flush()
+			return parts, nil
+		case c == '$':
+			part, ok, err := p.dollar()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				buf = append(buf, c)
+				p.pos++
+				continue
+			}
+			flush()
+			parts = append(parts, part...)
+		default:
+			buf = append(buf, c)
+			p.pos++
+		}
+	}
+	if inner {
+		return nil, fmt.Errorf("Unterminated placeholder in snippet %q", string(p.rs))
+	}
+	flush()
+	return parts, nil
+}
+
+// Reads the identifier, a field number or a variable name, at the
+// current position.
+func (p *snippetParser) name() string {
+	start := p.pos
+	if start < len(p.rs) && unicode.IsDigit(p.rs[start]) {
+		for p.pos < len(p.rs) && unicode.IsDigit(p.rs[p.pos]) {
+			p.pos++
+		}
+	} else {
+		for p.pos < len(p.rs) && (p.rs[p.pos] == '_' || unicode.IsLetter(p.rs[p.pos]) || p.pos > start && unicode.IsDigit(p.rs[p.pos])) {
+			p.pos++
+		}
+	}
+	return string(p.rs[start:p.pos])
+}
+
+// Parses the field or variable starting at the current '$', returns
+// false if it's a literal '$'.
+func (p *snippetParser) dollar() ([]snippetPart, bool, error) {
+	start := p.pos
+	p.pos++
+	braces := p.pos < len(p.rs) && p.rs[p.pos] == '{'
+	if braces {
+		p.pos++
+	}
+	name := p.name()
+	if name == "" {
+		p.pos = start
+		return nil, false, nil
+	}
+	field, err := strconv.Atoi(name)
+	if err != nil {
+		field = -1
+	}
+	if !braces {
+		if field >= 0 {
+			return []snippetPart{{field: field}}, true, nil
+		}
+		val, _ := p.vars(name)
+		return []snippetPart{{text: val, field: -1}}, true, nil
+	}
+	if p.pos >= len(p.rs) {
+		return nil, false, fmt.Errorf("Unterminated field in snippet %q", string(p.rs))
+	}
+	switch p.rs[p.pos] {
+	case '}':
+		p.pos++
+		if field >= 0 {
+			return []snippetPart{{field: field}}, true, nil
+		}
+		val, _ := p.vars(name)
+		return []snippetPart{{text: val, field: -1}}, true, nil
+	case ':':
+		p.pos++
+		placeholder, err := p.parse(true)
+		if err != nil {
+			return nil, false, err
+		}
+		p.pos++
+		if field >= 0 {
+			return []snippetPart{{field: field, hasPlaceholder: true, placeholder: placeholder}}, true, nil
+		}
+		if val, ok := p.vars(name); ok && val != "" {
+			return []snippetPart{{text: val, field: -1}}, true, nil
+		}
+		return placeholder, true, nil
+	case '/':
+		p.pos++
+		t, err := p.transform()
+		if err != nil {
+			return nil, false, err
+		}
+		if field >= 0 {
+			return []snippetPart{{field: field, transform: t}}, true, nil
+		}
+		val, _ := p.vars(name)
+		return []snippetPart{{text: t.apply(val), field: -1}}, true, nil
+	}
+	// not a field after all
+	p.pos = start
+	return nil, false, nil
+}
+
+// Reads the "regex/format/flags}" of a transform.
+func (p *snippetParser) transform() (*snippetTransform, error) {
+	// reads up to the next unescaped c, only unescaping '/'
+	part := func(c rune) (string, bool) {
+		var buf []rune
+		for ; p.pos < len(p.rs); p.pos++ {
+			switch r := p.rs[p.pos]; {
+			case r == '\\' && p.pos+1 < len(p.rs) && p.rs[p.pos+1] == '/':
+				buf = append(buf, '/')
+				p.pos++
+			case r == '\\' && p.pos+1 < len(p.rs):
+				buf = append(buf, r, p.rs[p.pos+1])
+				p.pos++
+			case r == c:
+				p.pos++
+				return string(buf), true
+			default:
+				buf = append(buf, r)
+			}
+		}
+		return string(buf), false
+	}
+	re, ok1 := part('/')
+	format, ok2 := part('/')
+	flags, ok3 := part('}')
+	if !ok1 || !ok2 || !ok3 {
+		return nil, fmt.Errorf("Unterminated transform in snippet %q", string(p.rs))
+	}
+	return newSnippetTransform(re, format, flags)
+}
+
+func newSnippetTransform(re, format, flags string) (*snippetTransform, error) {
+	t := &snippetTransform{format: format, global: strings.Contains(flags, "g")}
+	if strings.Contains(flags, "i") {
+		re = "(?i)" + re
+	}
+	var err error
+	if t.re, err = rubex.Compile(re); err != nil {
+		return nil, fmt.Errorf("Couldn't compile snippet transform regex %s: %s", re, err)
+	}
+	return t, nil
+}
+
+// Replaces the first match in s, or all of them with the g flag, by the
+// format.
+func (t *snippetTransform) apply(s string) string {
+	var buf strings.Builder
+	pos := 0
+	for pos <= len(s) {
+		m := t.re.FindStringSubmatchIndex(s[pos:])
+		if m == nil {
+			break
+		}
+		for i := range m {
+			if m[i] != -1 {
+				m[i] += pos
+			}
+		}
+		buf.WriteString(s[pos:m[0]])
+		buf.WriteString(t.expand(s, m, t.format))
+		if m[1] == m[0] {
+			// an empty match, move on a character
+			if m[1] < len(s) {
+				buf.WriteByte(s[m[1]])
+			}
+			pos = m[1] + 1
+		} else {
+			pos = m[1]
+		}
+		if !t.global {
+			break
+		}
+	}
+	if pos < len(s) {
+		buf.WriteString(s[pos:])
+	}
+	return buf.String()
+}
+
+// Writes the expansion of a replacement format, changing the case of the
+// text written as the \u, \l, \U, \L and \E escapes of the format say.
+type formatWriter struct {
+	buf      []rune
+	caseMode rune // 'U' or 'L' until \E
+	next     rune // 'u' or 'l' for the next character
+}
+
+func (w *formatWriter) write(s string) {
+	for _, c := range s {
+		switch {
+		case w.next == 'u':
+			c = unicode.ToUpper(c)
+		case w.next == 'l':
+			c = unicode.ToLower(c)
+		case w.caseMode == 'U':
+			c = unicode.ToUpper(c)
+		case w.caseMode == 'L':
+			c = unicode.ToLower(c)
+		}
+		w.next = 0
+		w.buf = append(w.buf, c)
+	}
+}
+
+// Handles the character escaped by a backslash in the format, returns
+// false if it's not one of the escapes the formats share.
+func (w *formatWriter) escape(c rune) bool {
+	switch c {
+	case 'n':
+		w.write("\n")
+	case 't':
+		w.write("\t")
+	case 'u', 'l':
+		w.next = c
+	case 'U', 'L':
+		w.caseMode = c
+	case 'E':
+		w.caseMode = 0
+	default:
+		return false
+	}
+	return true
+}
+
+func (w *formatWriter) String() string {
+	return string(w.buf)
+}
+
+// Returns the format with the captures of the match m in s expanded.
+func (t *snippetTransform) expand(s string, m []int, format string) string {
+	var (
+		w formatWriter
+		f = []rune(format)
+	)
+	capture := func(n int) string {
+		if 2*n+1 < len(m) && m[2*n] != -1 {
+			return s[m[2*n]:m[2*n+1]]
+		}
+		return ""
+	}
+	// reads up to the next unescaped c at the current nesting level
+	until := func(i int, c rune) (string, int) {
+		depth := 0
+		for j := i; j < len(f); j++ {
+			switch {
+			case f[j] == '\\' && j+1 < len(f):
+				j++
+			case f[j] == '(':
+				depth++
+			case f[j] == ')' && depth > 0:
+				depth--
+			case f[j] == c && depth == 0:
+				return string(f[i:j]), j
+			}
+		}
+		return string(f[i:]), len(f)
+	}
+	for i := 0; i < len(f); i++ {
+		switch c := f[i]; {
+		case c == '\\' && i+1 < len(f):
+			i++
+			if !w.escape(f[i]) {
+				w.write(string(f[i]))
+			}
+		case c == '$' && i+1 < len(f):
+			j, end := i+1, i+1
+			if f[j] == '{' {
+				j++
+			}
+			k := j
+			for k < len(f) && unicode.IsDigit(f[k]) {
+				k++
+			}
+			n, err := strconv.Atoi(string(f[j:k]))
+			if err != nil || j != i+1 && (k >= len(f) || f[k] != '}') {
+				w.write(string(c))
+				continue
+			}
+			end = k
+			if j != i+1 {
+				end++
+			}
+			w.write(capture(n))
+			i = end - 1
+		case c == '(' && i+2 < len(f) && f[i+1] == '?' && unicode.IsDigit(f[i+2]):
+			// (?n:if:else)
+			k := i + 2
+			for k < len(f) && unicode.IsDigit(f[k]) {
+				k++
+			}
+			if k >= len(f) || f[k] != ':' {
+				w.write(string(c))
+				continue
+			}
+			n, _ := strconv.Atoi(string(f[i+2 : k]))
+			ifPart, j := until(k+1, ':')
+			elsePart := ""
+			if j < len(f) && f[j] == ':' {
+				elsePart, j = until(j+1, ')')
+			} else if j < len(f) {
+				ifPart, j = until(k+1, ')')
+			}
+			if capture(n) != "" {
+				w.write(t.expand(s, m, ifPart))
+			} else {
+				w.write(t.expand(s, m, elsePart))
+			}
+			i = j
+		default:
+			w.write(string(c))
+		}
+	}
+	return w.String()
+}
+
+// Returns the plain text of the parts, the mirrors hold the text of the
+// placeholder of their field.
+func snippetText(parts []snippetPart, placeholders map[int][]snippetPart, depth int) string {
+	if depth > 8 {
+		return ""
+	}
+	var buf strings.Builder
+	for _, p := range parts {
+		switch {
+		case p.field < 0:
+			buf.WriteString(p.text)
+		case p.transform != nil:
+			buf.WriteString(p.transform.apply(snippetText(placeholders[p.field], placeholders, depth+1)))
+		case p.hasPlaceholder:
+			buf.WriteString(snippetText(p.placeholder, placeholders, depth+1))
+		default:
+			buf.WriteString(snippetText(placeholders[p.field], placeholders, depth+1))
+		}
+	}
+	return buf.String()
+}
+
+// Collects the placeholder of each field, the first one of the field.
+func snippetPlaceholders(parts []snippetPart, placeholders map[int][]snippetPart) {
+	for _, p := range parts {
+		if p.field < 0 || !p.hasPlaceholder {
+			continue
+		}
+		if _, ok := placeholders[p.field]; !ok {
+			placeholders[p.field] = p.placeholder
+		}
+		snippetPlaceholders(p.placeholder, placeholders)
+	}
+}
+
+// Renders a parsed snippet, writing the lines after the first one with
+// the indentation and the tabs as indent.
+type snippetRenderer struct {
+	buf          []rune
+	indent, tab  string
+	placeholders map[int][]snippetPart
+	fields       map[int][]text.Region
+	transforms   []snippetFieldTransform
+}
+
+func (r *snippetRenderer) write(s string) {
+	for _, c := range s {
+		switch c {
+		case '\n':
+			r.buf = append(r.buf, '\n')
+			r.buf = append(r.buf, []rune(r.indent)...)
+		case '\t':
+			r.buf = append(r.buf, []rune(r.tab)...)
+		default:
+			r.buf = append(r.buf, c)
+		}
+	}
+}
+
+func (r *snippetRenderer) render(parts []snippetPart) {
+	for _, p := range parts {
+		start := len(r.buf)
+		switch {
+		case p.field < 0:
+			r.write(p.text)
+			continue
+		case p.transform != nil:
+			r.write(p.transform.apply(snippetText(r.placeholders[p.field], r.placeholders, 0)))
+			r.transforms = append(r.transforms, snippetFieldTransform{
+				field:     p.field,
+				region:    text.Region{A: start, B: len(r.buf)},
+				transform: p.transform,
+			})
+			continue
+		case p.hasPlaceholder:
+			r.render(p.placeholder)
+		default:
+			r.write(snippetText(r.placeholders[p.field], r.placeholders, 0))
+		}
+		r.fields[p.field] = append(r.fields[p.field], text.Region{A: start, B: len(r.buf)})
+	}
+}
+
+// Returns the line of the point, which Line doesn't at the end of the
+// buffer.
+func (v *View) snippetLine(point int) text.Region {
+	row, _ := v.RowCol(point)
+	return v.Line(v.TextPoint(row, 0))
+}
+
+// Returns the value of the snippet variable at the region of the view.
+func (v *View) snippetVariable(r text.Region, name string) (string, bool) {
+	switch name {
+	case "TM_FILENAME":
+		return filepath.Base(v.FileName()), v.FileName() != ""
+	case "TM_FILEPATH":
+		return v.FileName(), v.FileName() != ""
+	case "TM_DIRECTORY":
+		return filepath.Dir(v.FileName()), v.FileName() != ""
+	case "TM_FULLNAME":
+		u := os.Getenv("USER")
+		return u, u != ""
+	case "TM_LINE_INDEX":
+		_, col := v.RowCol(r.Begin())
+		return strconv.Itoa(col), true
+	case "TM_LINE_NUMBER":
+		row, _ := v.RowCol(r.Begin())
+		return strconv.Itoa(row + 1), true
+	case "TM_CURRENT_LINE":
+		return v.Substr(v.snippetLine(r.Begin())), true
+	case "TM_CURRENT_WORD":
+		return v.Substr(v.Word(r.Begin())), true
+	case "TM_SELECTED_TEXT", "SELECTION":
+		return v.Substr(r), !r.Empty()
+	case "TM_SOFT_TABS":
+		if v.Settings().Bool("translate_tabs_to_spaces", false) {
+			return "YES", true
+		}
+		return "NO", true
+	case "TM_TAB_SIZE":
+		return strconv.Itoa(v.Settings().Int("tab_size", 4)), true
+	}
+	val, ok := GetEditor().resolveShellVariables(v.ScopeName(r.Begin()))[name]
+	return val, ok
+}
+
+// InsertSnippet inserts the snippet in place of every selection and
+// selects its first field, the fields of all the selections being
+// edited together. The cursors are moved to the end of the snippet
+// when it has no fields.
+func (v *View) InsertSnippet(edit *Edit, contents string) error {
+	v.lock.Lock()
+	v.snippet = nil
+	v.lock.Unlock()
+
+	tab := "\t"
+	if v.Settings().Bool("translate_tabs_to_spaces", false) {
+		tab = strings.Repeat(" ", v.Settings().Int("tab_size", 4))
+	}
+	var (
+		fields     = make(map[int][]text.Region)
+		transforms []snippetFieldTransform
+		exits      []text.Region
+		delta      int
+	)
+	for _, r := range v.Sel().Regions() {
+		r = text.Region{A: r.Begin() + delta, B: r.End() + delta}
+		parts, err := parseSnippet(contents, func(name string) (string, bool) {
+			return v.snippetVariable(r, name)
+		})
+		if err != nil {
+			return err
+		}
+		line := v.Substr(text.Region{A: v.snippetLine(r.Begin()).A, B: r.Begin()})
+		rr := &snippetRenderer{
+			indent:       line[:len(line)-len(strings.TrimLeft(line, " \t"))],
+			tab:          tab,
+			placeholders: make(map[int][]snippetPart),
+			fields:       make(map[int][]text.Region),
+		}
+		snippetPlaceholders(parts, rr.placeholders)
+		rr.render(parts)
+		if !v.Replace(edit, r, string(rr.buf)) {
+			continue
+		}
+		delta += len(rr.buf) - r.Size()
+
+		offset := func(fr text.Region) text.Region {
+			return text.Region{A: fr.A + r.Begin(), B: fr.B + r.Begin()}
+		}
+		for n, rs := range rr.fields {
+			for _, fr := range rs {
+				fields[n] = append(fields[n], offset(fr))
+			}
+		}
+		for _, t := range rr.transforms {
+			t.region = offset(t.region)
+			transforms = append(transforms, t)
+		}
+		if _, ok := rr.fields[0]; !ok {
+			exits = append(exits, text.Region{A: r.Begin() + len(rr.buf), B: r.Begin() + len(rr.buf)})
+		}
+	}
+
+	var numbers []int
+	for n := range fields {
+		if n != 0 {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	s := &snippetFields{}
+	index := make(map[int]int)
+	for _, n := range numbers {
+		index[n] = len(s.fields)
+		s.fields = append(s.fields, fields[n])
+	}
+	exits = append(exits, fields[0]...)
+	index[0] = len(s.fields)
+	s.fields = append(s.fields, exits)
+	for _, t := range transforms {
+		if i, ok := index[t.field]; ok {
+			t.field = i
+			s.transforms = append(s.transforms, t)
+		}
+	}
+	if len(s.fields) > 1 {
+		v.lock.Lock()
+		v.snippet = s
+		v.lock.Unlock()
+	}
+	v.selectField(s.fields[0])
+	return nil
+}
+
+func (v *View) selectField(rs []text.Region) {
+	if len(rs) == 0 {
+		return
+	}
+	v.Sel().Clear()
+	v.Sel().AddAll(rs)
+}
+
+// HasNextField returns whether the snippet being edited has a field
+// after the current one.
+func (v *View) HasNextField() bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.snippet != nil && v.snippet.current < len(v.snippet.fields)-1
+}
+
+// HasPrevField returns whether the snippet being edited has a field
+// before the current one.
+func (v *View) HasPrevField() bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.snippet != nil && v.snippet.current > 0
+}
+
+// NextField selects the next field of the snippet being edited, the
+// snippet is done once the last one is reached.
+func (v *View) NextField(edit *Edit) {
+	v.moveField(edit, 1)
+}
+
+// PrevField selects the previous field of the snippet being edited.
+func (v *View) PrevField(edit *Edit) {
+	v.moveField(edit, -1)
+}
+
+// ClearFields stops editing the fields of the snippet, leaving the
+// selection as is.
+func (v *View) ClearFields() {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.snippet = nil
+}
+
+func (v *View) moveField(edit *Edit, dir int) {
+	v.lock.Lock()
+	s := v.snippet
+	v.lock.Unlock()
+	if s == nil {
+		return
+	}
+	v.updateTransforms(edit, s)
+
+	v.lock.Lock()
+	i := s.current + dir
+	if i < 0 || i >= len(s.fields) {
+		v.lock.Unlock()
+		return
+	}
+	s.current = i
+	rs := append([]text.Region(nil), s.fields[i]...)
+	if i == len(s.fields)-1 {
+		v.snippet = nil
+	}
+	v.lock.Unlock()
+	v.selectField(rs)
+}
+
+// Updates the transforms of the current field with its text.
+func (v *View) updateTransforms(edit *Edit, s *snippetFields) {
+	for i := range s.transforms {
+		v.lock.Lock()
+		t := s.transforms[i]
+		if t.field != s.current || len(s.fields[t.field]) == 0 {
+			v.lock.Unlock()
+			continue
+		}
+		src := s.fields[t.field][0]
+		v.lock.Unlock()
+
+		val := t.transform.apply(v.Substr(src))
+		if val == v.Substr(t.region) {
+			continue
+		}
+		if !v.Replace(edit, t.region, val) {
+			continue
+		}
+		v.lock.Lock()
+		s.transforms[i].region = text.Region{A: t.region.A, B: t.region.A + len([]rune(val))}
+		v.lock.Unlock()
+	}
+}
+
+// Adjusts the regions of the fields to the change of the buffer, the
+// text inserted at the current field goes into it.
+func (s *snippetFields) adjust(position, delta int) {
+	for i := range s.fields {
+		for j := range s.fields[i] {
+			adjustField(&s.fields[i][j], position, delta, i == s.current)
+		}
+	}
+	for i := range s.transforms {
+		adjustField(&s.transforms[i].region, position, delta, false)
+	}
+}
+
+func adjustField(r *text.Region, position, delta int, grow bool) {
+	if delta < 0 {
+		r.Adjust(position, delta)
+		return
+	}
+	switch {
+	case r.A > position || r.A == position && !grow:
+		r.A += delta
+		r.B += delta
+	case r.B > position || r.B == position && grow:
+		r.B += delta
+	}
+}
+
+// TabTrigger returns the snippet which tab trigger was typed right
+// before the point and the region of the trigger. The snippets of the
+// best matching scope are preferred, then the longest trigger.
+func (v *View) TabTrigger(point int) (Snippet, text.Region, bool) {
+	var (
+		best      Snippet
+		bestR     text.Region
+		bestScore = -1
+		found     bool
+		isWord    = v.wordRunes()
+		scope     = v.ScopeName(typedPoint(point))
+	)
+	for _, s := range GetEditor().tabTriggerSnippets(v, typedPoint(point)) {
+		trigger := []rune(s.TabTrigger)
+		r := text.Region{A: point - len(trigger), B: point}
+		if r.A < 0 || v.Substr(r) != s.TabTrigger {
+			continue
+		}
+		// the trigger has to be a whole word
+		if r.A > 0 && isWord(trigger[0]) {
+			if rs := v.SubstrR(text.Region{A: r.A - 1, B: r.A}); len(rs) != 0 && isWord(rs[0]) {
+				continue
+			}
+		}
+		score := 0
+		if s.Scope != "" {
+			score = selector.Score(s.Scope, scope)
+		}
+		if score > bestScore || score == bestScore && r.Size() > bestR.Size() {
+			best, bestR, bestScore, found = s, r, score, true
+		}
+	}
+	return best, bestR, found
+}
@@ -0,0 +1,316 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jxo/lime/text"
+	"github.com/jxo/lime/util"
+)
+
+func TestSnippetTransform(t *testing.T) {
+	tests := []struct {
+		re, format, flags string
+		in, exp           string
+	}{
+		{"(.)", `\u$1`, "", "foo bar", "Foo bar"},
+		{"o", "0", "g", "foo boo", "f00 b00"},
+		{"O", "0", "", "foo", "foo"},
+		{"O", "0", "i", "foo", "f0o"},
+		{`(\w+) (\w+)`, `${2}_\U$1\E!`, "", "hello world", "world_HELLO!"},
+		{"^(a)?b", "(?1:yes:no)", "", "ab b", "yes b"},
+		{"^(a)?b", "(?1:yes:no)", "", "b ab", "no ab"},
+		{"x*", "-", "g", "ab", "-a-b-"},
+	}
+	for i, test := range tests {
+		tr, err := newSnippetTransform(test.re, test.format, test.flags)
+		if err != nil {
+			t.Fatalf("Test %d: %s", i, err)
+		}
+		if s := tr.apply(test.in); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+	}
+}
+
+func TestInsertSnippet(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	tests := []struct {
+		text     string
+		sel      []text.Region
+		contents string
+		exp      string
+		expSel   []text.Region
+	}{
+		{
+			"  ",
+			[]text.Region{{A: 2, B: 2}},
+			"for ${1:i} := 0; $1 < ${2:n}; $1++ {\n\t$0\n}",
+			"  for i := 0; i < n; i++ {\n  \t\n  }",
+			[]text.Region{{A: 6, B: 7}, {A: 14, B: 15}, {A: 21, B: 22}},
+		},
+		{
+			"x",
+			[]text.Region{{A: 1, B: 1}},
+			`\$1 \\ \} $ $$`,
+			`x$1 \ } $ $$`,
+			[]text.Region{{A: 12, B: 12}},
+		},
+		{
+			"a\nb",
+			[]text.Region{{A: 0, B: 1}, {A: 2, B: 3}},
+			"(${1:${SELECTION}})$0",
+			"(a)\n(b)",
+			[]text.Region{{A: 1, B: 2}, {A: 5, B: 6}},
+		},
+		{
+			"",
+			[]text.Region{{A: 0, B: 0}},
+			"${1:outer ${2:inner}} ${UNDEFINED:default}$UNDEFINED",
+			"outer inner default",
+			[]text.Region{{A: 0, B: 11}},
+		},
+		{
+			"",
+			[]text.Region{{A: 0, B: 0}},
+			"${1:foo} ${1/(.)/\\u$1/}",
+			"foo Foo",
+			[]text.Region{{A: 0, B: 3}},
+		},
+	}
+	for i, test := range tests {
+		v := w.NewFile()
+		e := v.BeginEdit()
+		v.Insert(e, 0, test.text)
+		v.EndEdit(e)
+		v.Sel().Clear()
+		v.Sel().AddAll(test.sel)
+
+		e = v.BeginEdit()
+		if err := v.InsertSnippet(e, test.contents); err != nil {
+			t.Errorf("Test %d: %s", i, err)
+		}
+		v.EndEdit(e)
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+		if sel := v.Sel().Regions(); !reflect.DeepEqual(sel, test.expSel) {
+			t.Errorf("Test %d: Expected selection %v, but got %v", i, test.expSel, sel)
+		}
+		v.SetScratch(true)
+		v.Close()
+	}
+
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	if err := v.InsertSnippet(e, "${1:unterminated"); err == nil {
+		t.Error("Expected an error for an unterminated field")
+	}
+	v.EndEdit(e)
+}
+
+// Replaces the selections like typing in the view does.
+func typeInView(v *View, s string) {
+	e := v.BeginEdit()
+	for i := 0; i < v.Sel().Len(); i++ {
+		v.Replace(e, v.Sel().Get(i), s)
+	}
+	v.EndEdit(e)
+}
+
+func TestSnippetFields(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	e := v.BeginEdit()
+	v.InsertSnippet(e, "(${1:a}, ${2:b} $1)$0 ${1/.*/\\U$0/}")
+	v.EndEdit(e)
+
+	checkSel := func(exp ...text.Region) {
+		t.Helper()
+		if sel := v.Sel().Regions(); !reflect.DeepEqual(sel, exp) {
+			t.Errorf("Expected selection %v, but got %v", exp, sel)
+		}
+	}
+	checkText := func(exp string) {
+		t.Helper()
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != exp {
+			t.Errorf("Expected %q, but got %q", exp, s)
+		}
+	}
+	checkText("(a, b a) A")
+	checkSel(text.Region{A: 1, B: 2}, text.Region{A: 6, B: 7})
+	if !v.HasNextField() || v.HasPrevField() {
+		t.Error("Expected only a next field")
+	}
+	if r := OnQueryContext.Call(v, "has_next_field", util.OpEqual, true, false); r != True {
+		t.Errorf("Expected the has_next_field context to hold, but got %v", r)
+	}
+
+	// the mirrors are typed in together and the fields grow
+	typeInView(v, "xy")
+	checkText("(xy, b xy) A")
+	typeInView(v, "z")
+	checkText("(xyz, b xyz) A")
+	checkSel(text.Region{A: 4, B: 4}, text.Region{A: 11, B: 11})
+
+	e = v.BeginEdit()
+	v.NextField(e)
+	v.EndEdit(e)
+	checkText("(xyz, b xyz) XYZ")
+	checkSel(text.Region{A: 6, B: 7})
+	if !v.HasPrevField() {
+		t.Error("Expected a previous field")
+	}
+
+	e = v.BeginEdit()
+	v.PrevField(e)
+	v.EndEdit(e)
+	checkSel(text.Region{A: 1, B: 4}, text.Region{A: 8, B: 11})
+
+	e = v.BeginEdit()
+	v.NextField(e)
+	v.NextField(e)
+	v.EndEdit(e)
+	checkSel(text.Region{A: 12, B: 12})
+	if v.HasNextField() || v.HasPrevField() {
+		t.Error("Expected the fields to be done once the exit is reached")
+	}
+	if r := OnQueryContext.Call(v, "has_next_field", util.OpEqual, false, false); r != True {
+		t.Errorf("Expected the has_next_field context not to hold, but got %v", r)
+	}
+}
+
+func TestSnippetVariables(t *testing.T) {
+	ed := GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	addSetSyntax(t, v.Settings(), "testdata/Symbols.tmLanguage")
+	e := v.BeginEdit()
+	v.Insert(e, 0, "def first\n")
+	v.EndEdit(e)
+	for start := time.Now(); v.Settings().Int("lime.syntax.updated", -1) != v.ChangeCount(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Waiting for 'lime.syntax.updated' took too long")
+		}
+	}
+	v.SetFileName("/tmp/test.sym")
+	ed.AddShellVariables("test.tmPreferences", &ShellVariables{
+		Scope:     "source.symbols",
+		Variables: map[string]string{"TM_COMMENT_START": "# "},
+	})
+	ed.AddShellVariables("test other.tmPreferences", &ShellVariables{
+		Scope:     "source.symbols entity.name.function",
+		Variables: map[string]string{"TM_COMMENT_START": "## "},
+	})
+	defer func() {
+		edl.Lock()
+		delete(ed.shellVariables, "test.tmPreferences")
+		delete(ed.shellVariables, "test other.tmPreferences")
+		edl.Unlock()
+	}()
+
+	tests := []struct {
+		point int
+		name  string
+		exp   string
+	}{
+		{0, "TM_COMMENT_START", "# "},
+		{5, "TM_COMMENT_START", "## "},
+		{5, "TM_FILENAME", "test.sym"},
+		{5, "TM_DIRECTORY", "/tmp"},
+		{5, "TM_LINE_NUMBER", "1"},
+		{5, "TM_LINE_INDEX", "5"},
+		{6, "TM_CURRENT_WORD", "first"},
+		{5, "TM_CURRENT_LINE", "def first"},
+		{10, "TM_LINE_NUMBER", "2"},
+		{5, "TM_TAB_SIZE", "4"},
+	}
+	for i, test := range tests {
+		if s, ok := v.snippetVariable(text.Region{A: test.point, B: test.point}, test.name); !ok || s != test.exp {
+			t.Errorf("Test %d: Expected %s to be %q, but got %q", i, test.name, test.exp, s)
+		}
+	}
+	if _, ok := v.snippetVariable(text.Region{}, "TM_SELECTED_TEXT"); ok {
+		t.Error("Expected no selected text for an empty selection")
+	}
+}
+
+func TestTabTrigger(t *testing.T) {
+	ed := GetEditor()
+	w := ed.NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	addSetSyntax(t, v.Settings(), "testdata/Symbols.tmLanguage")
+	e := v.BeginEdit()
+	v.Insert(e, 0, "def fun xdef de")
+	v.EndEdit(e)
+	for start := time.Now(); v.Settings().Int("lime.syntax.updated", -1) != v.ChangeCount(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Waiting for 'lime.syntax.updated' took too long")
+		}
+	}
+	snippets := map[string]*Snippet{
+		"test def.sublime-snippet":      {Content: "def", TabTrigger: "def", Scope: "source.symbols"},
+		"test def func.sublime-snippet": {Content: "def func", TabTrigger: "def", Scope: "source.symbols storage.type"},
+		"test de.sublime-snippet":       {Content: "de", TabTrigger: "de", Scope: "text"},
+		"test fun.sublime-snippet":      {Content: "fun", TabTrigger: "fun"},
+	}
+	for p, s := range snippets {
+		ed.AddSnippet(p, s)
+	}
+	defer func() {
+		edl.Lock()
+		for p := range snippets {
+			delete(ed.snippets, p)
+		}
+		edl.Unlock()
+	}()
+
+	tests := []struct {
+		point   int
+		exp     string
+		found   bool
+		trigger text.Region
+	}{
+		{3, "def func", true, text.Region{A: 0, B: 3}},
+		{7, "fun", true, text.Region{A: 4, B: 7}},
+		{12, "", false, text.Region{}},
+		{15, "", false, text.Region{}},
+		{2, "", false, text.Region{}},
+	}
+	for i, test := range tests {
+		s, r, ok := v.TabTrigger(test.point)
+		if ok != test.found || s.Content != test.exp || r != test.trigger {
+			t.Errorf("Test %d: Expected %q at %v, but got %q at %v (%v)", i, test.exp, test.trigger, s.Content, r, ok)
+		}
+	}
+
+	if _, err := ed.FindSnippet("Packages/Test/none.sublime-snippet"); err == nil {
+		t.Error("Expected an error for a snippet not loaded")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"reflect"
 
 	"github.com/jxo/lime"
+	"github.com/jxo/lime/log"
 	"github.com/jxo/lime/text"
 	"github.com/jxo/lime/util"
 	"github.com/limetext/gopy"
@@ -246,3 +247,42 @@ func fromPython(r py.Object) (interface{}, error) {
 		return nil, fmt.Errorf("Cannot convert type %s from python to go", r.Type())
 	}
 }
+
+// Returns a go function calling the python callable cb with the
+// arguments converted to python, nil if cb is None. The reference kept
+// on cb is dropped by calling release once the callback isn't needed.
+func pyCallback(cb py.Object) (call func(args ...interface{}), release func()) {
+	if _, ok := cb.(*py.NoneObject); ok || cb == nil {
+		return nil, func() {}
+	}
+	cb.Incref()
+	release = func() {
+		l := py.NewLock()
+		defer l.Unlock()
+		cb.Decref()
+	}
+	call = func(args ...interface{}) {
+		l := py.NewLock()
+		defer l.Unlock()
+		pyargs := make([]py.Object, 0, len(args))
+		defer func() {
+			for _, a := range pyargs {
+				a.Decref()
+			}
+		}()
+		for _, a := range args {
+			pa, err := toPython(a)
+			if err != nil {
+				log.Error(err)
+				return
+			}
+			pyargs = append(pyargs, pa)
+		}
+		if ret, err := cb.Base().CallFunctionObjArgs(pyargs...); err != nil {
+			log.Error("Error in callback: %v", err)
+		} else if ret != nil {
+			ret.Decref()
+		}
+	}
+	return call, release
+}
@@ -24,6 +24,10 @@ var (
 		Name:    "sublime.OnQueryContextGlue",
 		Pointer: (*OnQueryContextGlue)(nil),
 	}
+	_onQueryCompletionsGlueClass = py.Class{
+		Name:    "sublime.OnQueryCompletionsGlue",
+		Pointer: (*OnQueryCompletionsGlue)(nil),
+	}
 	_viewEventGlueClass = py.Class{
 		Name:    "sublime.ViewEventGlue",
 		Pointer: (*ViewEventGlue)(nil),
@@ -35,6 +39,10 @@ type (
 		py.BaseObject
 		inner py.Object
 	}
+	OnQueryCompletionsGlue struct {
+		py.BaseObject
+		inner py.Object
+	}
 	ViewEventGlue struct {
 		py.BaseObject
 		inner py.Object
@@ -167,3 +175,120 @@ func (c *OnQueryContextGlue) onQueryContext(v *lime.View, key string, operator u
 	}
 	return lime.Unknown
 }
+
+func (c *OnQueryCompletionsGlue) PyInit(args *py.Tuple, kwds *py.Dict) error {
+	if args.Size() != 1 {
+		return fmt.Errorf("Expected only 1 argument not %d", args.Size())
+	}
+	if v, err := args.GetItem(0); err != nil {
+		return err
+	} else {
+		c.inner = v
+	}
+	c.inner.Incref()
+	c.Incref()
+
+	lime.OnQueryCompletions.Add(c.onQueryCompletions)
+	return nil
+}
+
+func (c *OnQueryCompletionsGlue) onQueryCompletions(v *lime.View, prefix string, locations []int) ([]lime.Completion, int) {
+	l := py.NewLock()
+	defer l.Unlock()
+
+	var (
+		pv, pp, pl, ret py.Object
+		err             error
+	)
+	if pv, err = toPython(v); err != nil {
+		log.Error(err)
+		return nil, 0
+	}
+	defer pv.Decref()
+
+	if pp, err = toPython(prefix); err != nil {
+		log.Error(err)
+		return nil, 0
+	}
+	defer pp.Decref()
+
+	if pl, err = toPython(locations); err != nil {
+		log.Error(err)
+		return nil, 0
+	}
+	defer pl.Decref()
+
+	if ret, err = c.inner.Base().CallFunctionObjArgs(pv, pp, pl); err != nil {
+		log.Error(err)
+		return nil, 0
+	}
+	defer ret.Decref()
+
+	r, err := fromPython(ret)
+	if err != nil {
+		log.Error(err)
+		return nil, 0
+	}
+	cs, flags, err := completionsFromPython(r)
+	if err != nil {
+		log.Error(err)
+	}
+	return cs, flags
+}
+
+// Converts the value returned by on_query_completions, either a list
+// of [trigger, contents] pairs or a tuple of that list and the flags.
+func completionsFromPython(r interface{}) ([]lime.Completion, int, error) {
+	var (
+		items []interface{}
+		flags int
+	)
+	switch t := r.(type) {
+	case nil:
+		return nil, 0, nil
+	case List:
+		items = t
+	case Tuple:
+		if len(t) != 2 {
+			return nil, 0, fmt.Errorf("Expected a tuple of the completions and flags, not %v", t)
+		}
+		switch l := t[0].(type) {
+		case List:
+			items = l
+		case Tuple:
+			items = l
+		default:
+			return nil, 0, fmt.Errorf("Expected a list of completions, not %v", t[0])
+		}
+		f, ok := t[1].(int)
+		if !ok {
+			return nil, 0, fmt.Errorf("Expected integer completion flags, not %v", t[1])
+		}
+		flags = f
+	default:
+		return nil, 0, fmt.Errorf("Expected a list of completions, not %v", r)
+	}
+
+	var cs []lime.Completion
+	for _, it := range items {
+		var pair []interface{}
+		switch p := it.(type) {
+		case List:
+			pair = p
+		case Tuple:
+			pair = p
+		case string:
+			pair = []interface{}{p, p}
+		}
+		if len(pair) != 2 {
+			return nil, 0, fmt.Errorf("Expected a [trigger, contents] completion, not %v", it)
+		}
+		trigger, ok1 := pair[0].(string)
+		contents, ok2 := pair[1].(string)
+		if !ok1 || !ok2 {
+			return nil, 0, fmt.Errorf("Expected a [trigger, contents] completion, not %v", it)
+		}
+		cs = append(cs, lime.NewCompletion(trigger, contents))
+	}
+	return cs, flags, nil
+}
@@ -417,8 +417,8 @@ func main() {
 		{path.Join(sublimepath, "region_generated.go"), generateWrapper(reflect.TypeOf(text.Region{}), true, regexp.MustCompile("Cut|Clip|Covers").MatchString)},
 		{path.Join(sublimepath, "regionset_generated.go"), generateWrapper(reflect.TypeOf(&text.RegionSet{}), false, regexp.MustCompile("Less|Swap|Adjust|Has|Cut|Regions").MatchString)},
 		{path.Join(sublimepath, "edit_generated.go"), generateWrapper(reflect.TypeOf(&lime.Edit{}), false, regexp.MustCompile("Apply|Undo").MatchString)},
-		{path.Join(sublimepath, "view_generated.go"), generateWrapper(reflect.TypeOf(&lime.View{}), false, regexp.MustCompile("Buffer|Syntax|CommandHistory|Show|AddRegions|UndoStack|Transform|Reload|Save|Close|ExpandByClass|Erased|FileChanged|Inserted|Find$|^Status|Word|Line|Substr|FullLine|ChangeCount|FileName|^Name|RowCol|SetName|Size|TextPoint|AddObserver").MatchString)},
-		{path.Join(sublimepath, "window_generated.go"), generateWrapper(reflect.TypeOf(&lime.Window{}), false, regexp.MustCompile("OpenFile|SetActiveView|Close|Project$").MatchString)},
+		{path.Join(sublimepath, "view_generated.go"), generateWrapper(reflect.TypeOf(&lime.View{}), false, regexp.MustCompile("^Fold$|^Unfold|FoldBy|Buffer|Syntax|CommandHistory|Show|AddRegions|Symbols$|MatchSelector|FindBySelector|Completion|AutoComplete|Snippet|Field|TabTrigger|UndoStack|Encoding|Reopen|IsLoading|ReadOnly|Transform|Reload|Save|Close|ExpandByClass|Erased|FileChanged|Inserted|Find$|FindAll|FindReplace|WhenLoaded|^Status|Word|Line|Substr|FullLine|ChangeCount|FileName|^Name|RowCol|SetName|Size|TextPoint|AddObserver").MatchString)},
+		{path.Join(sublimepath, "window_generated.go"), generateWrapper(reflect.TypeOf(&lime.Window{}), false, regexp.MustCompile("^OpenFile|SetActiveView|Close|Project$|Layout$|ViewIndex|MergeGroup|^Find|CreateOutputPanel|ActivePanel|^Show|HidePanel|ExtractVariables|BuildSystems|LookupSymbol|Overlay$|Macro").MatchString)},
 		{path.Join(sublimepath, "settings_generated.go"), generateWrapper(reflect.TypeOf(&util.Settings{}), false, regexp.MustCompile("Parent|Set|Get|UnmarshalJSON|MarshalJSON|Int|Bool|String|ID").MatchString)},
 		{path.Join(sublimepath, "view_buffer_generated.go"), generateMethodsEx(
 			reflect.TypeOf(text.NewBuffer()),
@@ -442,7 +442,7 @@ func main() {
 			sn),
 		},
 		{path.Join(sublimepath, "sublime_generated.go"), generateMethodsEx(reflect.TypeOf(lime.GetEditor()),
-			regexp.MustCompile("Info|HandleInput|CommandHandler|Console|Frontend|SetActiveWindow|Init|Watch|Observe|SetClipboardFuncs|DefaultPath|UserPath|AddPackagesPath|RemovePackagesPath|KeyBindings|ColorScheme|Syntax|[lL]ock$|Settings|^Plat$|NewWindow|Close|^Clipboard$|UseClipboard").MatchString,
+			regexp.MustCompile("Info|HandleInput|CommandHandler|Console|Frontend|SetActiveWindow|Init|Watch|Observe|SetClipboardFuncs|BuildSystem|Palette|Symbol|DefaultPath|UserPath|LocalPath|Session$|^Exit$|Completions|Snippet|ShellVariables|AddPackagesPath|RemovePackagesPath|KeyBinding|Macro|ColorScheme|Syntax|[lL]ock$|Settings|^Plat$|NewWindow|Close|^Clipboard$|UseClipboard").MatchString,
 			"lime.GetEditor().",
 			sn),
 		},
@@ -28,6 +28,7 @@ var classes = []struct {
 	{"TextCommandGlue", &_textCommandGlueClass},
 	{"ApplicationCommandGlue", &_applicationCommandGlueClass},
 	{"OnQueryContextGlue", &_onQueryContextGlueClass},
+	{"OnQueryCompletionsGlue", &_onQueryCompletionsGlueClass},
 	{"ViewEventGlue", &_viewEventGlueClass},
 }
 
@@ -41,8 +42,8 @@ var constants = []struct {
 	{"OP_NOT_REGEX_MATCH", int(util.OpNotRegexMatch)},
 	{"OP_REGEX_CONTAINS", int(util.OpRegexContains)},
 	{"OP_NOT_REGEX_CONTAINS", int(util.OpNotRegexContains)},
-	{"INHIBIT_WORD_COMPLETIONS", 0},
-	{"INHIBIT_EXPLICIT_COMPLETIONS", 0},
+	{"INHIBIT_WORD_COMPLETIONS", lime.INHIBIT_WORD_COMPLETIONS},
+	{"INHIBIT_EXPLICIT_COMPLETIONS", lime.INHIBIT_EXPLICIT_COMPLETIONS},
 	{"LITERAL", int(lime.IGNORECASE)},
 	{"IGNORECASE", int(lime.LITERAL)},
 	{"CLASS_WORD_START", int(lime.CLASS_WORD_START)},
@@ -47,7 +47,7 @@ func TestSublimeApiMatchBefore(t *testing.T) {
 // Check if we are exporting extra functionality
 // All of exported api should exist in report/api
 func TestExportedApi(t *testing.T) {
-	skipKeys := []string{"sublime.TextCommandGlue", "sublime.ViewEventGlue", "sublime.ApplicationCommandGlue", "sublime.OnQueryContextGlue", "sublime.WindowCommandGlue"}
+	skipKeys := []string{"sublime.TextCommandGlue", "sublime.ViewEventGlue", "sublime.ApplicationCommandGlue", "sublime.OnQueryContextGlue", "sublime.OnQueryCompletionsGlue", "sublime.WindowCommandGlue"}
 	skipVals := []string{"CLASS_CLOSING_PARENTHESIS", "CLASS_MIDDLE_WORD", "CLASS_OPENING_PARENTHESIS", "CLASS_WORD_END_WITH_PUNCTUATION", "CLASS_WORD_START_WITH_PUNCTUATION", "register", "unregister", "console"}
 
 	l := py.NewLock()
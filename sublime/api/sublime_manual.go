@@ -118,6 +118,19 @@ func sublime_StatusMessage(tu *py.Tuple) (py.Object, error) {
 	return toPython(nil)
 }
 
+func sublime_GetMacro() (py.Object, error) {
+	m := lime.GetEditor().Macro()
+	ret := make(List, len(m))
+	for i, c := range m {
+		args := c.Args
+		if args == nil {
+			args = lime.Args{}
+		}
+		ret[i] = lime.Args{"command": c.Command, "args": args}
+	}
+	return toPython(ret)
+}
+
 func sublime_Console(tu *py.Tuple, kwargs *py.Dict) (py.Object, error) {
 	if tu.Size() != 1 {
 		return nil, fmt.Errorf("Unexpected argument count: %d", tu.Size())
@@ -170,6 +183,7 @@ var manual_methods = []py.Method{
 	{Name: "set_timeout", Func: sublime_SetTimeOut},
 	{Name: "packages_path", Func: sublime_PackagesPath},
 	{Name: "error_message", Func: sublime_ErrorMessage},
+	{Name: "get_macro", Func: sublime_GetMacro},
 	{Name: "message_dialog", Func: sublime_MessageDialog},
 	{Name: "ok_cancel_dialog", Func: sublime_OkCancelDialog},
 	{Name: "status_message", Func: sublime_StatusMessage},
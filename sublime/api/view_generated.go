@@ -127,8 +127,15 @@ func (o *View) Py_erase(tu *py.Tuple) (py.Object, error) {
 			}
 		}
 	}
-	o.data.Erase(arg1, arg2)
-	return toPython(nil)
+	ret0 := o.data.Erase(arg1, arg2)
+	var err error
+	var pyret0 py.Object
+
+	pyret0, err = toPython(ret0)
+	if err != nil {
+		return nil, err
+	}
+	return pyret0, err
 }
 
 func (o *View) Py_erase_regions(tu *py.Tuple) (py.Object, error) {
@@ -257,6 +264,18 @@ func (o *View) Py_find_by_class(tu *py.Tuple) (py.Object, error) {
 	return pyret0, err
 }
 
+func (o *View) Py_folded_regions() (py.Object, error) {
+	ret0 := o.data.FoldedRegions()
+	var err error
+	var pyret0 py.Object
+
+	pyret0, err = toPython(ret0)
+	if err != nil {
+		return nil, err
+	}
+	return pyret0, err
+}
+
 func (o *View) Py_get_regions(tu *py.Tuple) (py.Object, error) {
 	var (
 		arg1 string
@@ -325,6 +344,34 @@ func (o *View) Py_id() (py.Object, error) {
 	return pyret0, err
 }
 
+func (o *View) Py_indented_region(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 int
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else {
+		if v3, err2 := fromPython(v); err2 != nil {
+			return nil, err2
+		} else {
+			if v2, ok := v3.(int); !ok {
+				return nil, fmt.Errorf("Expected type int for lime.View.IndentedRegion() arg1, not %s", v.Type())
+			} else {
+				arg1 = v2
+			}
+		}
+	}
+	ret0 := o.data.IndentedRegion(arg1)
+	var err error
+	var pyret0 py.Object
+
+	pyret0, err = toPython(ret0)
+	if err != nil {
+		return nil, err
+	}
+	return pyret0, err
+}
+
 func (o *View) Py_insert(tu *py.Tuple) (py.Object, error) {
 	var (
 		arg1 *lime.Edit
@@ -393,6 +440,34 @@ func (o *View) Py_is_dirty() (py.Object, error) {
 	return pyret0, err
 }
 
+func (o *View) Py_is_folded(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 text.Region
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else {
+		if v3, err2 := fromPython(v); err2 != nil {
+			return nil, err2
+		} else {
+			if v2, ok := v3.(text.Region); !ok {
+				return nil, fmt.Errorf("Expected type text.Region for lime.View.IsFolded() arg1, not %s", v.Type())
+			} else {
+				arg1 = v2
+			}
+		}
+	}
+	ret0 := o.data.IsFolded(arg1)
+	var err error
+	var pyret0 py.Object
+
+	pyret0, err = toPython(ret0)
+	if err != nil {
+		return nil, err
+	}
+	return pyret0, err
+}
+
 func (o *View) Py_is_scratch() (py.Object, error) {
 	ret0 := o.data.IsScratch()
 	var err error
@@ -462,8 +537,15 @@ func (o *View) Py_replace(tu *py.Tuple) (py.Object, error) {
 			}
 		}
 	}
-	o.data.Replace(arg1, arg2, arg3)
-	return toPython(nil)
+	ret0 := o.data.Replace(arg1, arg2, arg3)
+	var err error
+	var pyret0 py.Object
+
+	pyret0, err = toPython(ret0)
+	if err != nil {
+		return nil, err
+	}
+	return pyret0, err
 }
 
 func (o *View) Py_scope_name(tu *py.Tuple) (py.Object, error) {
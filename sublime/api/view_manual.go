@@ -365,3 +365,270 @@ func (o *View) Py_find(tu *py.Tuple) (py.Object, error) {
 	}
 	return pyret0, err
 }
+
+// Returns the regions of the first argument, which is either
+// a single region or a list of regions.
+func regionsArg(tu *py.Tuple, fn string) ([]text.Region, error) {
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	if v2, ok := v.(*Region); ok {
+		return []text.Region{v2.data}, nil
+	}
+	l, ok := v.(*py.List)
+	if !ok {
+		return nil, fmt.Errorf("Expected type *Region or *py.List for %s arg1, not %s", fn, v.Type())
+	}
+	var ret []text.Region
+	for _, i := range l.Slice() {
+		if r, ok := i.(*Region); !ok {
+			return nil, fmt.Errorf("Expected a list of *Region for %s arg1, not %s", fn, i.Type())
+		} else {
+			ret = append(ret, r.data)
+		}
+	}
+	return ret, nil
+}
+
+func (o *View) Py_fold(tu *py.Tuple) (py.Object, error) {
+	regions, err := regionsArg(tu, "lime.View.Fold()")
+	if err != nil {
+		return nil, err
+	}
+	return toPython(o.data.Fold(regions...))
+}
+
+func (o *View) Py_unfold(tu *py.Tuple) (py.Object, error) {
+	regions, err := regionsArg(tu, "lime.View.Unfold()")
+	if err != nil {
+		return nil, err
+	}
+	var ret []text.Region
+	for _, r := range regions {
+		ret = append(ret, o.data.Unfold(r)...)
+	}
+	return toPython(ret)
+}
+
+func (o *View) Py_show_popup_menu(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 []string
+		arg3 int
+	)
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := v.(*py.List)
+	if !ok {
+		return nil, fmt.Errorf("Expected type *py.List for lime.View.ShowPopupMenu() arg1, not %s", v.Type())
+	}
+	for _, i := range l.Slice() {
+		if i2, ok := i.(*py.Unicode); !ok {
+			return nil, fmt.Errorf("Expected a list of strings for lime.View.ShowPopupMenu() arg1, not %s", i.Type())
+		} else {
+			arg1 = append(arg1, i2.String())
+		}
+	}
+	if v, err = tu.GetItem(1); err != nil {
+		return nil, err
+	}
+	onSel := v
+	if tu.Size() > 2 {
+		if v, err = tu.GetItem(2); err != nil {
+			return nil, err
+		}
+		if v2, ok := v.(*py.Long); !ok {
+			return nil, fmt.Errorf("Expected type *py.Long for lime.View.ShowPopupMenu() arg3, not %s", v.Type())
+		} else {
+			arg3 = int(v2.Int64())
+		}
+	}
+	arg2, release := pyCallback(onSel)
+	onDone := func(i int) {
+		if arg2 != nil {
+			arg2(i)
+		}
+		release()
+	}
+	o.data.ShowPopupMenu(arg1, onDone, arg3)
+	return toPython(nil)
+}
+
+// Returns the symbols as a list of (Region, name) tuples.
+func symbolsToPython(syms []lime.Symbol) (py.Object, error) {
+	ret := make(List, len(syms))
+	for i, s := range syms {
+		ret[i] = Tuple{s.Region, s.Name}
+	}
+	return toPython(ret)
+}
+
+func (o *View) Py_symbols() (py.Object, error) {
+	return symbolsToPython(o.data.Symbols())
+}
+
+func (o *View) Py_get_symbols() (py.Object, error) {
+	return symbolsToPython(o.data.Symbols())
+}
+
+func (o *View) Py_indexed_symbols() (py.Object, error) {
+	return symbolsToPython(o.data.IndexedSymbols())
+}
+
+func (o *View) Py_match_selector(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 int
+		arg2 string
+	)
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	if v2, ok := v.(*py.Long); !ok {
+		return nil, fmt.Errorf("Expected type *py.Long for lime.View.MatchSelector() arg1, not %s", v.Type())
+	} else {
+		arg1 = int(v2.Int64())
+	}
+	if v, err = tu.GetItem(1); err != nil {
+		return nil, err
+	}
+	if v2, ok := v.(*py.Unicode); !ok {
+		return nil, fmt.Errorf("Expected type *py.Unicode for lime.View.MatchSelector() arg2, not %s", v.Type())
+	} else {
+		arg2 = v2.String()
+	}
+	return toPython(o.data.MatchSelector(arg1, arg2))
+}
+
+func (o *View) Py_find_by_selector(tu *py.Tuple) (py.Object, error) {
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	v2, ok := v.(*py.Unicode)
+	if !ok {
+		return nil, fmt.Errorf("Expected type *py.Unicode for lime.View.FindBySelector() arg1, not %s", v.Type())
+	}
+	return toPython(o.data.FindBySelector(v2.String()))
+}
+
+func (o *View) Py_encoding() (py.Object, error) {
+	return toPython(o.data.Encoding())
+}
+
+func (o *View) Py_set_encoding(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 string
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else {
+		if v3, err2 := fromPython(v); err2 != nil {
+			return nil, err2
+		} else {
+			if v2, ok := v3.(string); !ok {
+				return nil, fmt.Errorf("Expected type string for lime.View.SetEncoding() arg1, not %s", v.Type())
+			} else {
+				arg1 = v2
+			}
+		}
+	}
+	o.data.SetEncoding(arg1)
+	return toPython(nil)
+}
+
+func (o *View) Py_line_endings() (py.Object, error) {
+	return toPython(o.data.LineEndings())
+}
+
+func (o *View) Py_set_line_endings(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 string
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else {
+		if v3, err2 := fromPython(v); err2 != nil {
+			return nil, err2
+		} else {
+			if v2, ok := v3.(string); !ok {
+				return nil, fmt.Errorf("Expected type string for lime.View.SetLineEndings() arg1, not %s", v.Type())
+			} else {
+				arg1 = v2
+			}
+		}
+	}
+	o.data.SetLineEndings(arg1)
+	return toPython(nil)
+}
+
+func (o *View) Py_is_loading() (py.Object, error) {
+	return toPython(o.data.IsLoading())
+}
+
+func (o *View) Py_is_read_only() (py.Object, error) {
+	return toPython(o.data.IsReadOnly())
+}
+
+func (o *View) Py_set_read_only(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 bool
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else {
+		if v3, err2 := fromPython(v); err2 != nil {
+			return nil, err2
+		} else {
+			if v2, ok := v3.(bool); !ok {
+				return nil, fmt.Errorf("Expected type bool for lime.View.SetReadOnly() arg1, not %s", v.Type())
+			} else {
+				arg1 = v2
+			}
+		}
+	}
+	o.data.SetReadOnly(arg1)
+	return toPython(nil)
+}
+
+func (o *View) Py_extract_completions(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 string
+		arg2 = -1
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else {
+		if v3, err2 := fromPython(v); err2 != nil {
+			return nil, err2
+		} else {
+			if v2, ok := v3.(string); !ok {
+				return nil, fmt.Errorf("Expected type string for lime.View.ExtractCompletions() arg1, not %s", v.Type())
+			} else {
+				arg1 = v2
+			}
+		}
+	}
+	if tu.Size() > 1 {
+		if v, err := tu.GetItem(1); err != nil {
+			return nil, err
+		} else {
+			if v3, err2 := fromPython(v); err2 != nil {
+				return nil, err2
+			} else {
+				if v2, ok := v3.(int); !ok {
+					return nil, fmt.Errorf("Expected type int for lime.View.ExtractCompletions() arg2, not %s", v.Type())
+				} else {
+					arg2 = v2
+				}
+			}
+		}
+	}
+	return toPython(o.data.ExtractCompletions(arg1, arg2))
+}
+
+func (o *View) Py_is_auto_complete_visible() (py.Object, error) {
+	return toPython(o.data.IsAutoCompleteVisible())
+}
@@ -35,6 +35,18 @@ type Window struct {
 func (o *Window) PyInit(args *py.Tuple, kwds *py.Dict) error {
 	return fmt.Errorf("Can't initialize type Window")
 }
+func (o *Window) Py_active_group() (py.Object, error) {
+	ret0 := o.data.ActiveGroup()
+	var err error
+	var pyret0 py.Object
+
+	pyret0, err = toPython(ret0)
+	if err != nil {
+		return nil, err
+	}
+	return pyret0, err
+}
+
 func (o *Window) Py_active_view() (py.Object, error) {
 	ret0 := o.data.ActiveView()
 	var err error
@@ -47,6 +59,76 @@ func (o *Window) Py_active_view() (py.Object, error) {
 	return pyret0, err
 }
 
+func (o *Window) Py_active_view_in_group(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 int
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else {
+		if v3, err2 := fromPython(v); err2 != nil {
+			return nil, err2
+		} else {
+			if v2, ok := v3.(int); !ok {
+				return nil, fmt.Errorf("Expected type int for lime.Window.ActiveViewInGroup() arg1, not %s", v.Type())
+			} else {
+				arg1 = v2
+			}
+		}
+	}
+	ret0 := o.data.ActiveViewInGroup(arg1)
+	var err error
+	var pyret0 py.Object
+
+	pyret0, err = toPython(ret0)
+	if err != nil {
+		return nil, err
+	}
+	return pyret0, err
+}
+
+func (o *Window) Py_destroy_output_panel(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 string
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else {
+		if v3, err2 := fromPython(v); err2 != nil {
+			return nil, err2
+		} else {
+			if v2, ok := v3.(string); !ok {
+				return nil, fmt.Errorf("Expected type string for lime.Window.DestroyOutputPanel() arg1, not %s", v.Type())
+			} else {
+				arg1 = v2
+			}
+		}
+	}
+	o.data.DestroyOutputPanel(arg1)
+	return toPython(nil)
+}
+
+func (o *Window) Py_focus_group(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 int
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else {
+		if v3, err2 := fromPython(v); err2 != nil {
+			return nil, err2
+		} else {
+			if v2, ok := v3.(int); !ok {
+				return nil, fmt.Errorf("Expected type int for lime.Window.FocusGroup() arg1, not %s", v.Type())
+			} else {
+				arg1 = v2
+			}
+		}
+	}
+	o.data.FocusGroup(arg1)
+	return toPython(nil)
+}
+
 func (o *Window) Py_id() (py.Object, error) {
 	ret0 := o.data.ID()
 	var err error
@@ -72,6 +154,30 @@ func (o *Window) Py_new_file() (py.Object, error) {
 	return pyret0, err
 }
 
+func (o *Window) Py_num_groups() (py.Object, error) {
+	ret0 := o.data.NumGroups()
+	var err error
+	var pyret0 py.Object
+
+	pyret0, err = toPython(ret0)
+	if err != nil {
+		return nil, err
+	}
+	return pyret0, err
+}
+
+func (o *Window) Py_panels() (py.Object, error) {
+	ret0 := o.data.Panels()
+	var err error
+	var pyret0 py.Object
+
+	pyret0, err = toPython(ret0)
+	if err != nil {
+		return nil, err
+	}
+	return pyret0, err
+}
+
 func (o *Window) Py_settings() (py.Object, error) {
 	ret0 := o.data.Settings()
 	var err error
@@ -99,3 +205,31 @@ func (o *Window) Py_views() (py.Object, error) {
 	}
 	return pyret0, err
 }
+
+func (o *Window) Py_views_in_group(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 int
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else {
+		if v3, err2 := fromPython(v); err2 != nil {
+			return nil, err2
+		} else {
+			if v2, ok := v3.(int); !ok {
+				return nil, fmt.Errorf("Expected type int for lime.Window.ViewsInGroup() arg1, not %s", v.Type())
+			} else {
+				arg1 = v2
+			}
+		}
+	}
+	ret0 := o.data.ViewsInGroup(arg1)
+	var err error
+	var pyret0 py.Object
+
+	pyret0, err = toPython(ret0)
+	if err != nil {
+		return nil, err
+	}
+	return pyret0, err
+}
@@ -110,3 +110,334 @@ func (o *Window) Py_focus_view(tu *py.Tuple) (py.Object, error) {
 	o.data.SetActiveView(arg1)
 	return toPython(nil)
 }
+
+func (o *Window) Py_get_layout() (py.Object, error) {
+	l := o.data.Layout()
+	cells := make(List, len(l.Cells))
+	for i, c := range l.Cells {
+		cells[i] = List{c[0], c[1], c[2], c[3]}
+	}
+	return toPython(lime.Args{
+		"cols":  l.Cols,
+		"rows":  l.Rows,
+		"cells": cells,
+	})
+}
+
+func (o *Window) Py_set_layout(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 lime.Layout
+	)
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(*py.Dict); !ok {
+		return nil, fmt.Errorf("Expected type *py.Dict for lime.Window.SetLayout() arg1, not %s", v.Type())
+	}
+	if v2, err := fromPython(v); err != nil {
+		return nil, err
+	} else if err := arg1.Set(v2); err != nil {
+		return nil, err
+	}
+	if err := o.data.SetLayout(arg1); err != nil {
+		return nil, err
+	}
+	return toPython(nil)
+}
+
+func (o *Window) Py_get_view_index(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 *lime.View
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else if v2, ok := v.(*View); !ok {
+		return nil, fmt.Errorf("Expected type *View for lime.Window.GetViewIndex() arg1, not %s", v.Type())
+	} else {
+		arg1 = v2.data
+	}
+	group, index := o.data.GetViewIndex(arg1)
+	return toPython(Tuple{group, index})
+}
+
+func (o *Window) Py_set_view_index(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 *lime.View
+		arg2 int
+		arg3 int
+	)
+	if v, err := tu.GetItem(0); err != nil {
+		return nil, err
+	} else if v2, ok := v.(*View); !ok {
+		return nil, fmt.Errorf("Expected type *View for lime.Window.SetViewIndex() arg1, not %s", v.Type())
+	} else {
+		arg1 = v2.data
+	}
+	for i, arg := range []*int{&arg2, &arg3} {
+		v, err := tu.GetItem(int64(i + 1))
+		if err != nil {
+			return nil, err
+		}
+		if v2, ok := v.(*py.Long); !ok {
+			return nil, fmt.Errorf("Expected type *py.Long for lime.Window.SetViewIndex() arg%d, not %s", i+2, v.Type())
+		} else {
+			*arg = int(v2.Int64())
+		}
+	}
+	o.data.SetViewIndex(arg1, arg2, arg3)
+	return toPython(nil)
+}
+
+func (o *Window) Py_create_output_panel(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 string
+	)
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	if v2, ok := v.(*py.Unicode); !ok {
+		return nil, fmt.Errorf("Expected type *py.Unicode for lime.Window.CreateOutputPanel() arg1, not %s", v.Type())
+	} else {
+		arg1 = v2.String()
+	}
+	// the optional unlisted arg is ignored as panels aren't listed anyway
+	return toPython(o.data.CreateOutputPanel(arg1))
+}
+
+// get_output_panel is the deprecated name of create_output_panel
+func (o *Window) Py_get_output_panel(tu *py.Tuple) (py.Object, error) {
+	return o.Py_create_output_panel(tu)
+}
+
+func (o *Window) Py_find_output_panel(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 string
+	)
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	if v2, ok := v.(*py.Unicode); !ok {
+		return nil, fmt.Errorf("Expected type *py.Unicode for lime.Window.FindOutputPanel() arg1, not %s", v.Type())
+	} else {
+		arg1 = v2.String()
+	}
+	if ret := o.data.FindOutputPanel(arg1); ret != nil {
+		return toPython(ret)
+	}
+	return toPython(nil)
+}
+
+func (o *Window) Py_find_open_file(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 string
+	)
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	if v2, ok := v.(*py.Unicode); !ok {
+		return nil, fmt.Errorf("Expected type *py.Unicode for lime.Window.FindOpenFile() arg1, not %s", v.Type())
+	} else {
+		arg1 = v2.String()
+	}
+	if ret := o.data.FindOpenFile(arg1); ret != nil {
+		return toPython(ret)
+	}
+	return toPython(nil)
+}
+
+func (o *Window) Py_active_panel() (py.Object, error) {
+	if ret := o.data.ActivePanel(); ret != "" {
+		return toPython(ret)
+	}
+	return toPython(nil)
+}
+
+func (o *Window) Py_extract_variables() (py.Object, error) {
+	vars := make(lime.Args)
+	for k, v := range o.data.ExtractVariables() {
+		vars[k] = v
+	}
+	return toPython(vars)
+}
+
+// Parses the quick panel items, either a list of strings or a list of
+// lists of strings which first string is the item text.
+func quickPanelItems(v py.Object, fn string) ([]lime.QuickPanelItem, error) {
+	l, ok := v.(*py.List)
+	if !ok {
+		return nil, fmt.Errorf("Expected type *py.List for %s arg1, not %s", fn, v.Type())
+	}
+	items := make([]lime.QuickPanelItem, 0, l.Size())
+	for _, i := range l.Slice() {
+		switch t := i.(type) {
+		case *py.Unicode:
+			items = append(items, lime.QuickPanelItem{Text: t.String()})
+		case *py.List:
+			var rows []string
+			for _, r := range t.Slice() {
+				if r2, ok := r.(*py.Unicode); !ok {
+					return nil, fmt.Errorf("Expected a list of strings for %s item, not %s", fn, r.Type())
+				} else {
+					rows = append(rows, r2.String())
+				}
+			}
+			if len(rows) == 0 {
+				return nil, fmt.Errorf("Expected a non empty list for %s item", fn)
+			}
+			items = append(items, lime.QuickPanelItem{Text: rows[0], Details: rows[1:]})
+		default:
+			return nil, fmt.Errorf("Expected type *py.Unicode or *py.List for %s item, not %s", fn, i.Type())
+		}
+	}
+	return items, nil
+}
+
+func (o *Window) Py_show_quick_panel(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1 []lime.QuickPanelItem
+		arg3 int
+		arg4 = -1
+		arg5 func(...interface{})
+	)
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	if arg1, err = quickPanelItems(v, "lime.Window.ShowQuickPanel()"); err != nil {
+		return nil, err
+	}
+	onSel, err := tu.GetItem(1)
+	if err != nil {
+		return nil, err
+	}
+	for i, arg := range []*int{&arg3, &arg4} {
+		if tu.Size() <= int64(i+2) {
+			break
+		}
+		v, err := tu.GetItem(int64(i + 2))
+		if err != nil {
+			return nil, err
+		}
+		if v2, ok := v.(*py.Long); !ok {
+			return nil, fmt.Errorf("Expected type *py.Long for lime.Window.ShowQuickPanel() arg%d, not %s", i+3, v.Type())
+		} else {
+			*arg = int(v2.Int64())
+		}
+	}
+	release5 := func() {}
+	if tu.Size() > 4 {
+		if v, err = tu.GetItem(4); err != nil {
+			return nil, err
+		}
+		arg5, release5 = pyCallback(v)
+	}
+	arg2, release2 := pyCallback(onSel)
+	var onHighlight func(int)
+	// the panel is done once an item is selected
+	onSelect := func(i int) {
+		if arg2 != nil {
+			arg2(i)
+		}
+		release2()
+		release5()
+	}
+	if arg5 != nil {
+		onHighlight = func(i int) { arg5(i) }
+	}
+	o.data.ShowQuickPanel(arg1, arg3, arg4, onSelect, onHighlight)
+	return toPython(nil)
+}
+
+func (o *Window) Py_show_input_panel(tu *py.Tuple) (py.Object, error) {
+	var (
+		arg1, arg2       string
+		onDone, onChange func(string)
+		onCancel         func()
+	)
+	for i, arg := range []*string{&arg1, &arg2} {
+		v, err := tu.GetItem(int64(i))
+		if err != nil {
+			return nil, err
+		}
+		if v2, ok := v.(*py.Unicode); !ok {
+			return nil, fmt.Errorf("Expected type *py.Unicode for lime.Window.ShowInputPanel() arg%d, not %s", i+1, v.Type())
+		} else {
+			*arg = v2.String()
+		}
+	}
+	var (
+		cbs      [3]func(...interface{})
+		releases [3]func()
+	)
+	for i := range cbs {
+		v, err := tu.GetItem(int64(i + 2))
+		if err != nil {
+			for _, r := range releases[:i] {
+				r()
+			}
+			return nil, err
+		}
+		cbs[i], releases[i] = pyCallback(v)
+	}
+	// the panel is done once it's confirmed or cancelled
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	onDone = func(s string) {
+		if cb := cbs[0]; cb != nil {
+			cb(s)
+		}
+		release()
+	}
+	if cb := cbs[1]; cb != nil {
+		onChange = func(s string) { cb(s) }
+	}
+	onCancel = func() {
+		if cb := cbs[2]; cb != nil {
+			cb()
+		}
+		release()
+	}
+	return toPython(o.data.ShowInputPanel(arg1, arg2, onDone, onChange, onCancel))
+}
+
+// Returns the locations as a list of (path, display path, (row, col))
+// tuples, the display path being relative to the project folders.
+func (o *Window) locationsToPython(locs []lime.SymbolLocation) (py.Object, error) {
+	idx := o.data.Project().FileIndex()
+	ret := make(List, len(locs))
+	for i, l := range locs {
+		ret[i] = Tuple{l.Path, idx.Rel(l.Path), Tuple{l.Row, l.Col}}
+	}
+	return toPython(ret)
+}
+
+func (o *Window) Py_lookup_symbol_in_index(tu *py.Tuple) (py.Object, error) {
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	v2, ok := v.(*py.Unicode)
+	if !ok {
+		return nil, fmt.Errorf("Expected type *py.Unicode for lime.Window.LookupSymbolInIndex() arg1, not %s", v.Type())
+	}
+	return o.locationsToPython(o.data.LookupSymbolInIndex(v2.String()))
+}
+
+func (o *Window) Py_lookup_symbol_in_open_files(tu *py.Tuple) (py.Object, error) {
+	v, err := tu.GetItem(0)
+	if err != nil {
+		return nil, err
+	}
+	v2, ok := v.(*py.Unicode)
+	if !ok {
+		return nil, fmt.Errorf("Expected type *py.Unicode for lime.Window.LookupSymbolInOpenFiles() arg1, not %s", v.Type())
+	}
+	return o.locationsToPython(o.data.LookupSymbolInOpenFiles(v2.String()))
+}
@@ -0,0 +1,33 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package sublime
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/loaders"
+)
+
+func newBuildSystem(path string) (*lime.BuildSystem, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := new(lime.BuildSystem)
+	if err := loaders.LoadJSON(d, b); err != nil {
+		return nil, err
+	}
+	if b.Name == "" {
+		b.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return b, nil
+}
+
+func isBuildSystem(path string) bool {
+	return filepath.Ext(path) == ".sublime-build"
+}
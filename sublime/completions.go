@@ -0,0 +1,24 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package sublime
+
+import (
+	"path/filepath"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/packages"
+)
+
+func newCompletions(path string) (*lime.Completions, error) {
+	c := new(lime.Completions)
+	if err := packages.LoadJSON(path, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func isCompletions(path string) bool {
+	return filepath.Ext(path) == ".sublime-completions"
+}
@@ -0,0 +1,24 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package sublime
+
+import (
+	"path/filepath"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/packages"
+)
+
+func newMacro(path string) (*lime.Macro, error) {
+	m := new(lime.Macro)
+	if err := packages.LoadJSON(path, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func isMacro(path string) bool {
+	return filepath.Ext(path) == ".sublime-macro"
+}
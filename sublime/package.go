@@ -28,8 +28,15 @@ type pkg struct {
 	defaultSettings  *util.HasSettings
 	defaultKB        *keys.HasKeyBindings
 	plugins          map[string]*plugin
-	syntaxes         map[string]*syntax
+	syntaxes         map[string]lime.Syntax
 	colorSchemes     map[string]*colorScheme
+	buildSystems     map[string]*lime.BuildSystem
+	paletteCommands  map[string]*lime.PaletteCommands
+	symbolSettings   map[string]*lime.SymbolSettings
+	macros           map[string]*lime.Macro
+	completions      map[string]*lime.Completions
+	snippets         map[string]*lime.Snippet
+	shellVariables   map[string]*lime.ShellVariables
 }
 
 func newPKG(dir string) packages.Package {
@@ -40,8 +47,15 @@ func newPKG(dir string) packages.Package {
 		defaultSettings:  new(util.HasSettings),
 		defaultKB:        new(keys.HasKeyBindings),
 		plugins:          make(map[string]*plugin),
-		syntaxes:         make(map[string]*syntax),
+		syntaxes:         make(map[string]lime.Syntax),
 		colorSchemes:     make(map[string]*colorScheme),
+		buildSystems:     make(map[string]*lime.BuildSystem),
+		paletteCommands:  make(map[string]*lime.PaletteCommands),
+		symbolSettings:   make(map[string]*lime.SymbolSettings),
+		macros:           make(map[string]*lime.Macro),
+		completions:      make(map[string]*lime.Completions),
+		snippets:         make(map[string]*lime.Snippet),
+		shellVariables:   make(map[string]*lime.ShellVariables),
 	}
 
 	ed := lime.GetEditor()
@@ -78,7 +92,8 @@ func (p *pkg) Load() {
 		p.loadPlugins()
 	}
 	// load files that could be anywhere in the package dir like syntax,
-	// colour scheme and preferences
+	// colour scheme, build system, commands, preferences, macros,
+	// completions and snippets
 	filepath.Walk(p.Path(), p.scan)
 }
 
@@ -140,6 +155,82 @@ func (p *pkg) loadSyntax(path string) {
 	lime.GetEditor().AddSyntax(path, syn)
 }
 
+func (p *pkg) loadBuildSystem(path string) {
+	log.Fine("Loading %s package build system %s", p.Name(), path)
+	b, err := newBuildSystem(path)
+	if err != nil {
+		log.Warn("Error loading %s build system %s: %s", p.Name(), path, err)
+		return
+	}
+
+	p.buildSystems[path] = b
+	lime.GetEditor().AddBuildSystem(path, b)
+}
+
+func (p *pkg) loadPaletteCommands(path string) {
+	log.Fine("Loading %s package commands %s", p.Name(), path)
+	c, err := newPaletteCommands(path)
+	if err != nil {
+		log.Warn("Error loading %s commands %s: %s", p.Name(), path, err)
+		return
+	}
+
+	p.paletteCommands[path] = c
+	lime.GetEditor().AddPaletteCommands(path, c)
+}
+
+func (p *pkg) loadPreferences(path string) {
+	log.Fine("Loading %s package preferences %s", p.Name(), path)
+	s, vars, err := newPreferences(path)
+	if err != nil {
+		log.Warn("Error loading %s preferences %s: %s", p.Name(), path, err)
+		return
+	}
+
+	p.symbolSettings[path] = s
+	lime.GetEditor().AddSymbolSettings(path, s)
+	if vars != nil {
+		p.shellVariables[path] = vars
+		lime.GetEditor().AddShellVariables(path, vars)
+	}
+}
+
+func (p *pkg) loadMacro(path string) {
+	log.Fine("Loading %s package macro %s", p.Name(), path)
+	m, err := newMacro(path)
+	if err != nil {
+		log.Warn("Error loading %s macro %s: %s", p.Name(), path, err)
+		return
+	}
+
+	p.macros[path] = m
+	lime.GetEditor().AddMacro(path, m)
+}
+
+func (p *pkg) loadCompletions(path string) {
+	log.Fine("Loading %s package completions %s", p.Name(), path)
+	c, err := newCompletions(path)
+	if err != nil {
+		log.Warn("Error loading %s completions %s: %s", p.Name(), path, err)
+		return
+	}
+
+	p.completions[path] = c
+	lime.GetEditor().AddCompletions(path, c)
+}
+
+func (p *pkg) loadSnippet(path string) {
+	log.Fine("Loading %s package snippet %s", p.Name(), path)
+	s, err := newSnippet(path)
+	if err != nil {
+		log.Warn("Error loading %s snippet %s: %s", p.Name(), path, err)
+		return
+	}
+
+	p.snippets[path] = s
+	lime.GetEditor().AddSnippet(path, s)
+}
+
 func (p *pkg) loadKeyBindings() {
 	log.Fine("Loading %s keybindings", p.Name())
 	ed := lime.GetEditor()
@@ -183,6 +274,24 @@ func (p *pkg) scan(path string, info os.FileInfo, err error) error {
 	if isSyntax(path) {
 		p.loadSyntax(path)
 	}
+	if isBuildSystem(path) {
+		p.loadBuildSystem(path)
+	}
+	if isPaletteCommands(path) {
+		p.loadPaletteCommands(path)
+	}
+	if isPreferences(path) {
+		p.loadPreferences(path)
+	}
+	if isMacro(path) {
+		p.loadMacro(path)
+	}
+	if isCompletions(path) {
+		p.loadCompletions(path)
+	}
+	if isSnippet(path) {
+		p.loadSnippet(path)
+	}
 	return nil
 }
 
@@ -6,6 +6,7 @@ package sublime
 
 import (
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/jxo/lime"
@@ -19,6 +20,14 @@ var (
 	pluginPath = filepath.Join("testdata", "package", "plugin.py")
 	synPath    = filepath.Join(pkgPath, "Go.tmLanguage")
 	csPath     = filepath.Join(pkgPath, "Twilight.tmTheme")
+	subSynPath = filepath.Join("syntax", "testdata", "Go.sublime-syntax")
+	buildPath  = filepath.Join(pkgPath, "Make.sublime-build")
+	cmdsPath   = filepath.Join(pkgPath, "Default.sublime-commands")
+	prefsPath  = filepath.Join(pkgPath, "Symbol List.tmPreferences")
+	macroPath  = filepath.Join(pkgPath, "Add Line.sublime-macro")
+	compsPath  = filepath.Join(pkgPath, "Go.sublime-completions")
+	snipPath   = filepath.Join(pkgPath, "func.sublime-snippet")
+	varsPath   = filepath.Join(pkgPath, "Comments.tmPreferences")
 )
 
 func TestLoadPlugin(t *testing.T) {
@@ -45,6 +54,162 @@ func TestLoadSyntax(t *testing.T) {
 	checkSyntax(pkg, t)
 }
 
+func TestLoadSublimeSyntax(t *testing.T) {
+	pkg := newPKG(pkgPath).(*pkg)
+	pkg.loadSyntax(subSynPath)
+	if _, ok := pkg.syntaxes[subSynPath]; !ok {
+		t.Errorf("Expected %s in %s package syntaxes", subSynPath, pkg.Name())
+	}
+	syn := lime.GetEditor().GetSyntax(subSynPath)
+	if syn == nil {
+		t.Fatalf("Expected %s from %s package in editor syntaxes", subSynPath, pkg.Name())
+	}
+	if _, err := syn.Parser("package main"); err != nil {
+		t.Errorf("Expected to get a parser from %s, but got an error: %s", subSynPath, err)
+	}
+}
+
+func TestLoadBuildSystem(t *testing.T) {
+	pkg := newPKG(pkgPath).(*pkg)
+	pkg.loadBuildSystem(buildPath)
+	b, ok := pkg.buildSystems[buildPath]
+	if !ok {
+		t.Fatalf("Expected %s in %s package build systems", buildPath, pkg.Name())
+	}
+	if b.Name != "Make" {
+		t.Errorf("Expected build system name to be %q, but got %q", "Make", b.Name)
+	}
+	if b.ShellCmd != "make" || len(b.Variants) != 1 {
+		t.Errorf("Expected build system %s to be loaded, but got %+v", buildPath, b)
+	}
+	found := false
+	for _, eb := range lime.GetEditor().BuildSystems() {
+		found = found || eb == b
+	}
+	if !found {
+		t.Errorf("Expected %s from %s package in editor build systems", buildPath, pkg.Name())
+	}
+}
+
+func TestLoadPaletteCommands(t *testing.T) {
+	pkg := newPKG(pkgPath).(*pkg)
+	pkg.loadPaletteCommands(cmdsPath)
+	c, ok := pkg.paletteCommands[cmdsPath]
+	if !ok {
+		t.Fatalf("Expected %s in %s package commands", cmdsPath, pkg.Name())
+	}
+	if len(*c) != 3 {
+		t.Fatalf("Expected 3 commands in %s, but got %d", cmdsPath, len(*c))
+	}
+	exp := lime.PaletteCommand{Caption: "Build: Base", Command: "build", Args: lime.Args{"variant": "Base"}}
+	if !reflect.DeepEqual((*c)[1], exp) {
+		t.Errorf("Expected %+v, but got %+v", exp, (*c)[1])
+	}
+}
+
+func TestLoadPreferences(t *testing.T) {
+	pkg := newPKG(pkgPath).(*pkg)
+	pkg.loadPreferences(prefsPath)
+	s, ok := pkg.symbolSettings[prefsPath]
+	if !ok {
+		t.Fatalf("Expected %s in %s package preferences", prefsPath, pkg.Name())
+	}
+	if s.Scope != "source.go entity.name.function" || !s.ShowInSymbolList || !s.ShowInIndexedSymbolList {
+		t.Errorf("Expected %s symbol settings to be loaded, but got %+v", prefsPath, s)
+	}
+	if s.SymbolTransformation == nil || s.SymbolIndexTransformation != nil {
+		t.Fatalf("Expected only the symbol transformation to be set, but got %+v", s)
+	}
+	if n := s.SymbolTransformation("\tRun"); n != "  Run" {
+		t.Errorf("Expected %q, but got %q", "  Run", n)
+	}
+}
+
+func TestLoadShellVariables(t *testing.T) {
+	pkg := newPKG(pkgPath).(*pkg)
+	pkg.loadPreferences(varsPath)
+	s, ok := pkg.shellVariables[varsPath]
+	if !ok {
+		t.Fatalf("Expected %s in %s package shell variables", varsPath, pkg.Name())
+	}
+	exp := lime.ShellVariables{Scope: "source.go", Variables: map[string]string{"TM_COMMENT_START": "// "}}
+	if !reflect.DeepEqual(*s, exp) {
+		t.Errorf("Expected %+v, but got %+v", exp, *s)
+	}
+	pkg.loadPreferences(prefsPath)
+	if _, ok := pkg.shellVariables[prefsPath]; ok {
+		t.Errorf("Expected no shell variables in %s", prefsPath)
+	}
+}
+
+func TestLoadMacro(t *testing.T) {
+	pkg := newPKG(pkgPath).(*pkg)
+	pkg.loadMacro(macroPath)
+	m, ok := pkg.macros[macroPath]
+	if !ok {
+		t.Fatalf("Expected %s in %s package macros", macroPath, pkg.Name())
+	}
+	exp := lime.Macro{
+		{Command: "move_to", Args: lime.Args{"to": "hardeol"}},
+		{Command: "insert", Args: lime.Args{"characters": "\n"}},
+	}
+	if !reflect.DeepEqual(*m, exp) {
+		t.Errorf("Expected %+v, but got %+v", exp, *m)
+	}
+	em, err := lime.GetEditor().FindMacro("Packages/package/Add Line.sublime-macro")
+	if err != nil {
+		t.Fatalf("Expected %s from %s package in editor macros, but got %s", macroPath, pkg.Name(), err)
+	}
+	if !reflect.DeepEqual(em, exp) {
+		t.Errorf("Expected %+v, but got %+v", exp, em)
+	}
+}
+
+func TestLoadCompletions(t *testing.T) {
+	pkg := newPKG(pkgPath).(*pkg)
+	pkg.loadCompletions(compsPath)
+	c, ok := pkg.completions[compsPath]
+	if !ok {
+		t.Fatalf("Expected %s in %s package completions", compsPath, pkg.Name())
+	}
+	exp := lime.Completions{
+		Scope: "source.go",
+		Completions: []lime.Completion{
+			{Trigger: "fallthrough", Contents: "fallthrough"},
+			{Trigger: "fmt", Annotation: "package", Contents: "fmt.Println(${1:msg})"},
+			{Trigger: "iota", Contents: "iota"},
+		},
+	}
+	if !reflect.DeepEqual(*c, exp) {
+		t.Errorf("Expected %+v, but got %+v", exp, *c)
+	}
+}
+
+func TestLoadSnippet(t *testing.T) {
+	pkg := newPKG(pkgPath).(*pkg)
+	pkg.loadSnippet(snipPath)
+	s, ok := pkg.snippets[snipPath]
+	if !ok {
+		t.Fatalf("Expected %s in %s package snippets", snipPath, pkg.Name())
+	}
+	exp := lime.Snippet{
+		Content:     "func ${1:name}(${2}) {\n\t${0}\n}",
+		TabTrigger:  "func",
+		Scope:       "source.go",
+		Description: "function",
+	}
+	if !reflect.DeepEqual(*s, exp) {
+		t.Errorf("Expected %+v, but got %+v", exp, *s)
+	}
+	es, err := lime.GetEditor().FindSnippet("Packages/package/func.sublime-snippet")
+	if err != nil {
+		t.Fatalf("Expected %s from %s package in editor snippets, but got %s", snipPath, pkg.Name(), err)
+	}
+	if !reflect.DeepEqual(es, exp) {
+		t.Errorf("Expected %+v, but got %+v", exp, es)
+	}
+}
+
 func checkPlugin(p *pkg, t *testing.T) {
 	if _, exist := p.plugins[pluginPath]; !exist {
 		t.Errorf("Expected to %s exist in %s package plugins", pluginPath, p.Name())
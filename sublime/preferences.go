@@ -0,0 +1,41 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package sublime
+
+import (
+	"path/filepath"
+
+	"github.com/jxo/lime"
+	"github.com/jxo/lime/sublime/textmate/preferences"
+)
+
+// Returns the symbol list settings and the shell variables of the
+// tmPreferences file, the latter being nil if it defines none.
+func newPreferences(path string) (*lime.SymbolSettings, *lime.ShellVariables, error) {
+	p, err := preferences.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := &lime.SymbolSettings{
+		Scope:                   p.Scope,
+		ShowInSymbolList:        p.Settings.ShowInSymbolList != 0,
+		ShowInIndexedSymbolList: p.Settings.ShowInIndexedSymbolList != 0,
+	}
+	if len(p.Settings.SymbolTransformation) != 0 {
+		s.SymbolTransformation = p.Settings.SymbolTransformation.Apply
+	}
+	if len(p.Settings.SymbolIndexTransformation) != 0 {
+		s.SymbolIndexTransformation = p.Settings.SymbolIndexTransformation.Apply
+	}
+	var vars *lime.ShellVariables
+	if len(p.Settings.ShellVariables) != 0 {
+		vars = &lime.ShellVariables{Scope: p.Scope, Variables: p.Settings.ShellVariables}
+	}
+	return s, vars, nil
+}
+
+func isPreferences(path string) bool {
+	return filepath.Ext(path) == ".tmPreferences"
+}
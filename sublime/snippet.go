@@ -0,0 +1,41 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package sublime
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/jxo/lime"
+)
+
+func newSnippet(path string) (*lime.Snippet, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s struct {
+		Content     string `xml:"content"`
+		TabTrigger  string `xml:"tabTrigger"`
+		Scope       string `xml:"scope"`
+		Description string `xml:"description"`
+	}
+	if err := xml.Unmarshal(d, &s); err != nil {
+		return nil, fmt.Errorf("Unable to load snippet %s: %s", path, err)
+	}
+	return &lime.Snippet{
+		Content:     s.Content,
+		TabTrigger:  strings.TrimSpace(s.TabTrigger),
+		Scope:       strings.TrimSpace(s.Scope),
+		Description: strings.TrimSpace(s.Description),
+	}, nil
+}
+
+func isSnippet(path string) bool {
+	return filepath.Ext(path) == ".sublime-snippet"
+}
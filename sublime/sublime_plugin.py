@@ -16,6 +16,9 @@ class Command(object):
     def is_visible(self, args=None):
         return True
 
+    def description(self, args=None):
+        return None
+
 
 class ApplicationCommand(Command):
     pass
@@ -117,6 +120,9 @@ def reload_plugin(module):
                     toadd = getattr(inst, "on_query_context", None)
                     if toadd:
                         sublime.OnQueryContextGlue(toadd)
+                    toadd = getattr(inst, "on_query_completions", None)
+                    if toadd:
+                        sublime.OnQueryCompletionsGlue(toadd)
                     for name in ["on_load"]:  # TODO
                         toadd = getattr(inst, name, None)
                         if toadd:
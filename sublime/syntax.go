@@ -7,7 +7,9 @@ package sublime
 import (
 	"path/filepath"
 
+	"github.com/jxo/lime"
 	"github.com/jxo/lime/parser"
+	sublimesyntax "github.com/jxo/lime/sublime/syntax"
 	"github.com/jxo/lime/sublime/textmate/language"
 )
 
@@ -16,7 +18,19 @@ type syntax struct {
 	l *language.Language
 }
 
-func newSyntax(path string) (*syntax, error) {
+// wrapper around sublime-syntax Syntax implementing lime.Syntax interface
+type sublimeSyntax struct {
+	s *sublimesyntax.Syntax
+}
+
+func newSyntax(path string) (lime.Syntax, error) {
+	if filepath.Ext(path) == ".sublime-syntax" {
+		if s, err := sublimesyntax.Load(path); err != nil {
+			return nil, err
+		} else {
+			return &sublimeSyntax{s: s}, nil
+		}
+	}
 	if l, err := language.Load(path); err != nil {
 		return nil, err
 	} else {
@@ -40,8 +54,23 @@ func (s *syntax) FileTypes() []string {
 	return s.l.FileTypes
 }
 
+// The sublime-syntax parser keeps its state in the parser itself so
+// unlike tmLanguage the syntax can be shared between parsers.
+func (s *sublimeSyntax) Parser(data string) (parser.Parser, error) {
+	return sublimesyntax.NewParser(s.s, []rune(data)), nil
+}
+
+func (s *sublimeSyntax) Name() string {
+	return s.s.Name
+}
+
+func (s *sublimeSyntax) FileTypes() []string {
+	return s.s.FileExtensions
+}
+
 func isSyntax(path string) bool {
-	if filepath.Ext(path) == ".tmLanguage" {
+	switch filepath.Ext(path) {
+	case ".tmLanguage", ".hidden-tmLanguage", ".sublime-syntax":
 		return true
 	}
 	return false
@@ -3,3 +3,489 @@
 // BSD-style license that can be found in the LICENSE file.
 
 package syntax
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/parser"
+	"github.com/jxo/lime/rubex"
+	"github.com/jxo/lime/sublime/textmate"
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// implements parser.Parser + parser.DataSource
+	//
+	// The parser works like sublime does, data is matched line by line
+	// against the patterns of the context on top of the stack, and
+	// push, set, pop and embed change the stack.
+	Parser struct {
+		syn   *Syntax
+		data  []rune
+		sdata string
+		stack []*frame
+		// compiled patterns with substituted back references
+		dynamic map[string]*rubex.Regexp
+	}
+
+	frame struct {
+		ctx  Context
+		name string
+		// syntax owning ctx, named contexts are resolved from it
+		syn     *Syntax
+		meta    *parser.Node
+		content *parser.Node
+		// text captured by the match pushing this frame, used by back
+		// references in pop patterns
+		captures []string
+		// patterns from with_prototype applying to this frame
+		protos []candidate
+		// set when the frame was created by embed
+		escape         *rubex.Regexp
+		escapeCaptures textmate.Captures
+		// patterns tried on this frame, see patterns()
+		cands []candidate
+	}
+
+	candidate struct {
+		pat *Pattern
+		syn *Syntax
+	}
+)
+
+// Maximum number of consecutive zero length stack changes on the same
+// position before the parser moves forward to avoid looping forever.
+const maxZeroLength = 64
+
+func NewParser(s *Syntax, data []rune) *Parser {
+	return &Parser{syn: s, data: data, dynamic: make(map[string]*rubex.Regexp)}
+}
+
+func (p *Parser) Data(a, b int) string {
+	a = text.Clamp(0, len(p.data), a)
+	b = text.Clamp(0, len(p.data), b)
+	return string(p.data[a:b])
+}
+
+// fix node range positions based on given look up table
+func (p *Parser) patch(lut []int, node *parser.Node) {
+	node.Range.A = lut[node.Range.A]
+	node.Range.B = lut[node.Range.B]
+	for _, child := range node.Children {
+		p.patch(lut, child)
+	}
+}
+
+// Creates a directed acyclic graph from the data using the syntax
+// contexts, each line is matched from the context on top of the stack.
+func (p *Parser) Parse() (*parser.Node, error) {
+	p.sdata = string(p.data)
+	rn := &parser.Node{P: p, Name: p.syn.Scope}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Panic during parse: %v\n", r)
+			log.Debug("%v", rn)
+		}
+	}()
+	p.stack = []*frame{{ctx: p.syn.Contexts["main"], name: "main", syn: p.syn, content: rn}}
+
+	for ls := 0; ls < len(p.sdata); {
+		le := strings.IndexByte(p.sdata[ls:], '\n')
+		if le == -1 {
+			le = len(p.sdata)
+		} else {
+			le += ls + 1
+		}
+		p.parseLine(ls, le)
+		ls = le
+	}
+	p.popTo(0, len(p.sdata), len(p.sdata))
+	rn.Range = text.Region{A: 0, B: len(p.sdata)}
+
+	// handling unicode characters different length
+	if len(p.sdata) != 0 {
+		lut := make([]int, len(p.sdata)+1)
+		j := 0
+		for i := range p.sdata {
+			lut[i] = j
+			j++
+		}
+		lut[len(p.sdata)] = len(p.data)
+		p.patch(lut, rn)
+	}
+	return rn, nil
+}
+
+func (p *Parser) top() *frame {
+	return p.stack[len(p.stack)-1]
+}
+
+// Returns the node new nodes of the frame at index i should be appended to.
+func (p *Parser) container(i int) *parser.Node {
+	for ; i >= 0; i-- {
+		if f := p.stack[i]; f.content != nil {
+			return f.content
+		} else if f.meta != nil {
+			return f.meta
+		}
+	}
+	return nil
+}
+
+func (p *Parser) parseLine(ls, le int) {
+	line := p.sdata[ls:le]
+	zero := 0
+	for pos := ls; pos < le; {
+		f := p.top()
+		pat, mo := p.firstMatch(f, line, pos-ls)
+		esc, emo := p.escapeMatch(line, pos-ls)
+		shift(mo, ls)
+		shift(emo, ls)
+
+		if esc != -1 && (mo == nil || emo[0] <= mo[0]) {
+			p.applyEscape(esc, emo)
+			pos = p.advance(pos, emo, &zero, true)
+			continue
+		}
+		if mo == nil {
+			return
+		}
+		if mo[0] == mo[1] && !pat.pat.changesStack() {
+			// a zero length match without effect, it would match again
+			// on the same position forever
+			pos = p.advance(pos, mo, &zero, false)
+			continue
+		}
+		p.apply(pat, mo)
+		pos = p.advance(pos, mo, &zero, true)
+	}
+}
+
+// Returns the position parsing should continue from after the match mo.
+func (p *Parser) advance(pos int, mo []int, zero *int, progress bool) int {
+	if mo[1] > pos {
+		*zero = 0
+		return mo[1]
+	}
+	if *zero++; progress && *zero < maxZeroLength {
+		return pos
+	}
+	*zero = 0
+	if pos < len(p.sdata) {
+		_, n := utf8.DecodeRuneInString(p.sdata[pos:])
+		return pos + n
+	}
+	return pos + 1
+}
+
+// Makes the match indexes relative to the start of the data
+// rather than the start of the line.
+func shift(mo textmate.MatchObject, add int) {
+	for i := range mo {
+		if mo[i] != -1 {
+			mo[i] += add
+		}
+	}
+}
+
+// Finds the pattern of the frame which matches first after pos.
+func (p *Parser) firstMatch(f *frame, data string, pos int) (ret candidate, mo textmate.MatchObject) {
+	for _, c := range p.patterns(f) {
+		var m textmate.MatchObject
+		if c.pat.dynamic() {
+			if f.captures == nil {
+				continue
+			}
+			if re := p.compile(c.pat.Match.withBackrefs(f.captures)); re != nil {
+				m = re.FindStringSubmatchIndexFrom(data, pos)
+			}
+		} else {
+			m = c.pat.Match.Find(data, pos)
+		}
+		if m != nil && (mo == nil || m[0] < mo[0]) {
+			ret, mo = c, m
+			if m[0] == pos {
+				break
+			}
+		}
+	}
+	return
+}
+
+// Finds the first matching escape of the embedded syntaxes on the stack,
+// returns the stack index of the embedding frame or -1.
+func (p *Parser) escapeMatch(data string, pos int) (idx int, mo textmate.MatchObject) {
+	idx = -1
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i].escape == nil {
+			continue
+		}
+		if m := p.stack[i].escape.FindStringSubmatchIndexFrom(data, pos); m != nil && (mo == nil || m[0] < mo[0]) {
+			idx, mo = i, m
+		}
+	}
+	return
+}
+
+// Returns the patterns which apply to the frame in the order they are
+// tried: with_prototype patterns, the prototype and the context itself.
+func (p *Parser) patterns(f *frame) []candidate {
+	if f.cands != nil {
+		return f.cands
+	}
+	ret := make([]candidate, 0, len(f.ctx))
+	ret = append(ret, f.protos...)
+	visited := make(map[string]bool)
+	if _, _, proto := f.ctx.meta(); proto && f.name != "prototype" {
+		if pr, ok := f.syn.Contexts["prototype"]; ok {
+			ret = p.expand(ret, pr, f.syn, visited)
+		}
+	}
+	f.cands = p.expand(ret, f.ctx, f.syn, visited)
+	return f.cands
+}
+
+// Appends the match patterns of ctx to ret following the includes.
+func (p *Parser) expand(ret []candidate, ctx Context, syn *Syntax, visited map[string]bool) []candidate {
+	for i := range ctx {
+		pat := &ctx[i]
+		if pat.Include == "" {
+			if !pat.Match.Empty() || pat.dynamic() {
+				ret = append(ret, candidate{pat, syn})
+			}
+			continue
+		}
+		key := syn.Scope + "#" + pat.Include
+		if visited[key] {
+			continue
+		}
+		visited[key] = true
+		if c, s, _ := p.resolve(syn, pat.Include); c != nil {
+			ret = p.expand(ret, c, s, visited)
+		}
+	}
+	return ret
+}
+
+// Finds the context referenced by name from syntax syn. The name could
+// be a context of syn, "scope:<scope>[#<context>]" or a path to a
+// sublime-syntax file optionally followed by "#<context>".
+func (p *Parser) resolve(syn *Syntax, name string) (Context, *Syntax, string) {
+	var (
+		s   *Syntax
+		err error
+	)
+	ctx := "main"
+	if i := strings.IndexByte(name, '#'); i != -1 {
+		name, ctx = name[:i], name[i+1:]
+	}
+	switch {
+	case name == "$self" || name == "$top_level_main":
+		s = syn
+	case strings.HasPrefix(name, "scope:"):
+		s, err = provider.SyntaxFromScope(name[6:])
+	case strings.HasSuffix(name, ".sublime-syntax"):
+		s, err = provider.SyntaxFromFile(name)
+	default:
+		s, ctx = syn, name
+	}
+	if err != nil {
+		log.Warn("Couldn't resolve context %s: %s", name, err)
+		return nil, nil, ""
+	}
+	if c, ok := s.Contexts[ctx]; ok {
+		return c, s, ctx
+	}
+	log.Fine("Not found in %s contexts: %s", syn.Name, ctx)
+	return nil, nil, ""
+}
+
+func (p *Parser) compile(pattern string) *rubex.Regexp {
+	if re, ok := p.dynamic[pattern]; ok {
+		return re
+	}
+	re, err := rubex.CompileWithOption(pattern, rubex.ONIG_OPTION_CAPTURE_GROUP)
+	if err != nil {
+		log.Warn("Couldn't compile syntax pattern %s: %s", pattern, err)
+		re = nil
+	}
+	p.dynamic[pattern] = re
+	return re
+}
+
+func (p *Parser) apply(c candidate, mo textmate.MatchObject) {
+	pat := c.pat
+	switch {
+	case pat.Pop:
+		// The popped context meta_scope also applies to the matched text
+		i := len(p.stack) - 1
+		p.matchNodes(pat, mo, p.metaContainer(i))
+		p.popTo(i, mo[0], mo[1])
+	case len(pat.Set) > 0:
+		i := len(p.stack) - 1
+		protos := p.top().protos
+		p.popTo(i, mo[0], mo[0])
+		p.push(c, pat.Set, protos, mo)
+	case len(pat.Push) > 0:
+		p.push(c, pat.Push, p.top().protos, mo)
+	case pat.Embed != "":
+		p.embed(c, mo)
+	default:
+		p.matchNodes(pat, mo, p.container(len(p.stack)-1))
+	}
+}
+
+// Returns the node the text of a match popping the frame at index i belongs to.
+func (p *Parser) metaContainer(i int) *parser.Node {
+	if f := p.stack[i]; f.meta != nil {
+		return f.meta
+	}
+	return p.container(i - 1)
+}
+
+// Pops the frames from index i up, content scopes end at contentEnd
+// and meta scopes at metaEnd. The bottom frame is never popped.
+func (p *Parser) popTo(i, contentEnd, metaEnd int) {
+	for j := len(p.stack) - 1; j >= i; j-- {
+		f := p.stack[j]
+		if f.content != nil {
+			f.content.Range.B = contentEnd
+		}
+		if f.meta != nil {
+			f.meta.Range.B = metaEnd
+		}
+	}
+	if i < 1 {
+		i = 1
+	}
+	if i < len(p.stack) {
+		p.stack = p.stack[:i]
+	}
+}
+
+func (p *Parser) push(c candidate, refs ContextRefs, protos []candidate, mo textmate.MatchObject) {
+	if len(c.pat.WithPrototype) > 0 {
+		protos = append(append([]candidate{}, protos...), p.expand(nil, c.pat.WithPrototype, c.syn, make(map[string]bool))...)
+	}
+	captures := p.captures(mo)
+	inner := p.container(len(p.stack) - 1)
+	for i, ref := range refs {
+		f := &frame{ctx: ref.Context, syn: c.syn, captures: captures, protos: protos}
+		if ref.Name != "" {
+			if f.ctx, f.syn, f.name = p.resolve(c.syn, ref.Name); f.ctx == nil {
+				continue
+			}
+		}
+		parent := p.container(len(p.stack) - 1)
+		meta, content, _ := f.ctx.meta()
+		if meta != "" {
+			f.meta = &parser.Node{Name: meta, Range: text.Region{A: mo[0], B: mo[0]}, P: p}
+			parent.Append(f.meta)
+			inner = f.meta
+		}
+		if content != "" {
+			// Only the last pushed context starts after the match, the
+			// previous ones contain the next context's meta scope
+			start := mo[0]
+			if i == len(refs)-1 {
+				start = mo[1]
+			}
+			f.content = &parser.Node{Name: content, Range: text.Region{A: start, B: start}, P: p}
+			if f.meta != nil {
+				f.meta.Append(f.content)
+			} else {
+				parent.Append(f.content)
+			}
+		}
+		p.stack = append(p.stack, f)
+	}
+	p.matchNodes(c.pat, mo, inner)
+	// Keep the children sorted, the match is before the content scope
+	sortNodes(inner)
+}
+
+func (p *Parser) embed(c candidate, mo textmate.MatchObject) {
+	pat := c.pat
+	p.matchNodes(pat, mo, p.container(len(p.stack)-1))
+
+	ctx, syn, name := p.resolve(c.syn, pat.Embed)
+	if ctx == nil {
+		return
+	}
+	f := &frame{ctx: ctx, syn: syn, name: name, captures: p.captures(mo), escapeCaptures: pat.EscapeCaptures}
+	if len(pat.WithPrototype) > 0 {
+		f.protos = p.expand(nil, pat.WithPrototype, c.syn, make(map[string]bool))
+	}
+	if pat.Escape.re != nil {
+		f.escape = pat.Escape.re
+	} else if pat.Escape.Pattern != "" {
+		f.escape = p.compile(pat.Escape.withBackrefs(f.captures))
+	}
+	name = syn.Scope
+	if pat.EmbedScope != "" {
+		name = pat.EmbedScope
+	}
+	f.content = &parser.Node{Name: name, Range: text.Region{A: mo[1], B: mo[1]}, P: p}
+	p.container(len(p.stack) - 1).Append(f.content)
+	p.stack = append(p.stack, f)
+}
+
+// Leaves the embedded syntax of the frame at index i.
+func (p *Parser) applyEscape(i int, mo textmate.MatchObject) {
+	capt := p.stack[i].escapeCaptures
+	p.popTo(i, mo[0], mo[0])
+	p.captureNodes(mo, capt, p.container(len(p.stack)-1))
+}
+
+// Returns the text of each group of the match.
+func (p *Parser) captures(mo textmate.MatchObject) []string {
+	ret := make([]string, len(mo)/2)
+	for i := range ret {
+		if a, b := mo[i*2], mo[i*2+1]; a >= 0 && b >= a {
+			ret[i] = p.sdata[a:b]
+		}
+	}
+	return ret
+}
+
+// Creates the nodes for the scope and captures of the match.
+func (p *Parser) matchNodes(pat *Pattern, mo textmate.MatchObject, parent *parser.Node) {
+	if pat.Scope != "" && mo[0] != mo[1] {
+		n := &parser.Node{Name: pat.Scope, Range: text.Region{A: mo[0], B: mo[1]}, P: p}
+		parent.Append(n)
+		parent = n
+	}
+	p.captureNodes(mo, pat.Captures, parent)
+}
+
+// Creates a node for each non empty capture, a capture is the child of the
+// previous capture containing it.
+func (p *Parser) captureNodes(mo textmate.MatchObject, capt textmate.Captures, parent *parser.Node) {
+	var created []*parser.Node
+	for _, c := range capt {
+		i := c.Key
+		if i*2+1 >= len(mo) || mo[i*2] < 0 || mo[i*2] == mo[i*2+1] {
+			continue
+		}
+		child := &parser.Node{Name: c.Name, Range: text.Region{A: mo[i*2], B: mo[i*2+1]}, P: p}
+		n := parent
+		for j := len(created) - 1; j >= 0; j-- {
+			if created[j].Range.Covers(child.Range) {
+				n = created[j]
+				break
+			}
+		}
+		n.Append(child)
+		sortNodes(n)
+		created = append(created, child)
+	}
+}
+
+func sortNodes(n *parser.Node) {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		return n.Children[i].Range.A < n.Children[j].Range.A
+	})
+}
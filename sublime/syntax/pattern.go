@@ -4,7 +4,16 @@
 
 package syntax
 
-import "github.com/jxo/lime/sublime/textmate"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/rubex"
+	"github.com/jxo/lime/sublime/textmate"
+	"gopkg.in/yaml.v1"
+)
 
 type (
 	Context []Pattern
@@ -13,13 +22,210 @@ type (
 		Include              string
 		MetaScope            string `yaml:"meta_scope"`
 		MetaContentScope     string `yaml:"meta_content_scope"`
-		MetaIncludePrototype string `yaml:"meta_include_prototype"`
-		Match                textmate.Regex
+		MetaIncludePrototype *bool  `yaml:"meta_include_prototype"`
+		Match                Regex
 		Scope                string
 		Captures             textmate.Captures
-		Push                 Context
+		Push                 ContextRefs
 		Pop                  bool
-		Set                  string
+		Set                  ContextRefs
 		Syntax               string
+		Embed                string
+		EmbedScope           string `yaml:"embed_scope"`
+		Escape               Regex
+		EscapeCaptures       textmate.Captures `yaml:"escape_captures"`
+		WithPrototype        Context           `yaml:"with_prototype"`
 	}
+
+	// ContextRef references a context either by its name(which may
+	// point into another syntax, e.g "scope:source.c#main") or
+	// holds an anonymous context defined inline.
+	ContextRef struct {
+		Name    string
+		Context Context
+	}
+
+	// The value of push and set which is either a single context or
+	// a list of contexts pushed in order.
+	ContextRefs []ContextRef
+
+	// Regex keeps the pattern as written in the syntax file so
+	// variables could be substituted before compiling it.
+	Regex struct {
+		Pattern string
+		re      *rubex.Regexp
+	}
+)
+
+var (
+	variableRe = regexp.MustCompile(`\{\{([A-Za-z0-9_]+)\}\}`)
+	backrefRe  = regexp.MustCompile(`\\([1-9])`)
 )
+
+// Returns the meta_scope, meta_content_scope and whether the prototype
+// context should be included for this context.
+func (c Context) meta() (scope, content string, prototype bool) {
+	prototype = true
+	for _, p := range c {
+		if p.Match.Pattern != "" || p.Include != "" {
+			break
+		}
+		if p.MetaScope != "" {
+			scope = p.MetaScope
+		}
+		if p.MetaContentScope != "" {
+			content = p.MetaContentScope
+		}
+		if p.MetaIncludePrototype != nil {
+			prototype = *p.MetaIncludePrototype
+		}
+	}
+	return
+}
+
+func (c Context) tweak(vars map[string]string) {
+	for i := range c {
+		c[i].tweak(vars)
+	}
+}
+
+func (p *Pattern) tweak(vars map[string]string) {
+	p.Scope = strings.TrimSpace(p.Scope)
+	// Back references to the pushing match can only be compiled
+	// once the captured text is known
+	if p.Pop && p.Match.hasBackrefs() {
+		p.Match.Pattern = expand(p.Match.Pattern, vars)
+	} else {
+		p.Match.compile(vars)
+	}
+	if p.Escape.hasBackrefs() {
+		p.Escape.Pattern = expand(p.Escape.Pattern, vars)
+	} else {
+		p.Escape.compile(vars)
+	}
+	p.Push.tweak(vars)
+	p.Set.tweak(vars)
+	p.WithPrototype.tweak(vars)
+}
+
+// Whether the pattern is a pop pattern referencing the captures
+// of the match which pushed its context.
+func (p *Pattern) dynamic() bool {
+	return p.Pop && p.Match.re == nil && p.Match.hasBackrefs()
+}
+
+// Whether the pattern changes the context stack when it matches.
+func (p *Pattern) changesStack() bool {
+	return p.Pop || len(p.Push) > 0 || len(p.Set) > 0 || p.Embed != ""
+}
+
+func (c *ContextRefs) SetYAML(tag string, value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		*c = ContextRefs{{Name: v}}
+		return true
+	case []interface{}:
+		names := make(ContextRefs, 0, len(v))
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				names = nil
+				break
+			}
+			names = append(names, ContextRef{Name: s})
+		}
+		if names != nil {
+			*c = names
+			return true
+		}
+		// Anonymous context, round trip it through yaml so each
+		// pattern gets decoded like the named contexts
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			log.Warn("Couldn't decode anonymous context: %s", err)
+			return false
+		}
+		var ctx Context
+		if err := yaml.Unmarshal(data, &ctx); err != nil {
+			log.Warn("Couldn't decode anonymous context: %s", err)
+			return false
+		}
+		*c = ContextRefs{{Context: ctx}}
+		return true
+	}
+	return false
+}
+
+func (c ContextRefs) tweak(vars map[string]string) {
+	for _, ref := range c {
+		ref.Context.tweak(vars)
+	}
+}
+
+func (r *Regex) SetYAML(tag string, value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	r.Pattern = fmt.Sprint(value)
+	return true
+}
+
+func (r Regex) Empty() bool {
+	return r.re == nil
+}
+
+func (r Regex) String() string {
+	return r.Pattern
+}
+
+// Substitutes variables and compiles the pattern, failures are
+// logged and leave the regex empty like the tmLanguage patterns.
+func (r *Regex) compile(vars map[string]string) {
+	if r.Pattern == "" {
+		return
+	}
+	r.Pattern = expand(r.Pattern, vars)
+	if re, err := rubex.CompileWithOption(r.Pattern, rubex.ONIG_OPTION_CAPTURE_GROUP); err != nil {
+		log.Warn("Couldn't compile syntax pattern %s: %s", r.Pattern, err)
+	} else {
+		r.re = re
+	}
+}
+
+// Returns the submatch indexes of the first match in data at or after pos.
+func (r *Regex) Find(data string, pos int) textmate.MatchObject {
+	if r.re == nil {
+		return nil
+	}
+	return r.re.FindStringSubmatchIndexFrom(data, pos)
+}
+
+func (r *Regex) hasBackrefs() bool {
+	return backrefRe.MatchString(r.Pattern)
+}
+
+// Replaces back references(\1 - \9) in the pattern with the
+// quoted text captured by another match.
+func (r *Regex) withBackrefs(captures []string) string {
+	return backrefRe.ReplaceAllStringFunc(r.Pattern, func(s string) string {
+		i := int(s[1] - '0')
+		if i < len(captures) {
+			return rubex.QuoteMeta(captures[i])
+		}
+		return ""
+	})
+}
+
+// Replaces {{name}} occurrences with the variable value, variables
+// could themselves refer to other variables.
+func expand(s string, vars map[string]string) string {
+	for i := 0; i < 10 && strings.Contains(s, "{{"); i++ {
+		s = variableRe.ReplaceAllStringFunc(s, func(m string) string {
+			if v, ok := vars[m[2:len(m)-2]]; ok {
+				return v
+			}
+			return m
+		})
+	}
+	return s
+}
@@ -5,22 +5,37 @@
 package syntax
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v1"
 )
 
-// For loading sublime-syntax files
-// https://www.sublimetext.com/docs/3/syntax.html
-type Syntax struct {
-	Name           string
-	FileExtensions []string `yaml:"file_extensions"`
-	FirstLineMatch string   `yaml:"first_line_match"`
-	Scope          string
-	Variables      map[string]string
-	Hidden         bool
-	Contexts       map[string]Context
-}
+type (
+	// For loading sublime-syntax files
+	// https://www.sublimetext.com/docs/3/syntax.html
+	Syntax struct {
+		Name           string
+		FileExtensions []string `yaml:"file_extensions"`
+		FirstLineMatch string   `yaml:"first_line_match"`
+		Scope          string
+		Variables      map[string]string
+		Hidden         bool
+		Contexts       map[string]Context
+	}
+
+	// Keeps track of loaded syntaxes so they could be referenced by
+	// scope or path from include and embed directives.
+	Provider struct {
+		sync.Mutex
+		scope  map[string]string
+		loaded map[string]*Syntax
+	}
+)
 
 func Load(filename string) (*Syntax, error) {
 	var syn Syntax
@@ -32,6 +47,69 @@ func Load(filename string) (*Syntax, error) {
 	if err != nil {
 		return nil, err
 	}
-
+	if _, ok := syn.Contexts["main"]; !ok {
+		return nil, fmt.Errorf("%s has no main context", filename)
+	}
+	syn.tweak()
+	provider.Add(syn.Scope, filename, &syn)
 	return &syn, nil
 }
+
+// Expands the variables and compiles every pattern of the syntax.
+func (s *Syntax) tweak() {
+	s.Scope = strings.TrimSpace(s.Scope)
+	vars := make(map[string]string, len(s.Variables))
+	for k, v := range s.Variables {
+		vars[k] = expand(v, s.Variables)
+	}
+	s.Variables = vars
+	for _, c := range s.Contexts {
+		c.tweak(vars)
+	}
+}
+
+func (t *Provider) Add(scope, filename string, s *Syntax) {
+	t.Lock()
+	defer t.Unlock()
+	t.scope[scope] = filename
+	t.loaded[filename] = s
+}
+
+// Returns the syntax with the given scope name.
+func (t *Provider) SyntaxFromScope(scope string) (*Syntax, error) {
+	t.Lock()
+	fn, ok := t.scope[scope]
+	t.Unlock()
+	if !ok {
+		return nil, errors.New("Can't handle scope " + scope)
+	}
+	return t.SyntaxFromFile(fn)
+}
+
+// Returns the syntax loaded from filename, a "Packages/<pkg>/<file>"
+// path as used by sublime is matched against the loaded files suffix.
+func (t *Provider) SyntaxFromFile(filename string) (*Syntax, error) {
+	t.Lock()
+	s, ok := t.loaded[filename]
+	if !ok && strings.HasPrefix(filename, "Packages/") {
+		suffix := filepath.FromSlash(strings.TrimPrefix(filename, "Packages"))
+		for fn, syn := range t.loaded {
+			if strings.HasSuffix(fn, suffix) {
+				s, ok = syn, true
+				break
+			}
+		}
+	}
+	t.Unlock()
+	if ok {
+		return s, nil
+	}
+	return Load(filename)
+}
+
+var provider Provider
+
+func init() {
+	provider.scope = make(map[string]string)
+	provider.loaded = make(map[string]*Syntax)
+}
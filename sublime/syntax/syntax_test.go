@@ -4,12 +4,142 @@
 
 package syntax
 
-import "testing"
-
-func Test(t *testing.T) {
-	// syn, err := Load("testdata/Go.sublime-syntax")
-	// if err != nil {
-	// 	t.Error(err)
-	// }
-	// fmt.Printf("%+v\n", pretty.PrettyFormat(syn))
+import (
+	"strings"
+	"testing"
+
+	"github.com/jxo/lime/parser"
+)
+
+const (
+	testSyntax     = "testdata/Test.sublime-syntax"
+	embeddedSyntax = "testdata/Embedded.sublime-syntax"
+)
+
+func TestLoad(t *testing.T) {
+	files := []string{
+		"testdata/Go.sublime-syntax",
+		testSyntax,
+		embeddedSyntax,
+	}
+	for _, fn := range files {
+		if _, err := Load(fn); err != nil {
+			t.Errorf("Tried to load %s, but got an error: %v", fn, err)
+		}
+	}
+
+	if _, err := Load("MissingFile"); err == nil {
+		t.Error("Tried to load MissingFile, expecting to get an error, but didn't")
+	}
+}
+
+func TestVariables(t *testing.T) {
+	syn, err := Load(testSyntax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `\b(let)\s+([a-z]+)`
+	if got := syn.Contexts["main"][0].Match.Pattern; got != exp {
+		t.Errorf("Expected the variables in the pattern to be expanded to %q, but got %q", exp, got)
+	}
+}
+
+func TestProviderSyntaxFromScope(t *testing.T) {
+	syn, _ := Load(embeddedSyntax)
+
+	if _, err := provider.SyntaxFromScope(syn.Scope); err != nil {
+		t.Errorf("Tried to load %s, but got an error: %v", syn.Scope, err)
+	}
+	if _, err := provider.SyntaxFromScope("MissingScope"); err == nil {
+		t.Error("Tried to load MissingScope, expecting to get an error, but didn't")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Load(embeddedSyntax); err != nil {
+		t.Fatal(err)
+	}
+	syn, err := Load(testSyntax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := strings.Join([]string{
+		`let foo # note`,
+		`"str # not comment"`,
+		`embed: 12 ; 34`,
+		`{ ! }`,
+		`!`,
+		`func go() x`,
+		`<<EOF`,
+		`body`,
+		`EOF`,
+		`x`,
+	}, "\n")
+
+	sh, err := parser.NewSyntaxHighlighter(NewParser(syn, []rune(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		at    string
+		nth   int
+		scope string
+	}{
+		{"let", 0, "source.test keyword.test"},
+		{"foo", 0, "source.test variable.test"},
+		{"#", 0, "source.test comment.line.test"},
+		{"str", 0, "source.test string.quoted.test"},
+		{"#", 1, "source.test string.quoted.test"},
+		{"12", 0, "source.test meta.embedded.test constant.numeric.embedded"},
+		{";", 0, "source.test punctuation.terminator.test"},
+		{"34", 0, "source.test"},
+		{"{", 0, "source.test meta.block.test"},
+		{"!", 0, "source.test meta.block.test meta.block.content.test keyword.bang.test"},
+		{"!", 1, "source.test"},
+		{"go", 0, "source.test meta.params.test entity.name.function.test"},
+		{"(", 0, "source.test meta.params.test"},
+		{"x", 0, "source.test"},
+		{"body", 0, "source.test string.heredoc.test"},
+		{"x", 1, "source.test"},
+	}
+	for i, test := range tests {
+		pos := nthIndex(data, test.at, test.nth)
+		if pos == -1 {
+			t.Fatalf("Test %d: %q not found in data", i, test.at)
+		}
+		if got := sh.ScopeName(pos); got != test.scope {
+			t.Errorf("Test %d: Expected scope of %q at %d to be %q, but got %q", i, test.at, pos, test.scope, got)
+		}
+	}
+}
+
+func TestParseUnicode(t *testing.T) {
+	syn, err := Load(testSyntax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []rune(`"ĉĝ" let foo`)
+	sh, err := parser.NewSyntaxHighlighter(NewParser(syn, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := sh.ScopeName(5), "source.test keyword.test"; got != exp {
+		t.Errorf("Expected scope at 5 to be %q, but got %q", exp, got)
+	}
+}
+
+// Returns the index of the nth occurrence of sub in s.
+func nthIndex(s, sub string, nth int) int {
+	off := 0
+	for ; nth >= 0; nth-- {
+		i := strings.Index(s[off:], sub)
+		if i == -1 {
+			return -1
+		}
+		if nth == 0 {
+			return off + i
+		}
+		off += i + len(sub)
+	}
+	return -1
 }
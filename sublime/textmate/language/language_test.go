@@ -145,3 +145,54 @@ func getParser(fn string, data string) (*Parser, error) {
 	}
 	return NewParser(l, []rune(data)), nil
 }
+
+func TestReparse(t *testing.T) {
+	if _, err := Load(gotmLang); err != nil {
+		t.Fatal(err)
+	}
+	d, err := ioutil.ReadFile("testdata/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := string(d)
+	tests := []struct {
+		pos int
+		del int
+		ins string
+	}{
+		{0, 0, "// "},
+		{120, 0, "x"},
+		{300, 4, ""},
+		{500, 0, "\"unterminated\n"},
+		{500, 14, ""},
+		{700, 0, "/* comment\n\n*/"},
+		{len(data) - 1, 0, "\nfunc main() {}\n"},
+		{200, 0, "ĉĝ"},
+		{40, 10, "\n\n"},
+	}
+	pr, err := getParser("source.go", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pr.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	for i, test := range tests {
+		data = data[:test.pos] + test.ins + data[test.pos+test.del:]
+		root, r, err := pr.Reparse([]rune(data))
+		if err != nil {
+			t.Fatalf("Test %d: %s", i, err)
+		}
+		if r.Size() == 0 || r.Size() == len([]rune(data)) {
+			t.Errorf("Test %d: Expected only a part of the data to be reparsed, but got %s", i, r)
+		}
+		exp, _ := getParser("source.go", data)
+		expRoot, err := exp.Parse()
+		if err != nil {
+			t.Fatalf("Test %d: %s", i, err)
+		}
+		if diff := util.Diff(expRoot.String(), root.String()); diff != "" {
+			t.Errorf("Test %d: Reparse differs from a full parse:\n%s", i, diff)
+		}
+	}
+}
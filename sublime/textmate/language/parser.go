@@ -5,6 +5,8 @@
 package language
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/jxo/lime/log"
@@ -12,14 +14,55 @@ import (
 	"github.com/jxo/lime/text"
 )
 
-// implements parser.Parser + parser.DataSource
-type Parser struct {
-	l    *Language
-	data []rune
-}
+type (
+	// implements parser.IncrementalParser + parser.DataSource
+	Parser struct {
+		l     *Language
+		data  []rune
+		sdata string
+		// the tree of the last parse, positions are byte offsets in sdata
+		root *parser.Node
+		// the parser state at the start of each parsed line
+		lines []state
+	}
+
+	// A begin/end pattern match whose end hasn't been found yet.
+	frame struct {
+		pat  *Pattern
+		node *parser.Node
+		rng  text.Region // node range when the begin was matched
+		// number of node children when the state was saved
+		children int
+		pos      int
+		end      int // the last end match, -1 for the end of data
+		found    bool
+	}
+
+	// The parser state at a position, enough to resume parsing from there.
+	state struct {
+		pos      int
+		children int // number of root node children
+		stack    []frame
+	}
+
+	// Keeps what's needed from the previous parse while reparsing
+	// so the old nodes could be reused once the state converges.
+	reparse struct {
+		lines []state // old states after the resumed one
+		delta int
+		end   int // old position where the unchanged data starts
+		// old children and range of the nodes reopened on resume
+		children map[*parser.Node][]*parser.Node
+		ranges   map[*parser.Node]text.Region
+		// position where the state converged
+		conv int
+	}
+)
+
+const maxiter = 10000
 
 func NewParser(l *Language, data []rune) *Parser {
-	return &Parser{l, data}
+	return &Parser{l: l, data: data}
 }
 
 func (p *Parser) Data(a, b int) string {
@@ -37,55 +80,344 @@ func (p *Parser) patch(lut []int, node *parser.Node) {
 	}
 }
 
-const maxiter = 10000
+// Returns the byte offset to rune offset look up table for the data, nil
+// if there are no multi byte characters.
+func (p *Parser) lut() []int {
+	if len(p.sdata) == len(p.data) {
+		return nil
+	}
+	lut := make([]int, len(p.sdata)+1)
+	j := 0
+	for i := range p.sdata {
+		lut[i] = j
+		j++
+	}
+	lut[len(p.sdata)] = len(p.data)
+	return lut
+}
 
 // Creates a directed acyclic graph from the data using the language
 // finds the match pattern starting from each character in data then creates a
 // node from the matched pattern and appends it to the root node
 func (p *Parser) Parse() (*parser.Node, error) {
-	sdata := string(p.data)
-	rn := parser.Node{P: p, Name: p.l.ScopeName}
-	defer rn.UpdateRange()
+	p.sdata = string(p.data)
+	p.root = &parser.Node{P: p, Name: p.l.ScopeName}
+	p.lines = nil
+	p.reset()
+	if err := p.parse(state{}, nil); err != nil {
+		p.root = nil
+		return nil, err
+	}
+	return p.result(), nil
+}
+
+// Reparse parses again only the lines starting from the first changed one
+// until the parser state at the start of a line is the same as it was on
+// the last parse, the rest of the old nodes are kept.
+func (p *Parser) Reparse(data []rune) (*parser.Node, text.Region, error) {
+	if p.root == nil {
+		p.data = data
+		root, err := p.Parse()
+		return root, text.Region{A: 0, B: len(data)}, err
+	}
+	old, sdata := p.sdata, string(data)
+	a := 0
+	for a < len(old) && a < len(sdata) && old[a] == sdata[a] {
+		a++
+	}
+	b := 0
+	for b < len(old)-a && b < len(sdata)-a && old[len(old)-1-b] == sdata[len(sdata)-1-b] {
+		b++
+	}
+	p.data, p.sdata = data, sdata
+	if a == len(old) && a == len(sdata) {
+		return p.result(), text.Region{}, nil
+	}
+
+	// resume from the last state saved before the changed line
+	ls := strings.LastIndex(sdata[:a], "\n") + 1
+	k := sort.Search(len(p.lines), func(i int) bool {
+		return p.lines[i].pos > ls
+	}) - 1
+	st := p.lines[k]
+	rp := &reparse{
+		lines:    p.lines[k+1:],
+		delta:    len(sdata) - len(old),
+		end:      len(old) - b,
+		children: make(map[*parser.Node][]*parser.Node),
+		ranges:   make(map[*parser.Node]text.Region),
+		conv:     len(sdata),
+	}
+	p.lines = p.lines[:k:k]
+	// the end matches found before the change might be after it
+	for _, l := range p.lines {
+		for i := range l.stack {
+			l.stack[i].end = rp.shift(l.stack[i].end)
+		}
+	}
+	st.stack = append([]frame(nil), st.stack...)
+	rp.reopen(p.root, st.children)
+	for i := range st.stack {
+		f := &st.stack[i]
+		rp.reopen(f.node, f.children)
+		f.node.Range = f.rng
+	}
+	p.reset()
+	for _, f := range st.stack {
+		f.pat.reset()
+	}
+	if err := p.parse(st, rp); err != nil {
+		p.root = nil
+		return nil, text.Region{}, err
+	}
+	r := text.Region{A: st.pos, B: rp.conv}
+	if lut := p.lut(); lut != nil {
+		r = text.Region{A: lut[r.A], B: lut[r.B]}
+	}
+	return p.result(), r, nil
+}
+
+// Returns a copy of the parse tree with rune offsets, the parser keeps its own
+// tree to be able to reparse.
+func (p *Parser) result() *parser.Node {
+	rn := p.root.Clone()
+	if lut := p.lut(); lut != nil {
+		p.patch(lut, rn)
+	}
+	return rn
+}
+
+func (p *Parser) reset() {
+	p.l.RootPattern.reset()
+	for _, pat := range p.l.Repository {
+		pat.reset()
+	}
+}
+
+// Parses the data from state st till the end of data, or if rp isn't nil
+// until the state converges with a state of the last parse.
+func (p *Parser) parse(st state, rp *reparse) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Error("Panic during parse: %v\n", r)
-			log.Debug("%v", rn)
+			log.Debug("%v", p.root)
+			err = fmt.Errorf("Panic during parse: %v", r)
 		}
 	}()
-	iter := maxiter
-	for i := 0; i < len(sdata) && iter > 0; iter-- {
-		pat, ret := p.l.RootPattern.Cache(sdata, i)
-		if ret == nil {
-			break
+	var (
+		data  = p.sdata
+		stack = st.stack
+		i     = st.pos
+		next  = st.pos // start of the next line to save the state for
+		last  = -1
+		iter  = maxiter
+	)
+	for {
+		cur := i
+		if len(stack) > 0 {
+			cur = stack[len(stack)-1].pos
 		}
-		nl := strings.IndexAny(sdata[i:], "\n\r")
-		// if the match object is not in this line we will move the
-		// position to beginning of the next line then we will look for
-		// a match again
-		if nl += i; nl != i-1 && nl <= ret[0] {
-			i = nl
-			for i < len(sdata) && (sdata[i] == '\n' || sdata[i] == '\r') {
-				i++
+		if cur >= next {
+			s := p.save(cur, stack)
+			if rp != nil && cur > st.pos && rp.converge(p, s) {
+				return
+			}
+			p.lines = append(p.lines, s)
+			if nl := strings.IndexByte(data[cur:], '\n'); nl != -1 {
+				next = cur + nl + 1
+			} else {
+				next = len(data) + 1
+			}
+		}
+		// guards against patterns matching over and over at the same position
+		if cur != last {
+			last, iter = cur, maxiter
+		} else if iter--; iter == 0 {
+			panic("reached maximum number of iterations")
+		}
+
+		if len(stack) == 0 {
+			if i >= len(data) {
+				break
+			}
+			pat, ret := p.l.RootPattern.Cache(data, i)
+			if ret == nil {
+				break
+			}
+			nl := strings.IndexAny(data[i:], "\n\r")
+			// if the match object is not in this line we will move the
+			// position to beginning of the next line then we will look for
+			// a match again
+			if nl += i; nl != i-1 && nl <= ret[0] {
+				i = nl
+				for i < len(data) && (data[i] == '\n' || data[i] == '\r') {
+					i++
+				}
+				continue
+			}
+			n, open := pat.createNode(data, i, p, ret)
+			p.root.Append(n)
+			if open {
+				stack = append(stack, newFrame(pat, n))
+			} else {
+				i = n.Range.B
 			}
 			continue
 		}
-		n := pat.CreateNode(sdata, i, p, ret)
-		rn.Append(n)
-		i = n.Range.B
-	}
-	// handling unicode characters different length
-	if len(sdata) != 0 {
-		lut := make([]int, len(sdata)+1)
-		j := 0
-		for i := range sdata {
-			lut[i] = j
-			j++
+		stack = step(data, p, stack)
+		if len(stack) == 0 {
+			i = p.root.Children[len(p.root.Children)-1].Range.B
+		}
+	}
+	p.root.Range = text.Region{}
+	p.root.UpdateRange()
+	return
+}
+
+// Returns a copy of the current parser state.
+func (p *Parser) save(pos int, stack []frame) state {
+	s := state{pos: pos, children: len(p.root.Children), stack: make([]frame, len(stack))}
+	copy(s.stack, stack)
+	for i := range s.stack {
+		s.stack[i].children = len(s.stack[i].node.Children)
+	}
+	return s
+}
+
+func newFrame(pat *Pattern, n *parser.Node) frame {
+	return frame{pat: pat, node: n, rng: n.Range, pos: n.Range.B, end: -1}
+}
+
+// Does a single step of the top frame, pushing the frame of the sub pattern
+// found or popping the top frame once its end is found.
+func step(data string, d parser.DataSource, stack []frame) []frame {
+	f := &stack[len(stack)-1]
+	child, pat, open := f.step(data, d)
+	if child != nil {
+		if open {
+			stack = append(stack, newFrame(pat, child))
 		}
-		lut[len(sdata)] = len(p.data)
-		p.patch(lut, &rn)
+		return stack
+	}
+	stack = stack[:len(stack)-1]
+	if len(stack) > 0 {
+		stack[len(stack)-1].pos = f.node.Range.B
+	}
+	return stack
+}
+
+func (f *frame) close() {
+	f.node.Range.B = f.end
+	f.node.UpdateRange()
+}
+
+// Truncates the children of a node opened before the resumed state, keeping
+// the old ones aside in case the state converges.
+func (rp *reparse) reopen(n *parser.Node, children int) {
+	rp.children[n] = n.Children
+	rp.ranges[n] = n.Range
+	n.Children = n.Children[:children:children]
+}
+
+// Returns the children the node had on the last parse.
+func (rp *reparse) oldChildren(n *parser.Node) []*parser.Node {
+	if c, ok := rp.children[n]; ok {
+		return c
+	}
+	return n.Children
+}
+
+func (rp *reparse) oldRange(n *parser.Node) text.Region {
+	if r, ok := rp.ranges[n]; ok {
+		return r
 	}
-	if iter == 0 {
-		panic("reached maximum number of iterations")
+	return n.Range
+}
+
+// Shifts a position of the old parse to the new data.
+func (rp *reparse) shift(pos int) int {
+	if pos >= rp.end {
+		return pos + rp.delta
+	}
+	return pos
+}
+
+// Checks whether the state s is the same as the old one at the same position
+// of the unchanged data, in which case the rest of the old parse is appended
+// to the new one.
+func (rp *reparse) converge(p *Parser, s state) bool {
+	if s.pos-rp.delta < rp.end {
+		return false
+	}
+	j := sort.Search(len(rp.lines), func(i int) bool {
+		return rp.lines[i].pos+rp.delta >= s.pos
+	})
+	if j == len(rp.lines) || rp.lines[j].pos+rp.delta != s.pos || !rp.equal(rp.lines[j], s) {
+		return false
+	}
+	o := rp.lines[j]
+	// maps the old open nodes to their depth, the old states
+	// children counts of those nodes are off by diff
+	remap := make(map[*parser.Node]int, len(s.stack))
+	diff := make([]int, len(s.stack))
+	for d := range s.stack {
+		n, on := s.stack[d].node, o.stack[d].node
+		tail := rp.oldChildren(on)[o.stack[d].children:]
+		for _, c := range tail {
+			shiftNode(c, rp.delta)
+		}
+		n.Children = append(n.Children, tail...)
+		n.Range.B = rp.oldRange(on).B + rp.delta
+		remap[on] = d
+		diff[d] = s.stack[d].children - o.stack[d].children
+	}
+	tail := rp.oldChildren(p.root)[o.children:]
+	for _, c := range tail {
+		shiftNode(c, rp.delta)
+	}
+	p.root.Children = append(p.root.Children, tail...)
+	rdiff := s.children - o.children
+
+	// the old states after the converged one are still valid once
+	// shifted and pointed at the new nodes
+	for _, l := range rp.lines[j:] {
+		l.pos += rp.delta
+		l.children += rdiff
+		for i := range l.stack {
+			f := &l.stack[i]
+			if d, ok := remap[f.node]; ok && d == i {
+				f.node, f.rng = s.stack[d].node, s.stack[d].rng
+				f.children += diff[d]
+			} else {
+				f.rng = text.Region{A: rp.shift(f.rng.A), B: rp.shift(f.rng.B)}
+			}
+			f.pos, f.end = rp.shift(f.pos), rp.shift(f.end)
+		}
+		p.lines = append(p.lines, l)
+	}
+	p.root.Range = text.Region{}
+	p.root.UpdateRange()
+	rp.conv = s.pos
+	return true
+}
+
+func (rp *reparse) equal(o, s state) bool {
+	if len(o.stack) != len(s.stack) {
+		return false
+	}
+	for i := range o.stack {
+		of, sf := o.stack[i], s.stack[i]
+		if of.pat != sf.pat || of.found != sf.found {
+			return false
+		}
+	}
+	return true
+}
+
+func shiftNode(n *parser.Node, delta int) {
+	n.Range.A += delta
+	n.Range.B += delta
+	for _, c := range n.Children {
+		shiftNode(c, delta)
 	}
-	return &rn, nil
 }
@@ -194,67 +194,100 @@ func (p *Pattern) CreateCaptureNodes(data string, pos int, d parser.DataSource,
 // Creates a root node for the pattern then creates a node for each capture and
 // appends them as child of root node
 func (p *Pattern) CreateNode(data string, pos int, d parser.DataSource, mo textmate.MatchObject) (ret *parser.Node) {
+	ret, open := p.createNode(data, pos, d, mo)
+	if !open {
+		return
+	}
+	stack := []frame{newFrame(p, ret)}
+	for len(stack) > 0 {
+		stack = step(data, d, stack)
+	}
+	return
+}
+
+// Creates the node of the pattern match, open is true when the pattern
+// is a begin/end pattern and the end still has to be searched for.
+func (p *Pattern) createNode(data string, pos int, d parser.DataSource, mo textmate.MatchObject) (ret *parser.Node, open bool) {
 	ret = &parser.Node{Name: p.Name, Range: text.Region{A: mo[0], B: mo[1]}, P: d}
-	defer ret.UpdateRange()
 
 	if !p.Match.Empty() {
 		p.CreateCaptureNodes(data, pos, d, mo, ret, p.Captures)
 	}
-	if p.Begin.Empty() {
-		return
-	}
-	if len(p.BeginCaptures) > 0 {
-		p.CreateCaptureNodes(data, pos, d, mo, ret, p.BeginCaptures)
-	} else {
-		p.CreateCaptureNodes(data, pos, d, mo, ret, p.Captures)
+	if !p.Begin.Empty() {
+		if len(p.BeginCaptures) > 0 {
+			p.CreateCaptureNodes(data, pos, d, mo, ret, p.BeginCaptures)
+		} else {
+			p.CreateCaptureNodes(data, pos, d, mo, ret, p.Captures)
+		}
+		if !p.End.Empty() {
+			return ret, true
+		}
 	}
+	ret.UpdateRange()
+	return ret, false
+}
 
-	if p.End.Empty() {
+// Searches for the end of the frame node from the frame position, if a sub
+// pattern matches before the end its node is appended to the frame node and
+// returned, otherwise the frame node is closed and child is nil.
+func (f *frame) step(data string, d parser.DataSource) (child *parser.Node, pat *Pattern, open bool) {
+	p, ret := f.pat, f.node
+	if f.pos >= len(data) {
+		if f.end == -1 {
+			f.end = len(data)
+		}
+		f.close()
 		return
 	}
-	var (
-		found  = false
-		i, end int
-	)
-	for i, end = ret.Range.B, len(data); i < len(data); {
-		endmatch := p.End.Find(data, i)
-		if endmatch == nil {
-			if !found {
-				// oops.. no end found at all, set it to the next line
-				if e2 := strings.IndexRune(data[i:], '\n'); e2 != -1 {
-					end = i + e2
-				} else {
-					end = len(data)
-				}
-				break
+	endmatch := p.End.Find(data, f.pos)
+	if endmatch == nil {
+		if !f.found {
+			// oops.. no end found at all, set it to the next line
+			if e2 := strings.IndexRune(data[f.pos:], '\n'); e2 != -1 {
+				f.end = f.pos + e2
+			} else {
+				f.end = len(data)
 			}
-			end = i
-			break
+		} else {
+			f.end = f.pos
 		}
-		end = endmatch[1]
+		f.close()
+		return
+	}
+	f.end = endmatch[1]
 
-		if len(p.cachedPatterns) > 0 {
-			// Might be more recursive patterns to apply before the end is reached
-			pattern2, match2 := p.FirstMatch(data, i)
-			if match2 != nil && (match2[0] < endmatch[0] || (match2[0] == endmatch[0] && ret.Range.A == ret.Range.B)) {
-				found = true
-				r := pattern2.CreateNode(data, i, d, match2)
-				ret.Append(r)
-				i = r.Range.B
-				continue
+	if len(p.cachedPatterns) > 0 {
+		// Might be more recursive patterns to apply before the end is reached
+		pattern2, match2 := p.FirstMatch(data, f.pos)
+		if match2 != nil && (match2[0] < endmatch[0] || (match2[0] == endmatch[0] && ret.Range.A == ret.Range.B)) {
+			f.found = true
+			child, open = pattern2.createNode(data, f.pos, d, match2)
+			ret.Append(child)
+			if !open {
+				f.pos = child.Range.B
 			}
+			return child, pattern2, open
 		}
-		if len(p.EndCaptures) > 0 {
-			p.CreateCaptureNodes(data, i, d, endmatch, ret, p.EndCaptures)
-		} else {
-			p.CreateCaptureNodes(data, i, d, endmatch, ret, p.Captures)
-		}
-		break
 	}
-	ret.Range.B = end
+	if len(p.EndCaptures) > 0 {
+		p.CreateCaptureNodes(data, f.pos, d, endmatch, ret, p.EndCaptures)
+	} else {
+		p.CreateCaptureNodes(data, f.pos, d, endmatch, ret, p.Captures)
+	}
+	f.close()
 	return
 }
 
+// Forgets the cached matches, they are only valid for the data and the
+// positions they have been searched with.
+func (p *Pattern) reset() {
+	p.cachedData, p.cachedPat, p.cachedMatch = "", nil, nil
+	p.initCache()
+	for i := range p.Patterns {
+		p.Patterns[i].reset()
+	}
+}
+
 func (p *Pattern) copy(l *Language) *Pattern {
 	ret := &Pattern{}
 	ret.Named = p.Named
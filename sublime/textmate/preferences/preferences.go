@@ -32,8 +32,8 @@ type (
 		CancelCompletion             textmate.Regex
 		ShowInSymbolList             int
 		ShowInIndexedSymbolList      int
-		SymbolTransformation         textmate.Regex
-		SymbolIndexTransformation    textmate.Regex
+		SymbolTransformation         Transformation
+		SymbolIndexTransformation    Transformation
 		ShellVariables               ShellVariables
 	}
 
@@ -0,0 +1,213 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package preferences
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/rubex"
+)
+
+type (
+	// Transformation is a list of substitutions applied in order, as in
+	// symbolTransformation, written "s/regex/format/options;" where
+	// format can refer to the captures as $n or ${n}, the "g" option
+	// replaces all the matches and lines starting with # are comments.
+	Transformation []Substitution
+
+	Substitution struct {
+		re     *rubex.Regexp
+		format string
+		global bool
+	}
+)
+
+// ParseTransformation parses the substitutions of the transformation.
+func ParseTransformation(s string) (Transformation, error) {
+	var (
+		t   Transformation
+		rs  = []rune(s)
+		pos = 0
+	)
+	// reads up to the next unescaped '/', unescaping it
+	part := func() (string, bool) {
+		var buf []rune
+		for ; pos < len(rs); pos++ {
+			switch c := rs[pos]; {
+			case c == '\\' && pos+1 < len(rs) && rs[pos+1] == '/':
+				buf = append(buf, '/')
+				pos++
+			case c == '\\' && pos+1 < len(rs):
+				buf = append(buf, c, rs[pos+1])
+				pos++
+			case c == '/':
+				pos++
+				return string(buf), true
+			default:
+				buf = append(buf, c)
+			}
+		}
+		return string(buf), false
+	}
+	for pos < len(rs) {
+		switch c := rs[pos]; {
+		case c == ';' || c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+			continue
+		case c == '#':
+			for pos < len(rs) && rs[pos] != '\n' {
+				pos++
+			}
+			continue
+		case c != 's' || pos+1 >= len(rs) || rs[pos+1] != '/':
+			return nil, fmt.Errorf("Expected a substitution at %d in %q", pos, s)
+		}
+		pos += 2
+		re, ok := part()
+		if !ok {
+			return nil, fmt.Errorf("Unterminated substitution regex in %q", s)
+		}
+		format, ok := part()
+		if !ok {
+			return nil, fmt.Errorf("Unterminated substitution format in %q", s)
+		}
+		var sub Substitution
+		for ; pos < len(rs) && rs[pos] != ';' && rs[pos] != '\n'; pos++ {
+			if rs[pos] == 'g' {
+				sub.global = true
+			}
+		}
+		var err error
+		if sub.re, err = rubex.Compile(re); err != nil {
+			return nil, fmt.Errorf("Couldn't compile substitution regex %s: %s", re, err)
+		}
+		sub.format = format
+		t = append(t, sub)
+	}
+	return t, nil
+}
+
+func (t *Transformation) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	tr, err := ParseTransformation(s)
+	if err != nil {
+		log.Warn("Couldn't parse transformation: %s", err)
+		return nil
+	}
+	*t = tr
+	return nil
+}
+
+// Apply returns s with the substitutions applied.
+func (t Transformation) Apply(s string) string {
+	for _, sub := range t {
+		s = sub.Apply(s)
+	}
+	return s
+}
+
+func (t Transformation) String() string {
+	if len(t) == 0 {
+		return "nil"
+	}
+	parts := make([]string, len(t))
+	for i, sub := range t {
+		parts[i] = sub.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// Apply replaces the first match in s, or all of them with the g option.
+func (sub Substitution) Apply(s string) string {
+	var buf strings.Builder
+	pos := 0
+	for pos <= len(s) {
+		m := sub.re.FindStringSubmatchIndex(s[pos:])
+		if m == nil {
+			break
+		}
+		buf.WriteString(s[pos : pos+m[0]])
+		buf.WriteString(sub.expand(s[pos:], m))
+		if m[1] == m[0] {
+			// an empty match, move on a character
+			if pos+m[1] < len(s) {
+				buf.WriteByte(s[pos+m[1]])
+			}
+			pos += m[1] + 1
+		} else {
+			pos += m[1]
+		}
+		if !sub.global {
+			break
+		}
+	}
+	if pos < len(s) {
+		buf.WriteString(s[pos:])
+	}
+	return buf.String()
+}
+
+func (sub Substitution) String() string {
+	opts := ""
+	if sub.global {
+		opts = "g"
+	}
+	return fmt.Sprintf("s/%s/%s/%s;", sub.re, sub.format, opts)
+}
+
+// Returns the format with the captures of the match m in s expanded.
+func (sub Substitution) expand(s string, m []int) string {
+	var buf strings.Builder
+	f := sub.format
+	for i := 0; i < len(f); i++ {
+		if f[i] != '$' || i+1 >= len(f) {
+			if f[i] == '\\' && i+1 < len(f) {
+				i++
+				switch f[i] {
+				case 'n':
+					buf.WriteByte('\n')
+				case 't':
+					buf.WriteByte('\t')
+				default:
+					buf.WriteByte(f[i])
+				}
+				continue
+			}
+			buf.WriteByte(f[i])
+			continue
+		}
+		j, end := i+1, i+1
+		if f[j] == '{' {
+			if k := strings.IndexByte(f[j:], '}'); k != -1 {
+				end = j + k + 1
+				j++
+			}
+		}
+		k := j
+		for k < len(f) && f[k] >= '0' && f[k] <= '9' {
+			k++
+		}
+		n, err := strconv.Atoi(f[j:k])
+		if err != nil {
+			buf.WriteByte(f[i])
+			continue
+		}
+		if end == i+1 {
+			end = k
+		}
+		if 2*n+1 < len(m) && m[2*n] != -1 {
+			buf.WriteString(s[m[2*n]:m[2*n+1]])
+		}
+		i = end - 1
+	}
+	return buf.String()
+}
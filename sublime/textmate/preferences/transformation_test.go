@@ -0,0 +1,40 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package preferences
+
+import "testing"
+
+func TestTransformation(t *testing.T) {
+	tests := []struct {
+		trans string
+		in    string
+		exp   string
+	}{
+		{`s/^\s+//;`, "  func", "func"},
+		{`s/\(.*//;`, "Run(a, b)", "Run"},
+		{`s/a/b/;`, "aaa", "baa"},
+		{`s/a/b/g;`, "aaa", "bbb"},
+		{`s/(\w+)\.(\w+)/$2 (${1})/;`, "view.Run", "Run (view)"},
+		{"# comment\ns/x/y/;\ns/y/z/;", "x", "z"},
+		{`s/\//./g;`, "a/b/c", "a.b.c"},
+		{`s/nomatch//;`, "name", "name"},
+	}
+	for i, test := range tests {
+		tr, err := ParseTransformation(test.trans)
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, but got %s", i, err)
+			continue
+		}
+		if res := tr.Apply(test.in); res != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, res)
+		}
+	}
+
+	for i, s := range []string{"x/a/b/", "s/a", "s/a/b"} {
+		if _, err := ParseTransformation(s); err == nil {
+			t.Errorf("Test %d: Expected an error parsing %q", i, s)
+		}
+	}
+}
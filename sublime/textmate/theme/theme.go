@@ -14,6 +14,7 @@ import (
 	"github.com/jxo/lime/loaders"
 	"github.com/jxo/lime/log"
 	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/selector"
 	"github.com/jxo/lime/util"
 )
 
@@ -86,31 +87,22 @@ func (s *Settings) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// ClosestMatchingSetting returns the setting which scope selector best
+// matches the scope name, later settings winning ties. The global
+// settings, which are the first ones, are returned if none matches.
 func (t *Theme) ClosestMatchingSetting(scope string) *ScopeSetting {
 	pe := util.Prof.Enter("ClosestMatchingSetting")
 	defer pe.Exit()
-	na := scope
-	for len(na) > 0 {
-		sn := na
-		i := strings.LastIndex(sn, " ")
-		if i != -1 {
-			sn = sn[i+1:]
-		}
-
-		for j := range t.Settings {
-			if t.Settings[j].Scope == sn {
-				return &t.Settings[j]
-			}
+	best, score := 0, 0
+	for i := range t.Settings {
+		if t.Settings[i].Scope == "" {
+			continue
 		}
-		if i2 := strings.LastIndex(na, "."); i2 == -1 {
-			break
-		} else if i > i2 {
-			na = na[:i]
-		} else {
-			na = strings.TrimSpace(na[:i2])
+		if s := selector.Score(t.Settings[i].Scope, scope); s > 0 && s >= score {
+			best, score = i, s
 		}
 	}
-	return &t.Settings[0]
+	return &t.Settings[best]
 }
 
 func (t *Theme) Spice(vr *render.ViewRegions) (ret render.Flavour) {
@@ -75,6 +75,32 @@ func TestLoadFromMissingFile(t *testing.T) {
 	}
 }
 
+func TestClosestMatchingSetting(t *testing.T) {
+	f := "testdata/Monokai.tmTheme"
+	th, err := Load(f)
+	if err != nil {
+		t.Fatalf("Tried to load %s, but got an error: %v", f, err)
+	}
+	tests := []struct {
+		scope string
+		exp   string
+	}{
+		{"source.go string.quoted.double.go", "String"},
+		{"source.go comment.line.double-slash.go", "Comment"},
+		{"source.go storage.type.go", "Storage type"},
+		{"source.go storage.modifier.go", "Storage"},
+		{"source.go meta.function.go entity.name.function.go", "Function name"},
+		{"source.go constant.other.go", "User-defined constant"},
+		{"source.go string.quoted.go invalid.deprecated.go", "Invalid deprecated"},
+		{"source.go", ""},
+	}
+	for i, test := range tests {
+		if s := th.ClosestMatchingSetting(test.scope); s.Name != test.exp {
+			t.Errorf("Test %d: Expected %q to match %q, but got %q", i, test.scope, test.exp, s.Name)
+		}
+	}
+}
+
 func TestGlobalSettings(t *testing.T) {
 	f := "testdata/Monokai.tmTheme"
 	th, err := Load(f)
@@ -0,0 +1,174 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/text"
+)
+
+// SymbolIndex is the index of the symbols defined in the project files.
+// It's built in the background once the file index is, and updated as
+// files are saved.
+type SymbolIndex struct {
+	project *Project
+	// The symbols of each indexed file
+	files map[string][]SymbolLocation
+	// Incremented on every rebuild so outdated builds are dropped
+	gen int
+	// The number of builds and updates in progress
+	pending int
+	done    *sync.Cond
+	lock    sync.Mutex
+}
+
+func newSymbolIndex(p *Project) *SymbolIndex {
+	i := &SymbolIndex{
+		project: p,
+		files:   make(map[string][]SymbolLocation),
+	}
+	i.done = sync.NewCond(&i.lock)
+	return i
+}
+
+// Rebuild drops the index and indexes the project files again in the
+// background.
+func (i *SymbolIndex) Rebuild() {
+	i.lock.Lock()
+	i.files = make(map[string][]SymbolLocation)
+	i.gen++
+	gen := i.gen
+	i.pending++
+	i.lock.Unlock()
+
+	fi := i.project.FileIndex()
+	go func() {
+		defer i.finish()
+		fi.Wait()
+		for _, f := range fi.Files() {
+			i.index(gen, f)
+		}
+	}()
+}
+
+// Update indexes the file again in the background, if it's one of the
+// project files.
+func (i *SymbolIndex) Update(path string) {
+	fi := i.project.FileIndex()
+	i.lock.Lock()
+	gen := i.gen
+	i.pending++
+	i.lock.Unlock()
+	go func() {
+		defer i.finish()
+		fi.Wait()
+		for _, f := range fi.Files() {
+			if f == path {
+				i.index(gen, f)
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the indexing in progress is done.
+func (i *SymbolIndex) Wait() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	for i.pending != 0 {
+		i.done.Wait()
+	}
+}
+
+func (i *SymbolIndex) finish() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.pending--; i.pending == 0 {
+		i.done.Broadcast()
+	}
+}
+
+// Lookup returns the locations of the symbols with the given name,
+// sorted by path and position.
+func (i *SymbolIndex) Lookup(name string) []SymbolLocation {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	var ret []SymbolLocation
+	for _, syms := range i.files {
+		for _, s := range syms {
+			if s.Name == name {
+				ret = append(ret, s)
+			}
+		}
+	}
+	sortSymbolLocations(ret)
+	return ret
+}
+
+// Symbols returns all the indexed symbols sorted by path and position.
+func (i *SymbolIndex) Symbols() []SymbolLocation {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	var ret []SymbolLocation
+	for _, syms := range i.files {
+		ret = append(ret, syms...)
+	}
+	sortSymbolLocations(ret)
+	return ret
+}
+
+func (i *SymbolIndex) index(gen int, path string) {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return
+	}
+	syn := GetEditor().fileTypeSyntax(ext[1:])
+	if syn == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Warn("Couldn't index symbols of %s: %s", path, err)
+		return
+	}
+	buf := text.NewBuffer()
+	defer buf.Close()
+	buf.Insert(0, string(data))
+	sh, _ := syntaxHighlighter(syn, buf.Substr(text.Region{A: 0, B: buf.Size()}))
+
+	var locs []SymbolLocation
+	for _, s := range extractSymbols(sh.Flatten(), buf.Substr, true) {
+		row, col := buf.RowCol(s.Region.A)
+		locs = append(locs, SymbolLocation{Path: path, Name: s.Name, Row: row + 1, Col: col + 1})
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.gen != gen {
+		return
+	}
+	if len(locs) == 0 {
+		delete(i.files, path)
+	} else {
+		i.files[path] = locs
+	}
+}
+
+func sortSymbolLocations(locs []SymbolLocation) {
+	sort.Slice(locs, func(a, b int) bool {
+		if locs[a].Path != locs[b].Path {
+			return locs[a].Path < locs[b].Path
+		}
+		if locs[a].Row != locs[b].Row {
+			return locs[a].Row < locs[b].Row
+		}
+		return locs[a].Col < locs[b].Col
+	})
+}
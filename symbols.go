@@ -0,0 +1,172 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jxo/lime/render"
+	"github.com/jxo/lime/selector"
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// SymbolSettings are the symbol list settings of the scopes matching
+	// the Scope selector, as defined in tmPreferences files. When set the
+	// transformations are applied to the symbol names.
+	SymbolSettings struct {
+		Scope                     string
+		ShowInSymbolList          bool
+		ShowInIndexedSymbolList   bool
+		SymbolTransformation      func(string) string
+		SymbolIndexTransformation func(string) string
+	}
+
+	// Symbol is a symbol of a view along with the region it spans over.
+	Symbol struct {
+		Region text.Region
+		Name   string
+	}
+
+	// SymbolLocation is where an indexed symbol is defined, Row and Col
+	// are 1 based.
+	SymbolLocation struct {
+		Path string
+		Name string
+		Row  int
+		Col  int
+	}
+)
+
+func (e *Editor) AddSymbolSettings(path string, s *SymbolSettings) {
+	edl.Lock()
+	defer edl.Unlock()
+	e.symbolSettings[path] = s
+}
+
+// Returns the symbol settings applying to the scope, each setting is
+// taken from the best scoring selector defining it.
+func (e *Editor) resolveSymbolSettings(scope string) SymbolSettings {
+	edl.Lock()
+	defer edl.Unlock()
+	var (
+		ret    SymbolSettings
+		scores [4]int
+	)
+	for _, s := range e.symbolSettings {
+		score := selector.Score(s.Scope, scope)
+		if score == 0 {
+			continue
+		}
+		if s.ShowInSymbolList && score > scores[0] {
+			ret.ShowInSymbolList, scores[0] = true, score
+		}
+		if s.ShowInIndexedSymbolList && score > scores[1] {
+			ret.ShowInIndexedSymbolList, scores[1] = true, score
+		}
+		if s.SymbolTransformation != nil && score > scores[2] {
+			ret.SymbolTransformation, scores[2] = s.SymbolTransformation, score
+		}
+		if s.SymbolIndexTransformation != nil && score > scores[3] {
+			ret.SymbolIndexTransformation, scores[3] = s.SymbolIndexTransformation, score
+		}
+	}
+	return ret
+}
+
+// Symbols returns the symbols of the view to be shown in its symbol
+// list, sorted by position.
+func (v *View) Symbols() []Symbol {
+	return v.symbols(false)
+}
+
+// IndexedSymbols returns the symbols of the view which are to be
+// indexed, sorted by position.
+func (v *View) IndexedSymbols() []Symbol {
+	return v.symbols(true)
+}
+
+func (v *View) symbols(indexed bool) []Symbol {
+	v.lock.Lock()
+	regions := make(render.ViewRegionMap)
+	for k, vr := range v.regions {
+		if strings.HasPrefix(k, "lime.syntax") {
+			regions[k] = vr
+		}
+	}
+	v.lock.Unlock()
+	return extractSymbols(regions, v.Substr, indexed)
+}
+
+// Extracts the symbols out of the syntax regions, the scopes of the
+// same symbol split by nested scopes are joined back together.
+func extractSymbols(regions render.ViewRegionMap, substr func(text.Region) string, indexed bool) []Symbol {
+	type part struct {
+		r     text.Region
+		trans func(string) string
+	}
+	var parts []part
+	ed := GetEditor()
+	for _, vr := range regions {
+		s := ed.resolveSymbolSettings(strings.TrimPrefix(vr.Scope, "lime.syntax "))
+		show, trans := s.ShowInSymbolList, s.SymbolTransformation
+		if indexed {
+			show, trans = s.ShowInIndexedSymbolList, s.SymbolIndexTransformation
+		}
+		if !show {
+			continue
+		}
+		for _, r := range vr.Regions.Regions() {
+			if !r.Empty() {
+				parts = append(parts, part{text.Region{A: r.Begin(), B: r.End()}, trans})
+			}
+		}
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].r.A < parts[j].r.A })
+
+	var merged []part
+	for _, p := range parts {
+		if n := len(merged) - 1; n >= 0 && p.r.A <= merged[n].r.B {
+			merged[n].r.B = text.Max(merged[n].r.B, p.r.B)
+		} else {
+			merged = append(merged, p)
+		}
+	}
+	var ret []Symbol
+	for _, p := range merged {
+		name := substr(p.r)
+		if p.trans != nil {
+			name = p.trans(name)
+		}
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		ret = append(ret, Symbol{Region: p.r, Name: name})
+	}
+	return ret
+}
+
+// LookupSymbolInIndex returns the locations of the symbol in the project
+// symbol index.
+func (w *Window) LookupSymbolInIndex(sym string) []SymbolLocation {
+	return w.Project().SymbolIndex().Lookup(sym)
+}
+
+// LookupSymbolInOpenFiles returns the locations of the symbol among the
+// indexed symbols of the window views.
+func (w *Window) LookupSymbolInOpenFiles(sym string) []SymbolLocation {
+	var ret []SymbolLocation
+	for _, v := range w.Views() {
+		for _, s := range v.IndexedSymbols() {
+			if s.Name != sym {
+				continue
+			}
+			row, col := v.RowCol(s.Region.A)
+			ret = append(ret, SymbolLocation{Path: v.FileName(), Name: s.Name, Row: row + 1, Col: col + 1})
+		}
+	}
+	return ret
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxo/lime/text"
+)
+
+// Adds symbol settings to the editor, returning the function removing them.
+func addTestSymbolSettings() func() {
+	ed := GetEditor()
+	ed.AddSymbolSettings("testdata/Symbol List.tmPreferences", &SymbolSettings{
+		Scope:                   "entity.name.function",
+		ShowInSymbolList:        true,
+		ShowInIndexedSymbolList: true,
+	})
+	ed.AddSymbolSettings("testdata/Symbol List Method.tmPreferences", &SymbolSettings{
+		Scope:                "meta.method entity.name.function",
+		SymbolTransformation: func(s string) string { return "  " + s + "()" },
+	})
+	ed.AddSymbolSettings("testdata/Symbol List Hidden.tmPreferences", &SymbolSettings{
+		Scope:                     "entity.name.function.hidden",
+		SymbolIndexTransformation: func(string) string { return "" },
+	})
+	return func() {
+		edl.Lock()
+		defer edl.Unlock()
+		delete(ed.symbolSettings, "testdata/Symbol List.tmPreferences")
+		delete(ed.symbolSettings, "testdata/Symbol List Method.tmPreferences")
+		delete(ed.symbolSettings, "testdata/Symbol List Hidden.tmPreferences")
+	}
+}
+
+func TestViewSymbols(t *testing.T) {
+	defer addTestSymbolSettings()()
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	e := v.BeginEdit()
+	v.Insert(e, 0, "func Run() {}\nfunc (v View) Size() {}\nfunc secret() {}\n")
+	v.EndEdit(e)
+	for start := time.Now(); v.Settings().Int("lime.syntax.updated", -1) != v.ChangeCount(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("Waiting for 'lime.syntax.updated' took too long")
+		}
+	}
+
+	// stands in for the regions of a parse, Size is split by a nested scope
+	v.AddRegions("lime.syntax source entity.name.function", []text.Region{{A: 5, B: 8}}, "source entity.name.function", "", 0)
+	v.AddRegions("lime.syntax source meta.method entity.name.function", []text.Region{{A: 28, B: 30}}, "source meta.method entity.name.function", "", 0)
+	v.AddRegions("lime.syntax source meta.method entity.name.function variable", []text.Region{{A: 30, B: 32}}, "source meta.method entity.name.function variable", "", 0)
+	v.AddRegions("lime.syntax source entity.name.function.hidden", []text.Region{{A: 43, B: 49}}, "source entity.name.function.hidden", "", 0)
+
+	exp := []Symbol{
+		{text.Region{A: 5, B: 8}, "Run"},
+		{text.Region{A: 28, B: 32}, "  Size()"},
+		{text.Region{A: 43, B: 49}, "secret"},
+	}
+	if s := v.Symbols(); !reflect.DeepEqual(s, exp) {
+		t.Errorf("Expected symbols %v, but got %v", exp, s)
+	}
+	exp = []Symbol{
+		{text.Region{A: 5, B: 8}, "Run"},
+		{text.Region{A: 28, B: 32}, "Size"},
+	}
+	if s := v.IndexedSymbols(); !reflect.DeepEqual(s, exp) {
+		t.Errorf("Expected indexed symbols %v, but got %v", exp, s)
+	}
+
+	expl := []SymbolLocation{{Name: "Size", Row: 2, Col: 15}}
+	if l := w.LookupSymbolInOpenFiles("Size"); !reflect.DeepEqual(l, expl) {
+		t.Errorf("Expected %v, but got %v", expl, l)
+	}
+	if l := w.LookupSymbolInOpenFiles("secret"); len(l) != 0 {
+		t.Errorf("Expected no location of an unindexed symbol, but got %v", l)
+	}
+}
+
+func TestSymbolIndex(t *testing.T) {
+	defer addTestSymbolSettings()()
+	const syntax = "testdata/Symbols.tmLanguage"
+	GetEditor().AddSyntax(syntax, newDummySytax(t, syntax))
+
+	dir, err := ioutil.TempDir("", "lime-symbols")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"a.sym": "def first\nother\ndef second\n",
+		"b.sym": "  def skipped\ndef first\n",
+	}
+	for f, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	defer os.RemoveAll(dir)
+	w.Project().AddFolder(dir)
+
+	idx := w.Project().SymbolIndex()
+	idx.Wait()
+	exp := []SymbolLocation{
+		{filepath.Join(dir, "a.sym"), "first", 1, 5},
+		{filepath.Join(dir, "b.sym"), "first", 2, 5},
+	}
+	if l := w.LookupSymbolInIndex("first"); !reflect.DeepEqual(l, exp) {
+		t.Errorf("Expected %v, but got %v", exp, l)
+	}
+	if l := idx.Symbols(); len(l) != 3 {
+		t.Errorf("Expected 3 indexed symbols, but got %v", l)
+	}
+
+	// saving a file updates its symbols
+	v := w.OpenFile(filepath.Join(dir, "b.sym"), 0)
+	e := v.BeginEdit()
+	v.Insert(e, v.Size(), "def third\n")
+	v.EndEdit(e)
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	idx.Wait()
+	if l := w.LookupSymbolInIndex("third"); len(l) != 1 || !strings.HasSuffix(l[0].Path, "b.sym") || l[0].Row != 3 {
+		t.Errorf("Expected third at b.sym:3, but got %v", l)
+	}
+}
@@ -23,32 +23,34 @@ type Syntax interface {
 	FileTypes() []string
 }
 
-func syntaxHighlighter(name, data string) parser.SyntaxHighlighter {
+// Returns the syntax highlighter for data along with the parser which
+// created it, the parser is nil for the default syntax.
+func syntaxHighlighter(name, data string) (parser.SyntaxHighlighter, parser.Parser) {
 	if name == "" {
-		return &syntax{}
+		return &syntax{}, nil
 	}
-	sh, err := syntaxProvider(name, data)
+	sh, pr, err := syntaxProvider(name, data)
 	if err != nil {
 		log.Error("%s, falling back to default syntax", err)
-		return &syntax{}
+		return &syntax{}, nil
 	}
-	return sh
+	return sh, pr
 }
 
-func syntaxProvider(name, data string) (parser.SyntaxHighlighter, error) {
+func syntaxProvider(name, data string) (parser.SyntaxHighlighter, parser.Parser, error) {
 	syn := GetEditor().GetSyntax(name)
 	if syn == nil {
-		return nil, fmt.Errorf("No syntax %s in editor", name)
+		return nil, nil, fmt.Errorf("No syntax %s in editor", name)
 	}
 	pr, err := syn.Parser(data)
 	if err != nil {
-		return nil, fmt.Errorf("Couldn't get parser from syntax: %s", err)
+		return nil, nil, fmt.Errorf("Couldn't get parser from syntax: %s", err)
 	}
 	sh, err := parser.NewSyntaxHighlighter(pr)
 	if err != nil {
-		return nil, fmt.Errorf("Couldn't create syntaxhighlighter: %s", err)
+		return nil, nil, fmt.Errorf("Couldn't create syntaxhighlighter: %s", err)
 	}
-	return sh, nil
+	return sh, pr, nil
 }
 
 type syntax struct{}
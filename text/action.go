@@ -31,6 +31,15 @@ type (
 		insertAction
 		region Region
 	}
+
+	// SerializedAction is the data of an insert or erase action, Value
+	// being the text inserted or erased at Point. It's used to store
+	// actions and to recreate them on another buffer.
+	SerializedAction struct {
+		Erase bool   `json:"erase,omitempty"`
+		Point int    `json:"point"`
+		Value string `json:"value"`
+	}
 )
 
 func (ca CompositeAction) String() string {
@@ -74,6 +83,11 @@ func (ca *CompositeAction) Len() int {
 	return len(ca.actions)
 }
 
+// Actions returns the sub-actions of this CompositeAction
+func (ca *CompositeAction) Actions() []Action {
+	return ca.actions
+}
+
 func (ia *insertAction) Apply() {
 	ia.buffer.Insert(ia.point, string(ia.value))
 }
@@ -120,3 +134,26 @@ func NewReplaceAction(b Buffer, region Region, value string) Action {
 		NewInsertAction(b, Clamp(0, b.Size()-region.Size(), region.Begin()), value),
 	}}
 }
+
+// SerializeAction returns the data of the insert or erase action, ok is
+// false for other actions. An erase action has to have been applied for
+// its value to be known.
+func SerializeAction(a Action) (s SerializedAction, ok bool) {
+	switch t := a.(type) {
+	case *insertAction:
+		return SerializedAction{Point: t.point, Value: string(t.value)}, true
+	case *eraseAction:
+		return SerializedAction{Erase: true, Point: t.point, Value: string(t.value)}, true
+	}
+	return s, false
+}
+
+// Action returns the action the data was serialized from, operating on
+// the given buffer.
+func (s SerializedAction) Action(b Buffer) Action {
+	v := []rune(s.Value)
+	if s.Erase {
+		return &eraseAction{insertAction{b, s.Point, v}, Region{s.Point, s.Point + len(v)}}
+	}
+	return &insertAction{b, s.Point, v}
+}
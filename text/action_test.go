@@ -88,3 +88,40 @@ func TestActionsUtf(t *testing.T) {
 		}
 	}
 }
+
+func TestSerializeAction(t *testing.T) {
+	const init = "hello world"
+	var buffer = NewBuffer()
+	buffer.Insert(0, init)
+	tests := []struct {
+		action   Action
+		expected string
+	}{
+		{NewInsertAction(buffer, 5, ",𝄞"), "hello,𝄞 world"},
+		{NewEraseAction(buffer, Region{0, 6}), "world"},
+		{NewEraseAction(buffer, Region{8, 4}), "hellrld"},
+	}
+	for i, test := range tests {
+		test.action.Apply()
+		s, ok := SerializeAction(test.action)
+		if !ok {
+			t.Fatalf("Test %d: Expected the action to be serialized", i)
+		}
+		test.action.Undo()
+
+		other := NewBuffer()
+		other.Insert(0, init)
+		a := s.Action(other)
+		a.Apply()
+		if d := other.Substr(Region{0, other.Size()}); d != test.expected {
+			t.Errorf("Test %d: Expected %s, but got %s", i, test.expected, d)
+		}
+		a.Undo()
+		if d := other.Substr(Region{0, other.Size()}); d != init {
+			t.Errorf("Test %d: Expected %s, but got %s", i, init, d)
+		}
+	}
+	if _, ok := SerializeAction(&CompositeAction{}); ok {
+		t.Error("Expected a composite action not to be serialized")
+	}
+}
@@ -5,6 +5,7 @@
 package text
 
 import (
+	"sort"
 	"sync"
 )
 
@@ -23,10 +24,22 @@ type RegionSet struct {
 func (r *RegionSet) Adjust(position, delta int) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
+	// Only the regions reaching into the erased text could end up
+	// overlapping others, all the other positions keep their order
+	var touched []bool
+	erased := Region{position + delta, position}
 	for i := range r.regions {
+		if delta < 0 && (erased.Contains(r.regions[i].A) || erased.Contains(r.regions[i].B)) {
+			if touched == nil {
+				touched = make([]bool, len(r.regions))
+			}
+			touched[i] = true
+		}
 		r.regions[i].Adjust(position, delta)
 	}
-	r.flush()
+	if touched != nil {
+		r.flushTouched(touched)
+	}
 }
 
 // Returns a list of the indices between start and end of the regions that overlaps
@@ -40,27 +53,24 @@ func (r *RegionSet) overlaps(reference Region, start, end int) (ret []int) {
 	return
 }
 
-// Merge all regions in the given "merge"-list with the region at index "reference"
+// Merge all regions in the given "merge"-list with the region at index "reference".
+// The list must be sorted.
 func (r *RegionSet) merge(reference int, merge []int) {
 	for _, j := range merge {
 		// merge "j" into "reference"
 		r.regions[reference] = r.regions[reference].Cover(r.regions[j])
 	}
-	l := len(merge) - 1
-	// keep track of how many indices we have removed thus far
-	adj := 0
-	for i, j1 := range merge {
-		j2 := len(r.regions) - adj
-		if i < l {
-			j2 = merge[i+1] - 1
-		}
-		// remove "j" from the region list by shifting all trailing regions up one step
-		if j2 > 0 && j1+1 <= j2 {
-			copy(r.regions[j1-adj:], r.regions[j1+1:j2])
+	// remove the merged regions, keeping the order of the others
+	k, m := 0, 0
+	for i := range r.regions {
+		if m < len(merge) && merge[m] == i {
+			m++
+			continue
 		}
-		adj++
+		r.regions[k] = r.regions[i]
+		k++
 	}
-	r.regions = r.regions[:len(r.regions)-len(merge)]
+	r.regions = r.regions[:k]
 }
 
 // TODO(q): There should be a on modified callback on the RegionSet
@@ -71,6 +81,53 @@ func (r *RegionSet) flush() {
 			continue
 		}
 		r.merge(ov[0], append(ov[1:], i))
+		// the merged region can now overlap others, check it again
+		i = ov[0] - 1
+	}
+}
+
+// Same as flush, but only the regions marked as touched are checked
+// against the others since the rest are known not to overlap.
+func (r *RegionSet) flushTouched(touched []bool) {
+	var idx []int
+	index := func() {
+		idx = idx[:0]
+		for i, t := range touched {
+			if t {
+				idx = append(idx, i)
+			}
+		}
+	}
+	index()
+	for i := 1; i < len(r.regions); i++ {
+		var ov []int
+		if touched[i] {
+			ov = r.overlaps(r.regions[i], 0, i)
+		} else {
+			for _, j := range idx {
+				if j >= i {
+					break
+				}
+				ov = append(ov, r.overlaps(r.regions[i], j, j+1)...)
+			}
+		}
+		if len(ov) == 0 {
+			continue
+		}
+		r.merge(ov[0], append(ov[1:], i))
+		// keep the marks in step with the merged regions
+		t := touched[:0]
+		for j := range touched {
+			if j == ov[0] {
+				t = append(t, true)
+			} else if k := sort.SearchInts(ov, j); j != i && (k == len(ov) || ov[k] != j) {
+				t = append(t, touched[j])
+			}
+		}
+		touched = t
+		index()
+		// the merged region can now overlap others, check it again
+		i = ov[0] - 1
 	}
 }
 
@@ -94,6 +94,70 @@ func TestRegionSetAdjust2(t *testing.T) {
 	}
 }
 
+func TestRegionSetflushTouched(t *testing.T) {
+	tests := []struct {
+		in      []Region
+		touched []bool
+		exp     []Region
+	}{
+		{
+			[]Region{{0, 5}, {10, 15}, {20, 25}},
+			[]bool{false, false, false},
+			[]Region{{0, 5}, {10, 15}, {20, 25}},
+		},
+		{
+			[]Region{{0, 5}, {3, 12}, {10, 15}, {20, 25}},
+			[]bool{false, true, false, false},
+			[]Region{{0, 15}, {20, 25}},
+		},
+		{
+			[]Region{{0, 10}, {2, 3}, {4, 5}, {20, 25}},
+			[]bool{true, false, false, false},
+			[]Region{{0, 10}, {20, 25}},
+		},
+		{
+			[]Region{{20, 25}, {0, 10}, {22, 30}, {5, 8}},
+			[]bool{false, false, true, true},
+			[]Region{{20, 30}, {0, 10}},
+		},
+		{
+			[]Region{{10, 10}, {10, 10}, {12, 14}},
+			[]bool{false, true, false},
+			[]Region{{10, 10}, {12, 14}},
+		},
+	}
+	for i, test := range tests {
+		var r RegionSet
+		r.regions = append([]Region{}, test.in...)
+		r.flushTouched(append([]bool{}, test.touched...))
+		if !reflect.DeepEqual(r.regions, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, r.regions)
+		}
+	}
+}
+
+// Adjust only checks the regions reaching into the erased text, which
+// must give the same result as checking all of them.
+func TestRegionSetAdjustErase(t *testing.T) {
+	in := []Region{{0, 3}, {5, 9}, {12, 12}, {14, 20}, {22, 30}, {31, 31}, {40, 45}}
+	for pos := 0; pos <= 46; pos++ {
+		for n := 1; n <= pos; n++ {
+			var r, exp RegionSet
+			r.AddAll(in)
+			exp.regions = append([]Region{}, r.regions...)
+			for i := range exp.regions {
+				exp.regions[i].Adjust(pos, -n)
+			}
+			exp.flush()
+
+			r.Adjust(pos, -n)
+			if !reflect.DeepEqual(r.regions, exp.regions) {
+				t.Errorf("Adjust(%d, %d): Expected %v, but got %v", pos, -n, exp.regions, r.regions)
+			}
+		}
+	}
+}
+
 func TestRegionSetCut(t *testing.T) {
 	tests := []struct {
 		A, B Region
@@ -4,24 +4,98 @@
 
 package lime
 
+import (
+	"time"
+)
+
 type (
+	// UndoStack is the undo history of a view. The edits are kept in a
+	// tree, so that the edits undone before making a new one aren't lost
+	// but become another branch of the tree. Undo and Redo walk the
+	// current branch, the one last edited or switched to.
 	UndoStack struct {
 		position int
-		actions  []*Edit
+		// The edits of the current branch and their nodes in the tree
+		actions []*Edit
+		nodes   []*undoNode
+		root    *undoNode
+	}
+
+	// A node of the undo tree, the edits of its children being made
+	// after its own. The active child is the one the current branch goes
+	// through.
+	undoNode struct {
+		edit     *Edit
+		parent   *undoNode
+		children []*undoNode
+		active   int
 	}
 )
 
-// Adds the provided Edit object to the UndoStack, potentially
-// destroying the old redo stack if one had been created.
-// TODO(.): It would be nice with branched undo histories
-func (us *UndoStack) Add(a *Edit) {
-	if us.position != len(us.actions) {
-		us.actions = us.actions[0:us.position]
+func (us *UndoStack) rootNode() *undoNode {
+	if us.root == nil {
+		us.root = &undoNode{}
 	}
-	us.actions = append(us.actions, a)
+	return us.root
+}
+
+// Returns the node of the last edit applied, the root if there's none.
+func (us *UndoStack) current() *undoNode {
+	if us.position == 0 {
+		return us.rootNode()
+	}
+	return us.nodes[us.position-1]
+}
+
+// Adds the provided Edit object to the UndoStack. The edits which
+// were undone are kept in another branch of the undo tree.
+func (us *UndoStack) Add(a *Edit) {
+	p := us.current()
+	n := &undoNode{edit: a, parent: p}
+	p.children = append(p.children, n)
+	p.active = len(p.children) - 1
+	us.actions = append(us.actions[:us.position], a)
+	us.nodes = append(us.nodes[:us.position], n)
 	us.position++
 }
 
+// Makes the current branch the one going through the active children
+// from the root.
+func (us *UndoStack) follow() {
+	us.actions, us.nodes = nil, nil
+	for n := us.rootNode(); len(n.children) != 0; {
+		n = n.children[n.active]
+		us.actions = append(us.actions, n.edit)
+		us.nodes = append(us.nodes, n)
+	}
+}
+
+// Walks the tree to the state following the edit of the node, undoing
+// the edits up to the common ancestor with the current state and
+// redoing those from there to the node.
+func (us *UndoStack) goTo(target *undoNode) {
+	ancestors := make(map[*undoNode]bool)
+	for n := target; n != nil; n = n.parent {
+		ancestors[n] = true
+	}
+	for us.position > 0 && !ancestors[us.current()] {
+		us.position--
+		us.actions[us.position].Undo()
+	}
+	for n := target; n.parent != nil; n = n.parent {
+		for i, c := range n.parent.children {
+			if c == n {
+				n.parent.active = i
+			}
+		}
+	}
+	us.follow()
+	for us.current() != target {
+		us.actions[us.position].Apply()
+		us.position++
+	}
+}
+
 // index returns the real index in the UndoStack of an undo item
 // relative to the current position.
 //
@@ -121,6 +195,7 @@ func (us *UndoStack) GlueFrom(mark int) {
 	}
 	e.v = us.actions[mark].v
 	e.savedSel.AddAll(us.actions[mark].savedSel.Regions())
+	e.created = us.actions[us.position-1].created
 
 	entries := make([]entry, us.position-mark)
 	for i := range entries {
@@ -129,9 +204,65 @@ func (us *UndoStack) GlueFrom(mark int) {
 		entries[i].args = a.args
 		e.composite.Add(a)
 	}
-	us.position = mark
-	us.actions = us.actions[:mark+1]
 	e.args = make(Args)
 	e.args["commands"] = entries
-	us.Add(&e)
+
+	// the glued node takes the place of the first edit glued, the
+	// edits made after the last one following it
+	first, last := us.nodes[mark], us.nodes[us.position-1]
+	n := &undoNode{edit: &e, parent: first.parent, children: last.children, active: last.active}
+	for _, c := range n.children {
+		c.parent = n
+	}
+	for i, c := range n.parent.children {
+		if c == first {
+			n.parent.children[i] = n
+		}
+	}
+	us.actions = append(append(us.actions[:mark:mark], &e), us.actions[us.position:]...)
+	us.nodes = append(append(us.nodes[:mark:mark], n), us.nodes[us.position:]...)
+	us.position = mark + 1
+}
+
+// SwitchBranch walks to the tip of another branch of the undo tree, dir
+// being 1 for the next branch, made after the current one, and -1 for
+// the previous one. The branch is taken at the closest fork before the
+// current position having one in that direction. Returns false if there
+// isn't any.
+func (us *UndoStack) SwitchBranch(dir int) bool {
+	for i := us.position; i >= 0; i-- {
+		n := us.rootNode()
+		if i > 0 {
+			n = us.nodes[i-1]
+		}
+		b := n.active + dir
+		if b < 0 || b >= len(n.children) {
+			continue
+		}
+		tip := n.children[b]
+		for len(tip.children) != 0 {
+			tip = tip.children[tip.active]
+		}
+		us.goTo(tip)
+		return true
+	}
+	return false
+}
+
+// UndoToTime walks the undo tree to the state the buffer was in at the
+// given time, that is following the last edit made before then in any
+// of the branches.
+func (us *UndoStack) UndoToTime(t time.Time) {
+	target := us.rootNode()
+	var walk func(n *undoNode)
+	walk = func(n *undoNode) {
+		for _, c := range n.children {
+			if !c.edit.created.After(t) && (target.edit == nil || !target.edit.created.After(c.edit.created)) {
+				target = c
+			}
+			walk(c)
+		}
+	}
+	walk(target)
+	us.goTo(target)
 }
@@ -6,6 +6,7 @@ package lime
 
 import (
 	"testing"
+	"time"
 
 	"github.com/jxo/lime/text"
 )
@@ -207,3 +208,111 @@ func TestUndoStackGlueFrom(t *testing.T) {
 		t.Errorf("Expected the UndoStack to only contain 2 things, but it had %d", len(us.actions))
 	}
 }
+
+func TestUndoStackBranches(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	insert := func(s string) {
+		e := v.BeginEdit()
+		v.Insert(e, v.Size(), s)
+		v.EndEdit(e)
+	}
+	content := func() string {
+		return v.Substr(text.Region{A: 0, B: v.Size()})
+	}
+	us := v.UndoStack()
+
+	insert("a")
+	insert("b")
+	us.Undo(true)
+	insert("c")
+	insert("d")
+	us.Undo(true)
+	us.Undo(true)
+	insert("e")
+	if s := content(); s != "ae" {
+		t.Fatalf("Expected %q, but got %q", "ae", s)
+	}
+
+	tests := []struct {
+		dir int
+		ok  bool
+		exp string
+	}{
+		{1, false, "ae"},
+		{-1, true, "acd"},
+		{-1, true, "ab"},
+		{-1, false, "ab"},
+		{1, true, "acd"},
+		{1, true, "ae"},
+	}
+	for i, test := range tests {
+		if ok := us.SwitchBranch(test.dir); ok != test.ok {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.ok, ok)
+		}
+		if s := content(); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+	}
+
+	// undo and redo walk the branch switched to
+	us.SwitchBranch(-1)
+	us.Undo(true)
+	if s := content(); s != "ac" {
+		t.Errorf("Expected %q, but got %q", "ac", s)
+	}
+	us.Redo(true)
+	us.Redo(true)
+	if s := content(); s != "acd" {
+		t.Errorf("Expected %q, but got %q", "acd", s)
+	}
+}
+
+func TestUndoStackUndoToTime(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	start := time.Now().Add(-time.Hour)
+	insert := func(s string, min int) {
+		e := v.BeginEdit()
+		e.created = start.Add(time.Duration(min) * time.Minute)
+		v.Insert(e, v.Size(), s)
+		v.EndEdit(e)
+	}
+	us := v.UndoStack()
+
+	insert("a", 0)
+	insert("b", 10)
+	us.Undo(true)
+	insert("c", 20)
+	insert("d", 30)
+
+	tests := []struct {
+		min int
+		exp string
+	}{
+		{15, "ab"},
+		{5, "a"},
+		{25, "ac"},
+		{-5, ""},
+		{40, "acd"},
+		{10, "ab"},
+	}
+	for i, test := range tests {
+		us.UndoToTime(start.Add(time.Duration(test.min) * time.Minute))
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jxo/lime/log"
+	"github.com/jxo/lime/text"
+)
+
+type (
+	// The undo history of a file kept between sessions when the
+	// "persistent_undo" setting is on. The journal is only restored when
+	// the file content still has the hash it had when it was written.
+	// Only the current branch of the undo tree is stored.
+	undoJournal struct {
+		Hash     string        `json:"hash"`
+		Position int           `json:"position"`
+		Edits    []journalEdit `json:"edits"`
+	}
+
+	// An Edit of the undo stack along with the insert and erase actions
+	// it's made of.
+	journalEdit struct {
+		Command string                  `json:"command"`
+		Args    Args                    `json:"args,omitempty"`
+		Time    time.Time               `json:"time"`
+		Sel     []text.Region           `json:"sel"`
+		Actions []text.SerializedAction `json:"actions"`
+	}
+)
+
+// The default cap in bytes of an undo journal.
+const defaultUndoJournalSize = 1 << 20
+
+func contentHash(s string) string {
+	h := sha1.Sum([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// Returns the path of the undo journal of the view file, an empty string
+// when the view history isn't to be persisted.
+func (v *View) undoJournalPath() string {
+	dir, fn := GetEditor().LocalPath(), v.FileName()
+	if dir == "" || fn == "" || !v.Settings().Bool("persistent_undo", false) {
+		return ""
+	}
+	return filepath.Join(dir, "Undo", contentHash(fn)+".json")
+}
+
+// Appends the insert and erase actions the action is made of, ok is false
+// if it contains actions which can't be serialized.
+func flattenAction(a text.Action, ret []text.SerializedAction) (_ []text.SerializedAction, ok bool) {
+	var actions []text.Action
+	switch t := a.(type) {
+	case *Edit:
+		actions = t.composite.Actions()
+	case *text.CompositeAction:
+		actions = t.Actions()
+	default:
+		s, ok := text.SerializeAction(a)
+		return append(ret, s), ok
+	}
+	for _, c := range actions {
+		if ret, ok = flattenAction(c, ret); !ok {
+			return ret, false
+		}
+	}
+	return ret, true
+}
+
+func (v *View) newUndoJournal() (*undoJournal, bool) {
+	j := &undoJournal{
+		Hash:     contentHash(v.Substr(text.Region{A: 0, B: v.Size()})),
+		Position: v.undoStack.position,
+		Edits:    make([]journalEdit, len(v.undoStack.actions)),
+	}
+	for i, e := range v.undoStack.actions {
+		actions, ok := flattenAction(e, nil)
+		if !ok {
+			return nil, false
+		}
+		j.Edits[i] = journalEdit{
+			Command: e.command,
+			Time:    e.created,
+			Sel:     e.savedSel.Regions(),
+			Actions: actions,
+		}
+		// args the command was run with can't always be stored
+		if _, err := json.Marshal(e.args); err == nil {
+			j.Edits[i].Args = e.args
+		}
+	}
+	return j, true
+}
+
+// Drops edits until the journal fits in size bytes, the edits which were
+// undone first and then the oldest ones. A size of 0 or less doesn't
+// limit the journal.
+func (j *undoJournal) limit(size int) {
+	if size <= 0 {
+		return
+	}
+	total := 0
+	sizes := make([]int, len(j.Edits))
+	for i, e := range j.Edits {
+		data, _ := json.Marshal(e)
+		sizes[i] = len(data) + 1
+		total += sizes[i]
+	}
+	first, last := 0, len(j.Edits)
+	for total > size && first < last {
+		if last > j.Position {
+			last--
+			total -= sizes[last]
+		} else {
+			total -= sizes[first]
+			first++
+		}
+	}
+	j.Position -= first
+	j.Edits = j.Edits[first:last]
+}
+
+// Writes the undo history of the view to its journal, the view content
+// being the one of the file.
+func (v *View) saveUndoJournal() {
+	path := v.undoJournalPath()
+	if path == "" {
+		return
+	}
+	j, ok := v.newUndoJournal()
+	if !ok {
+		log.Warn("Couldn't store the undo history of %s", v.FileName())
+		return
+	}
+	j.limit(v.Settings().Int("persistent_undo_max_size", defaultUndoJournalSize))
+	if len(j.Edits) == 0 {
+		os.Remove(path)
+		return
+	}
+	data, err := json.Marshal(j)
+	if err != nil {
+		log.Warn("Couldn't store the undo history of %s: %s", v.FileName(), err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Warn("Couldn't create the undo journal directory: %s", err)
+		return
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		log.Warn("Couldn't write the undo journal of %s: %s", v.FileName(), err)
+	}
+}
+
+// Restores the undo history of the view from its journal.
+func (v *View) loadUndoJournal() {
+	path := v.undoJournalPath()
+	if path == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var j undoJournal
+	if err := json.Unmarshal(data, &j); err != nil {
+		log.Warn("Couldn't load the undo journal of %s: %s", v.FileName(), err)
+		return
+	}
+	v.restoreUndoJournal(&j)
+}
+
+// Replaces the undo history of the view by the one of the journal, if
+// the journal was written for the current content of the view.
+func (v *View) restoreUndoJournal(j *undoJournal) bool {
+	if j.Hash != contentHash(v.Substr(text.Region{A: 0, B: v.Size()})) {
+		log.Fine("%s changed since its undo journal was written", v)
+		return false
+	}
+	if j.Position < 0 || j.Position > len(j.Edits) {
+		log.Warn("Invalid undo journal position %d for %s", j.Position, v)
+		return false
+	}
+	var us UndoStack
+	for _, je := range j.Edits {
+		e := &Edit{v: v, command: je.Command, args: je.Args, created: je.Time}
+		e.savedSel.AddAll(je.Sel)
+		for _, a := range je.Actions {
+			e.composite.Add(a.Action(v.buffer))
+		}
+		us.Add(e)
+	}
+	us.position = j.Position
+	v.undoStack = us
+	return true
+}
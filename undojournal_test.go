@@ -0,0 +1,133 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package lime
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jxo/lime/text"
+)
+
+func TestUndoJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lime-undo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ed := GetEditor()
+	ed.SetFrontend(&dummyFrontend{})
+	ed.SetLocalPath(filepath.Join(dir, "Local"))
+	defer ed.SetLocalPath("")
+	ed.Settings().Set("persistent_undo", true)
+	defer ed.Settings().Erase("persistent_undo")
+
+	file := filepath.Join(dir, "test.txt")
+	if err := ioutil.WriteFile(file, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	v := w.OpenFile(file, 0)
+	e := v.BeginEdit()
+	v.Insert(e, 5, " world")
+	v.EndEdit(e)
+	e = v.BeginEdit()
+	v.Replace(e, text.Region{A: 0, B: 1}, "H")
+	v.EndEdit(e)
+	e = v.BeginEdit()
+	v.Erase(e, text.Region{A: 5, B: 11})
+	v.EndEdit(e)
+	v.UndoStack().Undo(true)
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	v.Close()
+
+	v = w.OpenFile(file, 0)
+	if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != "Hello world" {
+		t.Fatalf("Expected %q, but got %q", "Hello world", s)
+	}
+	if p := v.UndoStack().Position(); p != 2 {
+		t.Errorf("Expected the undo position 2, but got %d", p)
+	}
+	tests := []struct {
+		undo bool
+		exp  string
+	}{
+		{false, "Hello"},
+		{true, "Hello world"},
+		{true, "hello world"},
+		{true, "hello"},
+		{true, "hello"},
+	}
+	for i, test := range tests {
+		if test.undo {
+			v.UndoStack().Undo(true)
+		} else {
+			v.UndoStack().Redo(true)
+		}
+		if s := v.Substr(text.Region{A: 0, B: v.Size()}); s != test.exp {
+			t.Errorf("Test %d: Expected %q, but got %q", i, test.exp, s)
+		}
+	}
+	v.SetScratch(true)
+	v.Close()
+
+	// the journal isn't restored once the file changed
+	if err := ioutil.WriteFile(file, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v = w.OpenFile(file, 0)
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	if p := v.UndoStack().Position(); p != 0 {
+		t.Errorf("Expected an empty undo stack, but got position %d", p)
+	}
+}
+
+func TestUndoJournalLimit(t *testing.T) {
+	edits := func(n int) []journalEdit {
+		ret := make([]journalEdit, n)
+		for i := range ret {
+			ret[i].Command = string(rune('a' + i))
+		}
+		return ret
+	}
+	// the size taken by n edits in the journal
+	data, _ := json.Marshal(journalEdit{Command: "a"})
+	size := func(n int) int {
+		return n * (len(data) + 1)
+	}
+	tests := []struct {
+		edits, position, size int
+		exp                   string
+		expPosition           int
+	}{
+		{4, 4, 0, "abcd", 4},
+		{4, 4, size(2), "cd", 2},
+		{4, 2, size(3), "abc", 2},
+		{4, 2, size(1), "b", 1},
+		{4, 0, size(2), "ab", 0},
+		{4, 4, 1, "", 0},
+	}
+	for i, test := range tests {
+		j := undoJournal{Position: test.position, Edits: edits(test.edits)}
+		j.limit(test.size)
+		got := ""
+		for _, e := range j.Edits {
+			got += e.Command
+		}
+		if got != test.exp || j.Position != test.expPosition {
+			t.Errorf("Test %d: Expected %s at %d, but got %s at %d", i, test.exp, test.expPosition, got, j.Position)
+		}
+	}
+}
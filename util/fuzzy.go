@@ -0,0 +1,118 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Fuzzy match scores
+const (
+	fuzzyMatch       = 16 // every matched character
+	fuzzyFirst       = 12 // match at the start of the string
+	fuzzyWordStart   = 10 // match after a separator
+	fuzzyCamelCase   = 8  // match of an upper case letter after a lower case one
+	fuzzyConsecutive = 8  // match right after the previous one
+	fuzzyLeadingGap  = 2  // every unmatched character before the first match
+	fuzzyGap         = 1  // every unmatched character between two matches
+	fuzzyMaxLeading  = 3  // max number of leading characters penalized
+)
+
+// FuzzyMatch matches the pattern against s the way the command palette
+// does: all the characters of the pattern, but spaces, must appear in s in
+// the same order ignoring case. Returns the score of the best match, the
+// higher the better, the rune indices in s of the matched characters and
+// whether s matched at all. Matches at word starts and consecutive
+// matches score better.
+func FuzzyMatch(pattern, s string) (score int, matches []int, ok bool) {
+	var p []rune
+	for _, c := range strings.ToLower(pattern) {
+		if !unicode.IsSpace(c) {
+			p = append(p, c)
+		}
+	}
+	r := []rune(s)
+	m, n := len(p), len(r)
+	if m == 0 {
+		return 0, nil, true
+	}
+	if m > n {
+		return 0, nil, false
+	}
+	lr := make([]rune, n)
+	for i, c := range r {
+		lr[i] = unicode.ToLower(c)
+	}
+
+	// best[i][j] is the best score matching p[:i+1] with p[i] at r[j],
+	// from[i][j] is where p[i-1] is matched for that score
+	const none = -1 << 30
+	best := make([][]int, m)
+	from := make([][]int, m)
+	for i := range best {
+		best[i] = make([]int, n)
+		from[i] = make([]int, n)
+		// the best score of the previous row up to j-2 with the gap
+		// penalty up to the start of the row added back, and its index
+		pmax, pidx := none, -1
+		for j := 0; j < n; j++ {
+			if i > 0 && j >= 2 && best[i-1][j-2] != none && best[i-1][j-2]+fuzzyGap*(j-2) > pmax {
+				pmax, pidx = best[i-1][j-2]+fuzzyGap*(j-2), j-2
+			}
+			best[i][j] = none
+			if lr[j] != p[i] {
+				continue
+			}
+			sc := fuzzyMatch + fuzzyBonus(r, j)
+			if i == 0 {
+				lead := j
+				if lead > fuzzyMaxLeading {
+					lead = fuzzyMaxLeading
+				}
+				best[i][j] = sc - fuzzyLeadingGap*lead
+				continue
+			}
+			prev, k := pmax-fuzzyGap*(j-1), pidx
+			if j >= 1 && best[i-1][j-1] != none && best[i-1][j-1]+fuzzyConsecutive >= prev {
+				prev, k = best[i-1][j-1]+fuzzyConsecutive, j-1
+			}
+			if k == -1 {
+				continue
+			}
+			best[i][j], from[i][j] = sc+prev, k
+		}
+	}
+
+	end := -1
+	for j := 0; j < n; j++ {
+		if b := best[m-1][j]; b != none && (end == -1 || b > best[m-1][end]) {
+			end = j
+		}
+	}
+	if end == -1 {
+		return 0, nil, false
+	}
+	score = best[m-1][end]
+	matches = make([]int, m)
+	for i, j := m-1, end; i >= 0; i-- {
+		matches[i] = j
+		j = from[i][j]
+	}
+	return score, matches, true
+}
+
+func fuzzyBonus(r []rune, j int) int {
+	if j == 0 {
+		return fuzzyFirst
+	}
+	switch prev := r[j-1]; {
+	case unicode.IsSpace(prev) || unicode.IsPunct(prev) || unicode.IsSymbol(prev):
+		return fuzzyWordStart
+	case unicode.IsLower(prev) && unicode.IsUpper(r[j]):
+		return fuzzyCamelCase
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 The lime Authors.
+// Use of this source code is governed by a 2-clause
+// BSD-style license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		ok         bool
+		matches    []int
+	}{
+		{"", "anything", true, nil},
+		{"tc", "Toggle Comment", true, []int{0, 7}},
+		{"TOGGLE", "Toggle Comment", true, []int{0, 1, 2, 3, 4, 5}},
+		{"sl", "Sort Lines", true, []int{0, 5}},
+		{"s l", "Sort Lines", true, []int{0, 5}},
+		{"fb", "FooBar", true, []int{0, 3}},
+		{"line", "Goto Line", true, []int{5, 6, 7, 8}},
+		{"lx", "Goto Line", false, nil},
+		{"toolong", "tool", false, nil},
+	}
+	for i, test := range tests {
+		_, matches, ok := FuzzyMatch(test.pattern, test.s)
+		if ok != test.ok {
+			t.Errorf("Test %d: Expected %q to match %q: %v, but got %v", i, test.pattern, test.s, test.ok, ok)
+		} else if !reflect.DeepEqual(matches, test.matches) {
+			t.Errorf("Test %d: Expected matches %v, but got %v", i, test.matches, matches)
+		}
+	}
+}
+
+func TestFuzzyMatchScore(t *testing.T) {
+	// each pattern should score better on the first string
+	tests := []struct {
+		pattern, better, worse string
+	}{
+		{"sl", "Sort Lines", "Set Syntax: Lua"},
+		{"line", "Goto Line", "Sublime: Inline"},
+		{"cc", "Change Case", "Toggle Comment"},
+		{"fb", "FooBar", "Foobar"},
+		{"go", "Goto Line", "Set Syntax: Go"},
+	}
+	for i, test := range tests {
+		b, _, _ := FuzzyMatch(test.pattern, test.better)
+		w, _, _ := FuzzyMatch(test.pattern, test.worse)
+		if b <= w {
+			t.Errorf("Test %d: Expected %q to score better on %q (%d) than on %q (%d)", i, test.pattern, test.better, b, test.worse, w)
+		}
+	}
+}
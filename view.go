@@ -10,8 +10,8 @@ import (
 	"os"
 	"path"
 	"reflect"
-	"regexp"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
 
@@ -20,6 +20,7 @@ import (
 	"github.com/jxo/lime/parser"
 	"github.com/jxo/lime/render"
 	"github.com/jxo/lime/rubex"
+	"github.com/jxo/lime/selector"
 	"github.com/jxo/lime/text"
 	"github.com/jxo/lime/util"
 )
@@ -36,11 +37,19 @@ type View struct {
 	undoStack        UndoStack
 	scratch          bool
 	overwrite        bool
+	readOnly         bool
+	loading          bool
+	whenLoaded       []func()
+	loadDone         chan struct{} // closed once the file is loaded or the loading stopped
 	cursyntax        string
 	syntax           parser.SyntaxHighlighter
 	regions          render.ViewRegionMap
+	autoComplete     *QuickPanel
+	completionWords  *viewWords
+	snippet          *snippetFields
 	editstack        []*Edit
 	lock             sync.Mutex
+	loadLock         sync.Mutex // held while inserting a chunk of the file being loaded
 	reparseChan      chan parseReq
 	status           map[string]string
 	defaultSettings  *util.HasSettings
@@ -123,14 +132,20 @@ func (v *View) Inserted(changed_buffer text.Buffer, region_inserted text.Region,
 // triggers the "OnModified" event, and adds a reparse request
 // to the parse go-routine.
 func (v *View) flush(position, delta int) {
+	loading := false
 	func() {
 		v.lock.Lock()
 		defer v.lock.Unlock()
+		loading = v.loading
 
 		e := util.Prof.Enter("view.flush")
 		defer e.Exit()
 		// TODO(.): issue #211
-		v.selection.Adjust(position, delta)
+		// the file being loaded is appended to the buffer, the selection
+		// stays where it was set
+		if !loading {
+			v.selection.Adjust(position, delta)
+		}
 		if v.syntax != nil {
 			v.syntax.Adjust(position, delta)
 		}
@@ -138,9 +153,15 @@ func (v *View) flush(position, delta int) {
 			v2.Regions.Adjust(position, delta)
 			v.regions[k] = v2
 		}
+		if v.snippet != nil {
+			v.snippet.adjust(position, delta)
+		}
 	}()
 	OnModified.Call(v)
-	v.reparse(false)
+	// the file being loaded is parsed once it's done
+	if !loading {
+		v.reparse(false)
+	}
 }
 
 // parsethread() would be the go-routine used for dealing with reparsing the
@@ -164,7 +185,15 @@ func (v *View) flush(position, delta int) {
 func (v *View) parsethread() {
 	pc := 0
 	lastParse := -1
-	doparse := func() (ret bool) {
+	var (
+		// Parser of the last parse when it's able to only reparse
+		// the changed part of the buffer
+		inc       parser.IncrementalParser
+		incSyntax string
+		// Whether the syntax regions of the view are those of the last parse
+		synced bool
+	)
+	doparse := func(forced bool) (ret bool) {
 		p := util.Prof.Enter("syntax.parse")
 		defer p.Exit()
 		defer func() {
@@ -179,12 +208,32 @@ func (v *View) parsethread() {
 
 		data := v.Substr(text.Region{0, v.Size()})
 		syntax := v.Settings().String("syntax", "")
-		sh := syntaxHighlighter(syntax, data)
+		var (
+			sh    parser.SyntaxHighlighter
+			root  *parser.Node
+			dirty text.Region
+		)
+		if inc != nil && !forced && syntax == incSyntax {
+			var err error
+			if root, dirty, err = inc.Reparse([]rune(data)); err != nil {
+				log.Error("Couldn't reparse: %s", err)
+				inc, root = nil, nil
+			} else {
+				sh = parser.NewNodeHighlighter(root)
+			}
+		}
+		if root == nil {
+			var pr parser.Parser
+			sh, pr = syntaxHighlighter(syntax, data)
+			inc, _ = pr.(parser.IncrementalParser)
+			incSyntax, synced = syntax, false
+		}
 
 		// Only set if it isn't invalid already, otherwise the
 		// current syntax highlighting will be more accurate
 		// as it will have had incremental adjustments done to it
 		if v.ChangeCount() != lastParse {
+			synced = false
 			return
 		}
 
@@ -192,6 +241,11 @@ func (v *View) parsethread() {
 		defer v.lock.Unlock()
 
 		v.syntax = sh
+		if root != nil && synced {
+			// Only the reparsed part of the regions has changed
+			v.patchSyntaxRegions(root, dirty)
+			return true
+		}
 		for k := range v.regions {
 			if strings.HasPrefix(k, "lime.syntax") {
 				delete(v.regions, k)
@@ -203,6 +257,7 @@ func (v *View) parsethread() {
 				v.regions[k] = v2
 			}
 		}
+		synced = true
 
 		return true
 	}
@@ -218,13 +273,49 @@ func (v *View) parsethread() {
 	for pr := range ch {
 		if cc := v.ChangeCount(); lastParse != cc || pr.forced {
 			lastParse = cc
-			if doparse() {
+			if doparse(pr.forced) {
 				v.Settings().Set("lime.syntax.updated", lastParse)
 			}
 		}
 	}
 }
 
+// Replaces the syntax regions within "dirty" with the scopes of "root".
+//
+// Note that it's presumed that the function calling this function
+// has locked the view!
+func (v *View) patchSyntaxRegions(root *parser.Node, dirty text.Region) {
+	if dirty.Empty() {
+		return
+	}
+	for k, v2 := range v.regions {
+		if !strings.HasPrefix(k, "lime.syntax") {
+			continue
+		}
+		v2.Regions.Subtract(dirty)
+		if v2.Regions.HasNonEmpty() {
+			v.regions[k] = v2
+		} else {
+			delete(v.regions, k)
+		}
+	}
+	for k, v2 := range parser.FlattenRegion(root, dirty) {
+		reg, ok := v.regions[k]
+		if !ok {
+			reg = v2
+		} else {
+			for _, r := range v2.Regions.Regions() {
+				if !r.Empty() {
+					reg.Regions.Add(r)
+				}
+			}
+		}
+		if reg.Regions.HasNonEmpty() {
+			v.regions[k] = reg
+		}
+	}
+}
+
 // Send a reparse request via the reparse channel.
 // If "forced" is set to true, then a reparse will be made
 // even if the Buffer appears to not have changed.
@@ -296,22 +387,48 @@ func (v *View) ExtractScope(point int) text.Region {
 
 // ScoreSelector() takes a point and a selector string and returns a score
 // as to how good that specific selector matches the scope name at
-// that point.
-func (v *View) ScoreSelector(point int, selector string) int {
-	// TODO(.): The algorithm to determine the score has not been compared
-	// to what ST3 actually does. Not sure if any plugin I personally
-	// care about uses this functionality, and if it does if it requires
-	// specific scores to be returned.
-	//
-	// The overall implementation should be fine as a higher score is
-	// returned the more specific a selector is due to the innermost
-	// scope name being concatenated last in the string returned by ScopeName
+// that point, 0 meaning it doesn't match. See the selector package for
+// the selector syntax and scoring.
+func (v *View) ScoreSelector(point int, sel string) int {
 	if sn := v.ScopeName(point); len(sn) > 0 {
-		return 1 + strings.Index(sn, selector)
+		return selector.Score(sel, sn)
 	}
 	return 0
 }
 
+// MatchSelector returns whether the selector matches the scope name
+// at point.
+func (v *View) MatchSelector(point int, sel string) bool {
+	return v.ScoreSelector(point, sel) > 0
+}
+
+// FindBySelector returns the regions of the text which syntax scope
+// matches the selector, sorted and with the adjacent ones joined.
+func (v *View) FindBySelector(sel string) []text.Region {
+	var rs []text.Region
+	v.lock.Lock()
+	for k, vr := range v.regions {
+		if strings.HasPrefix(k, "lime.syntax") && selector.Match(sel, strings.TrimPrefix(vr.Scope, "lime.syntax ")) {
+			rs = append(rs, vr.Regions.Regions()...)
+		}
+	}
+	v.lock.Unlock()
+
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Begin() < rs[j].Begin() })
+	var ret []text.Region
+	for _, r := range rs {
+		if r.Empty() {
+			continue
+		}
+		if n := len(ret) - 1; n >= 0 && r.Begin() <= ret[n].End() {
+			ret[n].B = text.Max(ret[n].B, r.End())
+		} else {
+			ret = append(ret, text.Region{A: r.Begin(), B: r.End()})
+		}
+	}
+	return ret
+}
+
 // Sel() returns a pointer to the RegionSet used by this View
 // to mark possibly multiple cursor positions and selection
 // regions.
@@ -340,8 +457,12 @@ func (v *View) Window() *Window {
 
 // Inserts text at the given position in the provided edit object.
 // Tabs are (sometimes, depending on the View's settings) translated to spaces.
-// The return value is the length of the string that was inserted.
+// The return value is the length of the string that was inserted, 0 if
+// the view can't be modified there.
 func (v *View) Insert(edit *Edit, point int, value string) int {
+	if !v.canModify(text.Region{A: point, B: point}) {
+		return 0
+	}
 	if t := v.Settings().Bool("translate_tabs_to_spaces", false); t && strings.Contains(value, "\t") {
 		tab_size := v.Settings().Int("tab_size", 4)
 		lines := strings.Split(value, "\n")
@@ -376,13 +497,23 @@ func (v *View) Insert(edit *Edit, point int, value string) int {
 }
 
 // Adds an Erase action of the given Region to the provided Edit object.
-func (v *View) Erase(edit *Edit, r text.Region) {
+// Returns false if the region can't be modified.
+func (v *View) Erase(edit *Edit, r text.Region) bool {
+	if !v.canModify(r) {
+		return false
+	}
 	edit.composite.AddExec(text.NewEraseAction(v.buffer, r))
+	return true
 }
 
 // Adds a Replace action of the given Region to the provided Edit object.
-func (v *View) Replace(edit *Edit, r text.Region, value string) {
+// Returns false if the region can't be modified.
+func (v *View) Replace(edit *Edit, r text.Region, value string) bool {
+	if !v.canModify(r) {
+		return false
+	}
 	edit.composite.AddExec(text.NewReplaceAction(v.buffer, r, value))
+	return true
 }
 
 // Creates a new Edit object. Think of it a bit like starting an SQL transaction.
@@ -519,13 +650,13 @@ func (v *View) FileChanged(filename string) {
 		return
 	}
 
-	if d, err := ioutil.ReadFile(filename); err != nil {
+	if d, enc, le, err := v.readFile(filename, ""); err != nil {
 		log.Error("Could not read file: %s\n. Error was: %v", filename, err)
 	} else {
-		edit := v.BeginEdit()
-		end := v.Size()
-		v.Replace(edit, text.Region{0, end}, string(d))
-		v.EndEdit(edit)
+		v.setContent(d)
+		v.Settings().Set("encoding", enc)
+		v.Settings().Set("line_endings", le)
+		v.saveUndoJournal()
 	}
 }
 
@@ -537,6 +668,9 @@ func (v *View) Save() error {
 // Saves the file to the specified filename
 func (v *View) SaveAs(name string) (err error) {
 	log.Fine("SaveAs(%s)", name)
+	if v.IsLoading() {
+		return fmt.Errorf("Can't save %s while it's loading", v.FileName())
+	}
 	v.Settings().Set("lime.saving", true)
 	defer v.Settings().Erase("lime.saving")
 	OnPreSave.Call(v)
@@ -551,6 +685,7 @@ func (v *View) SaveAs(name string) (err error) {
 		}
 		tmpf := path.Join(n, "tmp")
 		if err := v.nonAtomicSave(tmpf); err != nil {
+			os.RemoveAll(n)
 			return err
 		}
 		if err := os.Rename(tmpf, name); err != nil {
@@ -558,6 +693,7 @@ func (v *View) SaveAs(name string) (err error) {
 			// we can't go with os.Rename so we need to force
 			// not atomic saving sometimes as 4th test in TestSaveAsOpenFile
 			if err := v.nonAtomicSave(name); err != nil {
+				os.RemoveAll(n)
 				return err
 			}
 		}
@@ -576,12 +712,17 @@ func (v *View) SaveAs(name string) (err error) {
 	}
 
 	v.Settings().Set("lime.last_save_change_count", v.ChangeCount())
+	v.saveUndoJournal()
 	OnPostSave.Call(v)
 	return nil
 }
 
 func (v *View) nonAtomicSave(name string) error {
-	data := []byte(v.Substr(text.Region{0, v.Size()}))
+	s := convertLineEndings(v.Substr(text.Region{A: 0, B: v.Size()}), v.LineEndings())
+	data, err := encodeText(s, v.Encoding())
+	if err != nil {
+		return fmt.Errorf("Couldn't save %s: %s", name, err)
+	}
 	if err := ioutil.WriteFile(name, data, 0644); err != nil {
 		return err
 	}
@@ -706,7 +847,10 @@ func (v *View) Close() bool {
 		if !close_anyway {
 			return false
 		}
+	} else if !v.IsLoading() {
+		v.saveUndoJournal()
 	}
+	v.stopLoading()
 	if n := v.FileName(); n != "" {
 		GetEditor().UnWatch(n, v)
 	}
@@ -924,33 +1068,6 @@ func (v *View) ExpandByClass(r text.Region, classes int) text.Region {
 	return text.Region{a, b}
 }
 
-const (
-	LITERAL = 1 << iota
-	IGNORECASE
-)
-
-func (v *View) Find(pat string, pos int, flags int) text.Region {
-	r := text.Region{pos, v.Size()}
-	s := v.Substr(r)
-
-	if flags&LITERAL != 0 {
-		pat = "\\Q" + pat
-	}
-	if flags&IGNORECASE != 0 {
-		pat = "(?im)" + pat
-	} else {
-		pat = "(?m)" + pat
-	}
-	// Using regexp instead of rubex because rubex doesn't
-	// support flag for treating pattern as a literal text
-	if re, err := regexp.Compile(pat); err != nil {
-		log.Error(err)
-	} else if loc := re.FindStringIndex(s); loc != nil {
-		return text.Region{pos + loc[0], pos + loc[1]}
-	}
-	return text.Region{-1, -1}
-}
-
 func (v *View) Status() map[string]string {
 	m := make(map[string]string)
 	v.lock.Lock()
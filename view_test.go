@@ -10,6 +10,8 @@ import (
 	"math/rand"
 	"os"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -839,6 +841,114 @@ func TestTransform(t *testing.T) {
 	}
 }
 
+func TestViewIncrementalParse(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+
+	const syntax = "testdata/Go.tmLanguage"
+	addSetSyntax(t, v.Settings(), syntax)
+	d, err := ioutil.ReadFile("testdata/code.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// waits until the syntax is updated for the last change
+	wait := func(i int) {
+		for start := time.Now(); v.Settings().Int("lime.syntax.updated", -1) != v.ChangeCount(); {
+			if time.Since(start) > 10*time.Second {
+				t.Fatalf("Test %d: Waiting for 'lime.syntax.updated' took too long", i)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	e := v.BeginEdit()
+	v.Insert(e, 0, string(d))
+	v.EndEdit(e)
+	wait(-1)
+
+	tests := []struct {
+		pos int
+		del int
+		ins string
+	}{
+		{0, 0, "// "},
+		{150, 0, "\"unterminated\n"},
+		{150, 14, ""},
+		{300, 0, "/* comment\n*/"},
+		{v.Size(), 0, "\nfunc main() {}\n"},
+		{42, 10, ""},
+	}
+	for i, test := range tests {
+		e := v.BeginEdit()
+		if test.del > 0 {
+			v.Erase(e, text.Region{A: test.pos, B: test.pos + test.del})
+		}
+		if test.ins != "" {
+			v.Insert(e, test.pos, test.ins)
+		}
+		v.EndEdit(e)
+		wait(i)
+
+		sh, _ := syntaxHighlighter(syntax, v.Substr(text.Region{A: 0, B: v.Size()}))
+		exp := sh.Flatten()
+		want := make(map[string][]text.Region)
+		for k, vr := range exp {
+			if rs := normalize(vr.Regions.Regions()); len(rs) != 0 {
+				want[k] = rs
+			}
+		}
+		got := make(map[string][]text.Region)
+		v.lock.Lock()
+		for k, vr := range v.regions {
+			if !strings.HasPrefix(k, "lime.syntax") {
+				continue
+			}
+			if rs := normalize(vr.Regions.Regions()); len(rs) != 0 {
+				got[k] = rs
+			}
+		}
+		v.lock.Unlock()
+		// no stale regions nor keys may be left by the reparse
+		for k, rs := range want {
+			if !reflect.DeepEqual(got[k], rs) {
+				t.Errorf("Test %d: Expected %s regions to be %v, but got %v", i, k, rs, got[k])
+			}
+		}
+		for k, rs := range got {
+			if _, ok := want[k]; !ok {
+				t.Errorf("Test %d: Unexpected %s regions %v", i, k, rs)
+			}
+		}
+	}
+}
+
+// Returns the sorted non empty regions, merging the adjacent ones.
+func normalize(rs []text.Region) (ret []text.Region) {
+	sort.Sort(regionsByBegin(rs))
+	for _, r := range rs {
+		if r.Empty() {
+			continue
+		}
+		if l := len(ret) - 1; l >= 0 && ret[l].B >= r.A {
+			ret[l].B = text.Max(ret[l].B, r.B)
+			continue
+		}
+		ret = append(ret, r)
+	}
+	return
+}
+
+type regionsByBegin []text.Region
+
+func (r regionsByBegin) Len() int           { return len(r) }
+func (r regionsByBegin) Less(i, j int) bool { return r[i].A < r[j].A }
+func (r regionsByBegin) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
 // This is not 100% what ST3 does
 func TestViewExtractScope(t *testing.T) {
 	w := GetEditor().NewWindow()
@@ -1082,3 +1192,79 @@ func BenchmarkViewTransformTranscribe(b *testing.B) {
 	}
 	fmt.Println(util.Prof.String())
 }
+
+func TestViewScoreSelector(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	addSetSyntax(t, v.Settings(), "testdata/Symbols.tmLanguage")
+	e := v.BeginEdit()
+	v.Insert(e, 0, "def first\nother\ndef second\n")
+	v.EndEdit(e)
+	for start := time.Now(); v.Settings().Int("lime.syntax.updated", -1) != v.ChangeCount(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Waiting for 'lime.syntax.updated' took too long")
+		}
+	}
+
+	tests := []struct {
+		point    int
+		selector string
+		match    bool
+	}{
+		{5, "entity.name.function", true},
+		{5, "source meta entity", true},
+		{5, "source - meta", false},
+		{5, "storage, entity", true},
+		{1, "storage.type", true},
+		{1, "entity", false},
+		{12, "source - meta", true},
+		{12, "meta", false},
+	}
+	for i, test := range tests {
+		if m := v.MatchSelector(test.point, test.selector); m != test.match {
+			t.Errorf("Test %d: Expected %q matching at %d to be %t, but got %t", i, test.selector, test.point, test.match, m)
+		}
+	}
+	if a, b := v.ScoreSelector(5, "meta"), v.ScoreSelector(5, "entity.name"); a >= b {
+		t.Errorf("Expected the innermost scope to score higher, but got %d and %d", a, b)
+	}
+}
+
+func TestViewFindBySelector(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+	v := w.NewFile()
+	defer func() {
+		v.SetScratch(true)
+		v.Close()
+	}()
+	addSetSyntax(t, v.Settings(), "testdata/Symbols.tmLanguage")
+	e := v.BeginEdit()
+	v.Insert(e, 0, "def first\nother\ndef second\n")
+	v.EndEdit(e)
+	for start := time.Now(); v.Settings().Int("lime.syntax.updated", -1) != v.ChangeCount(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("Waiting for 'lime.syntax.updated' took too long")
+		}
+	}
+
+	tests := []struct {
+		selector string
+		exp      []text.Region
+	}{
+		{"entity.name.function", []text.Region{{A: 4, B: 9}, {A: 20, B: 26}}},
+		{"meta.function", []text.Region{{A: 0, B: 9}, {A: 16, B: 26}}},
+		{"source - meta", []text.Region{{A: 9, B: 16}}},
+		{"comment", nil},
+	}
+	for i, test := range tests {
+		if r := v.FindBySelector(test.selector); !reflect.DeepEqual(r, test.exp) {
+			t.Errorf("Test %d: Expected %v, but got %v", i, test.exp, r)
+		}
+	}
+}
@@ -52,7 +52,9 @@ type (
 )
 
 func NewWatcher() (*Watcher, error) {
-	w := &Watcher{fsEvent: make(chan notify.EventInfo, 5)}
+	// notify drops the events which don't fit in the channel, it's
+	// buffered for the bursts of removing or creating a whole tree
+	w := &Watcher{fsEvent: make(chan notify.EventInfo, 256)}
 	w.watched = make(map[string][]interface{})
 	w.watchers = make([]string, 0)
 	w.dirs = make([]string, 0)
@@ -218,6 +220,7 @@ func (w *Watcher) removeDir(name string) {
 			stat, err := os.Stat(p)
 			if err != nil {
 				log.Error("Stat error: %s", err)
+				continue
 			}
 			if err := w.watch(p, stat.IsDir()); err != nil {
 				log.Error("Could not watch: %s", err)
@@ -288,6 +291,18 @@ func (w *Watcher) parseEv(ev notify.EventInfo) {
 			}
 		}
 	}
+	// Same for files removed or renamed inside a watched directory,
+	// the FileRemoved callback of the directory is applied so file
+	// listings of the directory can be kept up to date
+	if cbs, exist := w.watched[dir]; ev.Event()&(notify.Remove|notify.Rename) != 0 && exist {
+		for _, cb := range cbs {
+			if c, ok := cb.(FileRemovedCallback); ok {
+				w.Unlock()
+				c.FileRemoved(path)
+				w.Lock()
+			}
+		}
+	}
 }
 
 func (w *Watcher) apply(path string, flags notify.Event) {
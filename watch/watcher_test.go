@@ -403,3 +403,25 @@ func TestRenameEvent(t *testing.T) {
 		t.Errorf("Expected dummy Text %s, but got %#v", "Renamed", d)
 	}
 }
+
+func TestDeleteEventInDir(t *testing.T) {
+	dir := "testdata"
+	name := filepath.Join(dir, "removed.txt")
+	absname, _ := filepath.Abs(name)
+	if err := ioutil.WriteFile(name, []byte(""), 0644); err != nil {
+		t.Fatalf("WriteFile error: %s", err)
+	}
+	defer os.Remove(name)
+	watcher := newWatcher(t)
+	defer watcher.Close()
+	d := newDummy(absname)
+	watch(t, watcher, dir, d)
+
+	if err := os.Remove(name); err != nil {
+		t.Fatalf("Couldn't remove file %s: %s", name, err)
+	}
+	d.Wait()
+	if !d.removed {
+		t.Errorf("Expected dummy Text %s, but got %#v", "Removed", d)
+	}
+}
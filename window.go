@@ -6,7 +6,7 @@ package lime
 
 import (
 	"fmt"
-	"io/ioutil"
+	"os"
 	"path/filepath"
 	"runtime/debug"
 	"sync"
@@ -16,14 +16,29 @@ import (
 	"github.com/jxo/lime/util"
 )
 
-type Window struct {
-	util.HasID
-	util.HasSettings
-	views       []*View
-	active_view *View
-	project     *Project
-	lock        sync.Mutex
-}
+type (
+	Window struct {
+		util.HasID
+		util.HasSettings
+		layout       Layout
+		groups       []group
+		active_group int
+		active_view  *View
+		panels       map[string]*View
+		active_panel string
+		overlay      *QuickPanel
+		project      *Project
+		findResults  *FindResults // the find in files search writing to the Find Results view
+		lock         sync.Mutex
+	}
+
+	// A group is a cell of the window layout holding its own
+	// ordered list of views.
+	group struct {
+		views  []*View
+		active *View
+	}
+)
 
 // implement the fmt.Stringer interface
 func (w *Window) String() string {
@@ -32,15 +47,15 @@ func (w *Window) String() string {
 
 func (w *Window) NewFile() *View {
 	w.lock.Lock()
-	defer w.lock.Unlock()
-
 	v := newView(w)
-	w.views = append(w.views, v)
+	g := &w.groupsLocked()[w.active_group]
+	g.views = append(g.views, v)
 
 	v.setBuffer(text.NewBuffer())
 	v.selection.Clear()
 	v.selection.Add(text.Region{A: 0, B: 0})
 	v.Settings().Set("lime.last_save_change_count", v.ChangeCount())
+	w.lock.Unlock()
 
 	w.SetActiveView(v)
 	OnNew.Call(v)
@@ -48,77 +63,328 @@ func (w *Window) NewFile() *View {
 	return v
 }
 
+// Returns the views of all the groups, in group order.
 func (w *Window) Views() []*View {
 	w.lock.Lock()
 	defer w.lock.Unlock()
-	ret := make([]*View, len(w.views))
-	copy(ret, w.views)
+	var ret []*View
+	for _, g := range w.groupsLocked() {
+		ret = append(ret, g.views...)
+	}
 	return ret
 }
 
 func (w *Window) remove(v *View) {
+	if w.removePanel(v) {
+		return
+	}
 	w.lock.Lock()
-	defer w.lock.Unlock()
-	for i, vv := range w.views {
-		if v == vv {
-			end := len(w.views) - 1
-			if i != end {
-				copy(w.views[i:], w.views[i+1:])
-			}
-			w.views = w.views[:end]
-			return
+	gi, i := w.indexLocked(v)
+	if gi == -1 {
+		w.lock.Unlock()
+		// the input panel views aren't children of the window
+		if !v.Settings().Bool("is_widget", false) {
+			log.Error("Wanted to remove view %s, but it doesn't appear to be a child of this window", v)
 		}
+		return
+	}
+	w.groups[gi].remove(i)
+	var next *View
+	active := w.active_view == v
+	if active {
+		w.active_view = nil
+		next = w.groups[w.active_group].active
+	}
+	w.lock.Unlock()
+
+	if next != nil {
+		w.SetActiveView(next)
 	}
-	log.Error("Wanted to remove view %s, but it doesn't appear to be a child of this window", v)
+}
+
+// Removes the view at index i, when it was the active one
+// the view taking its place is activated instead.
+func (g *group) remove(i int) {
+	v := g.views[i]
+	g.views = append(g.views[:i], g.views[i+1:]...)
+	if g.active != v {
+		return
+	}
+	g.active = nil
+	if i == len(g.views) {
+		i--
+	}
+	if i >= 0 {
+		g.active = g.views[i]
+	}
+}
+
+// FindOpenFile returns the view of the window which file is the given
+// one, or nil if the file isn't open.
+func (w *Window) FindOpenFile(filename string) *View {
+	if fn, err := filepath.Abs(filename); err == nil {
+		filename = fn
+	}
+	for _, v := range w.Views() {
+		if v.FileName() == filename {
+			return v
+		}
+	}
+	return nil
 }
 
 func (w *Window) OpenFile(filename string, flags int) *View {
 	v := w.NewFile()
 
 	v.SetScratch(true)
-	e := v.BeginEdit()
 	if fn, err := filepath.Abs(filename); err != nil {
 		v.SetFileName(filename)
 	} else {
 		v.SetFileName(fn)
 	}
-	if d, err := ioutil.ReadFile(filename); err != nil {
+	if fi, err := os.Stat(filename); err == nil {
+		if size := v.Settings().Int("async_load_size", defaultAsyncLoadSize); size > 0 && fi.Size() > int64(size) {
+			v.loadAsync(filename)
+			return v
+		}
+	}
+	e := v.BeginEdit()
+	if s, enc, le, err := v.readFile(filename, ""); err != nil {
 		log.Error("Couldn't load file %s: %s", filename, err)
 	} else {
-		v.Insert(e, 0, string(d))
+		v.Insert(e, 0, s)
+		v.Settings().Set("encoding", enc)
+		v.Settings().Set("line_endings", le)
 	}
 	v.EndEdit(e)
 	v.selection.Clear()
 	v.selection.Add(text.Region{A: 0, B: 0})
-	v.Settings().Set("lime.last_save_change_count", v.ChangeCount())
-	v.SetScratch(false)
-
-	OnLoad.Call(v)
+	v.loaded()
 
 	return v
 }
 
+// SetActiveView activates the view, focusing the group it's in.
 func (w *Window) SetActiveView(v *View) {
-	if w.active_view != nil {
-		OnDeactivated.Call(w.active_view)
+	w.lock.Lock()
+	if gi, _ := w.indexLocked(v); gi != -1 {
+		w.active_group = gi
+		w.groups[gi].active = v
 	}
+	prev := w.active_view
 	w.active_view = v
-	if w.active_view != nil {
-		OnActivated.Call(w.active_view)
+	w.lock.Unlock()
+
+	if prev != nil {
+		OnDeactivated.Call(prev)
+	}
+	if v != nil {
+		OnActivated.Call(v)
 	}
 }
 
 func (w *Window) ActiveView() *View {
+	w.lock.Lock()
+	defer w.lock.Unlock()
 	return w.active_view
 }
 
+// Layout returns a copy of the window layout.
+func (w *Window) Layout() Layout {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.groupsLocked()
+	return w.layout.clone()
+}
+
+// SetLayout changes the window layout, there's a group for each cell of
+// the layout. When there are less groups than before the views of the
+// removed groups are moved to the last group.
+func (w *Window) SetLayout(l Layout) error {
+	if err := l.Validate(); err != nil {
+		return err
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	groups := w.groupsLocked()
+	w.layout = l.clone()
+	n := len(l.Cells)
+	if n < len(groups) {
+		last := &groups[n-1]
+		for _, g := range groups[n:] {
+			last.views = append(last.views, g.views...)
+			if last.active == nil {
+				last.active = g.active
+			}
+		}
+		groups = groups[:n]
+	}
+	for len(groups) < n {
+		groups = append(groups, group{})
+	}
+	w.groups = groups
+	if w.active_group >= n {
+		w.active_group = n - 1
+	}
+	return nil
+}
+
+// MergeGroup removes the cell of the given group from the layout, moving
+// its views to the group that takes its space, or to the first group if
+// no cell could.
+func (w *Window) MergeGroup(group int) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	groups := w.groupsLocked()
+	if group < 0 || group >= len(groups) {
+		return fmt.Errorf("Invalid group: %d", group)
+	}
+	if len(groups) == 1 {
+		return fmt.Errorf("Can't merge the only group")
+	}
+	l, into := w.layout.Merge(group)
+	if into == -1 {
+		into = 0
+	}
+	g := groups[group]
+	groups = append(groups[:group], groups[group+1:]...)
+	dst := &groups[into]
+	dst.views = append(dst.views, g.views...)
+	if g.active != nil && (dst.active == nil || w.active_group == group) {
+		dst.active = g.active
+	}
+	if w.active_group == group {
+		w.active_group = into
+	} else if w.active_group > group {
+		w.active_group--
+	}
+	w.layout, w.groups = l, groups
+	return nil
+}
+
+// NumGroups returns the number of groups in the layout.
+func (w *Window) NumGroups() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.groupsLocked())
+}
+
+// ActiveGroup returns the index of the focused group.
+func (w *Window) ActiveGroup() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.groupsLocked()
+	return w.active_group
+}
+
+// FocusGroup focuses the group, activating its active view.
+func (w *Window) FocusGroup(group int) {
+	w.lock.Lock()
+	if group < 0 || group >= len(w.groupsLocked()) {
+		w.lock.Unlock()
+		log.Error("Tried to focus invalid group %d of %s", group, w)
+		return
+	}
+	w.active_group = group
+	v := w.groups[group].active
+	same := v == w.active_view
+	w.lock.Unlock()
+
+	if !same {
+		w.SetActiveView(v)
+	}
+}
+
+// ViewsInGroup returns the views of the group in tab order.
+func (w *Window) ViewsInGroup(group int) []*View {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if group < 0 || group >= len(w.groupsLocked()) {
+		return nil
+	}
+	ret := make([]*View, len(w.groups[group].views))
+	copy(ret, w.groups[group].views)
+	return ret
+}
+
+// ActiveViewInGroup returns the view that was last active in the group.
+func (w *Window) ActiveViewInGroup(group int) *View {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if group < 0 || group >= len(w.groupsLocked()) {
+		return nil
+	}
+	return w.groups[group].active
+}
+
+// GetViewIndex returns the group of the view and its index in
+// that group, or -1, -1 if the view isn't in this window.
+func (w *Window) GetViewIndex(v *View) (group, index int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.indexLocked(v)
+}
+
+// SetViewIndex moves the view to the given index of a group, the view
+// becomes the active one of that group.
+func (w *Window) SetViewIndex(v *View, group, index int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if group < 0 || group >= len(w.groupsLocked()) {
+		log.Error("Tried to move %s to invalid group %d of %s", v, group, w)
+		return
+	}
+	gi, i := w.indexLocked(v)
+	if gi == -1 {
+		log.Error("Tried to move view %s, but it doesn't appear to be a child of this window", v)
+		return
+	}
+	w.groups[gi].remove(i)
+	g := &w.groups[group]
+	index = text.Clamp(0, len(g.views), index)
+	g.views = append(g.views, nil)
+	copy(g.views[index+1:], g.views[index:])
+	g.views[index] = v
+	g.active = v
+	if w.active_view == v {
+		w.active_group = group
+	}
+}
+
+// Returns the window groups, creating the single group of
+// the default layout on first use. Must be called with the lock held.
+func (w *Window) groupsLocked() []group {
+	if w.groups == nil {
+		w.layout = defaultLayout()
+		w.groups = make([]group, 1)
+	}
+	return w.groups
+}
+
+func (w *Window) indexLocked(v *View) (int, int) {
+	for gi, g := range w.groupsLocked() {
+		for i, vv := range g.views {
+			if vv == v {
+				return gi, i
+			}
+		}
+	}
+	return -1, -1
+}
+
 // Closes the Window and all its Views.
 // Returns "true" if the Window closed successfully. Otherwise returns "false".
 func (w *Window) Close() bool {
+	w.cancelFindInFiles()
 	if !w.CloseAllViews() {
 		return false
 	}
+	w.destroyPanels()
+	if w.project != nil {
+		w.project.closeIndex()
+	}
 	GetEditor().remove(w)
+	OnCloseWindow.Call(w)
 
 	return true
 }
@@ -126,8 +392,8 @@ func (w *Window) Close() bool {
 // Closes all of the Window's Views.
 // Returns "true" if all the Views closed successfully. Otherwise returns "false".
 func (w *Window) CloseAllViews() bool {
-	for len(w.views) > 0 {
-		if !w.views[0].Close() {
+	for vs := w.Views(); len(vs) > 0; vs = w.Views() {
+		if !vs[0].Close() {
 			return false
 		}
 	}
@@ -6,6 +6,7 @@ package lime
 
 import (
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -199,3 +200,121 @@ func abs(path string) string {
 	abs, _ := filepath.Abs(path)
 	return abs
 }
+
+func TestWindowSetLayout(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	if w.NumGroups() != 1 {
+		t.Fatalf("Expected 1 group, but got %d", w.NumGroups())
+	}
+	v0 := w.NewFile()
+	defer v0.Close()
+
+	l := Layout{
+		Cols:  []float64{0, 0.5, 1},
+		Rows:  []float64{0, 1},
+		Cells: [][4]int{{0, 0, 1, 1}, {1, 0, 2, 1}},
+	}
+	if err := w.SetLayout(l); err != nil {
+		t.Fatal(err)
+	}
+	if w.NumGroups() != 2 {
+		t.Errorf("Expected 2 groups, but got %d", w.NumGroups())
+	}
+	if !reflect.DeepEqual(w.Layout(), l) {
+		t.Errorf("Expected layout %v, but got %v", l, w.Layout())
+	}
+
+	w.FocusGroup(1)
+	v1 := w.NewFile()
+	defer v1.Close()
+	if g, i := w.GetViewIndex(v1); g != 1 || i != 0 {
+		t.Errorf("Expected v1 at (1, 0), but got (%d, %d)", g, i)
+	}
+	if w.ActiveViewInGroup(0) != v0 || w.ActiveViewInGroup(1) != v1 {
+		t.Error("Expected each group to keep its own active view")
+	}
+
+	w.FocusGroup(0)
+	if w.ActiveView() != v0 {
+		t.Error("Expected focusing group 0 to activate v0")
+	}
+
+	if err := w.SetLayout(Layout{Cols: []float64{0, 1}, Rows: []float64{0, 1}, Cells: [][4]int{{0, 0, 2, 1}}}); err == nil {
+		t.Error("Expected an error for an invalid layout")
+	}
+	if err := w.SetLayout(defaultLayout()); err != nil {
+		t.Fatal(err)
+	}
+	if vs := w.ViewsInGroup(0); !reflect.DeepEqual(vs, []*View{v0, v1}) {
+		t.Errorf("Expected the views to be merged into group 0, but got %v", vs)
+	}
+}
+
+func TestWindowSetViewIndex(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	v0 := w.NewFile()
+	defer v0.Close()
+	v1 := w.NewFile()
+	defer v1.Close()
+	v2 := w.NewFile()
+	defer v2.Close()
+
+	w.SetViewIndex(v2, 0, 0)
+	if vs := w.ViewsInGroup(0); !reflect.DeepEqual(vs, []*View{v2, v0, v1}) {
+		t.Errorf("Expected [v2 v0 v1], but got %v", vs)
+	}
+
+	if err := w.SetLayout(defaultLayout().Split()); err != nil {
+		t.Fatal(err)
+	}
+	w.SetViewIndex(v2, 1, 0)
+	if w.ActiveGroup() != 1 {
+		t.Errorf("Expected the active group to follow the active view, but got %d", w.ActiveGroup())
+	}
+	if w.ActiveViewInGroup(0) != v0 {
+		t.Error("Expected v0 to become the active view of group 0")
+	}
+
+	if err := w.MergeGroup(1); err != nil {
+		t.Fatal(err)
+	}
+	if w.NumGroups() != 1 || len(w.ViewsInGroup(0)) != 3 {
+		t.Errorf("Expected a single group with 3 views, but got %d groups", w.NumGroups())
+	}
+	if w.ActiveGroup() != 0 || w.ActiveView() != v2 {
+		t.Error("Expected v2 to still be the active view after merging")
+	}
+
+	// merging the focused empty group keeps the active view of group 0
+	if err := w.SetLayout(defaultLayout().Split()); err != nil {
+		t.Fatal(err)
+	}
+	w.FocusGroup(1)
+	if err := w.MergeGroup(1); err != nil {
+		t.Fatal(err)
+	}
+	if w.ActiveViewInGroup(0) != v2 {
+		t.Error("Expected v2 to still be the active view of group 0 after merging an empty group")
+	}
+}
+
+func TestWindowRemoveActive(t *testing.T) {
+	w := GetEditor().NewWindow()
+	defer w.Close()
+
+	v0 := w.NewFile()
+	defer v0.Close()
+	v1 := w.NewFile()
+	v2 := w.NewFile()
+	defer v2.Close()
+
+	w.SetActiveView(v1)
+	v1.Close()
+	if w.ActiveView() != v2 {
+		t.Error("Expected the next view to be activated when closing the active one")
+	}
+}